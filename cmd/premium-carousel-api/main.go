@@ -1,14 +1,20 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
 
 	_ "github.com/lib/pq"
 	"github.com/mattes/migrate"
 	mpgsql "github.com/mattes/migrate/database/postgres"
 	_ "github.com/mattes/migrate/source/file"
 
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/domain"
 	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/infrastructure"
 	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/interfaces/handlers"
 	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/interfaces/loggers"
@@ -16,30 +22,52 @@ import (
 	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/usecases"
 )
 
+// configFileEnv is the environment variable with the path of the optional
+// yaml or json config file, its values are overridden by the environment
+const configFileEnv = "CONFIG_FILE"
+
+// etcdWatchRetry is the wait before watching the etcd settings again after
+// a failure
+const etcdWatchRetry = 10 * time.Second
+
 func main() { //nolint: funlen
 	var shutdownSequence = infrastructure.NewShutdownSequence()
 	var conf infrastructure.Config
 
+	if len(os.Args) > 1 && os.Args[1] == replayEventsCommand {
+		if err := infrastructure.Load(&conf, os.Getenv(configFileEnv)); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		if err := replayEvents(conf, os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", replayEventsCommand, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	fmt.Printf("Etag:%d\n", conf.BrowserCacheConf.InitEtag())
+	if err := infrastructure.Load(&conf, os.Getenv(configFileEnv)); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	shutdownSequence.SetTimeout(conf.ServiceConf.ShutdownTimeout)
 	shutdownSequence.Listen()
-	infrastructure.LoadFromEnv(&conf)
 
-	if jconf, err := json.MarshalIndent(conf, "", "    "); err == nil {
+	redacted := infrastructure.Redact(conf)
+	if jconf, err := json.MarshalIndent(redacted, "", "    "); err == nil {
 		fmt.Printf("Config: \n%s\n", jconf)
 	} else {
-		fmt.Printf("Config: \n%+v\n", conf)
+		fmt.Printf("Config: \n%+v\n", redacted)
 	}
 
 	fmt.Printf("Setting up Prometheus\n")
 
-	prometheus := infrastructure.MakePrometheusExporter(
-		conf.PrometheusConf.Port,
-		conf.PrometheusConf.Enabled,
-	)
+	prometheus := infrastructure.MakePrometheusExporter(conf.PrometheusConf.Enabled)
 
 	fmt.Printf("Setting up logger\n")
 
-	logger, err := infrastructure.MakeYapoLogger(&conf.LoggerConf,
+	logger, err := infrastructure.MakeLogger(&conf.LoggerConf,
 		prometheus.NewEventsCollector(
 			"premium_carousel_api_service_events_total",
 			"events tracker counter for premium-carousel-api service",
@@ -51,19 +79,40 @@ func main() { //nolint: funlen
 
 	}
 
-	shutdownSequence.Push(prometheus)
+	tracing, err := infrastructure.SetupTracing(conf.TracingConf, os.Stdout)
+	if err != nil {
+		panic(fmt.Errorf("error setting up tracing: %+v", err))
+	}
+	shutdownSequence.Push(tracing)
+	tracer := infrastructure.MakeTracer()
+	metrics := prometheus.NewBusinessMetrics()
+
 	logger.Info("Initializing resources")
 
-	regions, errorRegions := infrastructure.NewEtcd(
-		conf.EtcdConf.Host,
+	settings := infrastructure.MakeConfigReloader(conf, os.Getenv(configFileEnv), logger)
+	settings.ListenSignal()
+	shutdownSequence.Push(settings)
+	etcdClient, err := infrastructure.MakeEtcdClient(conf.EtcdConf, logger)
+	if err != nil {
+		panic(fmt.Errorf("error setting up etcd: %+v", err))
+	}
+	if conf.EtcdConf.SettingsPath != "" {
+		shutdownSequence.Push(watchSettings(conf.EtcdConf, etcdClient, logger, settings))
+	}
+
+	regions := infrastructure.LoadEtcdConfig(
+		etcdClient,
 		conf.EtcdConf.RegionPath,
-		conf.EtcdConf.Prefix,
+		conf.EtcdConf.SnapshotFile,
 		logger,
 	)
-
-	if errorRegions != nil {
-		panic("Unable to load regions remote config from etcd")
-	}
+	shutdownSequence.Push(infrastructure.PollEtcdKey(
+		etcdClient,
+		conf.EtcdConf.RegionPath,
+		conf.EtcdConf.RefreshInterval,
+		logger,
+		regions.Update,
+	))
 
 	redisHandler := infrastructure.NewRedisHandler(
 		conf.CacheConf.Host,
@@ -86,11 +135,12 @@ func main() { //nolint: funlen
 		conf.AdConf.Username,
 		conf.AdConf.Password,
 		logger,
+		prometheus.NewElasticsearchMetrics(),
 	)
-	var backendEventsProducer repository.KafkaProducer
-	var backendEventsRepository usecases.BackendEventsRepository
-	if conf.BackendEventsConf.Enabled {
-		backendEventsProducer, err = infrastructure.NewKafkaProducer(
+	var kafkaProducer repository.KafkaProducer
+	if conf.BackendEventsConf.Enabled || conf.PaymentsConsumerConf.Enabled ||
+		conf.AdEventsConsumerConf.Enabled {
+		kafkaProducer, err = infrastructure.NewKafkaProducer(
 			conf.KafkaProducerConf.Host,
 			conf.KafkaProducerConf.Port,
 			conf.KafkaProducerConf.Acks,
@@ -99,15 +149,14 @@ func main() { //nolint: funlen
 			conf.KafkaProducerConf.LingerMS,
 			conf.KafkaProducerConf.RequestTimeoutMS,
 			conf.KafkaProducerConf.EnableIdempotence,
+			conf.KafkaProducerConf.BufferSize,
+			conf.KafkaProducerConf.FlushTimeoutMS,
+			prometheus.NewKafkaProducerMetrics(),
 		)
 		if err != nil {
 			panic(fmt.Errorf("Error starting kafka producer: %+v", err))
 		}
-		shutdownSequence.Push(backendEventsProducer)
-		backendEventsRepository = repository.MakeBackendEventsProducer(
-			backendEventsProducer,
-			conf.BackendEventsConf.PremiumProductsTopic,
-		)
+		shutdownSequence.Push(kafkaProducer)
 	}
 
 	adRepo := repository.MakeAdRepository(
@@ -115,7 +164,7 @@ func main() { //nolint: funlen
 		regions,
 		conf.AdConf.Index,
 		conf.AdConf.ImageServerURL,
-		conf.AdConf.MaxAdsToDisplay,
+		loggers.MakeAdRepositoryLogger(logger),
 	)
 
 	cacheRepo := repository.NewCacheRepository(
@@ -123,15 +172,45 @@ func main() { //nolint: funlen
 		conf.CacheConf.Prefix,
 		conf.CacheConf.DefaultTTL,
 	)
+	if conf.LocalCacheConf.Enabled {
+		channel := conf.CacheConf.Prefix + ":" + conf.LocalCacheConf.Channel
+		twoTierCacheRepo := repository.NewTwoTierCacheRepository(
+			cacheRepo,
+			infrastructure.NewLocalCache(
+				conf.LocalCacheConf.MaxEntries,
+				conf.LocalCacheConf.MaxBytes,
+				prometheus.NewLocalCacheMetrics(),
+			),
+			redisHandler,
+			channel,
+			map[usecases.CacheType]time.Duration{
+				usecases.ProductCacheType:   conf.LocalCacheConf.ProductTTL,
+				usecases.MinifiedAdDataType: conf.LocalCacheConf.AdTTL,
+			},
+		)
+		shutdownSequence.Push(redisHandler.Subscribe(channel, twoTierCacheRepo.Invalidate))
+		cacheRepo = twoTierCacheRepo
+	}
+
+	idempotencyRepo := repository.MakeIdempotencyRepository(
+		redisHandler,
+		conf.CacheConf.Prefix,
+		conf.CacheConf.IdempotencyLockTTL,
+		conf.CacheConf.IdempotencyTTL,
+	)
 
-	purchaseRepo := repository.MakePurchaseRepository(
+	productRepo := repository.MakeProductRepository(
 		dbHandler,
+		conf.ControlPanelConf.ResultsPerPage,
+		loggers.MakeProductRepositoryLogger(logger),
 	)
+	prometheus.RegisterActiveProducts(productRepo, logger)
 
-	productRepo := repository.MakeProductRepository(
+	transactionRunner := repository.MakeTransactionRunner(
 		dbHandler,
 		conf.ControlPanelConf.ResultsPerPage,
 		loggers.MakeProductRepositoryLogger(logger),
+		conf.BackendEventsConf.PremiumProductsTopic,
 	)
 
 	getUserAdsInteractor := usecases.MakeGetUserAdsInteractor(
@@ -139,62 +218,225 @@ func main() { //nolint: funlen
 		productRepo,
 		cacheRepo,
 		loggers.MakeGetUserAdsLogger(logger),
-		conf.CacheConf.DefaultTTL,
-		conf.AdConf.MinAdsToDisplay,
+		transactionRunner,
+		conf.BackendEventsConf.Enabled,
+		tracer,
+		metrics,
 	)
 
 	getAdInteractor := usecases.MakeGetAdInteractor(
 		adRepo,
 		cacheRepo,
 		loggers.MakeGetAdLogger(logger),
-		conf.CacheConf.DefaultTTL,
+		settings,
+		tracer,
+		metrics,
 	)
 
 	addUserProductInteractor := usecases.MakeAddUserProductInteractor(
-		productRepo,
-		purchaseRepo,
+		transactionRunner,
 		cacheRepo,
 		loggers.MakeAddUserProductLogger(logger),
-		conf.CacheConf.DefaultTTL,
-		backendEventsRepository,
+		settings,
 		conf.BackendEventsConf.Enabled,
+		idempotencyRepo,
+		tracer,
 	)
 
 	setPartialConfigInteractor := usecases.MakeSetPartialConfigInteractor(
-		productRepo,
+		transactionRunner,
 		cacheRepo,
 		loggers.MakeSetPartialConfigLogger(logger),
-		conf.CacheConf.DefaultTTL,
+		settings,
+		conf.BackendEventsConf.Enabled,
+		tracer,
 	)
 
 	setConfigInteractor := usecases.MakeSetConfigInteractor(
-		productRepo,
+		transactionRunner,
 		cacheRepo,
 		loggers.MakeSetConfigLogger(logger),
-		conf.CacheConf.DefaultTTL,
+		settings,
+		conf.BackendEventsConf.Enabled,
+		tracer,
 	)
 
 	getUserProductsInteractor := usecases.MakeGetUserProductsInteractor(
 		productRepo,
 		loggers.MakeGetUserProductsLogger(logger),
+		tracer,
+	)
+
+	getUserProductInteractor := usecases.MakeGetUserProductInteractor(
+		productRepo,
+		loggers.MakeGetUserProductLogger(logger),
+		tracer,
 	)
 
 	getReportInteractor := usecases.MakeGetReportInteractor(
 		productRepo,
 		loggers.MakeGetReportLogger(logger),
+		tracer,
 	)
 
 	expireProductsInteractor := usecases.MakeExpireProductsInteractor(
 		productRepo,
+		cacheRepo,
 		loggers.MakeExpireProductsLogger(logger),
+		settings,
+		transactionRunner,
+		conf.BackendEventsConf.Enabled,
+		tracer,
 	)
 
+	activateProductsInteractor := usecases.MakeActivateProductsInteractor(
+		productRepo,
+		cacheRepo,
+		loggers.MakeActivateProductsLogger(logger),
+		settings,
+		transactionRunner,
+		conf.BackendEventsConf.Enabled,
+		tracer,
+	)
+
+	refreshProductsCacheInteractor := usecases.MakeRefreshProductsCacheInteractor(
+		productRepo,
+		cacheRepo,
+		loggers.MakeRefreshProductsCacheLogger(logger),
+		settings,
+		tracer,
+	)
+
+	evictAdInteractor := usecases.MakeEvictAdInteractor(
+		cacheRepo,
+		loggers.MakeEvictAdLogger(logger),
+		tracer,
+	)
+
+	var jobs handlers.JobTrigger
+	if conf.SchedulerConf.Enabled {
+		var elector infrastructure.LeaderElector
+		if conf.SchedulerConf.LeaderElection {
+			elector = infrastructure.MakePgsqlLeaderElector(dbHandler)
+		}
+		scheduler := infrastructure.MakeScheduler(elector, logger)
+		jobErrors := []error{
+			scheduler.Add("expire-products", conf.SchedulerConf.ExpireProducts,
+				expireProductsInteractor.ExpireProducts),
+			scheduler.Add("activate-products", conf.SchedulerConf.ActivateProducts,
+				activateProductsInteractor.ActivateProducts),
+			scheduler.Add("refresh-products-cache", conf.SchedulerConf.RefreshProductsCache,
+				refreshProductsCacheInteractor.RefreshProductsCache),
+		}
+		if conf.BackendEventsConf.Enabled {
+			reminderWindows, err := conf.BackendEventsConf.GetReminderWindows()
+			if err != nil {
+				panic(fmt.Errorf("error setting up reminders: %+v", err))
+			}
+			sendExpirationRemindersInteractor := usecases.MakeSendExpirationRemindersInteractor(
+				productRepo,
+				transactionRunner,
+				loggers.MakeSendExpirationRemindersLogger(logger),
+				reminderWindows,
+				tracer,
+			)
+			eventsPublisher := repository.MakeOutboxPublisher(kafkaProducer)
+			if conf.KafkaProducerConf.Async {
+				eventsPublisher = repository.MakeAsyncOutboxPublisher(kafkaProducer)
+			}
+			relayBackendEventsInteractor := usecases.MakeRelayBackendEventsInteractor(
+				repository.MakeOutboxRepository(dbHandler),
+				eventsPublisher,
+				loggers.MakeRelayBackendEventsLogger(logger),
+				conf.BackendEventsConf.RelayBatchSize,
+				conf.BackendEventsConf.RelayMaxBackoff,
+				conf.BackendEventsConf.OutboxRetention,
+				tracer,
+			)
+			jobErrors = append(jobErrors,
+				scheduler.Add("send-expiration-reminders",
+					conf.SchedulerConf.ExpirationReminders,
+					sendExpirationRemindersInteractor.SendExpirationReminders),
+				scheduler.Add("relay-backend-events",
+					conf.SchedulerConf.RelayBackendEvents,
+					relayBackendEventsInteractor.RelayBackendEvents),
+				scheduler.Add("purge-backend-events",
+					conf.SchedulerConf.PurgeBackendEvents,
+					relayBackendEventsInteractor.PurgeBackendEvents))
+		}
+		for _, err := range jobErrors {
+			if err != nil {
+				panic(fmt.Errorf("error setting up scheduler: %+v", err))
+			}
+		}
+		scheduler.Start()
+		shutdownSequence.Push(scheduler)
+		jobs = scheduler
+	}
+
+	if conf.PaymentsConsumerConf.Enabled {
+		plans, err := conf.PaymentsConsumerConf.GetPlans()
+		if err != nil {
+			panic(fmt.Errorf("error setting up payments consumer: %+v", err))
+		}
+		source, err := infrastructure.NewKafkaSource(
+			conf.PaymentsConsumerConf.Host,
+			conf.PaymentsConsumerConf.Port,
+			conf.PaymentsConsumerConf.GroupID,
+			conf.PaymentsConsumerConf.Topic,
+		)
+		if err != nil {
+			panic(fmt.Errorf("error starting payments consumer: %+v", err))
+		}
+		paymentsConsumer := infrastructure.MakeKafkaConsumer(
+			source,
+			&handlers.PaymentEventsHandler{
+				Interactor: addUserProductInteractor,
+				Plans:      plans,
+				DefaultConfig: domain.ProductParams{
+					Limit:              conf.PaymentsConsumerConf.DefaultLimit,
+					FillGapsWithRandom: conf.PaymentsConsumerConf.DefaultFillGapsWithRandom,
+				},
+				Now: time.Now,
+			},
+			kafkaProducer,
+			conf.PaymentsConsumerConf.DeadLetterTopic,
+			conf.PaymentsConsumerConf.PollTimeout,
+			conf.PaymentsConsumerConf.RetryBackoff,
+		)
+		paymentsConsumer.Start()
+		shutdownSequence.Push(paymentsConsumer)
+	}
+
+	if conf.AdEventsConsumerConf.Enabled {
+		source, err := infrastructure.NewKafkaSource(
+			conf.AdEventsConsumerConf.Host,
+			conf.AdEventsConsumerConf.Port,
+			conf.AdEventsConsumerConf.GroupID,
+			conf.AdEventsConsumerConf.Topic,
+		)
+		if err != nil {
+			panic(fmt.Errorf("error starting ad events consumer: %+v", err))
+		}
+		adEventsConsumer := infrastructure.MakeKafkaConsumer(
+			source,
+			&handlers.AdEventsHandler{
+				Interactor: evictAdInteractor,
+			},
+			kafkaProducer,
+			conf.AdEventsConsumerConf.DeadLetterTopic,
+			conf.AdEventsConsumerConf.PollTimeout,
+			conf.AdEventsConsumerConf.RetryBackoff,
+		)
+		adEventsConsumer.Start()
+		shutdownSequence.Push(adEventsConsumer)
+	}
+
 	// UserAdsHandler
 	getUserAdsHandler := handlers.GetUserAdsHandler{
-		Interactor:          getUserAdsInteractor,
-		GetAdInteractor:     getAdInteractor,
-		UnitOfAccountSymbol: conf.AdConf.UnitOfAccountSymbol,
-		CurrencySymbol:      conf.AdConf.CurrencySymbol,
+		Interactor:      getUserAdsInteractor,
+		GetAdInteractor: getAdInteractor,
+		Settings:        settings,
 	}
 
 	addUserProductHandler := handlers.AddUserProductHandler{
@@ -205,6 +447,10 @@ func main() { //nolint: funlen
 		Interactor: getUserProductsInteractor,
 	}
 
+	getUserProductHandler := handlers.GetUserProductHandler{
+		Interactor: getUserProductInteractor,
+	}
+
 	getReportHandler := handlers.GetReportHandler{
 		Interactor: getReportInteractor,
 	}
@@ -217,25 +463,44 @@ func main() { //nolint: funlen
 		Interactor: setConfigInteractor,
 	}
 
-	expireProductsHandler := handlers.ExpireProductsHandler{
-		Interactor: expireProductsInteractor,
-	}
-
 	// HealthHandler
 	var healthHandler handlers.HealthHandler
 
+	// Postgres and elasticsearch are needed to serve carousels, the cache
+	// and kafka failures are only reported
+	readinessHandler := handlers.ReadinessHandler{
+		Checks: []handlers.DependencyCheck{
+			{Name: "postgres", Checker: dbHandler, Critical: true},
+			{Name: "elasticsearch", Checker: elasticsearch, Critical: true},
+			{Name: "redis", Checker: redisHandler},
+		},
+		Timeout: conf.ServiceConf.HealthTimeout,
+	}
+	if checker, ok := kafkaProducer.(handlers.HealthChecker); ok {
+		readinessHandler.Checks = append(readinessHandler.Checks,
+			handlers.DependencyCheck{Name: "kafka", Checker: checker})
+	}
+	livenessHandler := handlers.LivenessHandler{}
+
 	useBrowserCache := handlers.Cache{
 		MaxAge:  conf.BrowserCacheConf.MaxAge,
 		Etag:    conf.BrowserCacheConf.Etag,
 		Enabled: conf.BrowserCacheConf.Enabled,
 	}
+	var authenticator infrastructure.Authenticator
+	if conf.AuthConf.Enabled {
+		authenticator, err = infrastructure.MakeAuthenticator(conf.AuthConf)
+		if err != nil {
+			panic(fmt.Errorf("error setting up authentication: %+v", err))
+		}
+	}
 	// Setting up router
 	maker := infrastructure.RouterMaker{
 		Logger:        logger,
 		Cors:          conf.CorsConf,
 		Cache:         useBrowserCache,
 		WrapperFuncs:  []infrastructure.WrapperFunc{prometheus.TrackHandlerFunc},
-		WithProfiling: conf.ServiceConf.Profiling,
+		Authenticator: authenticator,
 		Routes: infrastructure.Routes{
 			{
 				Groups: []infrastructure.Route{
@@ -245,6 +510,18 @@ func main() { //nolint: funlen
 						Pattern: "/healthcheck",
 						Handler: &healthHandler,
 					},
+					{
+						Name:    "Check service readiness",
+						Method:  "GET",
+						Pattern: "/readyz",
+						Handler: &readinessHandler,
+					},
+					{
+						Name:    "Check service liveness",
+						Method:  "GET",
+						Pattern: "/livez",
+						Handler: &livenessHandler,
+					},
 					{
 						Name:    "Get user ads",
 						Method:  "GET",
@@ -256,36 +533,42 @@ func main() { //nolint: funlen
 						Method:  "POST",
 						Pattern: "/assigns",
 						Handler: &addUserProductHandler,
+						Role:    handlers.EditorRole,
 					},
 					{
 						Name:    "Get user products",
 						Method:  "GET",
 						Pattern: "/assigns",
 						Handler: &getUserProductsHandler,
+						Role:    handlers.ReaderRole,
+					},
+					{
+						Name:    "Get user product",
+						Method:  "GET",
+						Pattern: "/assigns/{ID:[0-9]+}",
+						Handler: &getUserProductHandler,
+						Role:    handlers.ReaderRole,
 					},
 					{
 						Name:    "Set user product config",
 						Method:  "PUT",
 						Pattern: "/assigns/{ID:[0-9]+}",
 						Handler: &setConfigHandler,
+						Role:    handlers.EditorRole,
 					},
 					{
 						Name:    "Set partial user product config",
 						Method:  "PATCH",
 						Pattern: "/assigns/{ID:[0-9]+}",
 						Handler: &setPartialConfigHandler,
+						Role:    handlers.EditorRole,
 					},
 					{
 						Name:    "Get report",
 						Method:  "GET",
 						Pattern: "/report",
 						Handler: &getReportHandler,
-					},
-					{
-						Name:    "Expire products",
-						Method:  "GET",
-						Pattern: "/expire-products",
-						Handler: &expireProductsHandler,
+						Role:    handlers.ReaderRole,
 					},
 				},
 			},
@@ -294,12 +577,41 @@ func main() { //nolint: funlen
 
 	router := maker.NewRouter()
 
+	if conf.AdminConf.Enabled {
+		var adminAuthenticator infrastructure.Authenticator
+		if conf.AdminConf.AuthEnabled {
+			adminAuthenticator, err = infrastructure.MakeAuthenticator(conf.AuthConf)
+			if err != nil {
+				panic(fmt.Errorf("error setting up admin authentication: %+v", err))
+			}
+		}
+		adminServer := infrastructure.NewHTTPServer(
+			fmt.Sprintf("%s:%d", conf.AdminConf.Host, conf.AdminConf.Port),
+			makeAdminRouter(conf, logger, prometheus.Handler(), adminAuthenticator,
+				evictAdInteractor, jobs),
+			logger,
+		)
+		shutdownSequence.Push(adminServer)
+		go adminServer.ListenAndServe()
+	}
+
 	server := infrastructure.NewHTTPServer(
 		fmt.Sprintf("%s:%d", conf.Runtime.Host, conf.Runtime.Port),
 		router,
 		logger,
 	)
 	shutdownSequence.Push(server)
+	// The readiness fails first, and the server keeps serving until the pod
+	// is out of the service endpoints
+	shutdownSequence.Push(infrastructure.ShutdownFunc(func(ctx context.Context) error {
+		logger.Info("Draining requests")
+		readinessHandler.Drain()
+		select {
+		case <-time.After(conf.ServiceConf.DrainDelay):
+		case <-ctx.Done():
+		}
+		return nil
+	}))
 	logger.Info("Starting request serving")
 
 	go server.ListenAndServe()
@@ -307,6 +619,54 @@ func main() { //nolint: funlen
 	logger.Info("Server exited normally")
 }
 
+// makeAdminRouter builds the router of the admin server, serving the
+// profiling, metrics, cache and job endpoints. The job routes are only served
+// when jobs is set
+func makeAdminRouter(conf infrastructure.Config, logger loggers.Logger,
+	metrics http.Handler, authenticator infrastructure.Authenticator,
+	evictAdInteractor usecases.EvictAdInteractor, jobs handlers.JobTrigger) http.Handler {
+	routes := []infrastructure.Route{
+		{
+			Name:    "Evict cached ad",
+			Method:  "DELETE",
+			Pattern: "/cache/ads/{listID:[0-9]+}",
+			Handler: &handlers.EvictAdHandler{Interactor: evictAdInteractor},
+			Role:    handlers.AdminRole,
+		},
+	}
+	if jobs != nil {
+		routes = append(routes, infrastructure.Route{
+			Name:    "Run job",
+			Method:  "POST",
+			Pattern: "/jobs/{name}",
+			Handler: &handlers.RunJobHandler{Jobs: jobs},
+			Role:    handlers.AdminRole,
+		})
+	}
+	maker := infrastructure.RouterMaker{
+		Logger:        logger,
+		Cors:          conf.CorsConf,
+		WithProfiling: conf.ServiceConf.Profiling,
+		Metrics:       metrics,
+		DebugRole:     handlers.ReaderRole,
+		Authenticator: authenticator,
+		Routes:        infrastructure.Routes{{Groups: routes}},
+	}
+	return maker.NewRouter()
+}
+
+// watchSettings applies the settings of the etcd key at SettingsPath as they
+// change. The v2 api is long polled, the v3 one is read every RefreshInterval
+func watchSettings(conf infrastructure.EtcdConf, client infrastructure.EtcdClient,
+	logger loggers.Logger, settings *infrastructure.ConfigReloader) io.Closer {
+	if conf.Version == infrastructure.EtcdV2 {
+		return infrastructure.WatchEtcdKey(conf.Host, conf.Prefix, conf.SettingsPath,
+			etcdWatchRetry, logger, settings.Override)
+	}
+	return infrastructure.PollEtcdKey(client, conf.SettingsPath, conf.RefreshInterval,
+		logger, settings.Override)
+}
+
 // Autoexecute database migrations
 func setupMigrations(conf infrastructure.Config, dbHandler *infrastructure.PgsqlHandler, logger loggers.Logger) {
 	driver, err := mpgsql.WithInstance(dbHandler.Conn, &mpgsql.Config{})
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/infrastructure"
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/interfaces/loggers"
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/interfaces/repository"
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/usecases"
+)
+
+// replayEventsCommand is the name of the command that sends again the
+// backend events of the products sold in a date range
+const replayEventsCommand = "replay-events"
+
+// replayEventsArgs holds the parsed replay-events arguments
+type replayEventsArgs struct {
+	from   time.Time
+	to     time.Time
+	dryRun bool
+	rate   int
+}
+
+// parseReplayEventsArgs parses the replay-events arguments. Dates are given
+// as RFC3339 or YYYY-MM-DD, a day given as --to includes the whole day. The
+// only type supported is the sold product
+func parseReplayEventsArgs(args []string) (replayEventsArgs, error) {
+	parsed := replayEventsArgs{}
+	flags := flag.NewFlagSet(replayEventsCommand, flag.ContinueOnError)
+	from := flags.String("from", "", "start of the range, RFC3339 or YYYY-MM-DD")
+	to := flags.String("to", "", "end of the range, RFC3339 or YYYY-MM-DD including the whole day")
+	eventType := flags.String("type", string(repository.PremiumCarouselPurchase),
+		"type of the events to replay")
+	flags.BoolVar(&parsed.dryRun, "dry-run", false, "only log the events to replay")
+	flags.IntVar(&parsed.rate, "rate", 10, "max events sent per second, 0 for no limit")
+	if err := flags.Parse(args); err != nil {
+		return parsed, err
+	}
+	if *eventType != string(repository.PremiumCarouselPurchase) {
+		return parsed, fmt.Errorf("event type %q can't be replayed", *eventType)
+	}
+	var err error
+	if parsed.from, err = parseReplayDate(*from, false); err != nil {
+		return parsed, fmt.Errorf("bad --from: %v", err)
+	}
+	if parsed.to, err = parseReplayDate(*to, true); err != nil {
+		return parsed, fmt.Errorf("bad --to: %v", err)
+	}
+	if parsed.from.After(parsed.to) {
+		return parsed, fmt.Errorf("--from is after --to")
+	}
+	if parsed.rate < 0 {
+		return parsed, fmt.Errorf("--rate can't be negative")
+	}
+	return parsed, nil
+}
+
+// parseReplayDate parses a RFC3339 date or a YYYY-MM-DD day. A day is taken
+// as its start, or as its last microsecond when endOfDay is set, the
+// precision of the database timestamps
+func parseReplayDate(value string, endOfDay bool) (time.Time, error) {
+	if date, err := time.Parse(time.RFC3339, value); err == nil {
+		return date, nil
+	}
+	day, err := time.Parse("2006-01-02", value)
+	if err != nil || !endOfDay {
+		return day, err
+	}
+	return day.AddDate(0, 0, 1).Add(-time.Microsecond), nil
+}
+
+// replayEvents runs the replay-events command
+func replayEvents(conf infrastructure.Config, args []string) error {
+	parsed, err := parseReplayEventsArgs(args)
+	if err != nil {
+		return err
+	}
+	prometheus := infrastructure.MakePrometheusExporter(false)
+	logger, err := infrastructure.MakeLogger(&conf.LoggerConf,
+		prometheus.NewEventsCollector(
+			"premium_carousel_api_replay_events_total",
+			"events tracker counter for premium-carousel-api replay-events",
+		),
+	)
+	if err != nil {
+		return fmt.Errorf("error starting loggers: %v", err)
+	}
+	tracing, err := infrastructure.SetupTracing(conf.TracingConf, os.Stdout)
+	if err != nil {
+		return fmt.Errorf("error setting up tracing: %+v", err)
+	}
+	defer tracing.Close() // nolint: errcheck
+	tracer := infrastructure.MakeTracer()
+	dbHandler, err := infrastructure.MakePgsqlHandler(conf.DatabaseConf, logger)
+	if err != nil {
+		return fmt.Errorf("unable to connect with postgres database: %+v", err)
+	}
+	defer dbHandler.Close() // nolint: errcheck
+	var backendEventsRepository usecases.BackendEventsRepository
+	if !parsed.dryRun {
+		producer, err := infrastructure.NewKafkaProducer(
+			conf.KafkaProducerConf.Host,
+			conf.KafkaProducerConf.Port,
+			conf.KafkaProducerConf.Acks,
+			conf.KafkaProducerConf.CompressionType,
+			conf.KafkaProducerConf.Retries,
+			conf.KafkaProducerConf.LingerMS,
+			conf.KafkaProducerConf.RequestTimeoutMS,
+			conf.KafkaProducerConf.EnableIdempotence,
+			conf.KafkaProducerConf.BufferSize,
+			conf.KafkaProducerConf.FlushTimeoutMS,
+			nil,
+		)
+		if err != nil {
+			return fmt.Errorf("error starting kafka producer: %+v", err)
+		}
+		defer producer.Close() // nolint: errcheck
+		backendEventsRepository = repository.MakeBackendEventsProducer(
+			producer,
+			conf.BackendEventsConf.PremiumProductsTopic,
+		)
+	}
+	interactor := usecases.MakeReplaySoldProductsInteractor(
+		repository.MakeProductRepository(
+			dbHandler,
+			conf.ControlPanelConf.ResultsPerPage,
+			loggers.MakeProductRepositoryLogger(logger),
+		),
+		backendEventsRepository,
+		loggers.MakeReplaySoldProductsLogger(logger),
+		parsed.rate,
+		tracer,
+	)
+	replayed, err := interactor.ReplaySoldProducts(context.Background(), parsed.from, parsed.to, parsed.dryRun)
+	if parsed.dryRun {
+		logger.Info("Would replay %d sold product events from %v to %v", replayed, parsed.from, parsed.to)
+		return err
+	}
+	logger.Info("Replayed %d sold product events from %v to %v", replayed, parsed.from, parsed.to)
+	return err
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseReplayEventsArgs(t *testing.T) {
+	parsed, err := parseReplayEventsArgs([]string{"--from", "2020-01-01",
+		"--to", "2020-01-31T12:00:00Z", "--type", "premium_carousel_purchase",
+		"--dry-run", "--rate", "5"})
+	assert.NoError(t, err)
+	assert.Equal(t, replayEventsArgs{
+		from:   time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		to:     time.Date(2020, 1, 31, 12, 0, 0, 0, time.UTC),
+		dryRun: true,
+		rate:   5,
+	}, parsed)
+}
+
+func TestParseReplayEventsArgsWholeDays(t *testing.T) {
+	parsed, err := parseReplayEventsArgs([]string{"--from", "2020-01-31", "--to", "2020-01-31"})
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2020, 1, 31, 0, 0, 0, 0, time.UTC), parsed.from)
+	assert.Equal(t, time.Date(2020, 1, 31, 23, 59, 59, 999999000, time.UTC), parsed.to)
+}
+
+func TestParseReplayEventsArgsErrors(t *testing.T) {
+	cases := [][]string{
+		{"--to", "2020-01-31"},
+		{"--from", "2020-02-01", "--to", "2020-01-31"},
+		{"--from", "2020-01-01", "--to", "2020-01-31", "--type", "premium_carousel_expiration"},
+		{"--from", "2020-01-01", "--to", "2020-01-31", "--rate", "-1"},
+		{"--unknown"},
+	}
+	for _, args := range cases {
+		_, err := parseReplayEventsArgs(args)
+		assert.Error(t, err, args)
+	}
+}
@@ -0,0 +1,2 @@
+-- version is used as the entity tag of the assigns to detect concurrent updates
+ALTER TABLE user_product ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1 @@
+ALTER TABLE user_product DROP COLUMN IF EXISTS activated_at;
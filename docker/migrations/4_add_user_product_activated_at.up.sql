@@ -0,0 +1,4 @@
+-- activated_at is set the first time a product becomes active, products
+-- created inactive are activated by the scheduler once start_at is reached
+ALTER TABLE user_product ADD COLUMN IF NOT EXISTS activated_at TIMESTAMP;
+UPDATE user_product SET activated_at = start_at WHERE activated_at IS NULL;
@@ -0,0 +1,9 @@
+-- expiration reminders already sent, a product gets one reminder per window
+-- for each expiration date it had
+CREATE TABLE IF NOT EXISTS user_product_reminder(
+    user_product_id INTEGER NOT NULL REFERENCES user_product(id),
+    window_minutes  INTEGER NOT NULL,
+    expired_at      TIMESTAMP NOT NULL,
+    sent_at         TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_product_id, window_minutes, expired_at)
+);
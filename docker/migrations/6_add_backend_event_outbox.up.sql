@@ -0,0 +1,15 @@
+-- backend events waiting to be published, they are written along with the
+-- change they tell and published by the relay job in order of id per key
+CREATE TABLE IF NOT EXISTS backend_event_outbox(
+    id              BIGSERIAL PRIMARY KEY,
+    event_key       VARCHAR(64) NOT NULL,
+    topic           VARCHAR(255) NOT NULL,
+    payload         TEXT NOT NULL,
+    attempts        INTEGER NOT NULL DEFAULT 0,
+    last_error      TEXT,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at      TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    sent_at         TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS backend_event_outbox_pending_idx
+    ON backend_event_outbox(event_key, id) WHERE sent_at IS NULL;
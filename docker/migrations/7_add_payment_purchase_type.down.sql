@@ -0,0 +1,3 @@
+-- postgres can't drop a value from an enum type, purchases of type PAYMENT
+-- must be handled before going back to a version that doesn't know them
+SELECT 1;
@@ -0,0 +1,2 @@
+-- purchases created from the payments topic
+ALTER TYPE enum_purchase_type ADD VALUE IF NOT EXISTS 'PAYMENT';
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS purchase_payment_number_uniq;
@@ -0,0 +1,5 @@
+-- a payment creates a single purchase even when its message is read again
+-- after the idempotency key expired. Duplicated payments must be removed
+-- before running it
+CREATE UNIQUE INDEX IF NOT EXISTS purchase_payment_number_uniq
+    ON purchase(purchase_type, purchase_number) WHERE purchase_type = 'PAYMENT';
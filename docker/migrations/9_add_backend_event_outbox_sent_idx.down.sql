@@ -0,0 +1 @@
+DROP INDEX IF EXISTS backend_event_outbox_sent_idx;
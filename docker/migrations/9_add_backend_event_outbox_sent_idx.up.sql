@@ -0,0 +1,3 @@
+-- sent backend events, purged once past their retention
+CREATE INDEX IF NOT EXISTS backend_event_outbox_sent_idx
+    ON backend_event_outbox(sent_at) WHERE sent_at IS NOT NULL;
@@ -52,6 +52,8 @@ type PurchaseType string
 const (
 	// AdminPurchase defines a purchase set by admin
 	AdminPurchase PurchaseType = "ADMIN"
+	// PaymentPurchase defines a purchase paid by the user
+	PaymentPurchase PurchaseType = "PAYMENT"
 )
 
 // PurchaseStatus defines the purchase status
@@ -97,6 +99,7 @@ type Product struct {
 	ExpiredAt time.Time
 	CreatedAt time.Time
 	Config    ProductParams
+	Version   int
 }
 
 // ProductParams holds configurations to get user ads and fill carousel
@@ -0,0 +1,176 @@
+package infrastructure
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/interfaces/handlers"
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/usecases"
+)
+
+const (
+	// APIKeyHeader defines the header holding the api key of a request
+	APIKeyHeader = "X-Api-Key"
+	// bearerPrefix defines the Authorization scheme used by JWTs
+	bearerPrefix = "Bearer "
+)
+
+var (
+	// ErrMissingCredentials is returned for requests without credentials
+	ErrMissingCredentials = errors.New("missing credentials")
+	// ErrInvalidCredentials is returned for requests with unknown api keys
+	// or invalid tokens
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Authenticator resolves the identity doing a request
+type Authenticator interface {
+	Authenticate(r *http.Request) (handlers.Identity, error)
+}
+
+// authenticator validates api keys and JWTs signed with HS256
+type authenticator struct {
+	apiKeys   map[string]handlers.Identity
+	jwtKey    []byte
+	jwtIssuer string
+	now       func() time.Time
+}
+
+// jwtClaims holds the token claims used to build the request identity
+type jwtClaims struct {
+	Subject   string        `json:"sub"`
+	Role      handlers.Role `json:"role"`
+	Issuer    string        `json:"iss"`
+	ExpiresAt int64         `json:"exp"`
+	NotBefore int64         `json:"nbf"`
+}
+
+// MakeAuthenticator returns an Authenticator for the given configuration.
+// Api keys are given using the key:role:name format, separated by commas
+func MakeAuthenticator(conf AuthConf) (Authenticator, error) {
+	auth := &authenticator{
+		apiKeys:   make(map[string]handlers.Identity),
+		jwtKey:    []byte(conf.JWTKey),
+		jwtIssuer: conf.JWTIssuer,
+		now:       time.Now,
+	}
+	for i, entry := range strings.Split(conf.APIKeys, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		parts := strings.SplitN(strings.TrimSpace(entry), ":", 3)
+		if len(parts) != 3 || parts[0] == "" || !handlers.Role(parts[1]).Valid() {
+			return nil, fmt.Errorf("invalid api key entry at position %d", i)
+		}
+		auth.apiKeys[parts[0]] = handlers.Identity{
+			Subject: parts[2],
+			Role:    handlers.Role(parts[1]),
+		}
+	}
+	return auth, nil
+}
+
+// Authenticate returns the identity of the api key or the bearer token of
+// the request
+func (auth *authenticator) Authenticate(r *http.Request) (handlers.Identity, error) {
+	if key := r.Header.Get(APIKeyHeader); key != "" {
+		return auth.fromAPIKey(key)
+	}
+	if header := r.Header.Get("Authorization"); strings.HasPrefix(header, bearerPrefix) {
+		return auth.fromJWT(strings.TrimPrefix(header, bearerPrefix))
+	}
+	return handlers.Identity{}, ErrMissingCredentials
+}
+
+// fromAPIKey returns the identity owning the given key. Keys are compared
+// in constant time
+func (auth *authenticator) fromAPIKey(key string) (handlers.Identity, error) {
+	for known, identity := range auth.apiKeys {
+		if subtle.ConstantTimeCompare([]byte(known), []byte(key)) == 1 {
+			return identity, nil
+		}
+	}
+	return handlers.Identity{}, ErrInvalidCredentials
+}
+
+// fromJWT validates the token signature and claims and returns its identity
+func (auth *authenticator) fromJWT(token string) (handlers.Identity, error) {
+	parts := strings.Split(token, ".")
+	if len(auth.jwtKey) == 0 || len(parts) != 3 {
+		return handlers.Identity{}, ErrInvalidCredentials
+	}
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if decodeJWTPart(parts[0], &header) != nil || header.Alg != "HS256" {
+		return handlers.Identity{}, ErrInvalidCredentials
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(signature, signJWT(auth.jwtKey, parts[0]+"."+parts[1])) {
+		return handlers.Identity{}, ErrInvalidCredentials
+	}
+	var claims jwtClaims
+	if decodeJWTPart(parts[1], &claims) != nil {
+		return handlers.Identity{}, ErrInvalidCredentials
+	}
+	now := auth.now().Unix()
+	if claims.ExpiresAt == 0 || now >= claims.ExpiresAt || now < claims.NotBefore ||
+		(auth.jwtIssuer != "" && claims.Issuer != auth.jwtIssuer) ||
+		claims.Subject == "" || !claims.Role.Valid() {
+		return handlers.Identity{}, ErrInvalidCredentials
+	}
+	return handlers.Identity{Subject: claims.Subject, Role: claims.Role}, nil
+}
+
+// decodeJWTPart decodes a base64url encoded json token part into out
+func decodeJWTPart(part string, out interface{}) error {
+	raw, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, out)
+}
+
+// signJWT returns the HS256 signature of the signing input
+func signJWT(key []byte, signingInput string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(signingInput)) // nolint: errcheck
+	return mac.Sum(nil)
+}
+
+// authorize wraps the handler so only identities holding the required role
+// are allowed through. The identity is added to the request context
+func authorize(auth Authenticator, required handlers.Role,
+	handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		identity, err := auth.Authenticate(r)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="premium-carousel-api"`)
+			writeProblem(w, http.StatusUnauthorized, handlers.UnauthorizedCode, err.Error())
+			return
+		}
+		if !identity.Role.Allows(required) {
+			writeProblem(w, http.StatusForbidden, handlers.ForbiddenCode,
+				fmt.Sprintf("role %s is required", required))
+			return
+		}
+		handler(w, r.WithContext(handlers.ContextWithIdentity(r.Context(), identity)))
+	}
+}
+
+// writeProblem sends a problem response from outside a handler
+func writeProblem(w http.ResponseWriter, status int, code usecases.ErrorCode, detail string) {
+	problem := handlers.MakeProblemResponse(status, code, detail).
+		Body.(handlers.HeadedBody).Body
+	w.Header().Set("Content-Type", handlers.ProblemContentType)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(problem) // nolint: errcheck
+}
@@ -0,0 +1,138 @@
+package infrastructure
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/interfaces/handlers"
+)
+
+func makeTestJWT(key, alg string, claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": alg, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." +
+		base64.RawURLEncoding.EncodeToString(payload)
+	return signingInput + "." +
+		base64.RawURLEncoding.EncodeToString(signJWT([]byte(key), signingInput))
+}
+
+func makeTestAuthenticator(t *testing.T) Authenticator {
+	auth, err := MakeAuthenticator(AuthConf{
+		APIKeys:   "k1:reader:report-bot, k2:admin:ops",
+		JWTKey:    "secret",
+		JWTIssuer: "control-panel",
+	})
+	assert.NoError(t, err)
+	auth.(*authenticator).now = func() time.Time { return time.Unix(1000, 0) }
+	return auth
+}
+
+func TestMakeAuthenticatorBadAPIKeys(t *testing.T) {
+	for _, keys := range []string{"k1", "k1:root:name", ":admin:name"} {
+		_, err := MakeAuthenticator(AuthConf{APIKeys: keys})
+		assert.Error(t, err)
+	}
+}
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	auth := makeTestAuthenticator(t)
+	r := httptest.NewRequest("GET", "/report", nil)
+	r.Header.Set("X-API-Key", "k1")
+	identity, err := auth.Authenticate(r)
+	assert.NoError(t, err)
+	assert.Equal(t, handlers.Identity{Subject: "report-bot", Role: handlers.ReaderRole}, identity)
+
+	r.Header.Set("X-API-Key", "k3")
+	_, err = auth.Authenticate(r)
+	assert.Equal(t, ErrInvalidCredentials, err)
+}
+
+func TestAuthenticateMissingCredentials(t *testing.T) {
+	auth := makeTestAuthenticator(t)
+	r := httptest.NewRequest("GET", "/report", nil)
+	_, err := auth.Authenticate(r)
+	assert.Equal(t, ErrMissingCredentials, err)
+}
+
+func TestAuthenticateJWT(t *testing.T) {
+	auth := makeTestAuthenticator(t)
+	r := httptest.NewRequest("GET", "/report", nil)
+	r.Header.Set("Authorization", "Bearer "+makeTestJWT("secret", "HS256",
+		map[string]interface{}{"sub": "jane", "role": "editor",
+			"iss": "control-panel", "exp": 2000}))
+	identity, err := auth.Authenticate(r)
+	assert.NoError(t, err)
+	assert.Equal(t, handlers.Identity{Subject: "jane", Role: handlers.EditorRole}, identity)
+}
+
+func TestAuthenticateInvalidJWT(t *testing.T) {
+	auth := makeTestAuthenticator(t)
+	valid := map[string]interface{}{"sub": "jane", "role": "editor",
+		"iss": "control-panel", "exp": 2000}
+	tokens := map[string]string{
+		"bad signature": makeTestJWT("other", "HS256", valid),
+		"bad algorithm": makeTestJWT("secret", "none", valid),
+		"expired": makeTestJWT("secret", "HS256", map[string]interface{}{
+			"sub": "jane", "role": "editor", "iss": "control-panel", "exp": 1000}),
+		"not yet valid": makeTestJWT("secret", "HS256", map[string]interface{}{
+			"sub": "jane", "role": "editor", "iss": "control-panel",
+			"exp": 2000, "nbf": 1500}),
+		"without expiration": makeTestJWT("secret", "HS256", map[string]interface{}{
+			"sub": "jane", "role": "editor", "iss": "control-panel"}),
+		"bad issuer": makeTestJWT("secret", "HS256", map[string]interface{}{
+			"sub": "jane", "role": "editor", "iss": "other", "exp": 2000}),
+		"unknown role": makeTestJWT("secret", "HS256", map[string]interface{}{
+			"sub": "jane", "role": "root", "iss": "control-panel", "exp": 2000}),
+		"malformed": "abc.def",
+	}
+	for name, token := range tokens {
+		r := httptest.NewRequest("GET", "/report", nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		_, err := auth.Authenticate(r)
+		assert.Equal(t, ErrInvalidCredentials, err, name)
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	auth := makeTestAuthenticator(t)
+	var got handlers.Identity
+	handler := authorize(auth, handlers.EditorRole,
+		func(w http.ResponseWriter, r *http.Request) {
+			got, _ = handlers.IdentityFromContext(r.Context())
+			w.WriteHeader(http.StatusOK)
+		})
+	for key, expected := range map[string]int{
+		"":   http.StatusUnauthorized,
+		"k1": http.StatusForbidden,
+		"k2": http.StatusOK,
+	} {
+		r := httptest.NewRequest("PUT", "/assigns/1", nil)
+		if key != "" {
+			r.Header.Set("X-API-Key", key)
+		}
+		w := httptest.NewRecorder()
+		handler(w, r)
+		assert.Equal(t, expected, w.Code, key)
+		if expected != http.StatusOK {
+			assert.Equal(t, handlers.ProblemContentType, w.Header().Get("Content-Type"))
+		}
+	}
+	assert.Equal(t, handlers.Identity{Subject: "ops", Role: handlers.AdminRole}, got)
+}
+
+func TestAuthorizeUnauthorizedBody(t *testing.T) {
+	auth := makeTestAuthenticator(t)
+	handler := authorize(auth, handlers.ReaderRole,
+		func(w http.ResponseWriter, r *http.Request) {})
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest("GET", "/report", nil))
+	assert.Equal(t, `{"type":"about:blank","title":"Unauthorized","status":401,`+
+		`"detail":"missing credentials","code":"UNAUTHORIZED"}`+"\n", w.Body.String())
+	assert.NotEmpty(t, w.Header().Get("WWW-Authenticate"))
+}
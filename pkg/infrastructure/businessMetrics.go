@@ -0,0 +1,134 @@
+package infrastructure
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/domain"
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/interfaces/loggers"
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/usecases"
+)
+
+// BusinessMetrics implements usecases.Metrics with Prometheus
+type BusinessMetrics struct {
+	// carousels counts the carousels served, by result and empty reason
+	carousels *prometheus.CounterVec
+	// adsPerCarousel is the distribution of the ads of the served carousels
+	adsPerCarousel prometheus.Histogram
+	// cacheLookups counts the reads of the cache, by cache type and result
+	cacheLookups *prometheus.CounterVec
+}
+
+// makeBusinessMetrics creates the business metrics without registering them
+func makeBusinessMetrics() *BusinessMetrics {
+	return &BusinessMetrics{
+		carousels: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "carousels_total",
+				Help: "A counter of carousels served, by result and reason of the empty ones.",
+			},
+			[]string{"result", "reason"},
+		),
+		adsPerCarousel: prometheus.NewHistogram(
+			prometheus.HistogramOpts{
+				Name:    "carousel_ads",
+				Help:    "A histogram of ads returned per carousel served.",
+				Buckets: prometheus.LinearBuckets(1, 1, 15),
+			},
+		),
+		cacheLookups: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "cache_lookups_total",
+				Help: "A counter of cache reads, by cache type and hit or miss.",
+			},
+			[]string{"cache_type", "result"},
+		),
+	}
+}
+
+// NewBusinessMetrics creates and registers the business metrics
+func (*Prometheus) NewBusinessMetrics() *BusinessMetrics {
+	metrics := makeBusinessMetrics()
+	prometheus.MustRegister(metrics.carousels, metrics.adsPerCarousel,
+		metrics.cacheLookups)
+	return metrics
+}
+
+// CollectCarouselServed counts a carousel served with the given ads
+func (m *BusinessMetrics) CollectCarouselServed(ads int) {
+	m.carousels.WithLabelValues("served", "").Inc()
+	m.adsPerCarousel.Observe(float64(ads))
+}
+
+// CollectEmptyCarousel counts a carousel served without ads
+func (m *BusinessMetrics) CollectEmptyCarousel(reason usecases.EmptyCarouselReason) {
+	m.carousels.WithLabelValues("empty", string(reason)).Inc()
+}
+
+// CollectCacheLookup counts a read of the cache as a hit or a miss
+func (m *BusinessMetrics) CollectCacheLookup(typ usecases.CacheType, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	m.cacheLookups.WithLabelValues(string(typ), result).Inc()
+}
+
+// ActiveProductsCounter counts the active products of every type
+type ActiveProductsCounter interface {
+	CountActiveProducts(ctx context.Context) (map[domain.ProductType]int, error)
+}
+
+// activeProductsTimeout bounds the count made on every scrape
+const activeProductsTimeout = 5 * time.Second
+
+// activeProductsCollector counts the active products when they are scraped,
+// so every instance reports the current database state
+type activeProductsCollector struct {
+	counter ActiveProductsCounter
+	logger  loggers.Logger
+	desc    *prometheus.Desc
+}
+
+// makeActiveProductsCollector creates the collector without registering it
+func makeActiveProductsCollector(counter ActiveProductsCounter,
+	logger loggers.Logger) *activeProductsCollector {
+	return &activeProductsCollector{
+		counter: counter,
+		logger:  logger,
+		desc: prometheus.NewDesc("active_products",
+			"A gauge of active products by type.", []string{"type"}, nil),
+	}
+}
+
+// RegisterActiveProducts registers the active products metric, counted
+// through the given counter on every scrape
+func (*Prometheus) RegisterActiveProducts(counter ActiveProductsCounter, logger loggers.Logger) {
+	prometheus.MustRegister(makeActiveProductsCollector(counter, logger))
+}
+
+// Describe sends the description of the active products metric
+func (c *activeProductsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+// Collect sends the active products of every type. Nothing is sent when they
+// can't be counted, so the rest of the metrics are still scraped
+func (c *activeProductsCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), activeProductsTimeout)
+	defer cancel()
+	totals, err := c.counter.CountActiveProducts(ctx)
+	if err != nil {
+		c.logger.Error("error counting active products: %+v", err)
+		return
+	}
+	if _, ok := totals[domain.PremiumCarousel]; !ok {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, 0,
+			string(domain.PremiumCarousel))
+	}
+	for productType, total := range totals {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue,
+			float64(total), string(productType))
+	}
+}
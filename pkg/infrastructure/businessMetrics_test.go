@@ -0,0 +1,71 @@
+package infrastructure
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/domain"
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/usecases"
+)
+
+func TestBusinessMetricsCarousels(t *testing.T) {
+	m := makeBusinessMetrics()
+	m.CollectCarouselServed(3)
+	m.CollectEmptyCarousel(usecases.NotEnoughAdsReason)
+	m.CollectEmptyCarousel(usecases.NotEnoughAdsReason)
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.carousels.WithLabelValues("served", "")))
+	assert.Equal(t, float64(2), testutil.ToFloat64(m.carousels.WithLabelValues("empty", "not_enough_ads")))
+}
+
+func TestBusinessMetricsCacheLookups(t *testing.T) {
+	m := makeBusinessMetrics()
+	m.CollectCacheLookup(usecases.ProductCacheType, true)
+	m.CollectCacheLookup(usecases.ProductCacheType, false)
+	m.CollectCacheLookup(usecases.ProductCacheType, true)
+	assert.Equal(t, float64(2),
+		testutil.ToFloat64(m.cacheLookups.WithLabelValues("cache-product", "hit")))
+	assert.Equal(t, float64(1),
+		testutil.ToFloat64(m.cacheLookups.WithLabelValues("cache-product", "miss")))
+}
+
+type mockActiveProductsCounter struct {
+	mock.Mock
+}
+
+func (m *mockActiveProductsCounter) CountActiveProducts(ctx context.Context) (map[domain.ProductType]int, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(map[domain.ProductType]int), args.Error(1)
+}
+
+func TestActiveProductsCollector(t *testing.T) {
+	mCounter := &mockActiveProductsCounter{}
+	c := makeActiveProductsCollector(mCounter, &MockLoggerInfrastructure{})
+	mCounter.On("CountActiveProducts", mock.Anything).
+		Return(map[domain.ProductType]int{domain.PremiumCarousel: 4}, nil).Once()
+	mCounter.On("CountActiveProducts", mock.Anything).
+		Return(map[domain.ProductType]int{}, nil).Once()
+	assert.Equal(t, float64(4), testutil.ToFloat64(c))
+	assert.Equal(t, float64(0), testutil.ToFloat64(c))
+	mCounter.AssertExpectations(t)
+}
+
+func TestActiveProductsCollectorError(t *testing.T) {
+	mCounter := &mockActiveProductsCounter{}
+	mLogger := &MockLoggerInfrastructure{}
+	c := makeActiveProductsCollector(mCounter, mLogger)
+	mCounter.On("CountActiveProducts", mock.Anything).
+		Return(map[domain.ProductType]int(nil), fmt.Errorf("err"))
+	mLogger.On("Error")
+	ch := make(chan prometheus.Metric, 1)
+	c.Collect(ch)
+	close(ch)
+	assert.Empty(t, ch)
+	mCounter.AssertExpectations(t)
+	mLogger.AssertExpectations(t)
+}
@@ -1,20 +1,34 @@
 package infrastructure
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
 // ServiceConf holds configuration for this Service
 type ServiceConf struct {
-	Host      string `env:"HOST" envDefault:":8080"`
-	Profiling bool   `env:"PROFILING" envDefault:"true"`
+	Host string `env:"HOST" envDefault:":8080"`
+	// Profiling serves pprof on the admin server
+	Profiling bool `env:"PROFILING" envDefault:"true"`
+	// HealthTimeout bounds each dependency probe of /readyz and /livez
+	HealthTimeout time.Duration `env:"HEALTH_TIMEOUT" envDefault:"2s"`
+	// DrainDelay is how long the readiness fails before the server stops
+	// accepting requests on shutdown, so the pod is removed from the service
+	DrainDelay time.Duration `env:"DRAIN_DELAY" envDefault:"5s"`
+	// ShutdownTimeout bounds the whole shutdown, from draining the requests
+	// to closing the last background worker
+	ShutdownTimeout time.Duration `env:"SHUTDOWN_TIMEOUT" envDefault:"20s"`
 }
 
 // LoggerConf holds configuration for logging
@@ -28,19 +42,31 @@ type LoggerConf struct {
 	SyslogIdentity string `env:"SYSLOG_IDENTITY"`
 	SyslogEnabled  bool   `env:"SYSLOG_ENABLED" envDefault:"false"`
 	StdlogEnabled  bool   `env:"STDLOG_ENABLED" envDefault:"true"`
-	LogLevel       int    `env:"LOG_LEVEL" envDefault:"2"`
+	LogLevel       int    `env:"LOG_LEVEL" envDefault:"2" validate:"min=0,max=4"`
+	// Format is either text or json, json lines ignore the syslog settings
+	Format string `env:"FORMAT" envDefault:"text" validate:"oneof=text json"`
 }
 
-// PrometheusConf holds configuration to report to Prometheus
+// PrometheusConf holds configuration to report to Prometheus, the metrics
+// are served by the admin server
 type PrometheusConf struct {
-	Port    string `env:"PORT" envDefault:"8877"`
-	Enabled bool   `env:"ENABLED" envDefault:"false"`
+	Enabled bool `env:"ENABLED" envDefault:"false"`
+}
+
+// AdminConf holds the configuration of the admin server, serving the
+// profiling, metrics, cache and job endpoints apart from the product traffic.
+// When AuthEnabled every admin endpoint requires the credentials of AuthConf
+type AdminConf struct {
+	Enabled     bool   `env:"ENABLED" envDefault:"true"`
+	Host        string `env:"HOST" envDefault:"0.0.0.0"`
+	Port        int    `env:"PORT" envDefault:"8877" validate:"min=1,max=65535"`
+	AuthEnabled bool   `env:"AUTH_ENABLED" envDefault:"false"`
 }
 
 // RuntimeConfig config to start the app
 type RuntimeConfig struct {
 	Host string `env:"HOST" envDefault:"0.0.0.0"`
-	Port int    `env:"PORT" envDefault:"8080"`
+	Port int    `env:"PORT" envDefault:"8080" validate:"min=1,max=65535"`
 }
 
 // KafkaProducerConf holds configurations to connect and produce to kafka
@@ -53,27 +79,114 @@ type KafkaProducerConf struct {
 	LingerMS          int    `env:"LINGER_MS" envDefault:"0"`
 	RequestTimeoutMS  int    `env:"REQUEST_TIMEOUT_MS" envDefault:"30000"`
 	EnableIdempotence bool   `env:"ENABLE_IDEMPOTENCE" envDefault:"false"`
+	// BufferSize is how many messages may wait for a delivery report
+	BufferSize int `env:"BUFFER_SIZE" envDefault:"10000"`
+	// FlushTimeoutMS is how long the producer waits for pending messages on close
+	FlushTimeoutMS int `env:"FLUSH_TIMEOUT_MS" envDefault:"10000"`
+	// Async makes the relay enqueue each batch of events at once instead of
+	// waiting for every event before sending the next one
+	Async bool `env:"ASYNC" envDefault:"false"`
 }
 
 // BackendEventsConf holds backend events configurations
 type BackendEventsConf struct {
 	PremiumProductsTopic string `env:"PREMIUM_PRODUCTS_TOPIC" envDefault:"premium_product"`
 	Enabled              bool   `env:"ENABLED" envDefault:"false"`
+	// ReminderWindows is a comma separated list of durations before the
+	// expiration of a product when a reminder is sent
+	ReminderWindows string `env:"REMINDER_WINDOWS" envDefault:"168h,24h"`
+	// RelayBatchSize is how many outbox events the relay publishes per query
+	RelayBatchSize int `env:"RELAY_BATCH_SIZE" envDefault:"100"`
+	// RelayMaxBackoff caps the wait between attempts to publish an event
+	RelayMaxBackoff time.Duration `env:"RELAY_MAX_BACKOFF" envDefault:"10m"`
+	// OutboxRetention is how long the sent events are kept in the outbox
+	OutboxRetention time.Duration `env:"OUTBOX_RETENTION" envDefault:"168h" validate:"min=1"`
+}
+
+// GetReminderWindows returns the parsed expiration reminder windows
+func (bc BackendEventsConf) GetReminderWindows() ([]time.Duration, error) {
+	windows := []time.Duration{}
+	for _, value := range strings.Split(bc.ReminderWindows, ",") {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		window, err := time.ParseDuration(value)
+		if err != nil || window <= 0 {
+			return nil, fmt.Errorf("invalid reminder window %q", value)
+		}
+		windows = append(windows, window)
+	}
+	return windows, nil
+}
+
+// PaymentsConsumerConf holds the configuration of the payments topic consumer,
+// that creates the products of the confirmed payments
+type PaymentsConsumerConf struct {
+	Enabled         bool   `env:"ENABLED" envDefault:"false"`
+	Host            string `env:"HOST"`
+	Port            int    `env:"PORT" envDefault:"9092"`
+	GroupID         string `env:"GROUP_ID" envDefault:"premium-carousel-api"`
+	Topic           string `env:"TOPIC" envDefault:"payments"`
+	DeadLetterTopic string `env:"DEAD_LETTER_TOPIC" envDefault:"payments_dead_letter"`
+	// Plans is a comma separated list of plan:duration with the duration of
+	// the product bought with each plan
+	Plans        string        `env:"PLANS" envDefault:"monthly:720h"`
+	PollTimeout  time.Duration `env:"POLL_TIMEOUT" envDefault:"1s"`
+	RetryBackoff time.Duration `env:"RETRY_BACKOFF" envDefault:"5s"`
+	// DefaultLimit and DefaultFillGapsWithRandom are the config of the
+	// products created from payments
+	DefaultLimit              int  `env:"DEFAULT_LIMIT" envDefault:"20"`
+	DefaultFillGapsWithRandom bool `env:"DEFAULT_FILL_RANDOM" envDefault:"true"`
+}
+
+// GetPlans returns the parsed plan durations
+func (pc PaymentsConsumerConf) GetPlans() (map[string]time.Duration, error) {
+	plans := map[string]time.Duration{}
+	for _, value := range strings.Split(pc.Plans, ",") {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		parts := strings.SplitN(value, ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid plan %q", value)
+		}
+		duration, err := time.ParseDuration(strings.TrimSpace(parts[1]))
+		if err != nil || duration <= 0 {
+			return nil, fmt.Errorf("invalid plan %q", value)
+		}
+		plans[strings.TrimSpace(parts[0])] = duration
+	}
+	return plans, nil
+}
+
+// AdEventsConsumerConf holds the configuration of the ad events topic
+// consumer, that evicts the cached data of the ads updated or deleted
+type AdEventsConsumerConf struct {
+	Enabled         bool          `env:"ENABLED" envDefault:"false"`
+	Host            string        `env:"HOST"`
+	Port            int           `env:"PORT" envDefault:"9092"`
+	GroupID         string        `env:"GROUP_ID" envDefault:"premium-carousel-api"`
+	Topic           string        `env:"TOPIC" envDefault:"ad_events"`
+	DeadLetterTopic string        `env:"DEAD_LETTER_TOPIC" envDefault:"ad_events_dead_letter"`
+	PollTimeout     time.Duration `env:"POLL_TIMEOUT" envDefault:"1s"`
+	RetryBackoff    time.Duration `env:"RETRY_BACKOFF" envDefault:"5s"`
 }
 
 // DatabaseConf holds configuration for postgres database connection
 type DatabaseConf struct {
-	Host        string `env:"HOST" envDefault:"db"`
-	Port        int    `env:"PORT" envDefault:"5432"`
-	Dbname      string `env:"NAME" envDefault:"pgdb"`
+	Host        string `env:"HOST" envDefault:"db" validate:"required"`
+	Port        int    `env:"PORT" envDefault:"5432" validate:"min=1,max=65535"`
+	Dbname      string `env:"NAME" envDefault:"pgdb" validate:"required"`
 	DbUser      string `env:"USER" envDefault:"postgres"`
-	DbPasswd    string `env:"PASSWORD" envDefault:"postgres"`
+	DbPasswd    string `env:"PASSWORD" envDefault:"postgres" secret:"true"`
 	Sslmode     string `env:"SSL_MODE" envDefault:"disable"`
 	MaxIdle     int    `env:"MAX_IDLE" envDefault:"10"`
-	MaxOpen     int    `env:"MAX_OPEN" envDefault:"100"`
+	MaxOpen     int    `env:"MAX_OPEN" envDefault:"100" validate:"min=1"`
 	MgFolder    string `env:"MIGRATIONS_FOLDER" envDefault:"migrations"`
 	MgDriver    string `env:"MIGRATIONS_DRIVER" envDefault:"postgres"`
-	ConnRetries int    `env:"CONN_RETRIES" envDefault:"3"`
+	ConnRetries int    `env:"CONN_RETRIES" envDefault:"3" validate:"min=1"`
 }
 
 // GomsClientConf holds configuration regarding to our http client (premium-carousel-api itself in this case)
@@ -82,12 +195,22 @@ type GomsClientConf struct {
 	GetHealthcheckPath string `env:"HEALTH_PATH" envDefault:"/get/healthcheck"`
 }
 
-// EtcdConf configure how to read configuration from remote Etcd service
+// EtcdConf configure how to read configuration from remote Etcd service.
+// Version is the api used, v2 or v3 through its json gateway. Prefix is the
+// path of the v2 keys api. The regions are read again every RefreshInterval
+// and kept in SnapshotFile, used when etcd is down on startup
 type EtcdConf struct {
-	Host       string `env:"HOST" envDefault:"http://lb:2397"`
-	LastUpdate string `env:"LAST_UPDATE" envDefault:"/last_update"`
-	Prefix     string `env:"PREFIX" envDefault:"/v2/keys"`
-	RegionPath string `env:"REGION_PATH" envDefault:"/public/location/regions.json"`
+	Host            string        `env:"HOST" envDefault:"http://lb:2397"`
+	Version         string        `env:"VERSION" envDefault:"v2" validate:"oneof=v2 v3"`
+	Timeout         time.Duration `env:"TIMEOUT" envDefault:"5s" validate:"min=1"`
+	LastUpdate      string        `env:"LAST_UPDATE" envDefault:"/last_update"`
+	Prefix          string        `env:"PREFIX" envDefault:"/v2/keys"`
+	RegionPath      string        `env:"REGION_PATH" envDefault:"/public/location/regions.json"`
+	RefreshInterval time.Duration `env:"REFRESH_INTERVAL" envDefault:"1m" validate:"min=1"`
+	SnapshotFile    string        `env:"SNAPSHOT_FILE" envDefault:"/tmp/regions.json"`
+	// SettingsPath is the key watched for the settings reloaded at runtime,
+	// a json object like the config file, empty disables the watch
+	SettingsPath string `env:"SETTINGS_PATH"`
 }
 
 // CorsConf holds cors headers
@@ -95,7 +218,7 @@ type CorsConf struct {
 	Enabled bool   `env:"ENABLED" envDefault:"false"`
 	Origin  string `env:"ORIGIN" envDefault:"*"`
 	Methods string `env:"METHODS" envDefault:"GET, OPTIONS"`
-	Headers string `env:"HEADERS" envDefault:"Accept,Content-Type,Content-Length,If-None-Match,Accept-Encoding,User-Agent"`
+	Headers string `env:"HEADERS" envDefault:"Accept,Content-Type,Content-Length,If-None-Match,Accept-Encoding,User-Agent,Authorization,X-Api-Key,Idempotency-Key"`
 }
 
 // GetHeaders return map of cors used
@@ -121,11 +244,29 @@ type BrowserCacheConf struct {
 
 // CacheConf holds cache configurations
 type CacheConf struct {
-	Host       string        `env:"HOST" envDefault:"cache:6379"`
+	Host       string        `env:"HOST" envDefault:"cache:6379" validate:"required"`
 	Prefix     string        `env:"PREFIX" envDefault:"cache"`
-	Password   string        `env:"PASSWORD"`
+	Password   string        `env:"PASSWORD" secret:"true"`
 	DB         int           `env:"DB"`
-	DefaultTTL time.Duration `env:"DEFAULT_TTL" envDefault:"1h"`
+	DefaultTTL time.Duration `env:"DEFAULT_TTL" envDefault:"1h" validate:"min=1" reload:"true"`
+	// IdempotencyLockTTL is how long an idempotency key stays reserved while
+	// its request is running
+	IdempotencyLockTTL time.Duration `env:"IDEMPOTENCY_LOCK_TTL" envDefault:"1m" validate:"min=1"`
+	// IdempotencyTTL is how long idempotency keys are remembered
+	IdempotencyTTL time.Duration `env:"IDEMPOTENCY_TTL" envDefault:"24h"`
+}
+
+// LocalCacheConf holds the configuration of the in-process cache kept in
+// front of redis. It holds up to MaxEntries responses summing up to MaxBytes,
+// the products for ProductTTL and the ads for AdTTL. The pods tell each other
+// to drop their copies through the redis Channel, prefixed with CACHE_PREFIX
+type LocalCacheConf struct {
+	Enabled    bool          `env:"ENABLED" envDefault:"false"`
+	MaxEntries int           `env:"MAX_ENTRIES" envDefault:"10000" validate:"min=1"`
+	MaxBytes   int64         `env:"MAX_BYTES" envDefault:"67108864" validate:"min=1"`
+	ProductTTL time.Duration `env:"PRODUCT_TTL" envDefault:"5s"`
+	AdTTL      time.Duration `env:"AD_TTL" envDefault:"30s"`
+	Channel    string        `env:"CHANNEL" envDefault:"cache-invalidations" validate:"required"`
 }
 
 // ControlPanelConf holds Control Panel configurations
@@ -141,43 +282,241 @@ func (chc *BrowserCacheConf) InitEtag() int64 {
 
 // AdConf contains search-ms configuration params
 type AdConf struct {
-	Host                string `env:"HOST" envDefault:"http://10.15.1.78"`
+	Host                string `env:"HOST" envDefault:"http://10.15.1.78" validate:"required"`
 	Port                string `env:"PORT" envDefault:"19200"`
 	Username            string `env:"USERNAME" envDefault:"user"`
-	Password            string `env:"PASSWORD" envDefault:"pass"`
-	Index               string `env:"PATH" envDefault:"ads"`
+	Password            string `env:"PASSWORD" envDefault:"pass" secret:"true"`
+	Index               string `env:"PATH" envDefault:"ads" validate:"required"`
 	ImageServerURL      string `env:"IMAGE_SERVER_URL" envDefault:"https://img.yapo.cl/%s/%s/%s.jpg"`
-	CurrencySymbol      string `env:"CURRENCY_SYMBOL" envDefault:"$"`
-	UnitOfAccountSymbol string `env:"UNIT_OF_ACCOUNT_SYMBOL" envDefault:"UF"`
-	MaxAdsToDisplay     int    `env:"MAX_ADS_TO_DISPLAY" envDefault:"15"`
-	MinAdsToDisplay     int    `env:"MIN_ADS_TO_DISPLAY" envDefault:"2"`
+	CurrencySymbol      string `env:"CURRENCY_SYMBOL" envDefault:"$" reload:"true"`
+	UnitOfAccountSymbol string `env:"UNIT_OF_ACCOUNT_SYMBOL" envDefault:"UF" reload:"true"`
+	MaxAdsToDisplay     int    `env:"MAX_ADS_TO_DISPLAY" envDefault:"15" validate:"min=1" reload:"true"`
+	MinAdsToDisplay     int    `env:"MIN_ADS_TO_DISPLAY" envDefault:"2" validate:"min=0" reload:"true"`
+}
+
+// AuthConf holds the credentials accepted by the admin endpoints.
+// APIKeys is a comma separated list of key:role:name entries, roles may be
+// reader, editor or admin. JWTs must be signed with JWTKey using HS256
+type AuthConf struct {
+	Enabled   bool   `env:"ENABLED" envDefault:"true"`
+	APIKeys   string `env:"API_KEYS" secret:"true"`
+	JWTKey    string `env:"JWT_KEY" secret:"true"`
+	JWTIssuer string `env:"JWT_ISSUER"`
+}
+
+// SchedulerConf holds the schedules of the background jobs, given as cron
+// expressions (minute hour day month weekday) or @every <duration>. An empty
+// schedule disables its job. LeaderElection makes a single replica run
+// each job
+type SchedulerConf struct {
+	Enabled              bool   `env:"ENABLED" envDefault:"true"`
+	LeaderElection       bool   `env:"LEADER_ELECTION" envDefault:"true"`
+	ExpireProducts       string `env:"EXPIRE_PRODUCTS" envDefault:"* * * * *"`
+	ActivateProducts     string `env:"ACTIVATE_PRODUCTS" envDefault:"* * * * *"`
+	RefreshProductsCache string `env:"REFRESH_PRODUCTS_CACHE" envDefault:"*/30 * * * *"`
+	ExpirationReminders  string `env:"EXPIRATION_REMINDERS" envDefault:"*/10 * * * *"`
+	RelayBackendEvents   string `env:"RELAY_BACKEND_EVENTS" envDefault:"@every 5s"`
+	PurgeBackendEvents   string `env:"PURGE_BACKEND_EVENTS" envDefault:"0 * * * *"`
+}
+
+// TracingConf holds the OpenTelemetry tracing configuration. Exporter is
+// either stdout or otlp, the otlp exporter sends the spans over http to the
+// collector at Endpoint (host:port). SampleRatio is the fraction of the new
+// traces recorded, the traces started by the callers follow their decision
+type TracingConf struct {
+	Enabled     bool    `env:"ENABLED" envDefault:"false"`
+	Exporter    string  `env:"EXPORTER" envDefault:"otlp" validate:"oneof=stdout otlp"`
+	Endpoint    string  `env:"ENDPOINT" envDefault:"localhost:4318"`
+	Insecure    bool    `env:"INSECURE" envDefault:"true"`
+	ServiceName string  `env:"SERVICE_NAME" envDefault:"premium-carousel-api"`
+	SampleRatio float64 `env:"SAMPLE_RATIO" envDefault:"1"`
 }
 
 // Config holds all configuration for the service
 type Config struct {
-	ServiceConf       ServiceConf       `env:"SERVICE_"`
-	PrometheusConf    PrometheusConf    `env:"PROMETHEUS_"`
-	LoggerConf        LoggerConf        `env:"LOGGER_"`
-	Runtime           RuntimeConfig     `env:"APP_"`
-	GomsClientConf    GomsClientConf    `env:"GOMS_"`
-	EtcdConf          EtcdConf          `env:"ETCD_"`
-	CorsConf          CorsConf          `env:"CORS_"`
-	BrowserCacheConf  BrowserCacheConf  `env:"BROWSER_CACHE_"`
-	CacheConf         CacheConf         `env:"CACHE_"`
-	DatabaseConf      DatabaseConf      `env:"DATABASE_"`
-	AdConf            AdConf            `env:"AD_"`
-	ControlPanelConf  ControlPanelConf  `env:"CP_"`
-	KafkaProducerConf KafkaProducerConf `env:"KAFKA_PRODUCER_"`
-	BackendEventsConf BackendEventsConf `env:"BACKEND_EVENTS_"`
+	ServiceConf          ServiceConf          `env:"SERVICE_"`
+	PrometheusConf       PrometheusConf       `env:"PROMETHEUS_"`
+	AdminConf            AdminConf            `env:"ADMIN_"`
+	LoggerConf           LoggerConf           `env:"LOGGER_"`
+	Runtime              RuntimeConfig        `env:"APP_"`
+	GomsClientConf       GomsClientConf       `env:"GOMS_"`
+	EtcdConf             EtcdConf             `env:"ETCD_"`
+	CorsConf             CorsConf             `env:"CORS_"`
+	BrowserCacheConf     BrowserCacheConf     `env:"BROWSER_CACHE_"`
+	CacheConf            CacheConf            `env:"CACHE_"`
+	LocalCacheConf       LocalCacheConf       `env:"LOCAL_CACHE_"`
+	DatabaseConf         DatabaseConf         `env:"DATABASE_"`
+	AdConf               AdConf               `env:"AD_"`
+	ControlPanelConf     ControlPanelConf     `env:"CP_"`
+	KafkaProducerConf    KafkaProducerConf    `env:"KAFKA_PRODUCER_"`
+	BackendEventsConf    BackendEventsConf    `env:"BACKEND_EVENTS_"`
+	AuthConf             AuthConf             `env:"AUTH_"`
+	SchedulerConf        SchedulerConf        `env:"SCHEDULER_"`
+	PaymentsConsumerConf PaymentsConsumerConf `env:"PAYMENTS_CONSUMER_"`
+	AdEventsConsumerConf AdEventsConsumerConf `env:"AD_EVENTS_CONSUMER_"`
+	TracingConf          TracingConf          `env:"TRACING_"`
+}
+
+// SECRET defines the struct tag marking the config fields that must not be
+// printed, e.g. `secret:"true"`
+const SECRET = "secret"
+
+// RELOAD defines the struct tag marking the config fields that may be
+// reloaded while the service runs, e.g. `reload:"true"`
+const RELOAD = "reload"
+
+// redactedValue replaces the secret values on the printed config
+const redactedValue = "******"
+
+// ConfigError lists every problem found while loading the config
+type ConfigError []string
+
+// Error joins the problems found in a single message
+func (e ConfigError) Error() string {
+	return "invalid config: " + strings.Join(e, "; ")
 }
 
 // LoadFromEnv loads the config data from the environment variables
-func LoadFromEnv(data interface{}) {
-	load(reflect.ValueOf(data), "", "")
+func LoadFromEnv(data interface{}) error {
+	return Load(data, "")
+}
+
+// Load loads the config data from the environment variables, falling back to
+// the values of the yaml or json config file, if any, and then to the
+// envDefault tags. Nested keys of the file are joined with underscores, so
+// `database: {host: db}` sets DATABASE_HOST. Every unparseable value, unknown
+// key of the file and broken validate rule is reported in a ConfigError
+func Load(data interface{}, file string) error {
+	return loadConfig(data, file, nil)
+}
+
+// loadConfig loads the config data like Load, the overrides taking
+// precedence over every other source
+func loadConfig(data interface{}, file string, overrides map[string]string) error {
+	loader := configLoader{used: map[string]bool{}, overrides: overrides}
+	if file != "" {
+		values, err := readConfigFile(file)
+		if err != nil {
+			return err
+		}
+		loader.file = values
+	}
+	loader.load(reflect.ValueOf(data), "", "")
+	unknown := []string{}
+	for key := range loader.file {
+		if !loader.used[key] {
+			unknown = append(unknown, fmt.Sprintf("%s: unknown key in %s", key, file))
+		}
+	}
+	sort.Strings(unknown)
+	errs := append(loader.errs, unknown...)
+	errs = append(errs, validateConfig(reflect.ValueOf(data), "")...)
+	if checker, ok := data.(configChecker); ok {
+		errs = append(errs, checker.check()...)
+	}
+	if len(errs) > 0 {
+		return ConfigError(errs)
+	}
+	return nil
+}
+
+// configChecker is implemented by the configs whose fields depend on each
+// other, check reports the fields that don't agree
+type configChecker interface {
+	check() []string
 }
 
-// valueFromEnv lookup the best value for a variable on the environment
-func valueFromEnv(envTag, envDefault string) string {
+// check reports the features enabled without the ones they need, and the
+// limits that contradict each other. The outbox events are published by a
+// scheduled job, with the scheduler or the job disabled they would pile up
+// unsent
+func (conf *Config) check() (errs []string) {
+	if conf.BackendEventsConf.Enabled && !conf.SchedulerConf.Enabled {
+		errs = append(errs, "BACKEND_EVENTS_ENABLED: needs SCHEDULER_ENABLED to relay the events")
+	} else if conf.BackendEventsConf.Enabled && conf.SchedulerConf.RelayBackendEvents == "" {
+		errs = append(errs, "BACKEND_EVENTS_ENABLED: needs SCHEDULER_RELAY_BACKEND_EVENTS to relay the events")
+	}
+	if conf.AdConf.MinAdsToDisplay > conf.AdConf.MaxAdsToDisplay {
+		errs = append(errs, "AD_MIN_ADS_TO_DISPLAY: can't be over AD_MAX_ADS_TO_DISPLAY")
+	}
+	return errs
+}
+
+// configLoader fills the config fields, keeping track of the file keys used
+// and of the errors found
+type configLoader struct {
+	overrides map[string]string
+	file      map[string]string
+	used      map[string]bool
+	errs      []string
+}
+
+// readConfigFile reads a yaml or json config file, returning its values by
+// environment variable name
+func readConfigFile(file string) (map[string]string, error) {
+	b, err := ioutil.ReadFile(filepath.Clean(file))
+	if err != nil {
+		return nil, err
+	}
+	tree := map[string]interface{}{}
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(b, &tree)
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(b))
+		decoder.UseNumber()
+		err = decoder.Decode(&tree)
+	default:
+		return nil, fmt.Errorf("config file %s: unknown format, use .yaml, .yml or .json", file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("config file %s: %w", file, err)
+	}
+	values := map[string]string{}
+	if err := flattenConfig(tree, "", values); err != nil {
+		return nil, fmt.Errorf("config file %s: %w", file, err)
+	}
+	return values, nil
+}
+
+// flattenConfig adds the scalar values of tree to values, naming them after
+// their upper cased keys joined with underscores
+func flattenConfig(tree map[string]interface{}, prefix string, values map[string]string) error {
+	for key, value := range tree {
+		name := prefix + strings.ToUpper(key)
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if err := flattenConfig(v, name+"_", values); err != nil {
+				return err
+			}
+		case nil:
+			values[name] = ""
+		case string:
+			values[name] = v
+		case float64:
+			values[name] = strconv.FormatFloat(v, 'f', -1, 64)
+		case time.Time:
+			values[name] = v.Format(time.RFC3339)
+		case bool, int, int64, uint64, json.Number:
+			values[name] = fmt.Sprint(v)
+		default:
+			return fmt.Errorf("%s: unsupported value %v, only scalars are allowed", name, value)
+		}
+	}
+	return nil
+}
+
+// lookup finds the best value for a variable on the overrides, the
+// environment, the config file or the default, in that order
+func (l *configLoader) lookup(envTag, envDefault string) (string, error) {
+	if value, ok := l.overrides[envTag]; ok {
+		return value, nil
+	}
+	fileValue, inFile := l.file[envTag]
+	if inFile {
+		// The key is known even when the environment overrides it
+		l.used[envTag] = true
+	}
 	// Maybe it's a secret and <envTag>_FILE points to a file with the value
 	// https://rancher.com/docs/rancher/v1.6/en/cattle/secrets/#docker-hub-images
 	if fileName, ok := os.LookupEnv(fmt.Sprintf("%s_FILE", envTag)); ok {
@@ -187,74 +526,152 @@ func valueFromEnv(envTag, envDefault string) string {
 		// output of the Clean function and test if its what you expect
 		// you can find more info here: https://golang.org/pkg/path/filepath/#Clean
 		b, err := ioutil.ReadFile(filepath.Clean(fileName))
-		if err == nil {
-			return string(b)
+		if err != nil {
+			return "", err
 		}
-
-		fmt.Print(err)
+		return string(b), nil
 	}
 	// The value might be set directly on the environment
 	if value, ok := os.LookupEnv(envTag); ok {
-		return value
+		return value, nil
+	}
+	// Or on the config file
+	if inFile {
+		return fileValue, nil
 	}
 	// Nothing to do, return the default
-	return envDefault
+	return envDefault, nil
 }
 
 // load the variable defined in the envTag into Value
-func load(conf reflect.Value, envTag, envDefault string) { //nolint: gocyclo, gocognit
-	if conf.Kind() == reflect.Ptr {
-		reflectedConf := reflect.Indirect(conf)
-		// Only attempt to set writeable variables
-		if reflectedConf.IsValid() && reflectedConf.CanSet() {
-			value := valueFromEnv(envTag, envDefault)
-			// Print message if config is missing
-			if envTag != "" && value == "" && !strings.HasSuffix(envTag, "_") {
-				fmt.Printf("Config for %s missing\n", envTag)
+func (l *configLoader) load(conf reflect.Value, envTag, envDefault string) {
+	if conf.Kind() != reflect.Ptr {
+		return
+	}
+	reflectedConf := reflect.Indirect(conf)
+	// Only attempt to set writeable variables
+	if !reflectedConf.IsValid() || !reflectedConf.CanSet() {
+		return
+	}
+	if reflectedConf.Kind() == reflect.Struct && reflectedConf.Type() != reflect.TypeOf(time.Time{}) {
+		// Recursively load inner struct fields
+		for i := 0; i < reflectedConf.NumField(); i++ {
+			if tag, ok := reflectedConf.Type().Field(i).Tag.Lookup("env"); ok {
+				def, _ := reflectedConf.Type().Field(i).Tag.Lookup("envDefault")
+				l.load(reflectedConf.Field(i).Addr(), envTag+tag, def)
 			}
+		}
+		return
+	}
+	value, err := l.lookup(envTag, envDefault)
+	if err != nil {
+		l.errs = append(l.errs, fmt.Sprintf("%s: %v", envTag, err))
+		return
+	}
+	// Empty values keep the zero value, the required rule rejects them
+	if value == "" {
+		return
+	}
+	if err := parseConfigValue(reflectedConf, value); err != nil {
+		l.errs = append(l.errs, fmt.Sprintf("%s: invalid value %q: %v", envTag, value, err))
+	}
+}
 
-			switch reflectedConf.Interface().(type) {
-			case int:
-				if value, err := strconv.ParseInt(value, 10, 32); err == nil {
-					reflectedConf.Set(reflect.ValueOf(int(value)))
-				}
-			case int64:
-				if value, err := strconv.ParseInt(value, 10, 64); err == nil {
-					reflectedConf.Set(reflect.ValueOf(value))
-				}
-			case uint32:
-				if value, err := strconv.ParseUint(value, 10, 32); err == nil {
-					reflectedConf.Set(reflect.ValueOf(uint32(value)))
-				}
-			case float64:
-				if value, err := strconv.ParseFloat(value, 64); err == nil {
-					reflectedConf.Set(reflect.ValueOf(value))
-				}
-			case string:
-				reflectedConf.Set(reflect.ValueOf(value))
-			case bool:
-				if value, err := strconv.ParseBool(value); err == nil {
-					reflectedConf.Set(reflect.ValueOf(value))
-				}
-			case time.Time:
-				if value, err := time.Parse(time.RFC3339, value); err == nil {
-					reflectedConf.Set(reflect.ValueOf(value))
-				}
-			case time.Duration:
-				if t, err := time.ParseDuration(value); err == nil {
-					reflectedConf.Set(reflect.ValueOf(t))
-				}
-			}
+// parseConfigValue parses value into field according to the field type
+func parseConfigValue(field reflect.Value, value string) (err error) { //nolint: gocyclo
+	var parsed interface{}
+	switch field.Interface().(type) {
+	case int:
+		var v int64
+		v, err = strconv.ParseInt(value, 10, 32)
+		parsed = int(v)
+	case int64:
+		parsed, err = strconv.ParseInt(value, 10, 64)
+	case uint32:
+		var v uint64
+		v, err = strconv.ParseUint(value, 10, 32)
+		parsed = uint32(v)
+	case float64:
+		parsed, err = strconv.ParseFloat(value, 64)
+	case string:
+		parsed = value
+	case bool:
+		parsed, err = strconv.ParseBool(value)
+	case time.Time:
+		parsed, err = time.Parse(time.RFC3339, value)
+	case time.Duration:
+		parsed, err = time.ParseDuration(value)
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+	if err != nil {
+		if numErr, ok := err.(*strconv.NumError); ok {
+			err = numErr.Err
+		}
+		return err
+	}
+	field.Set(reflect.ValueOf(parsed))
+	return nil
+}
 
-			if reflectedConf.Kind() == reflect.Struct {
-				// Recursively load inner struct fields
-				for i := 0; i < reflectedConf.NumField(); i++ {
-					if tag, ok := reflectedConf.Type().Field(i).Tag.Lookup("env"); ok {
-						def, _ := reflectedConf.Type().Field(i).Tag.Lookup("envDefault")
-						load(reflectedConf.Field(i).Addr(), envTag+tag, def)
-					}
-				}
+// validateConfig checks every field of conf against the rules of its
+// validate tag, recursing into the nested configs. Broken rules are reported
+// by environment variable, the values of the secret fields are not shown
+func validateConfig(conf reflect.Value, envTag string) (errs []string) {
+	reflectedConf := reflect.Indirect(conf)
+	if reflectedConf.Kind() != reflect.Struct {
+		return nil
+	}
+	for i := 0; i < reflectedConf.NumField(); i++ {
+		field := reflectedConf.Type().Field(i)
+		tag, ok := field.Tag.Lookup("env")
+		if !ok {
+			continue
+		}
+		value := reflectedConf.Field(i)
+		if value.Kind() == reflect.Struct && value.Type() != reflect.TypeOf(time.Time{}) {
+			errs = append(errs, validateConfig(value, envTag+tag)...)
+			continue
+		}
+		shown := fmt.Sprintf("%q", fmt.Sprint(value.Interface()))
+		if field.Tag.Get(SECRET) == "true" {
+			shown = redactedValue
+		}
+		for _, rule := range brokenRules(value, field.Tag.Get(VALIDATE)) {
+			errs = append(errs, fmt.Sprintf("%s: value %s breaks rule %s", envTag+tag, shown, rule))
+		}
+	}
+	return errs
+}
+
+// Redact returns a copy of conf with the non empty secret fields masked, so
+// it can be printed safely
+func Redact(conf interface{}) interface{} {
+	value := reflect.Indirect(reflect.ValueOf(conf))
+	if value.Kind() != reflect.Struct {
+		return conf
+	}
+	redacted := reflect.New(value.Type()).Elem()
+	redacted.Set(value)
+	redact(redacted)
+	return redacted.Interface()
+}
+
+// redact masks the non empty secret fields of conf, recursing into the
+// nested structs
+func redact(conf reflect.Value) {
+	for i := 0; i < conf.NumField(); i++ {
+		field := conf.Field(i)
+		if !field.CanSet() {
+			continue
+		}
+		switch {
+		case conf.Type().Field(i).Tag.Get(SECRET) == "true":
+			if field.Kind() == reflect.String && field.String() != "" {
+				field.SetString(redactedValue)
 			}
+		case field.Kind() == reflect.Struct:
+			redact(field)
 		}
 	}
 }
@@ -0,0 +1,212 @@
+package infrastructure
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/usecases"
+)
+
+// ConfigReloaderLogger defines the log functions needed by ConfigReloader
+type ConfigReloaderLogger interface {
+	Info(format string, params ...interface{})
+	Error(format string, params ...interface{})
+}
+
+// ConfigReloader implements usecases.SettingsProvider with the fields of
+// Config tagged with reload. They are loaded again from the config file on
+// SIGHUP and whenever Override is called, every setting being replaced at
+// once. The changes of the other fields are only logged, they need a restart
+type ConfigReloader struct {
+	file     string
+	logger   ConfigReloaderLogger
+	settings atomic.Value
+	done     chan struct{}
+	// mutex serializes the reloads
+	mutex sync.Mutex
+	// initial is the config the service started with, applied is the last
+	// config whose settings were stored
+	initial   Config
+	applied   Config
+	overrides map[string]string
+}
+
+// MakeConfigReloader creates a ConfigReloader starting with the settings of
+// conf, that was loaded from file
+func MakeConfigReloader(conf Config, file string, logger ConfigReloaderLogger) *ConfigReloader {
+	reloader := &ConfigReloader{
+		file:    file,
+		logger:  logger,
+		done:    make(chan struct{}),
+		initial: conf,
+		applied: conf,
+	}
+	reloader.settings.Store(settingsFromConfig(conf))
+	return reloader
+}
+
+// settingsFromConfig returns the reloadable settings of conf
+func settingsFromConfig(conf Config) usecases.Settings {
+	return usecases.Settings{
+		CacheTTL:            conf.CacheConf.DefaultTTL,
+		MinAdsToDisplay:     conf.AdConf.MinAdsToDisplay,
+		MaxAdsToDisplay:     conf.AdConf.MaxAdsToDisplay,
+		CurrencySymbol:      conf.AdConf.CurrencySymbol,
+		UnitOfAccountSymbol: conf.AdConf.UnitOfAccountSymbol,
+	}
+}
+
+// Settings returns the settings in force
+func (r *ConfigReloader) Settings() usecases.Settings {
+	return r.settings.Load().(usecases.Settings)
+}
+
+// Reload loads the config again and applies its settings. An invalid config
+// is rejected, keeping the settings in force
+func (r *ConfigReloader) Reload() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.apply(r.overrides)
+}
+
+// Override sets the settings given in value over the ones of the config and
+// applies them. value is a json object like the config file, e.g.
+// {"ad": {"max_ads_to_display": 10}}, only the reloadable settings are
+// allowed. An empty value removes the previous overrides
+func (r *ConfigReloader) Override(value string) error {
+	overrides := map[string]string{}
+	if strings.TrimSpace(value) != "" {
+		tree := map[string]interface{}{}
+		decoder := json.NewDecoder(strings.NewReader(value))
+		decoder.UseNumber()
+		if err := decoder.Decode(&tree); err != nil {
+			return fmt.Errorf("invalid settings: %w", err)
+		}
+		if err := flattenConfig(tree, "", overrides); err != nil {
+			return fmt.Errorf("invalid settings: %w", err)
+		}
+	}
+	reloadable := reloadableKeys(reflect.TypeOf(Config{}), "")
+	errs := ConfigError{}
+	for key := range overrides {
+		if !reloadable[key] {
+			errs = append(errs, fmt.Sprintf("%s: can't be reloaded", key))
+		}
+	}
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		return errs
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if err := r.apply(overrides); err != nil {
+		return err
+	}
+	r.overrides = overrides
+	return nil
+}
+
+// apply loads the config under overrides and stores its settings, logging
+// what changed. The caller must hold the mutex
+func (r *ConfigReloader) apply(overrides map[string]string) error {
+	var conf Config
+	if err := loadConfig(&conf, r.file, overrides); err != nil {
+		r.logger.Error("Config not reloaded: %s", err)
+		return err
+	}
+	for _, change := range diffConfig(reflect.ValueOf(r.applied), reflect.ValueOf(conf), "", true) {
+		r.logger.Info("Config reloaded %s", change)
+	}
+	for _, change := range diffConfig(reflect.ValueOf(r.initial), reflect.ValueOf(conf), "", false) {
+		r.logger.Info("Config not reloaded %s, it needs a restart", change)
+	}
+	r.settings.Store(settingsFromConfig(conf))
+	r.applied = conf
+	return nil
+}
+
+// ListenSignal reloads the config each time the process receives SIGHUP,
+// until the reloader is closed
+func (r *ConfigReloader) ListenSignal() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, unix.SIGHUP)
+	go func() {
+		defer signal.Stop(signals)
+		for {
+			select {
+			case <-signals:
+				r.Reload() // nolint: errcheck
+			case <-r.done:
+				return
+			}
+		}
+	}()
+}
+
+// Close stops listening to SIGHUP
+func (r *ConfigReloader) Close() error {
+	close(r.done)
+	return nil
+}
+
+// reloadableKeys returns the environment variables of the fields of conf
+// tagged with reload
+func reloadableKeys(conf reflect.Type, envTag string) map[string]bool {
+	keys := map[string]bool{}
+	for i := 0; i < conf.NumField(); i++ {
+		field := conf.Field(i)
+		tag, ok := field.Tag.Lookup("env")
+		if !ok {
+			continue
+		}
+		if field.Type.Kind() == reflect.Struct && field.Type != reflect.TypeOf(time.Time{}) {
+			for key := range reloadableKeys(field.Type, envTag+tag) {
+				keys[key] = true
+			}
+			continue
+		}
+		if field.Tag.Get(RELOAD) == "true" {
+			keys[envTag+tag] = true
+		}
+	}
+	return keys
+}
+
+// diffConfig describes the fields that differ between before and after,
+// only the reloadable ones or only the others as told by reloadable. The
+// values of the secret fields are not shown
+func diffConfig(before, after reflect.Value, envTag string, reloadable bool) (changes []string) {
+	for i := 0; i < before.NumField(); i++ {
+		field := before.Type().Field(i)
+		tag, ok := field.Tag.Lookup("env")
+		if !ok {
+			continue
+		}
+		old, current := before.Field(i), after.Field(i)
+		if old.Kind() == reflect.Struct && old.Type() != reflect.TypeOf(time.Time{}) {
+			changes = append(changes, diffConfig(old, current, envTag+tag, reloadable)...)
+			continue
+		}
+		if (field.Tag.Get(RELOAD) == "true") != reloadable ||
+			reflect.DeepEqual(old.Interface(), current.Interface()) {
+			continue
+		}
+		if field.Tag.Get(SECRET) == "true" {
+			changes = append(changes, envTag+tag)
+			continue
+		}
+		changes = append(changes, fmt.Sprintf("%s: %q -> %q", envTag+tag,
+			fmt.Sprint(old.Interface()), fmt.Sprint(current.Interface())))
+	}
+	return changes
+}
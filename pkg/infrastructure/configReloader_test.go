@@ -0,0 +1,130 @@
+package infrastructure
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sys/unix"
+)
+
+// logRecorder keeps the messages logged through it
+type logRecorder struct {
+	mutex    sync.Mutex
+	messages []string
+}
+
+func (l *logRecorder) Info(format string, params ...interface{}) {
+	l.record(format, params...)
+}
+
+func (l *logRecorder) Error(format string, params ...interface{}) {
+	l.record(format, params...)
+}
+
+func (l *logRecorder) Debug(format string, params ...interface{}) {
+	l.record(format, params...)
+}
+
+func (l *logRecorder) record(format string, params ...interface{}) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.messages = append(l.messages, fmt.Sprintf(format, params...))
+}
+
+func (l *logRecorder) Messages() []string {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return append([]string{}, l.messages...)
+}
+
+// makeTestReloader creates a ConfigReloader over the config file with the
+// given content
+func makeTestReloader(t *testing.T, content string) (*ConfigReloader, string, *logRecorder) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, ioutil.WriteFile(file, []byte(content), 0600))
+	var conf Config
+	assert.NoError(t, Load(&conf, file))
+	logger := &logRecorder{}
+	return MakeConfigReloader(conf, file, logger), file, logger
+}
+
+func TestConfigReloaderReload(t *testing.T) {
+	reloader, file, logger := makeTestReloader(t, "ad:\n  max_ads_to_display: 10\n")
+	assert.Equal(t, 10, reloader.Settings().MaxAdsToDisplay)
+
+	content := "ad:\n  max_ads_to_display: 8\n  currency_symbol: CLP\ndatabase_max_open: 5\n"
+	assert.NoError(t, ioutil.WriteFile(file, []byte(content), 0600))
+	assert.NoError(t, reloader.Reload())
+	settings := reloader.Settings()
+	assert.Equal(t, 8, settings.MaxAdsToDisplay)
+	assert.Equal(t, "CLP", settings.CurrencySymbol)
+	assert.Equal(t, time.Hour, settings.CacheTTL)
+	assert.Equal(t, []string{
+		`Config reloaded AD_CURRENCY_SYMBOL: "$" -> "CLP"`,
+		`Config reloaded AD_MAX_ADS_TO_DISPLAY: "10" -> "8"`,
+		`Config not reloaded DATABASE_MAX_OPEN: "100" -> "5", it needs a restart`,
+	}, logger.Messages())
+}
+
+func TestConfigReloaderReloadInvalid(t *testing.T) {
+	reloader, file, logger := makeTestReloader(t, "")
+	assert.NoError(t, ioutil.WriteFile(file, []byte("ad_max_ads_to_display: 0\n"), 0600))
+	assert.Error(t, reloader.Reload())
+	assert.Equal(t, 15, reloader.Settings().MaxAdsToDisplay)
+	assert.Len(t, logger.Messages(), 1)
+}
+
+func TestConfigReloaderOverride(t *testing.T) {
+	reloader, _, logger := makeTestReloader(t, "")
+	err := reloader.Override(`{"ad": {"min_ads_to_display": 4}, "CACHE_DEFAULT_TTL": "30m"}`)
+	assert.NoError(t, err)
+	settings := reloader.Settings()
+	assert.Equal(t, 4, settings.MinAdsToDisplay)
+	assert.Equal(t, 30*time.Minute, settings.CacheTTL)
+	assert.Equal(t, []string{
+		`Config reloaded CACHE_DEFAULT_TTL: "1h0m0s" -> "30m0s"`,
+		`Config reloaded AD_MIN_ADS_TO_DISPLAY: "2" -> "4"`,
+	}, logger.Messages())
+
+	// The overrides survive the reloads until they are removed
+	assert.NoError(t, reloader.Reload())
+	assert.Equal(t, 4, reloader.Settings().MinAdsToDisplay)
+	assert.NoError(t, reloader.Override(""))
+	assert.Equal(t, 2, reloader.Settings().MinAdsToDisplay)
+	assert.Equal(t, time.Hour, reloader.Settings().CacheTTL)
+}
+
+func TestConfigReloaderOverrideMixedLimits(t *testing.T) {
+	reloader, _, _ := makeTestReloader(t, "")
+	err := reloader.Override(`{"ad": {"min_ads_to_display": 20}}`)
+	assert.Equal(t, ConfigError{"AD_MIN_ADS_TO_DISPLAY: can't be over AD_MAX_ADS_TO_DISPLAY"}, err)
+	assert.Error(t, reloader.Override(`{"ad": {"min_ads_to_display": -1}}`))
+	settings := reloader.Settings()
+	assert.Equal(t, 2, settings.MinAdsToDisplay)
+	assert.Equal(t, 15, settings.MaxAdsToDisplay)
+}
+
+func TestConfigReloaderOverrideErrors(t *testing.T) {
+	reloader, _, _ := makeTestReloader(t, "")
+	err := reloader.Override(`{"database": {"host": "other"}, "ad_max_ads_to_display": 3}`)
+	assert.Equal(t, ConfigError{"DATABASE_HOST: can't be reloaded"}, err)
+	assert.Error(t, reloader.Override(`{"ad_max_ads_to_display": "many"}`))
+	assert.Error(t, reloader.Override(`[1, 2]`))
+	assert.Equal(t, 15, reloader.Settings().MaxAdsToDisplay)
+}
+
+func TestConfigReloaderListenSignal(t *testing.T) {
+	reloader, file, _ := makeTestReloader(t, "")
+	reloader.ListenSignal()
+	defer reloader.Close() // nolint: errcheck
+	assert.NoError(t, ioutil.WriteFile(file, []byte("ad_unit_of_account_symbol: CLF\n"), 0600))
+	assert.NoError(t, unix.Kill(unix.Getpid(), unix.SIGHUP))
+	assert.Eventually(t, func() bool {
+		return reloader.Settings().UnitOfAccountSymbol == "CLF"
+	}, time.Second, time.Millisecond)
+}
@@ -3,6 +3,7 @@ package infrastructure
 import (
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -49,3 +50,154 @@ func TestConfigLoad(t *testing.T) {
 
 	assert.Equal(t, expected, conf)
 }
+
+func TestConfigLoadFile(t *testing.T) {
+	os.Setenv("LE_S", "from env")
+	defer os.Unsetenv("LE_S")
+	for _, file := range []string{"testdata/config.yaml", "testdata/config.json"} {
+		var conf TestConf
+		err := Load(&conf, file)
+		assert.NoError(t, err)
+		expected := TestConf{
+			I: 7,
+			S: "from env",
+			N: Nested{
+				F: true,
+			},
+			D: "file_default",
+		}
+		assert.Equal(t, expected, conf, file)
+	}
+}
+
+func TestConfigLoadFileErrors(t *testing.T) {
+	var conf TestConf
+	err := Load(&conf, "testdata/unknown.yaml")
+	assert.EqualError(t, err, "invalid config: NESTED_LE_X: unknown key in testdata/unknown.yaml")
+	assert.Equal(t, 7, conf.I)
+
+	err = Load(&conf, "testdata/list.yaml")
+	assert.EqualError(t, err, "config file testdata/list.yaml: LE_S: unsupported value [a b], only scalars are allowed")
+
+	err = Load(&conf, "testdata/from.data")
+	assert.Error(t, err)
+
+	err = Load(&conf, "testdata/missing.yaml")
+	assert.Error(t, err)
+}
+
+func TestConfigLoadInvalidValues(t *testing.T) {
+	env := map[string]string{
+		"LE_I":           "forty two",
+		"NESTED_LE_F":    "yes please",
+		"OTHERFILE_FILE": "testdata/not.data",
+	}
+	for k, v := range env {
+		os.Setenv(k, v)
+		defer os.Unsetenv(k)
+	}
+
+	var conf TestConf
+	err := LoadFromEnv(&conf)
+	assert.Equal(t, ConfigError{
+		`LE_I: invalid value "forty two": invalid syntax`,
+		`NESTED_LE_F: invalid value "yes please": invalid syntax`,
+		"OTHERFILE: open testdata/not.data: no such file or directory",
+	}, err)
+}
+
+func TestConfigLoadValidation(t *testing.T) {
+	type nested struct {
+		Port     int    `env:"PORT" validate:"min=1,max=65535"`
+		Password string `env:"PASSWORD" validate:"min=8" secret:"true"`
+	}
+	type conf struct {
+		Host   string `env:"HOST" validate:"required"`
+		Format string `env:"FORMAT" envDefault:"xml" validate:"oneof=text json"`
+		N      nested `env:"DB_"`
+	}
+	os.Setenv("DB_PASSWORD", "short")
+	defer os.Unsetenv("DB_PASSWORD")
+
+	var c conf
+	err := LoadFromEnv(&c)
+	assert.Equal(t, ConfigError{
+		`HOST: value "" breaks rule required`,
+		`FORMAT: value "xml" breaks rule oneof=text json`,
+		`DB_PORT: value "0" breaks rule min=1`,
+		"DB_PASSWORD: value ****** breaks rule min=8",
+	}, err)
+}
+
+func TestConfigDefaultsAreValid(t *testing.T) {
+	var conf Config
+	assert.NoError(t, LoadFromEnv(&conf))
+}
+
+func TestConfigCheckBackendEventsNeedScheduler(t *testing.T) {
+	os.Setenv("BACKEND_EVENTS_ENABLED", "true")
+	os.Setenv("SCHEDULER_ENABLED", "false")
+	defer os.Unsetenv("BACKEND_EVENTS_ENABLED")
+	defer os.Unsetenv("SCHEDULER_ENABLED")
+	var conf Config
+	err := LoadFromEnv(&conf)
+	assert.Equal(t, ConfigError{
+		"BACKEND_EVENTS_ENABLED: needs SCHEDULER_ENABLED to relay the events",
+	}, err)
+}
+
+func TestConfigCheckBackendEventsNeedRelaySchedule(t *testing.T) {
+	os.Setenv("BACKEND_EVENTS_ENABLED", "true")
+	os.Setenv("SCHEDULER_RELAY_BACKEND_EVENTS", "")
+	defer os.Unsetenv("BACKEND_EVENTS_ENABLED")
+	defer os.Unsetenv("SCHEDULER_RELAY_BACKEND_EVENTS")
+	var conf Config
+	err := LoadFromEnv(&conf)
+	assert.Equal(t, ConfigError{
+		"BACKEND_EVENTS_ENABLED: needs SCHEDULER_RELAY_BACKEND_EVENTS to relay the events",
+	}, err)
+}
+
+func TestRedact(t *testing.T) {
+	conf := Config{
+		DatabaseConf: DatabaseConf{Host: "db", DbPasswd: "postgres"},
+		CacheConf:    CacheConf{Host: "cache"},
+		AuthConf:     AuthConf{APIKeys: "key:admin:ops", JWTKey: "jwt"},
+	}
+	redacted := Redact(&conf).(Config)
+	assert.Equal(t, "db", redacted.DatabaseConf.Host)
+	assert.Equal(t, "******", redacted.DatabaseConf.DbPasswd)
+	assert.Equal(t, "", redacted.CacheConf.Password)
+	assert.Equal(t, "******", redacted.AuthConf.APIKeys)
+	assert.Equal(t, "******", redacted.AuthConf.JWTKey)
+	assert.Equal(t, "postgres", conf.DatabaseConf.DbPasswd)
+}
+
+func TestGetReminderWindows(t *testing.T) {
+	conf := BackendEventsConf{ReminderWindows: "168h, 24h,"}
+	windows, err := conf.GetReminderWindows()
+	assert.NoError(t, err)
+	assert.Equal(t, []time.Duration{168 * time.Hour, 24 * time.Hour}, windows)
+}
+
+func TestGetReminderWindowsError(t *testing.T) {
+	conf := BackendEventsConf{ReminderWindows: "168h,tomorrow"}
+	_, err := conf.GetReminderWindows()
+	assert.Error(t, err)
+}
+
+func TestGetPlans(t *testing.T) {
+	conf := PaymentsConsumerConf{Plans: "monthly:720h, weekly: 168h,"}
+	plans, err := conf.GetPlans()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]time.Duration{
+		"monthly": 720 * time.Hour,
+		"weekly":  168 * time.Hour,
+	}, plans)
+}
+
+func TestGetPlansError(t *testing.T) {
+	conf := PaymentsConsumerConf{Plans: "monthly"}
+	_, err := conf.GetPlans()
+	assert.Error(t, err)
+}
@@ -4,20 +4,57 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
+	"time"
 
 	"github.com/olivere/elastic/v7"
+	"github.com/prometheus/client_golang/prometheus"
 
 	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/interfaces/loggers"
 	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/interfaces/repository"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type elasticsearch struct {
-	client *elastic.Client
-	logger loggers.Logger
+	client  *elastic.Client
+	logger  loggers.Logger
+	metrics *ElasticsearchMetrics
 }
 
-// NewElasticsearch creates a new instance for elasticsearch connector
-func NewElasticsearch(host, port, username, password string, logger loggers.Logger) *elasticsearch {
+// ElasticsearchMetrics times the requests made to elasticsearch
+type ElasticsearchMetrics struct {
+	duration *prometheus.HistogramVec
+}
+
+// NewElasticsearchMetrics creates and registers the elasticsearch latency
+// histogram
+func (*Prometheus) NewElasticsearchMetrics() *ElasticsearchMetrics {
+	metrics := &ElasticsearchMetrics{
+		duration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "elasticsearch_request_duration_seconds",
+				Help:    "A histogram of latencies for elasticsearch requests.",
+				Buckets: []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+			},
+			[]string{"operation", "index", "success"},
+		),
+	}
+	prometheus.MustRegister(metrics.duration)
+	return metrics
+}
+
+// collectDuration records the latency of an elasticsearch request
+func (m *ElasticsearchMetrics) collectDuration(operation, index string, seconds float64, err error) {
+	if m != nil {
+		m.duration.WithLabelValues(operation, index, strconv.FormatBool(err == nil)).Observe(seconds)
+	}
+}
+
+// NewElasticsearch creates a new instance for elasticsearch connector. The
+// metrics may be nil to leave the requests untimed
+func NewElasticsearch(host, port, username, password string, logger loggers.Logger,
+	metrics *ElasticsearchMetrics) *elasticsearch {
 	client, err := elastic.NewClient(
 		elastic.SetURL(host+":"+port),
 		elastic.SetSniff(false),
@@ -35,21 +72,51 @@ func NewElasticsearch(host, port, username, password string, logger loggers.Logg
 	}
 	logger.Info("Connected to elasticsearch version: %s", esversion)
 	return &elasticsearch{
-		client: client,
-		logger: logger,
+		client:  client,
+		logger:  logger,
+		metrics: metrics,
 	}
 }
 
+// Check asks for the cluster health, it's used by the readiness probe. A red
+// cluster can't serve every search, so it's reported as failing
+func (e *elasticsearch) Check(ctx context.Context) error {
+	if e == nil {
+		return fmt.Errorf("elasticsearch client not connected")
+	}
+	health, err := e.client.ClusterHealth().Do(ctx)
+	if err != nil {
+		return err
+	}
+	if health != nil && health.Status == "red" {
+		return fmt.Errorf("elasticsearch cluster status is red")
+	}
+	return nil
+}
+
+// startElasticsearchSpan starts the client span timing a request on index
+func startElasticsearchSpan(ctx context.Context, operation, index string) (context.Context, trace.Span) {
+	return startSpan(ctx, "elasticsearch "+operation, trace.SpanKindClient,
+		attribute.String("db.system", "elasticsearch"),
+		attribute.String("db.operation", operation),
+		attribute.String("elasticsearch.index", index),
+	)
+}
+
 // Search executes search on index using given parameters
-func (e *elasticsearch) Search(index string,
+func (e *elasticsearch) Search(ctx context.Context, index string,
 	query repository.Query, from,
 	size int) (repository.SearchResult, error) {
+	ctx, span := startElasticsearchSpan(ctx, "search", index)
+	start := time.Now()
 	res, err := e.client.Search().
 		Index(index).
 		Query(query).
 		From(from).Size(size).
 		Pretty(true).
-		Do(context.Background())
+		Do(ctx)
+	e.metrics.collectDuration("search", index, time.Since(start).Seconds(), err)
+	endSpan(span, err)
 	if err != nil {
 		return nil, err
 	}
@@ -58,11 +125,15 @@ func (e *elasticsearch) Search(index string,
 }
 
 // GetDoc get specific doc from index
-func (e *elasticsearch) GetDoc(index string, id string) (json.RawMessage, error) {
+func (e *elasticsearch) GetDoc(ctx context.Context, index string, id string) (json.RawMessage, error) {
+	ctx, span := startElasticsearchSpan(ctx, "get", index)
+	start := time.Now()
 	res, err := e.client.Get().
 		Index(index).
 		Id(id).
-		Do(context.Background())
+		Do(ctx)
+	e.metrics.collectDuration("get", index, time.Since(start).Seconds(), err)
+	endSpan(span, err)
 	if err != nil {
 		return nil, err
 	}
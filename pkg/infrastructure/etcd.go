@@ -1,15 +1,22 @@
 package infrastructure
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/tidwall/gjson"
-
-	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/interfaces/repository"
 )
 
 // EtcdLogger defines log functions needed
@@ -19,12 +26,6 @@ type EtcdLogger interface {
 	Info(format string, params ...interface{})
 }
 
-// etcd works remote configuration service loader
-type etcd struct {
-	logger  EtcdLogger
-	content *EtcdContent
-}
-
 // EtcdContent holds response from Etcd Service
 type EtcdContent struct {
 	Action string   `json:"action"`
@@ -62,61 +63,349 @@ func readAll(resp *http.Response, logger EtcdLogger) ([]byte, error) {
 
 }
 
-// NewEtcd remote configuration loader
-// This method get the configuration file from remote host.
-// On success returns a Etcd struct, if not success returns an Error and Etcd
-// with an EtcdContent empty
-func NewEtcd(host, path, prefix string, logger EtcdLogger) (repository.Config, error) {
-	etcd := &etcd{logger: logger, content: &EtcdContent{}}
-	var netClient = &http.Client{
-		Timeout: time.Second * 100,
+const (
+	// EtcdV2 reads etcd through its v2 keys api
+	EtcdV2 = "v2"
+	// EtcdV3 reads etcd through the json gateway of its v3 api
+	EtcdV3 = "v3"
+)
+
+// ErrEtcdKeyNotFound is returned when the key read is not on etcd
+var ErrEtcdKeyNotFound = errors.New("etcd key not found")
+
+// EtcdClient reads the keys of etcd
+type EtcdClient interface {
+	// Read returns the value of key, or ErrEtcdKeyNotFound
+	Read(ctx context.Context, key string) (string, error)
+}
+
+// MakeEtcdClient creates an EtcdClient using the api version of conf
+func MakeEtcdClient(conf EtcdConf, logger EtcdLogger) (EtcdClient, error) {
+	client := &http.Client{Timeout: conf.Timeout}
+	switch conf.Version {
+	case EtcdV2:
+		return etcdV2Client{host: conf.Host, prefix: conf.Prefix, client: client, logger: logger}, nil
+	case EtcdV3:
+		return etcdV3Client{host: conf.Host, client: client, logger: logger}, nil
+	default:
+		return nil, fmt.Errorf("unknown etcd version %q", conf.Version)
 	}
-	url := fmt.Sprintf("%s%s%s", host, prefix, path)
-	resp, err := netClient.Get(url)
+}
+
+// etcdV2Client reads the keys through the etcd v2 keys api
+type etcdV2Client struct {
+	host   string
+	prefix string
+	client *http.Client
+	logger EtcdLogger
+}
+
+// Read returns the value of key
+func (c etcdV2Client) Read(ctx context.Context, key string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		fmt.Sprintf("%s%s%s", c.host, c.prefix, key), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.client.Do(req)
 	if err != nil {
-		logger.Error("Error to get url %s", url)
-		return etcd, err
+		return "", err
 	}
 	defer resp.Body.Close() // nolint: errcheck
-	body, err := readAll(resp, logger)
+	if resp.StatusCode == http.StatusNotFound {
+		return "", ErrEtcdKeyNotFound
+	}
+	body, err := readAll(resp, c.logger)
 	if err != nil {
-		return etcd, err
+		return "", err
+	}
+	var content EtcdContent
+	if err := json.Unmarshal(body, &content); err != nil {
+		return "", err
+	}
+	if content.Node.IsDir {
+		return "", fmt.Errorf("etcd key %s is a dir", key)
+	}
+	return content.Node.Value, nil
+}
+
+// etcdV3Client reads the keys through the json gateway of the etcd v3 api,
+// where keys and values are base64 encoded
+type etcdV3Client struct {
+	host   string
+	client *http.Client
+	logger EtcdLogger
+}
+
+// etcdV3RangeResponse holds the response of the v3 range endpoint
+type etcdV3RangeResponse struct {
+	Kvs []struct {
+		Value []byte `json:"value"`
+	} `json:"kvs"`
+}
+
+// Read returns the value of key
+func (c etcdV3Client) Read(ctx context.Context, key string) (string, error) {
+	request, err := json.Marshal(map[string][]byte{"key": []byte(key)})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		c.host+"/v3/kv/range", bytes.NewReader(request))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	body, err := readAll(resp, c.logger)
+	if err != nil {
+		return "", err
+	}
+	var content etcdV3RangeResponse
+	if err := json.Unmarshal(body, &content); err != nil {
+		return "", err
+	}
+	if len(content.Kvs) == 0 {
+		return "", ErrEtcdKeyNotFound
+	}
+	return string(content.Kvs[0].Value), nil
+}
+
+// EtcdConfig implements repository.Config over a json document read from
+// etcd. Every document read is kept in a local snapshot, used in its place
+// when etcd can't be read on startup
+type EtcdConfig struct {
+	logger       EtcdLogger
+	snapshotFile string
+	content      atomic.Value
+}
+
+// LoadEtcdConfig reads the json document at key, falling back to the
+// snapshot when etcd fails. When both fail the config is empty until the
+// first successful Update
+func LoadEtcdConfig(client EtcdClient, key, snapshotFile string, logger EtcdLogger) *EtcdConfig {
+	config := &EtcdConfig{logger: logger, snapshotFile: snapshotFile}
+	config.content.Store("")
+	value, err := client.Read(context.Background(), key)
+	if err == nil {
+		err = config.Update(value)
+	}
+	if err == nil {
+		logger.Info("Conf %s loaded", key)
+		return config
+	}
+	logger.Error("Error loading conf %s, using the snapshot %s: %s", key, snapshotFile, err)
+	if snapshotFile == "" {
+		return config
 	}
-	if jsonErr := json.Unmarshal(body, &etcd.content); jsonErr != nil {
-		logger.Error("Error %s", jsonErr.Error())
-		return etcd, jsonErr
+	snapshot, err := ioutil.ReadFile(filepath.Clean(snapshotFile))
+	if err != nil || !gjson.Valid(string(snapshot)) {
+		logger.Error("Error loading the snapshot %s: %v", snapshotFile, err)
+		return config
 	}
-	logger.Info("Conf %s loaded", url)
-	return etcd, nil
+	config.content.Store(string(snapshot))
+	return config
 }
 
-// Get gets the result of a GET method using given key
-func (v etcd) Get(key string) string {
-	if v.content == nil || &v.content.Node == nil {
-		v.logger.Error("Empty conf")
-		return ""
+// Update replaces the json document, keeping it in the snapshot. Empty or
+// invalid documents are rejected, keeping the current one
+func (c *EtcdConfig) Update(value string) error {
+	if strings.TrimSpace(value) == "" || !gjson.Valid(value) {
+		return fmt.Errorf("invalid json document %.20q", value)
 	}
-	if v.content.Node.IsDir {
-		v.logger.Error("Conf %s is a dir, i can not get some value", v.content.Node.Key)
-		return ""
+	if value == c.content.Load().(string) {
+		return nil
 	}
-	return gjson.Get(v.content.Node.Value, key).String()
+	c.content.Store(value)
+	if c.snapshotFile == "" {
+		return nil
+	}
+	// Write a temporary file first, so a failure never leaves a partial
+	// snapshot behind
+	temporary := c.snapshotFile + ".tmp"
+	if err := ioutil.WriteFile(temporary, []byte(value), 0600); err != nil {
+		c.logger.Error("Error writing the snapshot %s: %s", c.snapshotFile, err)
+		return nil
+	}
+	if err := os.Rename(temporary, c.snapshotFile); err != nil {
+		c.logger.Error("Error writing the snapshot %s: %s", c.snapshotFile, err)
+	}
+	return nil
 }
 
-// Get gets the result of a GET method using given key
-func (v etcd) GetMap(key string) map[string]string {
-	if v.content == nil || &v.content.Node == nil {
-		v.logger.Error("Empty conf")
-		return map[string]string{}
+// Get gets the value at the gjson path key of the document
+func (c *EtcdConfig) Get(key string) string {
+	return gjson.Get(c.content.Load().(string), key).String()
+}
+
+// etcdPoller reads a key periodically
+type etcdPoller struct {
+	client   EtcdClient
+	key      string
+	interval time.Duration
+	logger   EtcdLogger
+	onChange func(value string) error
+	ctx      context.Context
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+// PollEtcdKey calls onChange with the value of the key, then again each
+// time it changes, reading it every interval. The value is empty when the
+// key is missing. Close stops the polling
+func PollEtcdKey(client EtcdClient, key string, interval time.Duration, logger EtcdLogger,
+	onChange func(value string) error) io.Closer {
+	ctx, cancel := context.WithCancel(context.Background())
+	poller := &etcdPoller{
+		client:   client,
+		key:      key,
+		interval: interval,
+		logger:   logger,
+		onChange: onChange,
+		ctx:      ctx,
+		cancel:   cancel,
+		done:     make(chan struct{}),
 	}
-	if v.content.Node.IsDir {
-		v.logger.Error("Conf %s is a dir, i can not get some value", v.content.Node.Key)
-		return map[string]string{}
+	go poller.run()
+	return poller
+}
+
+// run reads the key every interval until the poller is closed
+func (p *etcdPoller) run() {
+	defer close(p.done)
+	last, first := "", true
+	for {
+		value, err := p.client.Read(p.ctx, p.key)
+		if errors.Is(err, ErrEtcdKeyNotFound) {
+			value, err = "", nil
+		}
+		switch {
+		case p.ctx.Err() != nil:
+			return
+		case err != nil:
+			p.logger.Error("Error polling %s: %s", p.key, err)
+		case first || value != last:
+			if err := p.onChange(value); err != nil {
+				p.logger.Error("Error applying %s: %s", p.key, err)
+			}
+			last, first = value, false
+		}
+		select {
+		case <-time.After(p.interval):
+		case <-p.ctx.Done():
+			return
+		}
 	}
-	result := make(map[string]string)
-	mapRes := gjson.Get(v.content.Node.Value, key).Map()
-	for k, v := range mapRes {
-		result[k] = v.String()
+}
+
+// Close stops the polling, waiting for it to end
+func (p *etcdPoller) Close() error {
+	p.cancel()
+	<-p.done
+	return nil
+}
+
+// etcdWatcher long polls a key of the etcd v2 api
+type etcdWatcher struct {
+	url      string
+	client   *http.Client
+	logger   EtcdLogger
+	onChange func(value string) error
+	retry    time.Duration
+	ctx      context.Context
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+// WatchEtcdKey calls onChange with the value of the key, then again each
+// time it changes, with an empty value when the key is missing or removed.
+// Failed requests are retried after retry. Close stops the watch
+func WatchEtcdKey(host, prefix, key string, retry time.Duration, logger EtcdLogger,
+	onChange func(value string) error) io.Closer {
+	ctx, cancel := context.WithCancel(context.Background())
+	watcher := &etcdWatcher{
+		url:      fmt.Sprintf("%s%s%s", host, prefix, key),
+		client:   &http.Client{},
+		logger:   logger,
+		onChange: onChange,
+		retry:    retry,
+		ctx:      ctx,
+		cancel:   cancel,
+		done:     make(chan struct{}),
 	}
-	return result
+	go watcher.run()
+	return watcher
+}
+
+// run reads the key and waits for its changes until the watcher is closed
+func (w *etcdWatcher) run() {
+	defer close(w.done)
+	index := 0
+	for {
+		value, next, err := w.get(index)
+		if w.ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			w.logger.Error("Error watching %s: %s", w.url, err)
+			// Read the key again, the changes may have been missed
+			index = 0
+			select {
+			case <-time.After(w.retry):
+				continue
+			case <-w.ctx.Done():
+				return
+			}
+		}
+		if err := w.onChange(value); err != nil {
+			w.logger.Error("Error applying %s: %s", w.url, err)
+		}
+		index = next
+	}
+}
+
+// get reads the key, or waits for its first change from index when index is
+// not zero. Returns the value and the index to wait for the next change
+func (w *etcdWatcher) get(index int) (value string, next int, err error) {
+	url := w.url
+	if index > 0 {
+		url = fmt.Sprintf("%s?wait=true&waitIndex=%d", w.url, index)
+	}
+	req, err := http.NewRequestWithContext(w.ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", 0, err
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return "", 0, err
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	// X-Etcd-Index is the index of the cluster when the key was read
+	clusterIndex, _ := strconv.Atoi(resp.Header.Get("X-Etcd-Index"))
+	if index == 0 && resp.StatusCode == http.StatusNotFound {
+		return "", clusterIndex + 1, nil
+	}
+	body, err := readAll(resp, w.logger)
+	if err != nil {
+		return "", 0, err
+	}
+	var content EtcdContent
+	if err := json.Unmarshal(body, &content); err != nil {
+		return "", 0, err
+	}
+	if index == 0 && clusterIndex > 0 {
+		return content.Node.Value, clusterIndex + 1, nil
+	}
+	return content.Node.Value, content.Node.ModifiedIndex + 1, nil
+}
+
+// Close stops the watch, waiting for it to end
+func (w *etcdWatcher) Close() error {
+	w.cancel()
+	<-w.done
+	return nil
 }
@@ -0,0 +1,204 @@
+package infrastructure
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatchEtcdKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v2/keys/settings", r.URL.Path)
+		switch r.URL.Query().Get("waitIndex") {
+		case "":
+			w.Header().Set("X-Etcd-Index", "5")
+			fmt.Fprint(w, `{"action":"get","node":{"key":"/settings","value":"a","modifiedIndex":3}}`)
+		case "6":
+			fmt.Fprint(w, `{"action":"set","node":{"key":"/settings","value":"b","modifiedIndex":7}}`)
+		case "8":
+			fmt.Fprint(w, `{"action":"delete","node":{"key":"/settings","modifiedIndex":9}}`)
+		default:
+			<-r.Context().Done()
+		}
+	}))
+	defer server.Close()
+
+	values := make(chan string, 10)
+	watcher := WatchEtcdKey(server.URL, "/v2/keys", "/settings", time.Millisecond,
+		&logRecorder{}, func(value string) error {
+			values <- value
+			return nil
+		})
+	for _, expected := range []string{"a", "b", ""} {
+		select {
+		case value := <-values:
+			assert.Equal(t, expected, value)
+		case <-time.After(time.Second):
+			t.Fatalf("value %q not received", expected)
+		}
+	}
+	assert.NoError(t, watcher.Close())
+}
+
+func TestWatchEtcdKeyMissingAndErrors(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch {
+		case requests == 1:
+			w.WriteHeader(http.StatusInternalServerError)
+		case r.URL.Query().Get("waitIndex") == "":
+			w.Header().Set("X-Etcd-Index", "5")
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"errorCode":100,"message":"Key not found","index":5}`)
+		default:
+			assert.Equal(t, "6", r.URL.Query().Get("waitIndex"))
+			<-r.Context().Done()
+		}
+	}))
+	defer server.Close()
+
+	values := make(chan string, 10)
+	logger := &logRecorder{}
+	watcher := WatchEtcdKey(server.URL, "/v2/keys", "/settings", time.Millisecond,
+		logger, func(value string) error {
+			values <- value
+			return fmt.Errorf("err")
+		})
+	select {
+	case value := <-values:
+		assert.Equal(t, "", value)
+	case <-time.After(time.Second):
+		t.Fatal("missing key not notified")
+	}
+	assert.NoError(t, watcher.Close())
+	messages := logger.Messages()
+	assert.Contains(t, messages[0], "Error watching")
+	assert.Contains(t, messages[len(messages)-1], "Error applying")
+}
+
+func TestEtcdV2ClientRead(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/keys/regions":
+			fmt.Fprint(w, `{"action":"get","node":{"key":"/regions","value":"{\"a\":1}"}}`)
+		case "/v2/keys/dir":
+			fmt.Fprint(w, `{"action":"get","node":{"key":"/dir","dir":true}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	client, err := MakeEtcdClient(EtcdConf{Host: server.URL, Version: EtcdV2, Prefix: "/v2/keys",
+		Timeout: time.Second}, &logRecorder{})
+	assert.NoError(t, err)
+
+	value, err := client.Read(context.Background(), "/regions")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"a":1}`, value)
+	_, err = client.Read(context.Background(), "/dir")
+	assert.Error(t, err)
+	_, err = client.Read(context.Background(), "/missing")
+	assert.Equal(t, ErrEtcdKeyNotFound, err)
+}
+
+func TestEtcdV3ClientRead(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/v3/kv/range", r.URL.Path)
+		var request map[string][]byte
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		if string(request["key"]) != "/regions" {
+			fmt.Fprint(w, `{"header":{"revision":"3"}}`)
+			return
+		}
+		fmt.Fprintf(w, `{"header":{"revision":"3"},"kvs":[{"key":"%s","value":"%s"}],"count":"1"}`,
+			base64.StdEncoding.EncodeToString([]byte("/regions")),
+			base64.StdEncoding.EncodeToString([]byte(`{"a":1}`)))
+	}))
+	defer server.Close()
+	client, err := MakeEtcdClient(EtcdConf{Host: server.URL, Version: EtcdV3, Timeout: time.Second},
+		&logRecorder{})
+	assert.NoError(t, err)
+
+	value, err := client.Read(context.Background(), "/regions")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"a":1}`, value)
+	_, err = client.Read(context.Background(), "/missing")
+	assert.Equal(t, ErrEtcdKeyNotFound, err)
+
+	_, err = MakeEtcdClient(EtcdConf{Version: "v4"}, &logRecorder{})
+	assert.Error(t, err)
+}
+
+// mockEtcdClient returns the values queued, failing once they run out
+type mockEtcdClient struct {
+	mutex  sync.Mutex
+	values []string
+}
+
+func (m *mockEtcdClient) Read(ctx context.Context, key string) (string, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if len(m.values) == 0 {
+		return "", fmt.Errorf("etcd down")
+	}
+	value := m.values[0]
+	m.values = m.values[1:]
+	if value == "" {
+		return "", ErrEtcdKeyNotFound
+	}
+	return value, nil
+}
+
+func TestLoadEtcdConfig(t *testing.T) {
+	snapshot := filepath.Join(t.TempDir(), "regions.json")
+	regions := `{"region":{"13":{"link":"region_metropolitana"}}}`
+
+	config := LoadEtcdConfig(&mockEtcdClient{values: []string{regions}}, "/regions", snapshot,
+		&logRecorder{})
+	assert.Equal(t, "region_metropolitana", config.Get("region.13.link"))
+
+	// etcd is down, the snapshot is used until it's read again
+	config = LoadEtcdConfig(&mockEtcdClient{}, "/regions", snapshot, &logRecorder{})
+	assert.Equal(t, "region_metropolitana", config.Get("region.13.link"))
+	assert.NoError(t, config.Update(`{"region":{"13":{"link":"rm"}}}`))
+	assert.Equal(t, "rm", config.Get("region.13.link"))
+	assert.Error(t, config.Update(""))
+	assert.Error(t, config.Update("{"))
+	assert.Equal(t, "rm", config.Get("region.13.link"))
+
+	// Without snapshot the config stays empty
+	config = LoadEtcdConfig(&mockEtcdClient{}, "/regions", filepath.Join(t.TempDir(), "none.json"),
+		&logRecorder{})
+	assert.Equal(t, "", config.Get("region.13.link"))
+}
+
+func TestPollEtcdKey(t *testing.T) {
+	client := &mockEtcdClient{values: []string{"a", "a", "b", "", "c"}}
+	values := make(chan string, 10)
+	poller := PollEtcdKey(client, "/settings", time.Millisecond, &logRecorder{},
+		func(value string) error {
+			values <- value
+			return nil
+		})
+	for _, expected := range []string{"a", "b", "", "c"} {
+		select {
+		case value := <-values:
+			assert.Equal(t, expected, value)
+		case <-time.After(time.Second):
+			t.Fatalf("value %q not received", expected)
+		}
+	}
+	assert.NoError(t, poller.Close())
+	assert.Empty(t, values)
+}
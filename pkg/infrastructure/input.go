@@ -35,6 +35,8 @@ const (
 	COOKIES InputSource = "cookies"
 	// FORM defines the constant for the FORM params
 	FORM InputSource = "form"
+	// IDENTITY defines the constant for the authenticated identity params
+	IDENTITY InputSource = "identity"
 
 	// NotSeteable defines the error string of this error
 	NotSeteable string = "PROVIDED_INPUT_IS_NOT_SETEABLE"
@@ -106,6 +108,12 @@ func (out *targetRequest) FromForm() handlers.TargetRequest {
 	return out
 }
 
+// FromIdentity sets the authenticated request identity as handler input
+func (out *targetRequest) FromIdentity() handlers.TargetRequest {
+	out.sources = append(out.sources, IDENTITY)
+	return out
+}
+
 type inputHandler struct {
 	inputRequest *inputRequest
 	output       handlers.HandlerInput
@@ -130,12 +138,9 @@ func (ih *inputHandler) SetInputRequest(ri handlers.InputRequest, hi handlers.Ha
 // Input does the actual process of getting the input
 func (ih *inputHandler) Input() (handlers.HandlerInput, *goutils.Response) { //nolint: funlen, gocyclo
 	if ih.inputRequest.outputs == nil || len(ih.inputRequest.outputs) == 0 {
-		return ih.output, &goutils.Response{
-			Code: http.StatusInternalServerError,
-			Body: goutils.GenericError{
-				ErrorMessage: "Output was not set correctly",
-			},
-		}
+		return ih.output, handlers.MakeProblemResponse(
+			http.StatusInternalServerError, handlers.InternalErrorCode,
+			"Output was not set correctly")
 	}
 
 	hasError := false
@@ -193,17 +198,28 @@ func (ih *inputHandler) Input() (handlers.HandlerInput, *goutils.Response) { //n
 						source,
 						reflectedOutput,
 					) != nil
+			case IDENTITY:
+				hasError = hasError ||
+					ih.parseInput(
+						ih.identityToMap(ih.inputRequest.httpRequest),
+						source,
+						reflectedOutput,
+					) != nil
 			}
 		}
 	}
 
 	if hasError {
-		return ih.output, &goutils.Response{
-			Code: http.StatusInternalServerError,
-			Body: goutils.GenericError{
-				ErrorMessage: "Output was not set correctly",
-			},
-		}
+		return ih.output, handlers.MakeProblemResponse(
+			http.StatusBadRequest, handlers.InvalidInputCode,
+			"Input could not be parsed")
+	}
+	fieldErrors := []handlers.FieldError{}
+	for _, output := range ih.inputRequest.outputs {
+		fieldErrors = append(fieldErrors, validateInput(reflect.ValueOf(output.out))...)
+	}
+	if len(fieldErrors) > 0 {
+		return ih.output, handlers.MakeValidationErrorResponse(fieldErrors)
 	}
 	return ih.output, nil
 }
@@ -234,6 +250,14 @@ func (ih *inputHandler) formToMap(values io.Reader) map[string]string {
 	return mapBody
 }
 
+func (ih *inputHandler) identityToMap(r *http.Request) map[string]string {
+	identity, _ := handlers.IdentityFromContext(r.Context())
+	return map[string]string{
+		"subject": identity.Subject,
+		"role":    string(identity.Role),
+	}
+}
+
 func (ih *inputHandler) parseInput(vars map[string]string, inputTag InputSource, input reflect.Value) error {
 	if input.Kind() != reflect.Ptr {
 		return ErrNotPointer
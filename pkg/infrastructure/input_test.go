@@ -7,6 +7,8 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"gopkg.in/gorilla/mux.v1"
+
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/interfaces/handlers"
 )
 
 func TestQueryParamsOK(t *testing.T) {
@@ -176,3 +178,24 @@ func TestEmptySourceOK(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, &expected, result2)
 }
+
+func TestIdentityOK(t *testing.T) {
+	type input struct {
+		Identity handlers.Identity `identity:"identity"`
+	}
+
+	result := input{}
+	identity := handlers.Identity{Subject: "jane", Role: handlers.EditorRole}
+	expected := input{identity}
+	r := httptest.NewRequest("GET", "/api/v1", nil)
+	r = r.WithContext(handlers.ContextWithIdentity(r.Context(), identity))
+
+	inputHandler := NewInputHandler()
+	ri := inputHandler.NewInputRequest(r)
+	ri.Set(&result).FromIdentity()
+
+	inputHandler.SetInputRequest(ri, &result)
+	result2, err := inputHandler.Input()
+	assert.Nil(t, err)
+	assert.Equal(t, &expected, result2)
+}
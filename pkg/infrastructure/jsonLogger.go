@@ -0,0 +1,113 @@
+package infrastructure
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/interfaces/loggers"
+)
+
+// Log levels, following the LoggerConf LogLevel definition
+const (
+	debugLevel = iota
+	infoLevel
+	warnLevel
+	errorLevel
+	critLevel
+)
+
+// jsonLogger struct that implements the Logger interface writing a JSON
+// object per line, so logs can be parsed and filtered by field
+type jsonLogger struct {
+	metrics EventCollector
+	level   int
+	out     io.Writer
+	mutex   *sync.Mutex
+	fields  map[string]string
+}
+
+// MakeJSONLogger creates a Logger that writes JSON lines to out
+func MakeJSONLogger(config *LoggerConf, metrics EventCollector, out io.Writer) loggers.Logger {
+	return jsonLogger{
+		metrics: metrics,
+		level:   config.LogLevel,
+		out:     out,
+		mutex:   &sync.Mutex{},
+		fields:  map[string]string{},
+	}
+}
+
+// With returns a copy of the logger that adds the field to its lines
+func (j jsonLogger) With(key, value string) loggers.Logger {
+	fields := make(map[string]string, len(j.fields)+1)
+	for k, v := range j.fields {
+		fields[k] = v
+	}
+	fields[key] = value
+	j.fields = fields
+	return j
+}
+
+// Debug logs a message at DEBUG level
+func (j jsonLogger) Debug(format string, params ...interface{}) {
+	j.write(debugLevel, "debug", format, params)
+}
+
+// Info logs a message at INFO level.
+// Info events are automatically exported to prometheus.
+func (j jsonLogger) Info(format string, params ...interface{}) {
+	j.metrics.CollectEvent(getEntityName(), getEventName(), getEventType())
+	j.write(infoLevel, "info", format, params)
+}
+
+// Success logs a message as Success event.
+// Success events are automatically exported to prometheus.
+func (j jsonLogger) Success(format string, params ...interface{}) {
+	j.metrics.CollectEvent(getEntityName(), getEventName(), getEventType())
+	j.write(infoLevel, "success", format, params)
+}
+
+// Warn logs a message at WARNING level.
+// warning events are automatically exported to prometheus.
+func (j jsonLogger) Warn(format string, params ...interface{}) {
+	j.metrics.CollectEvent(getEntityName(), getEventName(), getEventType())
+	j.write(warnLevel, "warning", format, params)
+}
+
+// Error logs a message at ERROR level.
+// Error events are automatically exported to prometheus.
+func (j jsonLogger) Error(format string, params ...interface{}) {
+	j.metrics.CollectEvent(getEntityName(), getEventName(), getEventType())
+	j.write(errorLevel, "error", format, params)
+}
+
+// Crit logs a message at CRITICAL level.
+// Critical events are automatically exported to prometheus.
+func (j jsonLogger) Crit(format string, params ...interface{}) {
+	j.metrics.CollectEvent(getEntityName(), getEventName(), getEventType())
+	j.write(critLevel, "critical", format, params)
+}
+
+// write writes the message as a JSON line when its level is enabled
+func (j jsonLogger) write(level int, levelName, format string, params []interface{}) {
+	if level < j.level {
+		return
+	}
+	entry := make(map[string]string, len(j.fields)+3)
+	for key, value := range j.fields {
+		entry[key] = value
+	}
+	entry["time"] = time.Now().UTC().Format(time.RFC3339Nano)
+	entry["level"] = levelName
+	entry["message"] = fmt.Sprintf(format, params...)
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	j.out.Write(append(line, '\n')) // nolint: errcheck
+}
@@ -0,0 +1,44 @@
+package infrastructure
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/interfaces/loggers"
+)
+
+func TestJSONLogger(t *testing.T) {
+	prom := Prometheus{}
+	ec := prom.NewEventsCollector("test_json_logger", "test")
+	out := &bytes.Buffer{}
+	logger := MakeJSONLogger(&LoggerConf{LogLevel: 1}, ec, out)
+	logger.Debug("debug")
+	logger.Info("info %d", 1)
+	logger.(loggers.FieldLogger).With(loggers.RequestIDField, "abc").Error("error")
+	logger.Warn("warning")
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Len(t, lines, 3)
+	entries := make([]map[string]string, len(lines))
+	for i, line := range lines {
+		assert.NoError(t, json.Unmarshal([]byte(line), &entries[i]))
+	}
+	assert.Equal(t, "info", entries[0]["level"])
+	assert.Equal(t, "info 1", entries[0]["message"])
+	assert.NotEmpty(t, entries[0]["time"])
+	assert.Equal(t, "error", entries[1]["level"])
+	assert.Equal(t, "abc", entries[1][loggers.RequestIDField])
+	assert.NotContains(t, entries[2], loggers.RequestIDField)
+}
+
+func TestMakeLogger(t *testing.T) {
+	logger, err := MakeLogger(&LoggerConf{Format: JSONLogFormat}, EventCollector{})
+	assert.NoError(t, err)
+	assert.IsType(t, jsonLogger{}, logger)
+	_, err = MakeLogger(&LoggerConf{Format: "xml"}, EventCollector{})
+	assert.Error(t, err)
+}
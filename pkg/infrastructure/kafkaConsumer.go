@@ -0,0 +1,179 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Yapo/logger"
+	"github.com/confluentinc/confluent-kafka-go/kafka" // nolint
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/interfaces/handlers"
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/interfaces/repository"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// MessageSource is where a KafkaConsumer reads its messages from. It's
+// implemented by kafka.Consumer, and can be replaced in tests
+type MessageSource interface {
+	ReadMessage(timeout time.Duration) (*kafka.Message, error)
+	CommitMessage(m *kafka.Message) ([]kafka.TopicPartition, error)
+	Close() error
+}
+
+// MessageHandler processes the messages read by a KafkaConsumer. Errors
+// wrapping handlers.ErrPoisonMessage send the message to the dead letter
+// topic, any other error retries it. ctx holds the span timing the message
+type MessageHandler interface {
+	Handle(ctx context.Context, message []byte) error
+}
+
+// KafkaConsumer reads the messages of a topic one at a time, and commits
+// each one only once it has been handled or set aside in the dead letter topic
+type KafkaConsumer struct {
+	source          MessageSource
+	handler         MessageHandler
+	deadLetters     repository.KafkaProducer
+	deadLetterTopic string
+	pollTimeout     time.Duration
+	retryBackoff    time.Duration
+	stop            chan struct{}
+	done            chan struct{}
+}
+
+// NewKafkaSource creates a kafka consumer subscribed to the topic. Offsets
+// are never committed automatically
+func NewKafkaSource(host string, port int, groupID, topic string) (MessageSource, error) {
+	consumer, err := kafka.NewConsumer(&kafka.ConfigMap{
+		"bootstrap.servers":  fmt.Sprintf("%v:%d", host, port),
+		"group.id":           groupID,
+		"auto.offset.reset":  "earliest",
+		"enable.auto.commit": false,
+	})
+	if err != nil {
+		logger.Crit("Failed to create consumer: %s\n", err)
+		return nil, err
+	}
+	if err := consumer.SubscribeTopics([]string{topic}, nil); err != nil {
+		consumer.Close() // nolint: errcheck
+		return nil, err
+	}
+	logger.Info("Consumer subscribed to kafka topic %s as %s", topic, groupID)
+	return consumer, nil
+}
+
+// MakeKafkaConsumer creates a KafkaConsumer that passes the messages of
+// source to handler
+func MakeKafkaConsumer(source MessageSource, handler MessageHandler,
+	deadLetters repository.KafkaProducer, deadLetterTopic string,
+	pollTimeout, retryBackoff time.Duration) *KafkaConsumer {
+	return &KafkaConsumer{
+		source:          source,
+		handler:         handler,
+		deadLetters:     deadLetters,
+		deadLetterTopic: deadLetterTopic,
+		pollTimeout:     pollTimeout,
+		retryBackoff:    retryBackoff,
+		stop:            make(chan struct{}),
+		done:            make(chan struct{}),
+	}
+}
+
+// Start launches the goroutine that consumes the messages
+func (c *KafkaConsumer) Start() {
+	go c.run()
+}
+
+// run reads and processes messages until the consumer is stopped
+func (c *KafkaConsumer) run() {
+	defer close(c.done)
+	for !c.stopped() {
+		msg, err := c.source.ReadMessage(c.pollTimeout)
+		if err != nil {
+			if kerr, ok := err.(kafka.Error); !ok || kerr.Code() != kafka.ErrTimedOut {
+				logger.Error("Failed to read kafka message: %v", err)
+			}
+			continue
+		}
+		if msg != nil {
+			c.process(msg)
+		}
+	}
+}
+
+// process handles the message until it succeeds or it's sent to the dead
+// letter topic, and then commits it. Stopping the consumer leaves the
+// message uncommitted, so it is read again on the next start
+func (c *KafkaConsumer) process(msg *kafka.Message) {
+	for {
+		err := c.handle(msg)
+		if err != nil && errors.Is(err, handlers.ErrPoisonMessage) {
+			err = c.sendToDeadLetters(msg, err)
+		}
+		if err == nil {
+			break
+		}
+		logger.Error("Failed to process kafka message at offset %v: %v",
+			msg.TopicPartition.Offset, err)
+		if !c.wait(c.retryBackoff) {
+			return
+		}
+	}
+	if _, err := c.source.CommitMessage(msg); err != nil {
+		logger.Error("Failed to commit kafka message at offset %v: %v",
+			msg.TopicPartition.Offset, err)
+	}
+}
+
+// handle passes the message to the handler within a consumer span, so every
+// attempt to process it is traced
+func (c *KafkaConsumer) handle(msg *kafka.Message) (err error) {
+	topic := ""
+	if msg.TopicPartition.Topic != nil {
+		topic = *msg.TopicPartition.Topic
+	}
+	ctx, span := startSpan(context.Background(), "consume "+topic, trace.SpanKindConsumer,
+		attribute.String("messaging.system", "kafka"),
+		attribute.String("messaging.destination.name", topic),
+		attribute.Int64("messaging.kafka.offset", int64(msg.TopicPartition.Offset)),
+	)
+	defer func() { endSpan(span, err) }()
+	return c.handler.Handle(ctx, msg.Value)
+}
+
+// sendToDeadLetters sets aside a message that can't be processed
+func (c *KafkaConsumer) sendToDeadLetters(msg *kafka.Message, cause error) error {
+	logger.Error("Sending kafka message at offset %v to %s: %v",
+		msg.TopicPartition.Offset, c.deadLetterTopic, cause)
+	return c.deadLetters.SendKeyedMessage(c.deadLetterTopic, msg.Key, msg.Value)
+}
+
+// wait sleeps for the given duration, returns false if the consumer is
+// stopped meanwhile
+func (c *KafkaConsumer) wait(d time.Duration) bool {
+	select {
+	case <-c.stop:
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+// stopped tells if Close was called
+func (c *KafkaConsumer) stopped() bool {
+	select {
+	case <-c.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// Close stops consuming, waits for the message being processed and closes
+// the source
+func (c *KafkaConsumer) Close() error {
+	close(c.stop)
+	<-c.done
+	return c.source.Close()
+}
@@ -0,0 +1,151 @@
+package infrastructure
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka" // nolint
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/interfaces/handlers"
+)
+
+// memorySource is an in-memory stand-in of a kafka consumer
+type memorySource struct {
+	messages  chan *kafka.Message
+	mutex     sync.Mutex
+	committed []*kafka.Message
+}
+
+func newMemorySource(values ...string) *memorySource {
+	source := &memorySource{messages: make(chan *kafka.Message, len(values))}
+	for i, value := range values {
+		source.messages <- &kafka.Message{
+			TopicPartition: kafka.TopicPartition{Offset: kafka.Offset(i)},
+			Value:          []byte(value),
+		}
+	}
+	return source
+}
+
+func (s *memorySource) ReadMessage(timeout time.Duration) (*kafka.Message, error) {
+	select {
+	case msg := <-s.messages:
+		return msg, nil
+	case <-time.After(timeout):
+		return nil, nil
+	}
+}
+
+func (s *memorySource) CommitMessage(m *kafka.Message) ([]kafka.TopicPartition, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.committed = append(s.committed, m)
+	return nil, nil
+}
+
+func (s *memorySource) Committed() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	values := []string{}
+	for _, m := range s.committed {
+		values = append(values, string(m.Value))
+	}
+	return values
+}
+
+func (s *memorySource) Close() error {
+	return nil
+}
+
+type mockMessageHandler struct {
+	mock.Mock
+}
+
+func (m *mockMessageHandler) Handle(ctx context.Context, message []byte) error {
+	args := m.Called(string(message))
+	return args.Error(0)
+}
+
+type mockKafkaProducer struct {
+	mock.Mock
+}
+
+func (m *mockKafkaProducer) SendMessage(topic string, message []byte) error {
+	args := m.Called(topic, message)
+	return args.Error(0)
+}
+
+func (m *mockKafkaProducer) SendKeyedMessage(topic string, key, message []byte) error {
+	args := m.Called(topic, key, string(message))
+	return args.Error(0)
+}
+
+func (m *mockKafkaProducer) SendMessageAsync(topic string, key, message []byte,
+	onDelivery func(error)) error {
+	args := m.Called(topic, key, string(message))
+	return args.Error(0)
+}
+
+func (m *mockKafkaProducer) Close() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+func TestKafkaConsumerCommitsHandledMessages(t *testing.T) {
+	source := newMemorySource("a", "b")
+	mHandler := &mockMessageHandler{}
+	mHandler.On("Handle", "a").Return(nil)
+	mHandler.On("Handle", "b").Return(nil)
+	consumer := MakeKafkaConsumer(source, mHandler, nil, "dlq",
+		time.Millisecond, time.Millisecond)
+	consumer.Start()
+	assert.Eventually(t, func() bool {
+		return len(source.Committed()) == 2
+	}, time.Second, time.Millisecond)
+	assert.NoError(t, consumer.Close())
+	assert.Equal(t, []string{"a", "b"}, source.Committed())
+	mHandler.AssertExpectations(t)
+}
+
+func TestKafkaConsumerRetriesFailedMessages(t *testing.T) {
+	source := newMemorySource()
+	mHandler := &mockMessageHandler{}
+	mHandler.On("Handle", "a").Return(fmt.Errorf("err")).Once()
+	mHandler.On("Handle", "a").Return(nil).Once()
+	consumer := MakeKafkaConsumer(source, mHandler, nil, "dlq",
+		time.Millisecond, time.Millisecond)
+	consumer.process(&kafka.Message{Value: []byte("a")})
+	assert.Equal(t, []string{"a"}, source.Committed())
+	mHandler.AssertExpectations(t)
+}
+
+func TestKafkaConsumerSendsPoisonToDeadLetters(t *testing.T) {
+	source := newMemorySource()
+	mHandler := &mockMessageHandler{}
+	mProducer := &mockKafkaProducer{}
+	mHandler.On("Handle", "a").Return(fmt.Errorf("%w: bad", handlers.ErrPoisonMessage))
+	mProducer.On("SendKeyedMessage", "dlq", []byte("k"), "a").Return(nil)
+	consumer := MakeKafkaConsumer(source, mHandler, mProducer, "dlq",
+		time.Millisecond, time.Millisecond)
+	consumer.process(&kafka.Message{Key: []byte("k"), Value: []byte("a")})
+	assert.Equal(t, []string{"a"}, source.Committed())
+	mHandler.AssertExpectations(t)
+	mProducer.AssertExpectations(t)
+}
+
+func TestKafkaConsumerStopLeavesMessageUncommitted(t *testing.T) {
+	source := newMemorySource()
+	mHandler := &mockMessageHandler{}
+	mHandler.On("Handle", "a").Return(fmt.Errorf("err"))
+	consumer := MakeKafkaConsumer(source, mHandler, nil, "dlq",
+		time.Millisecond, time.Hour)
+	close(consumer.stop)
+	consumer.process(&kafka.Message{Value: []byte("a")})
+	assert.Empty(t, source.Committed())
+	mHandler.AssertExpectations(t)
+}
@@ -1,17 +1,80 @@
 package infrastructure
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/Yapo/logger"
 	"github.com/confluentinc/confluent-kafka-go/kafka" // nolint
+	"github.com/prometheus/client_golang/prometheus"
 	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/interfaces/repository"
 )
 
-// KafkaProducer struct representing a message producer for kafka
+// defaultKafkaCheckTimeout bounds the health check without a deadline
+const defaultKafkaCheckTimeout = 5 * time.Second
+
+// ErrProducerBufferFull is returned by SendMessageAsync when the producer
+// already holds as many undelivered messages as its buffer allows
+var ErrProducerBufferFull = errors.New("kafka producer buffer is full")
+
+// KafkaProducer struct representing a message producer for kafka. Every
+// delivery report is handled by a single goroutine: synchronous sends wait
+// for the report of their message, asynchronous ones only take a slot of
+// the buffer that is released once the report arrives
 type KafkaProducer struct {
 	producer *kafka.Producer
+	// buffer bounds the messages waiting for a delivery report
+	buffer chan struct{}
+	// flushTimeoutMS is how long Close waits for pending messages
+	flushTimeoutMS int
+	metrics        *KafkaProducerMetrics
+	// done is closed when the delivery reports goroutine ends
+	done chan struct{}
+}
+
+// KafkaProducerMetrics counts the messages acknowledged and rejected by kafka
+type KafkaProducerMetrics struct {
+	delivered *prometheus.CounterVec
+	failed    *prometheus.CounterVec
+}
+
+// NewKafkaProducerMetrics creates and registers the kafka producer counters
+func (*Prometheus) NewKafkaProducerMetrics() *KafkaProducerMetrics {
+	metrics := &KafkaProducerMetrics{
+		delivered: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "kafka_producer_delivered_messages_total",
+				Help: "A counter of messages acknowledged by kafka.",
+			},
+			[]string{"topic"},
+		),
+		failed: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "kafka_producer_failed_messages_total",
+				Help: "A counter of messages that could not be delivered to kafka.",
+			},
+			[]string{"topic"},
+		),
+	}
+	prometheus.MustRegister(metrics.delivered, metrics.failed)
+	return metrics
+}
+
+// collectDelivered counts a message acknowledged by kafka
+func (m *KafkaProducerMetrics) collectDelivered(topic string) {
+	if m != nil {
+		m.delivered.WithLabelValues(topic).Inc()
+	}
+}
+
+// collectFailed counts a message that could not be delivered
+func (m *KafkaProducerMetrics) collectFailed(topic string) {
+	if m != nil {
+		m.failed.WithLabelValues(topic).Inc()
+	}
 }
 
 // NewKafkaProducer creates a new KafkaProducer with the given brokers
@@ -24,7 +87,9 @@ func NewKafkaProducer(
 	lingerMS int,
 	requestTimeoutMS int,
 	enableIdempotence bool,
-
+	bufferSize int,
+	flushTimeoutMS int,
+	metrics *KafkaProducerMetrics,
 ) (repository.KafkaProducer, error) {
 	conf := &kafka.ConfigMap{
 		"bootstrap.servers":  fmt.Sprintf("%v:%d", host, port),
@@ -45,31 +110,156 @@ func NewKafkaProducer(
 	} else {
 		logger.Info("Producer connected to kafka using config: \n%+v\n", conf)
 	}
-	return &KafkaProducer{producer: producer}, nil
+	k := &KafkaProducer{
+		producer:       producer,
+		buffer:         make(chan struct{}, bufferSize),
+		flushTimeoutMS: flushTimeoutMS,
+		metrics:        metrics,
+		done:           make(chan struct{}),
+	}
+	go k.handleEvents(producer.Events())
+	return k, nil
 }
 
 // SendMessage sends a message with the specified topic
-func (k KafkaProducer) SendMessage(topic string, message []byte) error {
-	deliveryChan := make(chan kafka.Event)
+func (k *KafkaProducer) SendMessage(topic string, message []byte) error {
+	return k.SendKeyedMessage(topic, nil, message)
+}
+
+// SendKeyedMessage sends a message with the specified topic and key, and
+// waits until kafka acknowledges it
+func (k *KafkaProducer) SendKeyedMessage(topic string, key, message []byte) error {
+	result := make(chan error, 1)
+	k.buffer <- struct{}{}
+	if err := k.produce(topic, key, message, result); err != nil {
+		return err
+	}
+	return <-result
+}
+
+// SendMessageAsync enqueues a message with the specified topic and key
+// without waiting for kafka. It fails right away when the buffer is full.
+// onDelivery, if given, is called with the delivery result from the delivery
+// reports goroutine, so it must not block
+func (k *KafkaProducer) SendMessageAsync(topic string, key, message []byte,
+	onDelivery func(error)) error {
+	select {
+	case k.buffer <- struct{}{}:
+	default:
+		k.metrics.collectFailed(topic)
+		return ErrProducerBufferFull
+	}
+	// a nil func must not reach the report as a typed value
+	var opaque interface{}
+	if onDelivery != nil {
+		opaque = onDelivery
+	}
+	return k.produce(topic, key, message, opaque)
+}
+
+// produce hands the message to kafka, opaque is passed along to its delivery
+// report. The caller must have taken a slot of the buffer, which is given
+// back if the message can't be enqueued
+func (k *KafkaProducer) produce(topic string, key, message []byte, opaque interface{}) error {
 	err := k.producer.Produce(&kafka.Message{
 		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
+		Key:            key,
 		Value:          message,
-	}, deliveryChan)
-	e := <-deliveryChan
-	m := e.(*kafka.Message)
-	err = m.TopicPartition.Error
+		Opaque:         opaque,
+	}, nil)
 	if err != nil {
-		logger.Error("Failed to send the message %s: %v", string(message), err)
+		k.release()
+		k.metrics.collectFailed(topic)
+		logger.Error("Failed to enqueue the message %s: %v", string(message), err)
+	}
+	return err
+}
+
+// handleEvents reads the delivery reports until the producer is closed
+func (k *KafkaProducer) handleEvents(events chan kafka.Event) {
+	defer close(k.done)
+	for e := range events {
+		k.handleEvent(e)
+	}
+}
+
+// handleEvent processes a single event coming from kafka
+func (k *KafkaProducer) handleEvent(e kafka.Event) {
+	switch ev := e.(type) {
+	case *kafka.Message:
+		k.report(ev)
+	case kafka.Error:
+		logger.Error("Kafka producer error: %v", ev)
+	default:
+		logger.Debug("Ignored kafka producer event: %v", ev)
+	}
+}
+
+// report frees the buffer slot of a message and passes its delivery result
+// to the sender, through the channel it waits on or its callback
+func (k *KafkaProducer) report(m *kafka.Message) {
+	k.release()
+	topic := ""
+	if m.TopicPartition.Topic != nil {
+		topic = *m.TopicPartition.Topic
+	}
+	err := m.TopicPartition.Error
+	if err != nil {
+		k.metrics.collectFailed(topic)
+		logger.Error("Failed to send the message %s: %v", string(m.Value), err)
 	} else {
-		logger.Info("Delivered message to topic %s [%d] at offset %v",
-			*m.TopicPartition.Topic, m.TopicPartition.Partition, m.TopicPartition.Offset)
+		k.metrics.collectDelivered(topic)
+		logger.Debug("Delivered message to topic %s [%d] at offset %v",
+			topic, m.TopicPartition.Partition, m.TopicPartition.Offset)
 	}
-	close(deliveryChan)
+	switch sender := m.Opaque.(type) {
+	case chan error:
+		sender <- err
+	case func(error):
+		sender(err)
+	}
+}
+
+// release gives back a slot of the buffer
+func (k *KafkaProducer) release() {
+	select {
+	case <-k.buffer:
+	default:
+	}
+}
+
+// Check asks kafka for the cluster metadata, it's used by the readiness probe
+func (k *KafkaProducer) Check(ctx context.Context) error {
+	timeout := defaultKafkaCheckTimeout
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+	_, err := k.producer.GetMetadata(nil, false, int(timeout/time.Millisecond))
 	return err
 }
 
-// Close close the KafkaProducer
-func (k KafkaProducer) Close() error {
+// Close waits for the pending messages up to the flush timeout and closes
+// the KafkaProducer
+func (k *KafkaProducer) Close() error {
+	return k.Shutdown(context.Background())
+}
+
+// Shutdown is Close giving up on the pending messages earlier when the
+// deadline of ctx comes before the flush timeout
+func (k *KafkaProducer) Shutdown(ctx context.Context) error {
+	timeoutMS := k.flushTimeoutMS
+	if deadline, ok := ctx.Deadline(); ok {
+		if left := int(time.Until(deadline) / time.Millisecond); left < timeoutMS {
+			timeoutMS = left
+		}
+	}
+	if timeoutMS < 0 {
+		timeoutMS = 0
+	}
+	if pending := k.producer.Flush(timeoutMS); pending > 0 {
+		logger.Error("Kafka producer closed with %d undelivered messages", pending)
+	}
 	k.producer.Close()
+	<-k.done
 	return nil
 }
@@ -0,0 +1,74 @@
+package infrastructure
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka" // nolint
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func makeTestKafkaProducer(bufferSize int) *KafkaProducer {
+	return &KafkaProducer{
+		buffer: make(chan struct{}, bufferSize),
+		metrics: &KafkaProducerMetrics{
+			delivered: prometheus.NewCounterVec(prometheus.CounterOpts{Name: "delivered"}, []string{"topic"}),
+			failed:    prometheus.NewCounterVec(prometheus.CounterOpts{Name: "failed"}, []string{"topic"}),
+		},
+	}
+}
+
+func TestKafkaProducerReportDelivered(t *testing.T) {
+	k := makeTestKafkaProducer(1)
+	k.buffer <- struct{}{}
+	topic := "topic"
+	result := make(chan error, 1)
+	k.handleEvent(&kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic},
+		Opaque:         result,
+	})
+	assert.NoError(t, <-result)
+	assert.Len(t, k.buffer, 0)
+	assert.Equal(t, float64(1), testutil.ToFloat64(k.metrics.delivered.WithLabelValues(topic)))
+}
+
+func TestKafkaProducerReportCallback(t *testing.T) {
+	k := makeTestKafkaProducer(1)
+	k.buffer <- struct{}{}
+	topic := "topic"
+	var delivered error = fmt.Errorf("not reported")
+	k.handleEvent(&kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic},
+		Opaque:         func(err error) { delivered = err },
+	})
+	assert.NoError(t, delivered)
+	assert.Len(t, k.buffer, 0)
+}
+
+func TestKafkaProducerReportFailed(t *testing.T) {
+	k := makeTestKafkaProducer(1)
+	k.buffer <- struct{}{}
+	topic := "topic"
+	k.handleEvent(&kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Error: fmt.Errorf("err")},
+	})
+	assert.Len(t, k.buffer, 0)
+	assert.Equal(t, float64(1), testutil.ToFloat64(k.metrics.failed.WithLabelValues(topic)))
+}
+
+func TestKafkaProducerIgnoresOtherEvents(t *testing.T) {
+	k := makeTestKafkaProducer(1)
+	k.buffer <- struct{}{}
+	k.handleEvent(kafka.Error{})
+	assert.Len(t, k.buffer, 1)
+}
+
+func TestKafkaProducerAsyncBufferFull(t *testing.T) {
+	k := makeTestKafkaProducer(1)
+	k.buffer <- struct{}{}
+	err := k.SendMessageAsync("topic", nil, []byte("{}"), nil)
+	assert.Equal(t, ErrProducerBufferFull, err)
+	assert.Equal(t, float64(1), testutil.ToFloat64(k.metrics.failed.WithLabelValues("topic")))
+}
@@ -0,0 +1,77 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+)
+
+// leaderLockPrefix namespaces the advisory locks taken by this service
+const leaderLockPrefix = "premium-carousel-api:job:"
+
+// PgsqlLeaderElector elects the leader of each job using postgres session
+// advisory locks. The process holding the lock of a job leads it until it
+// closes the elector or loses its database connection
+type PgsqlLeaderElector struct {
+	db    *sql.DB
+	conns map[string]*sql.Conn
+	mutex sync.Mutex
+}
+
+// MakePgsqlLeaderElector creates a new PgsqlLeaderElector using the handler
+// connection pool
+func MakePgsqlLeaderElector(handler *PgsqlHandler) *PgsqlLeaderElector {
+	return &PgsqlLeaderElector{
+		db:    handler.Conn,
+		conns: make(map[string]*sql.Conn),
+	}
+}
+
+// IsLeader reports whether this process holds the job lock, trying to take
+// it when not. Locks belong to a database session, so a dedicated
+// connection is kept for every job led
+func (e *PgsqlLeaderElector) IsLeader(job string) (bool, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	ctx := context.Background()
+	if conn, ok := e.conns[job]; ok {
+		if conn.PingContext(ctx) == nil {
+			return true, nil
+		}
+		// the session is gone along with its lock
+		conn.Close() // nolint: errcheck
+		delete(e.conns, job)
+	}
+	conn, err := e.db.Conn(ctx)
+	if err != nil {
+		return false, err
+	}
+	var acquired bool
+	err = conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock(hashtext($1))",
+		leaderLockPrefix+job).Scan(&acquired)
+	if err != nil || !acquired {
+		conn.Close() // nolint: errcheck
+		return false, err
+	}
+	e.conns[job] = conn
+	return true, nil
+}
+
+// Close releases every lock held and its connection
+func (e *PgsqlLeaderElector) Close() error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	var lastErr error
+	for job, conn := range e.conns {
+		_, err := conn.ExecContext(context.Background(),
+			"SELECT pg_advisory_unlock(hashtext($1))", leaderLockPrefix+job)
+		if err != nil {
+			lastErr = err
+		}
+		if err := conn.Close(); err != nil {
+			lastErr = err
+		}
+		delete(e.conns, job)
+	}
+	return lastErr
+}
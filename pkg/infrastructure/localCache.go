@@ -0,0 +1,192 @@
+package infrastructure
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// LocalCacheMetrics holds the metrics of a LocalCache
+type LocalCacheMetrics struct {
+	// lookups counts the reads of the cache by result
+	lookups *prometheus.CounterVec
+	// evictions counts the entries dropped before being deleted, by reason
+	evictions *prometheus.CounterVec
+	// entries and size are the number of entries held and their bytes
+	entries prometheus.Gauge
+	size    prometheus.Gauge
+}
+
+// makeLocalCacheMetrics creates the local cache metrics without registering
+// them
+func makeLocalCacheMetrics() *LocalCacheMetrics {
+	return &LocalCacheMetrics{
+		lookups: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "local_cache_lookups_total",
+				Help: "A counter of in-process cache reads, by hit or miss.",
+			},
+			[]string{"result"},
+		),
+		evictions: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "local_cache_evictions_total",
+				Help: "A counter of in-process cache entries evicted, by expired or size.",
+			},
+			[]string{"reason"},
+		),
+		entries: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "local_cache_entries",
+				Help: "A gauge of entries held by the in-process cache.",
+			},
+		),
+		size: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "local_cache_bytes",
+				Help: "A gauge of bytes held by the in-process cache.",
+			},
+		),
+	}
+}
+
+// NewLocalCacheMetrics creates and registers the local cache metrics
+func (*Prometheus) NewLocalCacheMetrics() *LocalCacheMetrics {
+	metrics := makeLocalCacheMetrics()
+	prometheus.MustRegister(metrics.lookups, metrics.evictions, metrics.entries, metrics.size)
+	return metrics
+}
+
+// collectLookup counts a read of the cache as a hit or a miss
+func (m *LocalCacheMetrics) collectLookup(hit bool) {
+	if m == nil {
+		return
+	}
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	m.lookups.WithLabelValues(result).Inc()
+}
+
+// collectEviction counts an entry evicted for reason
+func (m *LocalCacheMetrics) collectEviction(reason string) {
+	if m != nil {
+		m.evictions.WithLabelValues(reason).Inc()
+	}
+}
+
+// setSize sets the entries held and their bytes
+func (m *LocalCacheMetrics) setSize(entries int, size int64) {
+	if m != nil {
+		m.entries.Set(float64(entries))
+		m.size.Set(float64(size))
+	}
+}
+
+// localCacheEntry is a value held by LocalCache
+type localCacheEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+// LocalCache is an in-process LRU cache implementing repository.LocalCache.
+// It holds up to maxEntries values summing up to maxBytes, counting their
+// keys, evicting the least recently used ones. A zero limit disables it.
+// It's safe for concurrent use
+type LocalCache struct {
+	maxEntries int
+	maxBytes   int64
+	metrics    *LocalCacheMetrics
+	now        func() time.Time
+	mutex      sync.Mutex
+	size       int64
+	// recent has the entries from the most to the least recently used
+	recent *list.List
+	items  map[string]*list.Element
+}
+
+// NewLocalCache creates a LocalCache with the given limits. metrics may be
+// nil
+func NewLocalCache(maxEntries int, maxBytes int64, metrics *LocalCacheMetrics) *LocalCache {
+	return &LocalCache{
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		metrics:    metrics,
+		now:        time.Now,
+		recent:     list.New(),
+		items:      map[string]*list.Element{},
+	}
+}
+
+// Get returns the value of key, if it's held and not expired
+func (c *LocalCache) Get(key string) ([]byte, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	element, ok := c.items[key]
+	if ok && c.now().After(element.Value.(*localCacheEntry).expiresAt) {
+		c.remove(element)
+		c.metrics.collectEviction("expired")
+		c.metrics.setSize(len(c.items), c.size)
+		ok = false
+	}
+	c.metrics.collectLookup(ok)
+	if !ok {
+		return nil, false
+	}
+	c.recent.MoveToFront(element)
+	return element.Value.(*localCacheEntry).value, true
+}
+
+// Set holds value under key for expiration, evicting the least recently used
+// values past the limits. Values not fitting in the cache aren't held
+func (c *LocalCache) Set(key string, value []byte, expiration time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if element, ok := c.items[key]; ok {
+		c.remove(element)
+	}
+	size := int64(len(key) + len(value))
+	if expiration <= 0 || c.maxEntries <= 0 || size > c.maxBytes {
+		c.metrics.setSize(len(c.items), c.size)
+		return
+	}
+	c.items[key] = c.recent.PushFront(&localCacheEntry{
+		key:       key,
+		value:     value,
+		expiresAt: c.now().Add(expiration),
+	})
+	c.size += size
+	for len(c.items) > c.maxEntries || c.size > c.maxBytes {
+		c.remove(c.recent.Back())
+		c.metrics.collectEviction("size")
+	}
+	c.metrics.setSize(len(c.items), c.size)
+}
+
+// Del drops the value of key
+func (c *LocalCache) Del(key string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if element, ok := c.items[key]; ok {
+		c.remove(element)
+		c.metrics.setSize(len(c.items), c.size)
+	}
+}
+
+// Len returns the number of entries held, expired or not
+func (c *LocalCache) Len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return len(c.items)
+}
+
+// remove drops element. The caller must hold the mutex
+func (c *LocalCache) remove(element *list.Element) {
+	entry := c.recent.Remove(element).(*localCacheEntry)
+	delete(c.items, entry.key)
+	c.size -= int64(len(entry.key) + len(entry.value))
+}
@@ -0,0 +1,91 @@
+package infrastructure
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalCacheGetSet(t *testing.T) {
+	metrics := makeLocalCacheMetrics()
+	cache := NewLocalCache(10, 1024, metrics)
+	_, ok := cache.Get("a")
+	assert.False(t, ok)
+	cache.Set("a", []byte("1"), time.Minute)
+	cache.Set("a", []byte("22"), time.Minute)
+	value, ok := cache.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("22"), value)
+	cache.Del("a")
+	_, ok = cache.Get("a")
+	assert.False(t, ok)
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.lookups.WithLabelValues("hit")))
+	assert.Equal(t, float64(2), testutil.ToFloat64(metrics.lookups.WithLabelValues("miss")))
+	assert.Equal(t, float64(0), testutil.ToFloat64(metrics.entries))
+	assert.Equal(t, float64(0), testutil.ToFloat64(metrics.size))
+}
+
+func TestLocalCacheExpiration(t *testing.T) {
+	metrics := makeLocalCacheMetrics()
+	cache := NewLocalCache(10, 1024, metrics)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+	cache.Set("a", []byte("1"), time.Second)
+	cache.Set("b", []byte("1"), 0)
+	assert.Equal(t, 1, cache.Len())
+
+	now = now.Add(2 * time.Second)
+	_, ok := cache.Get("a")
+	assert.False(t, ok)
+	assert.Equal(t, 0, cache.Len())
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.evictions.WithLabelValues("expired")))
+}
+
+func TestLocalCacheLimits(t *testing.T) {
+	metrics := makeLocalCacheMetrics()
+	cache := NewLocalCache(2, 8, metrics)
+	cache.Set("a", []byte("1"), time.Minute)
+	cache.Set("b", []byte("1"), time.Minute)
+	cache.Get("a")
+	// b is the least recently used
+	cache.Set("c", []byte("1"), time.Minute)
+	_, ok := cache.Get("b")
+	assert.False(t, ok)
+	_, ok = cache.Get("a")
+	assert.True(t, ok)
+
+	// c and a don't fit with d, 2 + 2 + 7 bytes
+	cache.Set("d", []byte("123456"), time.Minute)
+	assert.Equal(t, 1, cache.Len())
+	// Values bigger than the cache aren't held
+	cache.Set("e", []byte("12345678"), time.Minute)
+	_, ok = cache.Get("e")
+	assert.False(t, ok)
+	assert.Equal(t, float64(3), testutil.ToFloat64(metrics.evictions.WithLabelValues("size")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.entries))
+	assert.Equal(t, float64(7), testutil.ToFloat64(metrics.size))
+}
+
+func TestLocalCacheConcurrency(t *testing.T) {
+	cache := NewLocalCache(50, 1024, nil)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				key := fmt.Sprintf("%d-%d", i, j%60)
+				cache.Set(key, []byte(key), time.Minute)
+				cache.Get(key)
+				cache.Del(fmt.Sprintf("%d-%d", i, j%7))
+			}
+		}(i)
+	}
+	wg.Wait()
+	assert.LessOrEqual(t, cache.Len(), 50)
+}
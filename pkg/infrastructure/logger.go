@@ -0,0 +1,27 @@
+package infrastructure
+
+import (
+	"fmt"
+	"os"
+
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/interfaces/loggers"
+)
+
+const (
+	// TextLogFormat writes printf-style lines through the Yapo/logger library
+	TextLogFormat = "text"
+	// JSONLogFormat writes a JSON object per line to the standard output
+	JSONLogFormat = "json"
+)
+
+// MakeLogger creates the Logger of the configured format
+func MakeLogger(config *LoggerConf, metrics EventCollector) (loggers.Logger, error) {
+	switch config.Format {
+	case TextLogFormat, "":
+		return MakeYapoLogger(config, metrics)
+	case JSONLogFormat:
+		return MakeJSONLogger(config, metrics, os.Stdout), nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q", config.Format)
+	}
+}
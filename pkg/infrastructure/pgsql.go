@@ -1,15 +1,60 @@
 package infrastructure
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 
 	_ "github.com/jinzhu/gorm/dialects/postgres"
 	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/interfaces/loggers"
 	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/interfaces/repository"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// sqlRunner runs statements, it's implemented by sql.DB and sql.Tx
+type sqlRunner interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// startPgsqlSpan starts the client span timing a statement
+func startPgsqlSpan(ctx context.Context, statement string) (context.Context, trace.Span) {
+	operation := "SQL"
+	if fields := strings.Fields(statement); len(fields) > 0 {
+		operation = strings.ToUpper(fields[0])
+	}
+	return startSpan(ctx, "postgresql "+operation, trace.SpanKindClient,
+		attribute.String("db.system", "postgresql"),
+		attribute.String("db.operation", operation),
+		attribute.String("db.statement", statement),
+	)
+}
+
+// pgsqlExec executes a statement that returns no rows
+func pgsqlExec(ctx context.Context, runner sqlRunner, statement string, params ...interface{}) error {
+	ctx, span := startPgsqlSpan(ctx, statement)
+	_, err := runner.ExecContext(ctx, statement, params...)
+	endSpan(span, err)
+	return err
+}
+
+// pgsqlQuery executes a statement that returns rows
+func pgsqlQuery(ctx context.Context, runner sqlRunner, statement string,
+	params ...interface{}) (repository.DbResult, error) {
+	ctx, span := startPgsqlSpan(ctx, statement)
+	rows, err := runner.QueryContext(ctx, statement, params...)
+	endSpan(span, err)
+	if err != nil {
+		return new(PgsqlRow), err
+	}
+	return PgsqlRow{
+		Rows: rows,
+	}, nil
+}
+
 // PgsqlHandler allows connection with postgres database
 type PgsqlHandler struct {
 	Conn *sql.DB
@@ -17,7 +62,12 @@ type PgsqlHandler struct {
 
 // Healthcheck verifies a connection to the database is still alive
 func (handler *PgsqlHandler) Healthcheck() bool {
-	return handler.Conn.Ping() == nil
+	return handler.Check(context.Background()) == nil
+}
+
+// Check pings the database, it's used by the readiness probe
+func (handler *PgsqlHandler) Check(ctx context.Context) error {
+	return handler.Conn.PingContext(ctx)
 }
 
 // Close closes db connection
@@ -26,27 +76,68 @@ func (handler *PgsqlHandler) Close() error {
 }
 
 // Insert executes an insert query in db
-func (handler *PgsqlHandler) Insert(statement string, params ...interface{}) error {
-	_, err := handler.Conn.Exec(statement, params...)
-	return err
+func (handler *PgsqlHandler) Insert(ctx context.Context, statement string, params ...interface{}) error {
+	return pgsqlExec(ctx, handler.Conn, statement, params...)
 }
 
 // Update executes an update query in db
-func (handler *PgsqlHandler) Update(statement string, params ...interface{}) error {
-	_, err := handler.Conn.Exec(statement, params...)
-	return err
+func (handler *PgsqlHandler) Update(ctx context.Context, statement string, params ...interface{}) error {
+	return pgsqlExec(ctx, handler.Conn, statement, params...)
 }
 
 // Query executes a query that returns rows, typically a SELECT.
-func (handler *PgsqlHandler) Query(statement string, params ...interface{}) (repository.DbResult, error) {
-	rows, err := handler.Conn.Query(statement, params...)
+func (handler *PgsqlHandler) Query(ctx context.Context, statement string,
+	params ...interface{}) (repository.DbResult, error) {
+	return pgsqlQuery(ctx, handler.Conn, statement, params...)
+}
+
+// Begin starts a new transaction
+func (handler *PgsqlHandler) Begin(ctx context.Context) (repository.DbTransaction, error) {
+	tx, err := handler.Conn.BeginTx(ctx, nil)
 	if err != nil {
-		fmt.Println(err)
-		return new(PgsqlRow), err
+		return nil, err
 	}
-	return PgsqlRow{
-		Rows: rows,
-	}, nil
+	return &PgsqlTransaction{Tx: tx}, nil
+}
+
+// PgsqlTransaction runs statements in a postgres transaction
+type PgsqlTransaction struct {
+	Tx *sql.Tx
+}
+
+// Insert executes an insert query in the transaction
+func (t *PgsqlTransaction) Insert(ctx context.Context, statement string, params ...interface{}) error {
+	return pgsqlExec(ctx, t.Tx, statement, params...)
+}
+
+// Update executes an update query in the transaction
+func (t *PgsqlTransaction) Update(ctx context.Context, statement string, params ...interface{}) error {
+	return pgsqlExec(ctx, t.Tx, statement, params...)
+}
+
+// Query executes a query that returns rows in the transaction. The rows must
+// be closed before running another statement
+func (t *PgsqlTransaction) Query(ctx context.Context, statement string,
+	params ...interface{}) (repository.DbResult, error) {
+	return pgsqlQuery(ctx, t.Tx, statement, params...)
+}
+
+// Commit commits the transaction
+func (t *PgsqlTransaction) Commit() error {
+	return t.Tx.Commit()
+}
+
+// Rollback aborts the transaction
+func (t *PgsqlTransaction) Rollback() error {
+	return t.Tx.Rollback()
+}
+
+// Close rolls the transaction back unless it is already done
+func (t *PgsqlTransaction) Close() error {
+	if err := t.Tx.Rollback(); err != nil && err != sql.ErrTxDone {
+		return err
+	}
+	return nil
 }
 
 // PgsqlRow represents the result of a query
@@ -5,13 +5,12 @@ import (
 	"regexp"
 	"strings"
 
-	"github.com/Yapo/logger"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 // Prometheus provides both, a way to instrument http.HandlerFunc with
-// Prometheus, and a Prometheus http.server that can exposes metrics in a given port
+// Prometheus, and the handler exposing the metrics, served by the admin server
 type Prometheus struct {
 	// common  metrics for handlers
 	// counter metric of HTTP request qty
@@ -24,16 +23,13 @@ type Prometheus struct {
 	requestSize prometheus.ObserverVec
 	// responseSize  metric of HTTP response size
 	responseSize prometheus.ObserverVec
-	// Exporter params
-	// server exposes all metrics on /metrics path using a given port
-	server *http.Server
 	// enabled enables prometheus exporter
 	enabled bool
 }
 
 // MakePrometheusExporter Builds a fresh Prometheus, initializing its
 // metrics
-func MakePrometheusExporter(port string, enabled bool) *Prometheus {
+func MakePrometheusExporter(enabled bool) *Prometheus {
 	p := Prometheus{
 		counter: prometheus.NewCounterVec(
 			prometheus.CounterOpts{
@@ -75,9 +71,6 @@ func MakePrometheusExporter(port string, enabled bool) *Prometheus {
 
 	// Register all of the common metrics in the standard registry
 	prometheus.MustRegister(p.counter, p.duration, p.inFlight, p.requestSize, p.responseSize)
-
-	// start prometheus exposer server in /metrics endpoint
-	p.expose(port)
 	return &p
 }
 
@@ -151,24 +144,11 @@ func (v *EventCollector) CollectEvent(entityName, eventName, eventType string) {
 	v.CounterVec.WithLabelValues(entityName, eventName, eventType).Inc()
 }
 
-// expose starts prometheus exporter metrics server exposing metrics in "/metrics" path
-func (p *Prometheus) expose(port string) {
-	if !p.enabled {
-		return
-	}
-	p.server = &http.Server{Addr: ":" + port}
-	http.Handle("/metrics", promhttp.Handler())
-	go func() {
-		if err := p.server.ListenAndServe(); err != http.ErrServerClosed {
-			logger.Error("Prometheus error: %s", err)
-		}
-	}()
-}
-
-// Close closes prometheus server
-func (p *Prometheus) Close() error {
+// Handler returns the handler exposing the metrics, or nil when the
+// exporter is disabled
+func (p *Prometheus) Handler() http.Handler {
 	if !p.enabled {
 		return nil
 	}
-	return p.server.Close()
+	return promhttp.Handler()
 }
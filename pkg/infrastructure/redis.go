@@ -1,12 +1,16 @@
 package infrastructure
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"time"
 
 	"github.com/go-redis/redis"
 	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/interfaces/loggers"
 	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/interfaces/repository"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // RedisHandler handler for the request made to redis
@@ -28,9 +32,26 @@ func NewRedisHandler(address, password string, db int, logger loggers.Logger) *R
 	}
 }
 
+// client returns a client bound to ctx, and the client span timing the
+// command run on it
+func (r *RedisHandler) client(ctx context.Context, command string) (*redis.Client, trace.Span) {
+	ctx, span := startSpan(ctx, "redis "+command, trace.SpanKindClient,
+		attribute.String("db.system", "redis"),
+		attribute.String("db.operation", command),
+	)
+	return r.Client.WithContext(ctx), span
+}
+
+// Check pings redis, it's used by the readiness probe
+func (r *RedisHandler) Check(ctx context.Context) error {
+	return r.Client.WithContext(ctx).Ping().Err()
+}
+
 // HGet gets the result of a HGET command with the given key/field
-func (r *RedisHandler) HGet(key, field string) (string, bool) {
-	cmdResult := r.Client.HGet(key, field)
+func (r *RedisHandler) HGet(ctx context.Context, key, field string) (string, bool) {
+	client, span := r.client(ctx, "HGET")
+	cmdResult := client.HGet(key, field)
+	endSpan(span, ignoreRedisNil(cmdResult.Err()))
 	if err := cmdResult.Err(); err != nil {
 		r.Logger.Error("redisError: %+v\n", err)
 		return "", false
@@ -43,8 +64,10 @@ func (r *RedisHandler) HGet(key, field string) (string, bool) {
 }
 
 // HGetAll gets all result of a HGETALL command with the given key
-func (r *RedisHandler) HGetAll(key string) (map[string]string, bool) {
-	cmdResult := r.Client.HGetAll(key)
+func (r *RedisHandler) HGetAll(ctx context.Context, key string) (map[string]string, bool) {
+	client, span := r.client(ctx, "HGETALL")
+	cmdResult := client.HGetAll(key)
+	endSpan(span, cmdResult.Err())
 	if err := cmdResult.Err(); err != nil {
 		r.Logger.Error("redisError: %+v\n", err)
 		return map[string]string{}, false
@@ -57,22 +80,79 @@ func (r *RedisHandler) HGetAll(key string) (map[string]string, bool) {
 	return map[string]string{}, false
 }
 
-// Get gets the result of a GET command with the given key
-func (r *RedisHandler) Get(key string) (repository.RedisResult, error) {
-	result := r.Client.Get(key)
+// Get gets the result of a GET command with the given key. Its span tells
+// whether the key was found through the cache.hit attribute
+func (r *RedisHandler) Get(ctx context.Context, key string) (repository.RedisResult, error) {
+	client, span := r.client(ctx, "GET")
+	result := client.Get(key)
 	err := result.Err()
+	span.SetAttributes(attribute.Bool("cache.hit", err == nil))
+	endSpan(span, ignoreRedisNil(err))
 	if err == redis.Nil {
-		return result, fmt.Errorf("KEY_NOT_FOUND: %s", key)
+		return result, fmt.Errorf("%w: %s", repository.ErrKeyNotFound, key)
 	}
 	return result, err
 }
 
 // Set sets a value in redis with the given key
-func (r *RedisHandler) Set(key string, value interface{}, expiration time.Duration) error {
-	return r.Client.Set(key, value, expiration).Err()
+func (r *RedisHandler) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	client, span := r.client(ctx, "SET")
+	err := client.Set(key, value, expiration).Err()
+	endSpan(span, err)
+	return err
+}
+
+// SetNX sets a value in redis with the given key only if the key does not
+// exist. Reports whether the value was set
+func (r *RedisHandler) SetNX(ctx context.Context, key string, value interface{},
+	expiration time.Duration) (bool, error) {
+	client, span := r.client(ctx, "SETNX")
+	set, err := client.SetNX(key, value, expiration).Result()
+	endSpan(span, err)
+	return set, err
 }
 
 // Del deletes the given key from the database in redis
-func (r *RedisHandler) Del(key string) error {
-	return r.Client.Del(key).Err()
+func (r *RedisHandler) Del(ctx context.Context, key string) error {
+	client, span := r.client(ctx, "DEL")
+	err := client.Del(key).Err()
+	endSpan(span, err)
+	return err
+}
+
+// Publish sends message to the subscribers of channel
+func (r *RedisHandler) Publish(ctx context.Context, channel, message string) error {
+	client, span := r.client(ctx, "PUBLISH")
+	err := client.Publish(channel, message).Err()
+	endSpan(span, err)
+	return err
+}
+
+// Subscribe calls handler with each message sent to channel, one at a time,
+// until the returned closer is closed. The connection is restored when it's
+// lost, the messages sent meanwhile are missed
+func (r *RedisHandler) Subscribe(channel string, handler func(message string)) io.Closer {
+	pubsub := r.Client.Subscribe(channel)
+	messages := pubsub.Channel()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for message := range messages {
+			handler(message.Payload)
+		}
+	}()
+	return CloserFunc(func() error {
+		err := pubsub.Close()
+		<-done
+		return err
+	})
+}
+
+// ignoreRedisNil drops the error redis gives for missing keys, which is
+// not a failure of the command
+func ignoreRedisNil(err error) error {
+	if err == redis.Nil {
+		return nil
+	}
+	return err
 }
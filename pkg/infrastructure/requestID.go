@@ -0,0 +1,38 @@
+package infrastructure
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"regexp"
+
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/interfaces/handlers"
+)
+
+// RequestIDHeader defines the header holding the id that correlates the logs
+// of a request
+const RequestIDHeader = "X-Request-ID"
+
+// validRequestID matches the request ids propagated from the callers. Other
+// values are replaced, so they can't tamper the logs
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`) // nolint: gochecknoglobals
+
+// withRequestID propagates the request id received, or generates a new one,
+// adding it to the request context and to the response headers
+func withRequestID(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(RequestIDHeader)
+		if !validRequestID.MatchString(requestID) {
+			requestID = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, requestID)
+		handler(w, r.WithContext(handlers.ContextWithRequestID(r.Context(), requestID)))
+	}
+}
+
+// newRequestID returns a random request id
+func newRequestID() string {
+	id := make([]byte, 16)
+	rand.Read(id) // nolint: errcheck
+	return hex.EncodeToString(id)
+}
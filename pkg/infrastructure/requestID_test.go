@@ -0,0 +1,39 @@
+package infrastructure
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/interfaces/handlers"
+)
+
+func TestWithRequestIDPropagated(t *testing.T) {
+	var got string
+	handler := withRequestID(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = handlers.RequestIDFromContext(r.Context())
+	})
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set(RequestIDHeader, "abc-123")
+	handler(w, r)
+	assert.Equal(t, "abc-123", got)
+	assert.Equal(t, "abc-123", w.Header().Get(RequestIDHeader))
+}
+
+func TestWithRequestIDGenerated(t *testing.T) {
+	for _, received := range []string{"", "bad id\n{}"} {
+		var got string
+		handler := withRequestID(func(w http.ResponseWriter, r *http.Request) {
+			got, _ = handlers.RequestIDFromContext(r.Context())
+		})
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set(RequestIDHeader, received)
+		handler(w, r)
+		assert.Len(t, got, 32)
+		assert.Equal(t, got, w.Header().Get(RequestIDHeader))
+	}
+}
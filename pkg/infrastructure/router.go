@@ -11,7 +11,8 @@ import (
 	"gopkg.in/gorilla/mux.v1"
 )
 
-// Route stands for an http endpoint description
+// Route stands for an http endpoint description. Routes with a Role are
+// only served to authenticated identities holding that role or a greater one
 type Route struct {
 	Name      string
 	Method    string
@@ -19,6 +20,7 @@ type Route struct {
 	Handler   handlers.Handler
 	UseCache  bool
 	TimeCache time.Duration
+	Role      handlers.Role
 }
 
 type routeGroups struct {
@@ -33,14 +35,19 @@ type WrapperFunc func(pattern string, handler http.HandlerFunc) http.HandlerFunc
 // Routes is an array of routes with a common prefix
 type Routes []routeGroups
 
-// RouterMaker gathers route and wrapper information to build a router
+// RouterMaker gathers route and wrapper information to build a router.
+// Metrics, when set, is served on /metrics. When an Authenticator is set the
+// profiling and metrics endpoints require the DebugRole
 type RouterMaker struct {
 	Logger        loggers.Logger
 	WrapperFuncs  []WrapperFunc
 	WithProfiling bool
+	Metrics       http.Handler
+	DebugRole     handlers.Role
 	Routes        Routes
 	Cors          handlers.Cors
 	Cache         handlers.Cache
+	Authenticator Authenticator
 }
 
 // NewRouter setups a Router based on the provided routes
@@ -62,9 +69,14 @@ func (maker *RouterMaker) NewRouter() http.Handler {
 				}
 			}
 			handler := handlers.MakeJSONHandlerFunc(route.Handler, hLogger, hInputHandler, maker.Cors, cache)
+			if route.Role != "" && maker.Authenticator != nil {
+				handler = authorize(maker.Authenticator, route.Role, handler)
+			}
 			for _, wrapFunc := range maker.WrapperFuncs {
 				handler = wrapFunc(route.Pattern, handler)
 			}
+			handler = withRequestID(handler)
+			handler = withTracing(route.Method, routeGroup.Prefix+route.Pattern, handler)
 			subRouter.
 				Methods(route.Method).
 				Path(route.Pattern).
@@ -72,18 +84,28 @@ func (maker *RouterMaker) NewRouter() http.Handler {
 				Handler(handler)
 		}
 	}
+	if maker.Metrics != nil {
+		router.Handle("/metrics", maker.debug(maker.Metrics.ServeHTTP))
+	}
 	if maker.WithProfiling {
-		router.HandleFunc("/debug/pprof/", pprof.Index)
-		router.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
-		router.HandleFunc("/debug/pprof/profile", pprof.Profile)
-		router.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
-		router.HandleFunc("/debug/pprof/trace", pprof.Trace)
+		router.Handle("/debug/pprof/", maker.debug(pprof.Index))
+		router.Handle("/debug/pprof/cmdline", maker.debug(pprof.Cmdline))
+		router.Handle("/debug/pprof/profile", maker.debug(pprof.Profile))
+		router.Handle("/debug/pprof/symbol", maker.debug(pprof.Symbol))
+		router.Handle("/debug/pprof/trace", maker.debug(pprof.Trace))
 
-		router.Handle("/debug/pprof/block", pprof.Handler("block"))
-		router.Handle("/debug/pprof/goroutine", pprof.Handler("goroutine"))
-		router.Handle("/debug/pprof/heap", pprof.Handler("heap"))
-		router.Handle("/debug/pprof/mutex", pprof.Handler("mutex"))
-		router.Handle("/debug/pprof/threadcreate", pprof.Handler("threadcreate"))
+		for _, profile := range []string{"block", "goroutine", "heap", "mutex", "threadcreate"} {
+			router.Handle("/debug/pprof/"+profile, maker.debug(pprof.Handler(profile).ServeHTTP))
+		}
 	}
 	return context.ClearHandler(router)
 }
+
+// debug protects the profiling and metrics handlers with the DebugRole when
+// an Authenticator is set
+func (maker *RouterMaker) debug(handler http.HandlerFunc) http.HandlerFunc {
+	if maker.Authenticator == nil || maker.DebugRole == "" {
+		return handler
+	}
+	return authorize(maker.Authenticator, maker.DebugRole, handler)
+}
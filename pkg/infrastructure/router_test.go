@@ -7,6 +7,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/interfaces/handlers"
 )
 
 func TestRouterWithProfiling(t *testing.T) {
@@ -22,3 +24,59 @@ func TestRouterWithProfiling(t *testing.T) {
 		assert.Equal(t, with, doesMatch)
 	}
 }
+
+func TestRouterWithRoles(t *testing.T) {
+	var h handlers.HealthHandler
+	logger := &MockLoggerInfrastructure{}
+	for _, level := range []string{"Debug", "Info", "Warn", "Error", "Crit"} {
+		logger.On(level).Maybe()
+	}
+	maker := RouterMaker{
+		Logger:        logger,
+		Cors:          CorsConf{},
+		Authenticator: makeTestAuthenticator(t),
+		Routes: Routes{
+			{
+				Groups: []Route{
+					{Name: "public", Method: "GET", Pattern: "/healthcheck", Handler: &h},
+					{Name: "private", Method: "GET", Pattern: "/report", Handler: &h,
+						Role: handlers.ReaderRole},
+				},
+			},
+		},
+	}
+	router := maker.NewRouter()
+	for path, expected := range map[string]int{
+		"/healthcheck": http.StatusOK,
+		"/report":      http.StatusUnauthorized,
+	} {
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, httptest.NewRequest("GET", path, nil))
+		assert.Equal(t, expected, resp.Code, path)
+		assert.NotEmpty(t, resp.Header().Get(RequestIDHeader), path)
+	}
+}
+
+func TestRouterWithMetrics(t *testing.T) {
+	metrics := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("metrics")) // nolint: errcheck
+	})
+	maker := RouterMaker{
+		Metrics:       metrics,
+		WithProfiling: true,
+		DebugRole:     handlers.ReaderRole,
+		Authenticator: makeTestAuthenticator(t),
+	}
+	router := maker.NewRouter()
+	for _, path := range []string{"/metrics", "/debug/pprof/heap"} {
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, httptest.NewRequest("GET", path, nil))
+		assert.Equal(t, http.StatusUnauthorized, resp.Code, path)
+	}
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	req.Header.Set(APIKeyHeader, "k1")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "metrics", resp.Body.String())
+}
@@ -0,0 +1,48 @@
+package infrastructure
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseScheduleErrors(t *testing.T) {
+	for _, spec := range []string{
+		"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *",
+		"* * * 13 *", "* * * * 8", "*/0 * * * *", "a * * * *",
+		"5-1 * * * *", "@every", "@every -1m", "@every abc",
+	} {
+		_, err := ParseSchedule(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	// 2021-03-10 is a wednesday
+	from := time.Date(2021, 3, 10, 10, 7, 30, 0, time.UTC)
+	for spec, expected := range map[string]time.Time{
+		"* * * * *":         time.Date(2021, 3, 10, 10, 8, 0, 0, time.UTC),
+		"*/15 * * * *":      time.Date(2021, 3, 10, 10, 15, 0, 0, time.UTC),
+		"5 * * * *":         time.Date(2021, 3, 10, 11, 5, 0, 0, time.UTC),
+		"0 3 * * *":         time.Date(2021, 3, 11, 3, 0, 0, 0, time.UTC),
+		"0 0 1 * *":         time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC),
+		"30 9 * * 1-5":      time.Date(2021, 3, 11, 9, 30, 0, 0, time.UTC),
+		"0 0 * * 7":         time.Date(2021, 3, 14, 0, 0, 0, 0, time.UTC),
+		"0 0 15 * 5":        time.Date(2021, 3, 12, 0, 0, 0, 0, time.UTC),
+		"0 0 29 2 *":        time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC),
+		"10,20 10-11 * * *": time.Date(2021, 3, 10, 10, 10, 0, 0, time.UTC),
+		"@daily":            time.Date(2021, 3, 11, 0, 0, 0, 0, time.UTC),
+		"@every 90s":        time.Date(2021, 3, 10, 10, 9, 0, 0, time.UTC),
+	} {
+		schedule, err := ParseSchedule(spec)
+		assert.NoError(t, err, spec)
+		assert.Equal(t, expected, schedule.Next(from), spec)
+	}
+}
+
+func TestScheduleNextNeverMatches(t *testing.T) {
+	schedule, err := ParseSchedule("0 0 31 2 *")
+	assert.NoError(t, err)
+	assert.True(t, schedule.Next(time.Now()).IsZero())
+}
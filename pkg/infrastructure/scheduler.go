@@ -0,0 +1,205 @@
+package infrastructure
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/interfaces/handlers"
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/interfaces/loggers"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// LeaderElector decides which process runs each job when many replicas of
+// the service are running
+type LeaderElector interface {
+	// IsLeader reports whether this process leads the given job
+	IsLeader(job string) (bool, error)
+	io.Closer
+}
+
+// Job is a task run by the Scheduler following its schedule. Each run gets
+// a context holding the span timing it
+type Job struct {
+	Name     string
+	Schedule Schedule
+	Run      func(ctx context.Context) error
+	// busy holds a token while the job runs, so runs never overlap
+	busy chan struct{}
+}
+
+// Scheduler runs jobs in background following their schedules, or on
+// demand through Trigger. Runs of the same job never overlap, and when an
+// elector is set only its leader runs each scheduled job
+type Scheduler struct {
+	jobs    []Job
+	elector LeaderElector
+	logger  loggers.Logger
+	now     func() time.Time
+	// ctx is given to every run and cancelled by Close, so running jobs
+	// stop instead of holding the shutdown
+	ctx    context.Context
+	cancel context.CancelFunc
+	// mutex guards closed, so no run is triggered once Close waits for them
+	mutex   sync.Mutex
+	closed  bool
+	running sync.WaitGroup
+}
+
+// MakeScheduler creates a new Scheduler. The elector may be nil to run every
+// job on this process
+func MakeScheduler(elector LeaderElector, logger loggers.Logger) *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Scheduler{
+		elector: elector,
+		logger:  logger,
+		now:     time.Now,
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+// Add registers a job to be run following the given schedule, see
+// ParseSchedule for the accepted formats. An empty schedule disables the job
+func (s *Scheduler) Add(name, spec string, run func(ctx context.Context) error) error {
+	if spec == "" {
+		s.logger.Info("Job %s is disabled", name)
+		return nil
+	}
+	schedule, err := ParseSchedule(spec)
+	if err != nil {
+		return fmt.Errorf("job %s: %v", name, err)
+	}
+	s.jobs = append(s.jobs, Job{Name: name, Schedule: schedule, Run: run,
+		busy: make(chan struct{}, 1)})
+	return nil
+}
+
+// Start launches the registered jobs
+func (s *Scheduler) Start() {
+	for _, job := range s.jobs {
+		s.running.Add(1)
+		go s.loop(job)
+	}
+}
+
+// loop waits for every activation of the job until the scheduler is closed
+func (s *Scheduler) loop(job Job) {
+	defer s.running.Done()
+	for {
+		next := job.Schedule.Next(s.now())
+		if next.IsZero() {
+			s.logger.Warn("Job %s has no next run, stopping it", job.Name)
+			return
+		}
+		timer := time.NewTimer(next.Sub(s.now()))
+		select {
+		case <-s.ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			s.run(job)
+		}
+	}
+}
+
+// Trigger starts a run of the named job in background, regardless of the
+// leader election. It fails with handlers.ErrJobRunning when the job is
+// already running on this process
+func (s *Scheduler) Trigger(name string) error {
+	for _, job := range s.jobs {
+		if job.Name != name {
+			continue
+		}
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		if s.closed {
+			return fmt.Errorf("scheduler is closed")
+		}
+		if !job.acquire() {
+			return handlers.ErrJobRunning
+		}
+		s.logger.Info("Job %s triggered", job.Name)
+		s.running.Add(1)
+		go func(job Job) {
+			defer s.running.Done()
+			s.execute(job)
+		}(job)
+		return nil
+	}
+	return handlers.ErrJobNotFound
+}
+
+// run runs the job if this process leads it and the job is not running yet
+func (s *Scheduler) run(job Job) {
+	if s.elector != nil {
+		leader, err := s.elector.IsLeader(job.Name)
+		if err != nil {
+			s.logger.Error("Unable to elect the leader of job %s: %+v", job.Name, err)
+			return
+		}
+		if !leader {
+			s.logger.Debug("Skipping job %s, another process leads it", job.Name)
+			return
+		}
+	}
+	if !job.acquire() {
+		s.logger.Info("Skipping job %s, it's still running", job.Name)
+		return
+	}
+	s.execute(job)
+}
+
+// execute runs the job and releases it. Panics are recovered so a failing
+// job does not bring the service down
+func (s *Scheduler) execute(job Job) {
+	defer job.release()
+	start := s.now()
+	ctx, span := startSpan(s.ctx, "job "+job.Name, trace.SpanKindInternal,
+		attribute.String("job.name", job.Name))
+	var err error
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger.Error("Job %s panicked: %+v", job.Name, r)
+			err = fmt.Errorf("job panicked: %+v", r)
+		}
+		endSpan(span, err)
+	}()
+	if err = job.Run(ctx); err != nil {
+		s.logger.Error("Job %s failed after %s: %+v", job.Name, s.now().Sub(start), err)
+		return
+	}
+	s.logger.Info("Job %s done in %s", job.Name, s.now().Sub(start))
+}
+
+// acquire takes the job token, reporting false when the job is running
+func (job Job) acquire() bool {
+	select {
+	case job.busy <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// release gives back the job token
+func (job Job) release() {
+	<-job.busy
+}
+
+// Close stops scheduling jobs, cancels the running ones and waits for them
+// to return, then gives up the leadership of every job
+func (s *Scheduler) Close() error {
+	s.mutex.Lock()
+	s.closed = true
+	s.mutex.Unlock()
+	s.cancel()
+	s.running.Wait()
+	if s.elector != nil {
+		return s.elector.Close()
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package infrastructure
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/interfaces/handlers"
+)
+
+type mockLeaderElector struct {
+	mock.Mock
+}
+
+func (m *mockLeaderElector) IsLeader(job string) (bool, error) {
+	args := m.Called(job)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *mockLeaderElector) Close() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+func makeTestSchedulerLogger() *MockLoggerInfrastructure {
+	logger := &MockLoggerInfrastructure{}
+	logger.On("Info").Maybe()
+	logger.On("Debug").Maybe()
+	logger.On("Warn").Maybe()
+	logger.On("Error").Maybe()
+	return logger
+}
+
+func TestSchedulerAddErrors(t *testing.T) {
+	scheduler := MakeScheduler(nil, makeTestSchedulerLogger())
+	assert.Error(t, scheduler.Add("job", "* *", func(context.Context) error { return nil }))
+	assert.NoError(t, scheduler.Add("disabled", "", func(context.Context) error { return nil }))
+	assert.Empty(t, scheduler.jobs)
+}
+
+func TestSchedulerRunsJobs(t *testing.T) {
+	elector := &mockLeaderElector{}
+	elector.On("IsLeader", "job").Return(true, nil)
+	elector.On("Close").Return(nil).Once()
+	scheduler := MakeScheduler(elector, makeTestSchedulerLogger())
+	var runs int32
+	assert.NoError(t, scheduler.Add("job", "@every 5ms", func(context.Context) error {
+		if atomic.AddInt32(&runs, 1) == 1 {
+			return fmt.Errorf("err")
+		}
+		panic("recovered")
+	}))
+	scheduler.Start()
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&runs) >= 3 },
+		time.Second, time.Millisecond)
+	assert.NoError(t, scheduler.Close())
+	stopped := atomic.LoadInt32(&runs)
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, stopped, atomic.LoadInt32(&runs))
+	elector.AssertExpectations(t)
+}
+
+func TestSchedulerSkipsWhenNotLeader(t *testing.T) {
+	elector := &mockLeaderElector{}
+	elected := make(chan struct{}, 10)
+	elector.On("IsLeader", "job").Return(false, nil).
+		Run(func(mock.Arguments) { elected <- struct{}{} })
+	elector.On("Close").Return(nil).Once()
+	scheduler := MakeScheduler(elector, makeTestSchedulerLogger())
+	var runs int32
+	assert.NoError(t, scheduler.Add("job", "@every 5ms", func(context.Context) error {
+		atomic.AddInt32(&runs, 1)
+		return nil
+	}))
+	scheduler.Start()
+	<-elected
+	<-elected
+	assert.NoError(t, scheduler.Close())
+	assert.Equal(t, int32(0), atomic.LoadInt32(&runs))
+	elector.AssertExpectations(t)
+}
+
+func TestSchedulerCloseWaitsRunningJob(t *testing.T) {
+	scheduler := MakeScheduler(nil, makeTestSchedulerLogger())
+	started := make(chan struct{})
+	var done int32
+	assert.NoError(t, scheduler.Add("job", "@every 1ms", func(context.Context) error {
+		if atomic.LoadInt32(&done) == 0 {
+			close(started)
+		}
+		time.Sleep(20 * time.Millisecond)
+		atomic.StoreInt32(&done, 1)
+		return nil
+	}))
+	scheduler.Start()
+	<-started
+	assert.NoError(t, scheduler.Close())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&done))
+}
+
+func TestSchedulerCloseCancelsRunningJob(t *testing.T) {
+	scheduler := MakeScheduler(nil, makeTestSchedulerLogger())
+	started := make(chan struct{})
+	var cancelled int32
+	assert.NoError(t, scheduler.Add("job", "@every 1h", func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		atomic.StoreInt32(&cancelled, 1)
+		return ctx.Err()
+	}))
+	assert.NoError(t, scheduler.Trigger("job"))
+	<-started
+	assert.NoError(t, scheduler.Close())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&cancelled))
+}
+
+func TestSchedulerTrigger(t *testing.T) {
+	scheduler := MakeScheduler(nil, makeTestSchedulerLogger())
+	release := make(chan struct{})
+	var runs int32
+	assert.NoError(t, scheduler.Add("job", "@every 1h", func(context.Context) error {
+		atomic.AddInt32(&runs, 1)
+		<-release
+		return nil
+	}))
+	assert.Equal(t, handlers.ErrJobNotFound, scheduler.Trigger("other"))
+	assert.NoError(t, scheduler.Trigger("job"))
+	assert.Equal(t, handlers.ErrJobRunning, scheduler.Trigger("job"))
+	close(release)
+	assert.NoError(t, scheduler.Close())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&runs))
+	assert.Error(t, scheduler.Trigger("job"))
+}
@@ -39,7 +39,17 @@ func (s *Server) ListenAndServe() {
 	s.logger.Info("Closing server...")
 }
 
-// Close shuts down http.server
+// Close stops accepting connections and waits for every in-flight request
 func (s *Server) Close() error {
-	return s.server.Shutdown(context.Background())
+	return s.Shutdown(context.Background())
+}
+
+// Shutdown stops accepting connections and waits for the in-flight requests
+// until ctx is done, then the remaining connections are dropped
+func (s *Server) Shutdown(ctx context.Context) error {
+	if err := s.server.Shutdown(ctx); err != nil {
+		s.logger.Error("Server not drained: %+v", err)
+		return s.server.Close()
+	}
+	return nil
 }
@@ -1,11 +1,13 @@
 package infrastructure
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
 	"os/signal"
 	"sync"
+	"time"
 
 	"golang.org/x/sys/unix"
 )
@@ -15,6 +17,37 @@ import (
 type ShutdownSequence struct {
 	sequence  []io.Closer
 	waitGroup *sync.WaitGroup
+	// timeout bounds the close of the whole sequence, zero waits for every task
+	timeout time.Duration
+}
+
+// ContextCloser is a task that stops early once the given context is done.
+// The sequence closes these through Shutdown, so they are cancelled when the
+// shutdown deadline is reached instead of being left behind
+type ContextCloser interface {
+	Shutdown(ctx context.Context) error
+}
+
+// CloserFunc adapts a function to be pushed into the ShutdownSequence
+type CloserFunc func() error
+
+// Close calls the function
+func (f CloserFunc) Close() error {
+	return f()
+}
+
+// ShutdownFunc adapts a function that honors the shutdown deadline to be
+// pushed into the ShutdownSequence
+type ShutdownFunc func(ctx context.Context) error
+
+// Close calls the function without a deadline
+func (f ShutdownFunc) Close() error {
+	return f(context.Background())
+}
+
+// Shutdown calls the function
+func (f ShutdownFunc) Shutdown(ctx context.Context) error {
+	return f(ctx)
 }
 
 // Push pushes a new component into the stack to be turned off.
@@ -33,6 +66,14 @@ func NewShutdownSequence() *ShutdownSequence {
 	}
 }
 
+// SetTimeout sets how long the whole sequence may take to close. The deadline
+// is shared by every task: a ContextCloser is cancelled once it's reached, and
+// any other task still closing then is abandoned. It must be called before
+// Listen
+func (s *ShutdownSequence) SetTimeout(timeout time.Duration) {
+	s.timeout = timeout
+}
+
 // Wait waits until the internal waitGroup counter is zero.
 func (s *ShutdownSequence) Wait() {
 	s.waitGroup.Wait()
@@ -40,37 +81,75 @@ func (s *ShutdownSequence) Wait() {
 
 // close does the actual closing of things
 func (s *ShutdownSequence) close() {
+	ctx := context.Background()
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
 	for i := range s.sequence {
-		if err := s.sequence[i].Close(); err != nil {
-			fmt.Printf("Error closing the task of type %T: %+v\n", s.sequence[i].Close(), err)
+		task := s.sequence[i]
+		if err := closeTask(ctx, task); err != nil {
+			fmt.Printf("Error closing the task of type %T: %+v\n", task, err)
 		}
 		s.waitGroup.Done()
 	}
 }
 
-// Listen launches a go routines that waits for sigint and then stops each task in the stack.
+// closeTask closes a task within the deadline of ctx
+func closeTask(ctx context.Context, task io.Closer) error {
+	if closer, ok := task.(ContextCloser); ok {
+		return closer.Shutdown(ctx)
+	}
+	if ctx.Done() == nil {
+		return task.Close()
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- task.Close()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("not closed before the shutdown deadline: %v", ctx.Err())
+	}
+}
+
+// Listen launches a go routines that waits for SIGINT or SIGTERM and then stops each
+// task in the stack. A second signal exits right away.
 // You need to call Listen before calling Wait, otherwise you risk waiting indefinitely
 func (s *ShutdownSequence) Listen() {
 	go func() {
 		sigint := make(chan os.Signal, 1)
 		signal.Notify(sigint)
-		loop := true
-		for loop {
+		closing := false
+		for {
 			sig := <-sigint
 			if !canIgnoreSignal(sig) {
 				fmt.Printf("Received signal: %s\n", sig)
 			}
-			if sig == os.Interrupt {
-				// We received an interrupt signal, shut down.
-				fmt.Printf("Proceeding to shut down\n")
-				loop = false
+			if !isTerminationSignal(sig) {
+				continue
+			}
+			if closing {
+				fmt.Printf("Shutting down right away\n")
+				os.Exit(1)
 			}
+			// We received a termination signal, shut down.
+			fmt.Printf("Proceeding to shut down\n")
+			closing = true
+			// At the end of close all processes must be done
+			go s.close()
 		}
-		s.close()
-		// At this point all processes must be done
 	}()
 }
 
 func canIgnoreSignal(s os.Signal) bool {
 	return s == unix.SIGURG
 }
+
+// isTerminationSignal tells if the signal asks the process to stop
+func isTerminationSignal(s os.Signal) bool {
+	return s == os.Interrupt || s == unix.SIGTERM
+}
@@ -0,0 +1,68 @@
+package infrastructure
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sys/unix"
+)
+
+func TestShutdownSequenceClosesInOrder(t *testing.T) {
+	sequence := NewShutdownSequence()
+	closed := []string{}
+	for _, name := range []string{"db", "kafka", "server"} {
+		name := name
+		sequence.Push(CloserFunc(func() error {
+			closed = append(closed, name)
+			return fmt.Errorf("err")
+		}))
+	}
+	sequence.close()
+	sequence.Wait()
+	assert.Equal(t, []string{"server", "kafka", "db"}, closed)
+}
+
+func TestShutdownSequenceTimeout(t *testing.T) {
+	sequence := NewShutdownSequence()
+	sequence.SetTimeout(time.Millisecond)
+	var closeErr error
+	sequence.Push(ShutdownFunc(func(ctx context.Context) error {
+		closeErr = ctx.Err()
+		return nil
+	}))
+	sequence.Push(CloserFunc(func() error {
+		select {}
+	}))
+	sequence.close()
+	sequence.Wait()
+	assert.Equal(t, context.DeadlineExceeded, closeErr)
+}
+
+func TestShutdownSequenceSharesDeadline(t *testing.T) {
+	sequence := NewShutdownSequence()
+	sequence.SetTimeout(20 * time.Millisecond)
+	closed := []string{}
+	for _, name := range []string{"db", "kafka", "server"} {
+		name := name
+		sequence.Push(ShutdownFunc(func(ctx context.Context) error {
+			<-ctx.Done()
+			closed = append(closed, name)
+			return ctx.Err()
+		}))
+	}
+	start := time.Now()
+	sequence.close()
+	sequence.Wait()
+	assert.Less(t, int64(time.Since(start)), int64(time.Second))
+	assert.Equal(t, []string{"server", "kafka", "db"}, closed)
+}
+
+func TestIsTerminationSignal(t *testing.T) {
+	assert.True(t, isTerminationSignal(os.Interrupt))
+	assert.True(t, isTerminationSignal(unix.SIGTERM))
+	assert.False(t, isTerminationSignal(unix.SIGHUP))
+}
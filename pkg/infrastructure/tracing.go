@@ -0,0 +1,167 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/usecases"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName identifies the spans created by this service
+const tracerName = "premium-carousel-api"
+
+const (
+	// StdoutExporter writes the spans to the standard output, one json per span
+	StdoutExporter = "stdout"
+	// OTLPExporter sends the spans to an OpenTelemetry collector over http
+	OTLPExporter = "otlp"
+)
+
+// tracingCloser flushes the pending spans and stops the tracer provider
+type tracingCloser struct {
+	provider *sdktrace.TracerProvider
+}
+
+// Close flushes the pending spans and stops the tracer provider
+func (c tracingCloser) Close() error {
+	return c.Shutdown(context.Background())
+}
+
+// Shutdown flushes the pending spans until ctx is done and stops the tracer
+// provider
+func (c tracingCloser) Shutdown(ctx context.Context) error {
+	if c.provider == nil {
+		return nil
+	}
+	return c.provider.Shutdown(ctx)
+}
+
+// SetupTracing sets the global tracer provider following conf. The W3C
+// traceparent header is always propagated, even when tracing is disabled, so
+// the traces of the callers are not broken by this service. out is where the
+// stdout exporter writes the spans
+func SetupTracing(conf TracingConf, out io.Writer) (io.Closer, error) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	if !conf.Enabled {
+		return tracingCloser{}, nil
+	}
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch conf.Exporter {
+	case StdoutExporter:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(out))
+	case OTLPExporter:
+		options := []otlptracehttp.Option{otlptracehttp.WithEndpoint(conf.Endpoint)}
+		if conf.Insecure {
+			options = append(options, otlptracehttp.WithInsecure())
+		}
+		exporter, err = otlptracehttp.New(context.Background(), options...)
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q", conf.Exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(conf.SampleRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(
+			attribute.String("service.name", conf.ServiceName))),
+	)
+	otel.SetTracerProvider(provider)
+	return tracingCloser{provider: provider}, nil
+}
+
+// startSpan starts a span of this service as a child of the span held by ctx
+func startSpan(ctx context.Context, name string, kind trace.SpanKind,
+	attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name,
+		trace.WithSpanKind(kind), trace.WithAttributes(attributes...))
+}
+
+// endSpan ends span, recording err as its failure when not nil
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// otelTracer implements usecases.Tracer over the global tracer provider
+type otelTracer struct{}
+
+// otelSpan implements usecases.Span over an OpenTelemetry span
+type otelSpan struct {
+	span trace.Span
+}
+
+// MakeTracer creates a usecases.Tracer sending the spans to the global
+// tracer provider set by SetupTracing
+func MakeTracer() usecases.Tracer {
+	return otelTracer{}
+}
+
+// Start starts an internal span for the usecase
+func (otelTracer) Start(ctx context.Context, name string) (context.Context, usecases.Span) {
+	ctx, span := startSpan(ctx, name, trace.SpanKindInternal)
+	return ctx, otelSpan{span: span}
+}
+
+// End ends the span. Expected domain errors, like a product not found, are
+// recorded through their code without failing the span, only the
+// unavailable dependencies and the unknown errors fail it
+func (s otelSpan) End(err error) {
+	var domainErr *usecases.DomainError
+	if errors.As(err, &domainErr) {
+		s.span.SetAttributes(attribute.String("error.code", string(domainErr.Code)))
+		if !strings.HasSuffix(string(domainErr.Code), "_UNAVAILABLE") {
+			err = nil
+		}
+	}
+	endSpan(s.span, err)
+}
+
+// statusRecorder keeps the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader records the status code before writing it
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// withTracing times each request in a server span, continuing the trace
+// given in the traceparent header of the request, if any
+func withTracing(method, pattern string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := startSpan(ctx, method+" "+pattern, trace.SpanKindServer,
+			attribute.String("http.request.method", method),
+			attribute.String("http.route", pattern),
+		)
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		handler(recorder, r.WithContext(ctx))
+		span.SetAttributes(attribute.Int("http.response.status_code", recorder.status))
+		if recorder.status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(recorder.status))
+		}
+		span.End()
+	}
+}
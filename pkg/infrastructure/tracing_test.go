@@ -0,0 +1,110 @@
+package infrastructure
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/usecases"
+)
+
+// recordSpans sets a global tracer provider that keeps the ended spans in
+// memory, restoring the previous one when the test ends
+func recordSpans(t *testing.T) *tracetest.InMemoryExporter {
+	exporter := tracetest.NewInMemoryExporter()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return exporter
+}
+
+// spanAttribute returns the value of the attribute key of span
+func spanAttribute(span tracetest.SpanStub, key string) attribute.Value {
+	for _, kv := range span.Attributes {
+		if string(kv.Key) == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestSetupTracingDisabled(t *testing.T) {
+	closer, err := SetupTracing(TracingConf{}, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, closer.Close())
+}
+
+func TestSetupTracingUnknownExporter(t *testing.T) {
+	_, err := SetupTracing(TracingConf{Enabled: true, Exporter: "zipkin"}, nil)
+	assert.Error(t, err)
+}
+
+func TestSetupTracingStdout(t *testing.T) {
+	previous := otel.GetTracerProvider()
+	defer otel.SetTracerProvider(previous)
+	out := &bytes.Buffer{}
+	closer, err := SetupTracing(TracingConf{Enabled: true, Exporter: StdoutExporter,
+		ServiceName: "test", SampleRatio: 1}, out)
+	assert.NoError(t, err)
+	_, span := startSpan(context.Background(), "op", trace.SpanKindInternal)
+	endSpan(span, nil)
+	assert.NoError(t, closer.Close())
+	assert.Contains(t, out.String(), `"Name":"op"`)
+}
+
+func TestTracerEnd(t *testing.T) {
+	exporter := recordSpans(t)
+	tracer := MakeTracer()
+	errs := []error{
+		nil,
+		usecases.ErrProductNotFound,
+		&usecases.DomainError{Code: usecases.DatabaseUnavailableCode, Message: "db"},
+		fmt.Errorf("err"),
+	}
+	for _, err := range errs {
+		_, span := tracer.Start(context.Background(), "usecase")
+		span.End(err)
+	}
+	spans := exporter.GetSpans()
+	assert.Len(t, spans, 4)
+	assert.Equal(t, codes.Unset, spans[0].Status.Code)
+	assert.Equal(t, codes.Unset, spans[1].Status.Code)
+	assert.Equal(t, "PRODUCT_NOT_FOUND", spanAttribute(spans[1], "error.code").AsString())
+	assert.Equal(t, codes.Error, spans[2].Status.Code)
+	assert.Equal(t, codes.Error, spans[3].Status.Code)
+}
+
+func TestWithTracingContinuesTrace(t *testing.T) {
+	exporter := recordSpans(t)
+	handler := withTracing("GET", "/products/{id}", func(w http.ResponseWriter, r *http.Request) {
+		_, span := startSpan(r.Context(), "inner", trace.SpanKindInternal)
+		endSpan(span, nil)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	r := httptest.NewRequest("GET", "/products/1", nil)
+	r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	handler(httptest.NewRecorder(), r)
+	spans := exporter.GetSpans()
+	assert.Len(t, spans, 2)
+	inner, server := spans[0], spans[1]
+	assert.Equal(t, "GET /products/{id}", server.Name)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext.TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", server.Parent.SpanID().String())
+	assert.Equal(t, server.SpanContext.SpanID(), inner.Parent.SpanID())
+	assert.Equal(t, int64(503), spanAttribute(server, "http.response.status_code").AsInt64())
+	assert.Equal(t, "/products/{id}", spanAttribute(server, "http.route").AsString())
+	assert.Equal(t, codes.Error, server.Status.Code)
+}
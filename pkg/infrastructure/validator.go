@@ -0,0 +1,170 @@
+package infrastructure
+
+import (
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/interfaces/handlers"
+)
+
+// VALIDATE defines the struct tag holding the validation rules of a field.
+// Rules are comma separated and may receive a param after an equal sign,
+// e.g. `validate:"required,min=0,max=100"`
+const VALIDATE = "validate"
+
+// validationRule reports whether the value satisfies the rule using param
+type validationRule func(value reflect.Value, param string) bool
+
+// validationRules holds the rules available to the validate tag
+var validationRules = map[string]validationRule{
+	// required fails on zero values
+	"required": func(value reflect.Value, param string) bool {
+		return !value.IsZero()
+	},
+	// min checks numbers are not lower than param and strings are not
+	// shorter than param
+	"min": func(value reflect.Value, param string) bool {
+		limit, size, ok := compareParams(value, param)
+		return ok && size >= limit
+	},
+	// max checks numbers are not greater than param and strings are not
+	// longer than param
+	"max": func(value reflect.Value, param string) bool {
+		limit, size, ok := compareParams(value, param)
+		return ok && size <= limit
+	},
+	// email checks non empty strings are plain email addresses
+	"email": func(value reflect.Value, param string) bool {
+		if value.Kind() != reflect.String || value.String() == "" {
+			return value.Kind() == reflect.String
+		}
+		address, err := mail.ParseAddress(value.String())
+		return err == nil && address.Address == value.String()
+	},
+	// oneof checks strings are one of the space separated words of param
+	"oneof": func(value reflect.Value, param string) bool {
+		if value.Kind() != reflect.String {
+			return false
+		}
+		for _, option := range strings.Fields(param) {
+			if value.String() == option {
+				return true
+			}
+		}
+		return false
+	},
+	// csvint checks non empty strings are comma separated integers. A range
+	// may be given as param using the from..to format
+	"csvint": func(value reflect.Value, param string) bool {
+		if value.Kind() != reflect.String {
+			return false
+		}
+		if value.String() == "" {
+			return true
+		}
+		from, to, err := parseRange(param)
+		if err != nil {
+			return false
+		}
+		for _, v := range strings.Split(value.String(), ",") {
+			number, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+			if err != nil || number < from || number > to {
+				return false
+			}
+		}
+		return true
+	},
+}
+
+// compareParams returns the param limit and the value size to compare
+func compareParams(value reflect.Value, param string) (limit, size int64, ok bool) {
+	limit, err := strconv.ParseInt(param, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return limit, value.Int(), true
+	case reflect.String, reflect.Slice, reflect.Map:
+		return limit, int64(value.Len()), true
+	default:
+		return 0, 0, false
+	}
+}
+
+// parseRange parses a from..to range, an empty range allows any number
+func parseRange(param string) (from, to int64, err error) {
+	from, to = -1<<63, 1<<63-1
+	if param == "" {
+		return from, to, nil
+	}
+	bounds := strings.SplitN(param, "..", 2)
+	if from, err = strconv.ParseInt(bounds[0], 10, 64); err != nil {
+		return 0, 0, err
+	}
+	if len(bounds) < 2 {
+		return 0, 0, strconv.ErrSyntax
+	}
+	to, err = strconv.ParseInt(bounds[1], 10, 64)
+	return from, to, err
+}
+
+// validateInput checks every field of the input against the rules of its
+// validate tag. Returns a FieldError for each broken rule, unknown rules
+// are reported as broken
+func validateInput(input reflect.Value) (fieldErrors []handlers.FieldError) {
+	reflectedInput := reflect.Indirect(input)
+	if reflectedInput.Kind() != reflect.Struct {
+		return nil
+	}
+	for i := 0; i < reflectedInput.NumField(); i++ {
+		field := reflectedInput.Type().Field(i)
+		tag, ok := field.Tag.Lookup(VALIDATE)
+		if !ok || tag == "" {
+			continue
+		}
+		value := reflectedInput.Field(i)
+		for _, rule := range brokenRules(value, tag) {
+			fieldErrors = append(fieldErrors, handlers.FieldError{
+				Field: fieldName(field),
+				Value: value.Interface(),
+				Rule:  rule,
+			})
+		}
+	}
+	return fieldErrors
+}
+
+// brokenRules returns the rules of the validate tag broken by value, unknown
+// rules are reported as broken
+func brokenRules(value reflect.Value, tag string) (broken []string) {
+	if tag == "" {
+		return nil
+	}
+	for _, rule := range strings.Split(tag, ",") {
+		name, param := rule, ""
+		if pos := strings.Index(rule, "="); pos >= 0 {
+			name, param = rule[:pos], rule[pos+1:]
+		}
+		check, exists := validationRules[name]
+		if !exists || !check(value, param) {
+			broken = append(broken, rule)
+		}
+	}
+	return broken
+}
+
+// fieldName returns the name the field has on the request
+func fieldName(field reflect.StructField) string {
+	for _, source := range []string{"json", string(QUERY), string(PATH),
+		string(HEADERS), string(COOKIES), string(FORM)} {
+		if tag, ok := field.Tag.Lookup(source); ok {
+			if name := strings.Split(tag, ",")[0]; name != "" && name != "-" {
+				return name
+			}
+		}
+	}
+	return field.Name
+}
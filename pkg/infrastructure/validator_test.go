@@ -0,0 +1,111 @@
+package infrastructure
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/interfaces/handlers"
+)
+
+func TestValidateInputOK(t *testing.T) {
+	type input struct {
+		ID         int    `path:"id" validate:"min=1"`
+		Email      string `json:"email" validate:"required,email"`
+		Categories string `json:"categories" validate:"csvint=1000..9999"`
+		Limit      int    `json:"limit" validate:"min=0,max=100"`
+		Keywords   string `json:"keywords" validate:"max=5"`
+		Comment    string `json:"comment"`
+	}
+	in := input{ID: 1, Email: "user@mail.com", Categories: "2020, 1000",
+		Limit: 100, Keywords: "a,b"}
+	assert.Empty(t, validateInput(reflect.ValueOf(&in)))
+}
+
+func TestValidateInputEmptyOptionalValues(t *testing.T) {
+	type input struct {
+		Email      string `query:"email" validate:"email"`
+		Categories string `json:"categories" validate:"csvint"`
+	}
+	assert.Empty(t, validateInput(reflect.ValueOf(&input{})))
+}
+
+func TestValidateInputErrors(t *testing.T) {
+	type input struct {
+		ID         int    `path:"id" validate:"min=1"`
+		Email      string `json:"email" validate:"required,email"`
+		Other      string `json:"other,omitempty" validate:"email"`
+		Categories string `json:"categories" validate:"csvint=1000..9999"`
+		Limit      int    `json:"limit" validate:"min=0,max=100"`
+		Page       int    `validate:"unknown"`
+	}
+	in := input{ID: 0, Other: "Name <a@b.cl>", Categories: "2020,0,abc", Limit: 101}
+	expected := []handlers.FieldError{
+		{Field: "id", Value: 0, Rule: "min=1"},
+		{Field: "email", Value: "", Rule: "required"},
+		{Field: "other", Value: "Name <a@b.cl>", Rule: "email"},
+		{Field: "categories", Value: "2020,0,abc", Rule: "csvint=1000..9999"},
+		{Field: "limit", Value: 101, Rule: "max=100"},
+		{Field: "Page", Value: 0, Rule: "unknown"},
+	}
+	assert.Equal(t, expected, validateInput(reflect.ValueOf(&in)))
+}
+
+func TestValidateInputBadParams(t *testing.T) {
+	type input struct {
+		Limit      int    `json:"limit" validate:"min=a"`
+		Enabled    bool   `json:"enabled" validate:"max=1"`
+		Categories string `json:"categories" validate:"csvint=1000"`
+	}
+	in := input{Categories: "1000"}
+	expected := []handlers.FieldError{
+		{Field: "limit", Value: 0, Rule: "min=a"},
+		{Field: "enabled", Value: false, Rule: "max=1"},
+		{Field: "categories", Value: "1000", Rule: "csvint=1000"},
+	}
+	assert.Equal(t, expected, validateInput(reflect.ValueOf(&in)))
+}
+
+func TestValidateInputOneOf(t *testing.T) {
+	type input struct {
+		Sort  string `json:"sort" validate:"oneof=asc desc"`
+		Order string `json:"order" validate:"oneof=asc desc"`
+		Limit int    `json:"limit" validate:"oneof=1 2"`
+	}
+	in := input{Sort: "desc", Order: "random", Limit: 1}
+	expected := []handlers.FieldError{
+		{Field: "order", Value: "random", Rule: "oneof=asc desc"},
+		{Field: "limit", Value: 1, Rule: "oneof=1 2"},
+	}
+	assert.Equal(t, expected, validateInput(reflect.ValueOf(&in)))
+}
+
+func TestValidateInputNotStruct(t *testing.T) {
+	in := map[string]interface{}{}
+	assert.Empty(t, validateInput(reflect.ValueOf(&in)))
+}
+
+func TestInputValidationError(t *testing.T) {
+	type input struct {
+		Email string `json:"email" validate:"email"`
+		Limit int    `json:"limit" validate:"max=100"`
+	}
+	result := input{}
+	r := httptest.NewRequest("POST", "/api/v1/",
+		strings.NewReader(`{"email": "nope", "limit": 1000}`))
+
+	inputHandler := NewInputHandler()
+	ri := inputHandler.NewInputRequest(r)
+	ri.Set(&result).FromJSONBody()
+
+	inputHandler.SetInputRequest(ri, &result)
+	_, response := inputHandler.Input()
+	expected := handlers.MakeValidationErrorResponse([]handlers.FieldError{
+		{Field: "email", Value: "nope", Rule: "email"},
+		{Field: "limit", Value: 1000, Rule: "max=100"},
+	})
+	assert.Equal(t, expected, response)
+}
@@ -1,6 +1,8 @@
 package infrastructure
 
 import (
+	"strings"
+
 	"github.com/Yapo/logger"
 	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/interfaces/loggers"
 )
@@ -8,6 +10,8 @@ import (
 // yapoLogger struct that implements the Logger interface using the Yapo/logger library
 type yapoLogger struct {
 	metrics EventCollector
+	// prefix holds the fields written before every message
+	prefix string
 }
 
 // MakeYapoLogger creates and sets up a yapo flavored Logger
@@ -40,42 +44,49 @@ func (y *yapoLogger) init(config *LoggerConf) error {
 	return nil
 }
 
+// With returns a copy of the logger that writes the field before its messages
+func (y yapoLogger) With(key, value string) loggers.Logger {
+	field := strings.ReplaceAll(key+"="+value, "%", "%%")
+	y.prefix += "[" + field + "] "
+	return y
+}
+
 // Debug logs a message at DEBUG level
 func (y yapoLogger) Debug(format string, params ...interface{}) {
-	logger.Debug(format, params...)
+	logger.Debug(y.prefix+format, params...)
 }
 
 // Info logs a message at INFO level.
 // Info events are automatically exported to prometheus.
 func (y yapoLogger) Info(format string, params ...interface{}) {
 	y.metrics.CollectEvent(getEntityName(), getEventName(), getEventType())
-	logger.Info(format, params...)
+	logger.Info(y.prefix+format, params...)
 }
 
 // Success logs a message as Success event.
 // Success events are automatically exported to prometheus.
 func (y yapoLogger) Success(format string, params ...interface{}) {
 	y.metrics.CollectEvent(getEntityName(), getEventName(), getEventType())
-	logger.Info(format, params...)
+	logger.Info(y.prefix+format, params...)
 }
 
 // Warn logs a message at WARNING level.
 // warning events are automatically exported to prometheus.
 func (y yapoLogger) Warn(format string, params ...interface{}) {
 	y.metrics.CollectEvent(getEntityName(), getEventName(), getEventType())
-	logger.Warn(format, params...)
+	logger.Warn(y.prefix+format, params...)
 }
 
 // Error logs a message at ERROR level.
 // Error events are automatically exported to prometheus.
 func (y yapoLogger) Error(format string, params ...interface{}) {
 	y.metrics.CollectEvent(getEntityName(), getEventName(), getEventType())
-	logger.Error(format, params...)
+	logger.Error(y.prefix+format, params...)
 }
 
 // LogCrit logs a message at CRITICAL level.
 // Critical events are automatically exported to prometheus.
 func (y yapoLogger) Crit(format string, params ...interface{}) {
 	y.metrics.CollectEvent(getEntityName(), getEventName(), getEventType())
-	logger.Crit(format, params...)
+	logger.Crit(y.prefix+format, params...)
 }
@@ -4,6 +4,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/interfaces/loggers"
 )
 
 func TestYapoLoggerNotStarted(t *testing.T) {
@@ -35,4 +37,7 @@ func TestYapoLogger(t *testing.T) {
 	logger.Error("error")
 	logger.Crit("critical")
 	logger.Success("success")
+	tagged := logger.(loggers.FieldLogger).With("request_id", "100%")
+	tagged.Info("info %d", 1)
+	assert.Equal(t, "[request_id=100%%] ", tagged.(yapoLogger).prefix)
 }
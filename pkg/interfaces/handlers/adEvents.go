@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/usecases"
+)
+
+const (
+	// AdUpdatedAction is the action of the events sent when an ad is edited
+	AdUpdatedAction = "update"
+	// AdDeletedAction is the action of the events sent when an ad is deleted
+	AdDeletedAction = "delete"
+)
+
+// AdEventsHandler evicts the cached data of the ads updated or deleted,
+// read from the ad events topic
+type AdEventsHandler struct {
+	Interactor usecases.EvictAdInteractor
+}
+
+// AdEventsLogger logger for AdEvents Handler
+type AdEventsLogger interface{}
+
+// adEvent is the message published on the ad events topic
+type adEvent struct {
+	ListID json.Number `json:"list_id"`
+	Action string      `json:"action"`
+}
+
+// Handle evicts the ad of an update or delete event, other events are
+// ignored. Messages that can't be processed are reported with
+// ErrPoisonMessage, any other error may be retried
+func (h *AdEventsHandler) Handle(ctx context.Context, message []byte) error {
+	var event adEvent
+	if err := json.Unmarshal(message, &event); err != nil {
+		return fmt.Errorf("%w: %v", ErrPoisonMessage, err)
+	}
+	if event.Action != AdUpdatedAction && event.Action != AdDeletedAction {
+		return nil
+	}
+	if event.ListID == "" {
+		return fmt.Errorf("%w: missing list id", ErrPoisonMessage)
+	}
+	return h.Interactor.EvictAd(ctx, event.ListID.String())
+}
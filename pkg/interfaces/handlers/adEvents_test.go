@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockEvictAdInteractor struct {
+	mock.Mock
+}
+
+func (m *mockEvictAdInteractor) EvictAd(ctx context.Context, listID string) error {
+	args := m.Called(ctx, listID)
+	return args.Error(0)
+}
+
+func TestAdEventsHandlerOK(t *testing.T) {
+	mInteractor := &mockEvictAdInteractor{}
+	h := AdEventsHandler{Interactor: mInteractor}
+	mInteractor.On("EvictAd", mock.Anything, "123").Return(nil).Twice()
+	assert.NoError(t, h.Handle(context.Background(), []byte(`{"list_id": 123, "action": "update"}`)))
+	assert.NoError(t, h.Handle(context.Background(), []byte(`{"list_id": "123", "action": "delete"}`)))
+	mInteractor.AssertExpectations(t)
+}
+
+func TestAdEventsHandlerIgnored(t *testing.T) {
+	mInteractor := &mockEvictAdInteractor{}
+	h := AdEventsHandler{Interactor: mInteractor}
+	assert.NoError(t, h.Handle(context.Background(), []byte(`{"list_id": 123, "action": "insert"}`)))
+	mInteractor.AssertExpectations(t)
+}
+
+func TestAdEventsHandlerPoison(t *testing.T) {
+	mInteractor := &mockEvictAdInteractor{}
+	h := AdEventsHandler{Interactor: mInteractor}
+	err := h.Handle(context.Background(), []byte(`{`))
+	assert.True(t, errors.Is(err, ErrPoisonMessage))
+	err = h.Handle(context.Background(), []byte(`{"action": "delete"}`))
+	assert.True(t, errors.Is(err, ErrPoisonMessage))
+	mInteractor.AssertExpectations(t)
+}
+
+func TestAdEventsHandlerError(t *testing.T) {
+	mInteractor := &mockEvictAdInteractor{}
+	h := AdEventsHandler{Interactor: mInteractor}
+	mInteractor.On("EvictAd", mock.Anything, "123").Return(fmt.Errorf("err"))
+	err := h.Handle(context.Background(), []byte(`{"list_id": 123, "action": "update"}`))
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, ErrPoisonMessage))
+	mInteractor.AssertExpectations(t)
+}
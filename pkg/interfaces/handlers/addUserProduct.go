@@ -19,39 +19,44 @@ type AddUserProductHandler struct {
 	Interactor usecases.AddUserProductInteractor
 }
 
+// requestKeyPrefix namespaces the idempotency keys sent by the clients, apart
+// from the keys of the consumers. The keys are also namespaced by the
+// authenticated client, so clients can't collide with each other
+const requestKeyPrefix = "request:"
+
 // AddUserProductLogger logger for AddUserProduct Handler
 type AddUserProductLogger interface{}
 
 // addUserProductHandlerInput is the handler expected input
 type addUserProductHandlerInput struct {
-	UserID             int       `json:"user_id"`
-	Email              string    `json:"email"`
-	PurchaseNumber     int       `json:"purchase_number"`
-	PurchasePrice      int       `json:"purchase_price"`
+	RequestContext
+	Identity           Identity  `identity:"identity"`
+	IdempotencyKey     string    `headers:"Idempotency-Key" validate:"max=255"`
+	UserID             int       `json:"user_id" validate:"min=1"`
+	Email              string    `json:"email" validate:"required,email"`
+	PurchaseNumber     int       `json:"purchase_number" validate:"min=0"`
+	PurchasePrice      int       `json:"purchase_price" validate:"min=0"`
 	PurchaseType       string    `json:"purchase_type"`
-	Categories         string    `json:"categories"`
+	Categories         string    `json:"categories" validate:"csvint=1000..9999"`
 	Exclude            string    `json:"exclude"`
 	Keywords           string    `json:"keywords"`
 	Comment            string    `json:"comment"`
-	Limit              int       `json:"limit"`
-	PriceRange         int       `json:"price_range"`
+	Limit              int       `json:"limit" validate:"min=0,max=100"`
+	PriceRange         int       `json:"price_range" validate:"min=0"`
+	StartAt            time.Time `json:"start_at"`
 	ExpiredAt          time.Time `json:"expiration"`
 	FillGapsWithRandom bool      `json:"fill_random"`
 }
 
-// getUserRequestOutput is the handler output
-type addUserProductRequestOutput struct {
-	response string
-}
-
 // Input returns a fresh, empty instance of addUserProductHandlerInput
 func (*AddUserProductHandler) Input(ir InputRequest) HandlerInput {
 	input := addUserProductHandlerInput{}
-	ir.Set(&input).FromJSONBody()
+	ir.Set(&input).FromJSONBody().FromHeaders().FromIdentity()
 	return &input
 }
 
-// Execute adds a new user product using controlpanel
+// Execute adds a new user product using controlpanel. The created product
+// is returned along with its location
 func (h *AddUserProductHandler) Execute(ig InputGetter) *goutils.Response {
 	input, response := ig()
 	if response != nil {
@@ -59,13 +64,12 @@ func (h *AddUserProductHandler) Execute(ig InputGetter) *goutils.Response {
 	}
 	in := input.(*addUserProductHandlerInput)
 	if in.ExpiredAt.Before(time.Now()) {
-		return &goutils.Response{
-			Code: http.StatusBadRequest,
-			Body: goutils.GenericError{
-				ErrorMessage: fmt.Sprintf(`bad expiration date: %+v`,
-					in.ExpiredAt),
-			},
-		}
+		return MakeProblemResponse(http.StatusBadRequest, InvalidInputCode,
+			fmt.Sprintf(`bad expiration date: %+v`, in.ExpiredAt))
+	}
+	if !in.StartAt.IsZero() && !in.StartAt.Before(in.ExpiredAt) {
+		return MakeProblemResponse(http.StatusBadRequest, InvalidInputCode,
+			fmt.Sprintf(`bad start date: %+v`, in.StartAt))
 	}
 	config := domain.ProductParams{
 		Categories:         h.getCategories(in.Categories),
@@ -79,32 +83,22 @@ func (h *AddUserProductHandler) Execute(ig InputGetter) *goutils.Response {
 
 	purchaseType, err := h.getPurchaseType(in.PurchaseType)
 	if err != nil {
-		return &goutils.Response{
-			Code: http.StatusBadRequest,
-			Body: goutils.GenericError{
-				ErrorMessage: fmt.Sprintf(`%+v`, err),
-			},
-		}
+		return MakeProblemResponse(http.StatusBadRequest, InvalidInputCode,
+			err.Error())
 	}
-	err = h.Interactor.AddUserProduct(in.UserID, in.Email,
-		in.PurchaseNumber, in.PurchasePrice, purchaseType,
-		domain.PremiumCarousel, in.ExpiredAt, config)
-	if err != nil {
-		return &goutils.Response{
-			Code: http.StatusBadRequest,
-			Body: goutils.GenericError{
-				ErrorMessage: fmt.Sprintf(`%+v`, err),
-			},
-		}
-	}
-	body := addUserProductRequestOutput{
-		response: "OK",
+	idempotencyKey := ""
+	if in.IdempotencyKey != "" {
+		idempotencyKey = requestKeyPrefix + in.Identity.Subject + ":" + in.IdempotencyKey
 	}
-
-	return &goutils.Response{
-		Code: http.StatusOK,
-		Body: body,
+	product, err := h.Interactor.AddUserProduct(in.Context(), idempotencyKey, in.UserID,
+		in.Email, in.PurchaseNumber, in.PurchasePrice, purchaseType,
+		domain.PremiumCarousel, in.StartAt, in.ExpiredAt, config)
+	if err != nil {
+		return MakeErrorResponse(err)
 	}
+	response = makeProductResponse(http.StatusCreated, product)
+	response.Body.(HeadedBody).Headers["Location"] = fmt.Sprintf("/assigns/%d", product.ID)
+	return response
 }
 
 func (h *AddUserProductHandler) getCategories(raw string) (categories []int) {
@@ -113,7 +107,7 @@ func (h *AddUserProductHandler) getCategories(raw string) (categories []int) {
 	}
 	categoriesArr := strings.Split(raw, ",")
 	for _, c := range categoriesArr {
-		cat, _ := strconv.Atoi(c)
+		cat, _ := strconv.Atoi(strings.TrimSpace(c))
 		categories = append(categories, cat)
 	}
 	return categories
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"testing"
@@ -11,6 +12,7 @@ import (
 	"github.com/stretchr/testify/mock"
 
 	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/domain"
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/usecases"
 )
 
 func TestAddUserProductHandlerInput(t *testing.T) {
@@ -20,6 +22,8 @@ func TestAddUserProductHandlerInput(t *testing.T) {
 	mMockInputRequest.On("Set",
 		mock.AnythingOfType("*handlers.addUserProductHandlerInput")).Return(mTargetRequest)
 	mTargetRequest.On("FromJSONBody").Return(mTargetRequest)
+	mTargetRequest.On("FromHeaders").Return(mTargetRequest)
+	mTargetRequest.On("FromIdentity").Return(mTargetRequest)
 	input := h.Input(mMockInputRequest)
 	var expected *addUserProductHandlerInput
 	assert.IsType(t, expected, input)
@@ -31,13 +35,13 @@ type mockAddUserProductInteractor struct {
 	mock.Mock
 }
 
-func (m *mockAddUserProductInteractor) AddUserProduct(userID int,
-	email string, purchaseNumber, purchasePrice int,
+func (m *mockAddUserProductInteractor) AddUserProduct(ctx context.Context, idempotencyKey string,
+	userID int, email string, purchaseNumber, purchasePrice int,
 	purchaseType domain.PurchaseType, productType domain.ProductType,
-	expiredAt time.Time, config domain.ProductParams) error {
-	args := m.Called(userID, email, purchaseNumber, purchasePrice,
-		purchaseType, productType, expiredAt, config)
-	return args.Error(0)
+	startAt, expiredAt time.Time, config domain.ProductParams) (domain.Product, error) {
+	args := m.Called(ctx, idempotencyKey, userID, email, purchaseNumber, purchasePrice,
+		purchaseType, productType, startAt, expiredAt, config)
+	return args.Get(0).(domain.Product), args.Error(1)
 }
 
 func TestAddUserProductHandlerErrorBadInput(t *testing.T) {
@@ -58,8 +62,11 @@ func TestAddUserProductHandlerErrorBadInput(t *testing.T) {
 }
 
 func TestAddUserProductHandlerOK(t *testing.T) {
+	product := domain.Product{ID: 7, UserID: 123, Version: 1,
+		Purchase: domain.Purchase{ID: 3, Number: 10, Price: 1000}}
 	mInteractor := &mockAddUserProductInteractor{}
-	mInteractor.On("AddUserProduct",
+	mInteractor.On("AddUserProduct", mock.Anything,
+		mock.AnythingOfType("string"),
 		mock.AnythingOfType("int"),
 		mock.AnythingOfType("string"),
 		mock.AnythingOfType("int"),
@@ -67,8 +74,9 @@ func TestAddUserProductHandlerOK(t *testing.T) {
 		mock.AnythingOfType("domain.PurchaseType"),
 		domain.PremiumCarousel,
 		mock.AnythingOfType("time.Time"),
+		mock.AnythingOfType("time.Time"),
 		mock.AnythingOfType("domain.ProductParams"),
-	).Return(nil)
+	).Return(product, nil)
 	h := AddUserProductHandler{
 		Interactor: mInteractor,
 	}
@@ -81,9 +89,13 @@ func TestAddUserProductHandlerOK(t *testing.T) {
 	getter := MakeMockInputGetter(&input, nil)
 	r := h.Execute(getter)
 	expected := &goutils.Response{
-		Code: http.StatusOK,
-		Body: addUserProductRequestOutput{
-			response: "OK",
+		Code: http.StatusCreated,
+		Body: HeadedBody{
+			Headers: map[string]string{
+				"ETag":     `"1"`,
+				"Location": "/assigns/7",
+			},
+			Body: makeProductOutput(product),
 		},
 	}
 	assert.Equal(t, expected, r)
@@ -112,7 +124,8 @@ func TestAddUserProductHandlerBadPurchaseType(t *testing.T) {
 func TestAddUserProductHandlerError(t *testing.T) {
 	err := fmt.Errorf("err")
 	mInteractor := &mockAddUserProductInteractor{}
-	mInteractor.On("AddUserProduct",
+	mInteractor.On("AddUserProduct", mock.Anything,
+		mock.AnythingOfType("string"),
 		mock.AnythingOfType("int"),
 		mock.AnythingOfType("string"),
 		mock.AnythingOfType("int"),
@@ -120,8 +133,9 @@ func TestAddUserProductHandlerError(t *testing.T) {
 		mock.AnythingOfType("domain.PurchaseType"),
 		domain.PremiumCarousel,
 		mock.AnythingOfType("time.Time"),
+		mock.AnythingOfType("time.Time"),
 		mock.AnythingOfType("domain.ProductParams"),
-	).Return(err)
+	).Return(domain.Product{}, err)
 	h := AddUserProductHandler{
 		Interactor: mInteractor,
 	}
@@ -133,13 +147,7 @@ func TestAddUserProductHandlerError(t *testing.T) {
 	}
 	getter := MakeMockInputGetter(&input, nil)
 	r := h.Execute(getter)
-	expected := &goutils.Response{
-		Code: http.StatusBadRequest,
-		Body: goutils.GenericError{
-			ErrorMessage: fmt.Sprintf(`%+v`, err),
-		},
-	}
-	assert.Equal(t, expected, r)
+	assertProblem(t, r, http.StatusInternalServerError, InternalErrorCode, "Internal error")
 	mInteractor.AssertExpectations(t)
 }
 
@@ -155,13 +163,62 @@ func TestAddUserProductHandlerBadExpiredAtTime(t *testing.T) {
 	}
 	getter := MakeMockInputGetter(&input, nil)
 	r := h.Execute(getter)
-	expected := &goutils.Response{
-		Code: http.StatusBadRequest,
-		Body: goutils.GenericError{
-			ErrorMessage: fmt.Sprintf(`bad expiration date: %+v`,
-				input.ExpiredAt),
-		},
+	expected := MakeProblemResponse(http.StatusBadRequest,
+		InvalidInputCode, fmt.Sprintf(`bad expiration date: %+v`,
+			input.ExpiredAt))
+	assert.Equal(t, expected, r)
+	mInteractor.AssertExpectations(t)
+}
+
+func TestAddUserProductHandlerBadStartAtTime(t *testing.T) {
+	mInteractor := &mockAddUserProductInteractor{}
+	h := AddUserProductHandler{
+		Interactor: mInteractor,
+	}
+	expiredAt := time.Now().Add(time.Hour * 24 * 30)
+	input := addUserProductHandlerInput{
+		UserID:    123,
+		Email:     "test@test.cl",
+		StartAt:   expiredAt.Add(time.Hour),
+		ExpiredAt: expiredAt,
 	}
+	getter := MakeMockInputGetter(&input, nil)
+	r := h.Execute(getter)
+	expected := MakeProblemResponse(http.StatusBadRequest,
+		InvalidInputCode, fmt.Sprintf(`bad start date: %+v`,
+			input.StartAt))
+	assert.Equal(t, expected, r)
+	mInteractor.AssertExpectations(t)
+}
+
+func TestAddUserProductHandlerIdempotencyKeyReused(t *testing.T) {
+	mInteractor := &mockAddUserProductInteractor{}
+	mInteractor.On("AddUserProduct", mock.Anything,
+		"request:jane:key-1",
+		123,
+		"test@test.cl",
+		mock.AnythingOfType("int"),
+		mock.AnythingOfType("int"),
+		mock.AnythingOfType("domain.PurchaseType"),
+		domain.PremiumCarousel,
+		mock.AnythingOfType("time.Time"),
+		mock.AnythingOfType("time.Time"),
+		mock.AnythingOfType("domain.ProductParams"),
+	).Return(domain.Product{}, usecases.ErrIdempotencyKeyReused)
+	h := AddUserProductHandler{
+		Interactor: mInteractor,
+	}
+	input := addUserProductHandlerInput{
+		Identity:       Identity{Subject: "jane", Role: EditorRole},
+		IdempotencyKey: "key-1",
+		UserID:         123,
+		Email:          "test@test.cl",
+		ExpiredAt:      time.Now().Add(time.Hour * 24 * 365),
+	}
+	getter := MakeMockInputGetter(&input, nil)
+	r := h.Execute(getter)
+	expected := MakeProblemResponse(http.StatusConflict,
+		usecases.IdempotencyKeyReusedCode, usecases.ErrIdempotencyKeyReused.Error())
 	assert.Equal(t, expected, r)
 	mInteractor.AssertExpectations(t)
 }
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/Yapo/goutils"
+
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/usecases"
+)
+
+// EvictAdHandler implements the handler interface and responds to
+// /cache/ads/{listID} evicting the cached data of the ad
+type EvictAdHandler struct {
+	Interactor usecases.EvictAdInteractor
+}
+
+// evictAdHandlerInput is the handler expected input
+type evictAdHandlerInput struct {
+	RequestContext
+	ListID string `path:"listID"`
+}
+
+// Input returns a fresh, empty instance of evictAdHandlerInput
+func (*EvictAdHandler) Input(ir InputRequest) HandlerInput {
+	input := evictAdHandlerInput{}
+	ir.Set(&input).FromPath()
+	return &input
+}
+
+// Execute evicts the ad from the cache, so it's read again from search on
+// the next request
+func (h *EvictAdHandler) Execute(ig InputGetter) *goutils.Response {
+	input, response := ig()
+	if response != nil {
+		return response
+	}
+	in := input.(*evictAdHandlerInput)
+	if err := h.Interactor.EvictAd(in.Context(), in.ListID); err != nil {
+		return MakeErrorResponse(err)
+	}
+	return &goutils.Response{
+		Code: http.StatusNoContent,
+	}
+}
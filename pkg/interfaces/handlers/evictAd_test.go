@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/Yapo/goutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestEvictAdHandlerInput(t *testing.T) {
+	var h EvictAdHandler
+	mMockInputRequest := &MockInputRequest{}
+	mTargetRequest := &MockTargetRequest{}
+	mMockInputRequest.On("Set", mock.Anything).Return(mTargetRequest)
+	mTargetRequest.On("FromPath").Return(mTargetRequest)
+	input := h.Input(mMockInputRequest)
+	var expected *evictAdHandlerInput
+	assert.IsType(t, expected, input)
+	mMockInputRequest.AssertExpectations(t)
+	mTargetRequest.AssertExpectations(t)
+}
+
+func TestEvictAdHandlerOK(t *testing.T) {
+	mInteractor := &mockEvictAdInteractor{}
+	mInteractor.On("EvictAd", mock.Anything, "123").Return(nil)
+	h := EvictAdHandler{Interactor: mInteractor}
+	getter := MakeMockInputGetter(&evictAdHandlerInput{ListID: "123"}, nil)
+	r := h.Execute(getter)
+	assert.Equal(t, &goutils.Response{Code: http.StatusNoContent}, r)
+	mInteractor.AssertExpectations(t)
+}
+
+func TestEvictAdHandlerError(t *testing.T) {
+	mInteractor := &mockEvictAdInteractor{}
+	mInteractor.On("EvictAd", mock.Anything, "123").Return(fmt.Errorf("err"))
+	h := EvictAdHandler{Interactor: mInteractor}
+	getter := MakeMockInputGetter(&evictAdHandlerInput{ListID: "123"}, nil)
+	r := h.Execute(getter)
+	assert.Equal(t, http.StatusInternalServerError, r.Code)
+	mInteractor.AssertExpectations(t)
+}
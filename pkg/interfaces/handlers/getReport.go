@@ -23,6 +23,7 @@ type GetReportLogger interface{}
 
 // getReportHandlerInput is the handler expected input
 type getReportHandlerInput struct {
+	RequestContext
 	StartDate string `query:"start_date"`
 	EndDate   string `query:"end_date"`
 }
@@ -45,23 +46,15 @@ func (h *GetReportHandler) Execute(ig InputGetter) *goutils.Response {
 	if response != nil {
 		return response
 	}
-	startDate, endDate, err := h.validate(input.(*getReportHandlerInput))
+	in := input.(*getReportHandlerInput)
+	startDate, endDate, err := h.validate(in)
 	if err != nil {
-		return &goutils.Response{
-			Code: http.StatusBadRequest,
-			Body: goutils.GenericError{
-				ErrorMessage: fmt.Sprintf(`%+v`, err),
-			},
-		}
+		return MakeProblemResponse(http.StatusBadRequest, InvalidInputCode,
+			err.Error())
 	}
-	products, err := h.Interactor.GetReport(startDate, endDate)
+	products, err := h.Interactor.GetReport(in.Context(), startDate, endDate)
 	if err != nil {
-		return &goutils.Response{
-			Code: http.StatusBadRequest,
-			Body: goutils.GenericError{
-				ErrorMessage: fmt.Sprintf(`%+v`, err),
-			},
-		}
+		return MakeErrorResponse(err)
 	}
 	productsOut := []productsOutput{}
 	for _, v := range products {
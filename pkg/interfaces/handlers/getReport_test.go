@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"testing"
@@ -31,9 +32,9 @@ type mockGetReportInteractor struct {
 	mock.Mock
 }
 
-func (m *mockGetReportInteractor) GetReport(start,
+func (m *mockGetReportInteractor) GetReport(ctx context.Context, start,
 	end time.Time) ([]domain.Product, error) {
-	args := m.Called(start, end)
+	args := m.Called(ctx, start, end)
 	return args.Get(0).([]domain.Product), args.Error(1)
 }
 
@@ -58,7 +59,7 @@ func TestGetReportHandlerErrorBadInput(t *testing.T) {
 func TestGetReportHandlerOK(t *testing.T) {
 	mInteractor := &mockGetReportInteractor{}
 	testTime := time.Now()
-	mInteractor.On("GetReport", mock.AnythingOfType("time.Time"),
+	mInteractor.On("GetReport", mock.Anything, mock.AnythingOfType("time.Time"),
 		mock.AnythingOfType("time.Time")).
 		Return([]domain.Product{{ID: 123, Purchase: domain.Purchase{ID: 1,
 			Type: domain.AdminPurchase}}}, nil)
@@ -86,7 +87,7 @@ func TestGetReportHandlerError(t *testing.T) {
 	mInteractor := &mockGetReportInteractor{}
 	testTime := time.Now()
 	err := fmt.Errorf("err")
-	mInteractor.On("GetReport", mock.AnythingOfType("time.Time"),
+	mInteractor.On("GetReport", mock.Anything, mock.AnythingOfType("time.Time"),
 		mock.AnythingOfType("time.Time")).
 		Return([]domain.Product{{ID: 123}}, err)
 	h := GetReportHandler{
@@ -98,13 +99,7 @@ func TestGetReportHandlerError(t *testing.T) {
 	}
 	getter := MakeMockInputGetter(&input, nil)
 	r := h.Execute(getter)
-	expected := &goutils.Response{
-		Code: http.StatusBadRequest,
-		Body: goutils.GenericError{
-			ErrorMessage: fmt.Sprintf(`%+v`, err),
-		},
-	}
-	assert.Equal(t, expected, r)
+	assertProblem(t, r, http.StatusInternalServerError, InternalErrorCode, "Internal error")
 	mInteractor.AssertExpectations(t)
 }
 
@@ -120,12 +115,8 @@ func TestGetReportHandlerErrorNotValidInterval(t *testing.T) {
 	}
 	getter := MakeMockInputGetter(&input, nil)
 	r := h.Execute(getter)
-	expected := &goutils.Response{
-		Code: http.StatusBadRequest,
-		Body: goutils.GenericError{
-			ErrorMessage: fmt.Sprintf(`invalid date interval`),
-		},
-	}
+	expected := MakeProblemResponse(http.StatusBadRequest,
+		InvalidInputCode, "invalid date interval")
 	assert.Equal(t, expected, r)
 	mInteractor.AssertExpectations(t)
 }
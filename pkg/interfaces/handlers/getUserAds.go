@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
 
@@ -13,11 +14,12 @@ import (
 // GetUserAdsHandler implements the handler interface and responds to /ads with
 // related user ads
 type GetUserAdsHandler struct {
-	Interactor          usecases.GetUserAdsInteractor
-	GetAdInteractor     usecases.GetAdInteractor
-	Logger              GetUserAdsLogger
-	UnitOfAccountSymbol string
-	CurrencySymbol      string
+	Interactor      usecases.GetUserAdsInteractor
+	GetAdInteractor usecases.GetAdInteractor
+	Logger          GetUserAdsLogger
+	// Settings is read once per request, its snapshot gives the currency
+	// symbols and the ads limits
+	Settings usecases.SettingsProvider
 }
 
 // GetUserAdsLogger logger for GetUserAds Handler
@@ -25,6 +27,7 @@ type GetUserAdsLogger interface{}
 
 // getUserAdsHandlerInput is the handler expected input
 type getUserAdsHandlerInput struct {
+	RequestContext
 	ListID string `path:"listID"`
 }
 
@@ -66,27 +69,23 @@ func (h *GetUserAdsHandler) Execute(ig InputGetter) *goutils.Response {
 		return response
 	}
 	in := input.(*getUserAdsHandlerInput)
+	settings := h.Settings.Settings()
 
-	currentAdview, err := h.GetAdInteractor.GetAd(in.ListID)
+	currentAdview, err := h.GetAdInteractor.GetAd(in.Context(), in.ListID)
 	if err != nil {
-		return &goutils.Response{
-			Code: http.StatusNoContent,
-		}
+		return MakeErrorResponse(err)
 	}
 
-	resp, err := h.Interactor.GetUserAds(currentAdview)
+	resp, err := h.Interactor.GetUserAds(in.Context(), currentAdview, settings)
 	if err != nil {
-		return &goutils.Response{
-			Code: http.StatusNoContent,
-		}
+		return MakeErrorResponse(err)
 	}
 	body := getUserRequestOutput{
-		Ads: h.fillResponse(resp, in.ListID),
+		Ads: h.fillResponse(resp, in.ListID, settings),
 	}
 	if len(body.Ads) == 0 {
-		return &goutils.Response{
-			Code: http.StatusNoContent,
-		}
+		return MakeErrorResponse(fmt.Errorf("only the current ad is available: %w",
+			usecases.ErrNotEnoughAds))
 	}
 	return &goutils.Response{
 		Code: http.StatusOK,
@@ -95,11 +94,12 @@ func (h *GetUserAdsHandler) Execute(ig InputGetter) *goutils.Response {
 }
 
 // fillResponse parses domain struct to expected handler output
-func (h *GetUserAdsHandler) fillResponse(ads domain.Ads, listID string) []adsOutput {
+func (h *GetUserAdsHandler) fillResponse(ads domain.Ads, listID string,
+	settings usecases.Settings) []adsOutput {
 	resp := []adsOutput{}
 	for _, ad := range ads {
 		if ad.ID == listID {
-			continue;
+			continue
 		}
 		adOutTemp := adsOutput{
 			ID:       ad.ID,
@@ -116,10 +116,10 @@ func (h *GetUserAdsHandler) fillResponse(ads domain.Ads, listID string) []adsOut
 			IsRelated: ad.IsRelated,
 		}
 		if ad.Currency == "uf" {
-			adOutTemp.Currency = h.UnitOfAccountSymbol
+			adOutTemp.Currency = settings.UnitOfAccountSymbol
 			adOutTemp.Price = adOutTemp.Price / 100
 		} else {
-			adOutTemp.Currency = h.CurrencySymbol
+			adOutTemp.Currency = settings.CurrencySymbol
 		}
 		resp = append(resp, adOutTemp)
 	}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"testing"
@@ -10,6 +11,7 @@ import (
 	"github.com/stretchr/testify/mock"
 
 	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/domain"
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/usecases"
 )
 
 func TestGetUserAdsHandlerInput(t *testing.T) {
@@ -30,8 +32,9 @@ type mockGetUserAdsInteractor struct {
 	mock.Mock
 }
 
-func (m *mockGetUserAdsInteractor) GetUserAds(currentAdview domain.Ad) (domain.Ads, error) {
-	args := m.Called(currentAdview)
+func (m *mockGetUserAdsInteractor) GetUserAds(ctx context.Context, currentAdview domain.Ad,
+	settings usecases.Settings) (domain.Ads, error) {
+	args := m.Called(ctx, currentAdview, settings)
 	return args.Get(0).(domain.Ads), args.Error(1)
 }
 
@@ -39,21 +42,23 @@ type mockGetAdInteractor struct {
 	mock.Mock
 }
 
-func (m *mockGetAdInteractor) GetAd(listID string) (domain.Ad, error) {
-	args := m.Called(listID)
+func (m *mockGetAdInteractor) GetAd(ctx context.Context, listID string) (domain.Ad, error) {
+	args := m.Called(ctx, listID)
 	return args.Get(0).(domain.Ad), args.Error(1)
 }
 
 func TestGetUserAdsHandlerOK(t *testing.T) {
 	mInteractor := &mockGetUserAdsInteractor{}
 	mGetAdInteractor := &mockGetAdInteractor{}
-	mGetAdInteractor.On("GetAd", mock.AnythingOfType("string")).
+	mGetAdInteractor.On("GetAd", mock.Anything, mock.AnythingOfType("string")).
 		Return(domain.Ad{ID: "123", UserID: 465}, nil)
-	mInteractor.On("GetUserAds", mock.AnythingOfType("domain.Ad")).
+	mInteractor.On("GetUserAds", mock.Anything, mock.AnythingOfType("domain.Ad"),
+		mock.AnythingOfType("usecases.Settings")).
 		Return(domain.Ads{{ID: "321", UserID: 465}}, nil)
 	h := GetUserAdsHandler{
 		Interactor:      mInteractor,
 		GetAdInteractor: mGetAdInteractor,
+		Settings:        usecases.StaticSettings{},
 	}
 	var input getUserAdsHandlerInput
 	input.ListID = "123"
@@ -70,17 +75,41 @@ func TestGetUserAdsHandlerOK(t *testing.T) {
 	mGetAdInteractor.AssertExpectations(t)
 }
 
+func TestGetUserAdsHandlerRequestContext(t *testing.T) {
+	mInteractor := &mockGetUserAdsInteractor{}
+	mGetAdInteractor := &mockGetAdInteractor{}
+	ctx := ContextWithRequestID(context.Background(), "abc")
+	mGetAdInteractor.On("GetAd", ctx, "123").
+		Return(domain.Ad{ID: "123", UserID: 465}, nil)
+	mInteractor.On("GetUserAds", ctx, mock.AnythingOfType("domain.Ad"),
+		mock.AnythingOfType("usecases.Settings")).
+		Return(domain.Ads{{ID: "321", UserID: 465}}, nil)
+	h := GetUserAdsHandler{
+		Interactor:      mInteractor,
+		GetAdInteractor: mGetAdInteractor,
+		Settings:        usecases.StaticSettings{},
+	}
+	var input getUserAdsHandlerInput
+	input.ListID = "123"
+	input.SetContext(ctx)
+	r := h.Execute(MakeMockInputGetter(&input, nil))
+	assert.Equal(t, http.StatusOK, r.Code)
+	mInteractor.AssertExpectations(t)
+	mGetAdInteractor.AssertExpectations(t)
+}
+
 func TestGetUserAdsHandlerWithUF(t *testing.T) {
 	mInteractor := &mockGetUserAdsInteractor{}
 	mGetAdInteractor := &mockGetAdInteractor{}
-	mGetAdInteractor.On("GetAd", mock.AnythingOfType("string")).
+	mGetAdInteractor.On("GetAd", mock.Anything, mock.AnythingOfType("string")).
 		Return(domain.Ad{ID: "123", UserID: 465}, nil)
-	mInteractor.On("GetUserAds", mock.AnythingOfType("domain.Ad")).
+	mInteractor.On("GetUserAds", mock.Anything, mock.AnythingOfType("domain.Ad"),
+		mock.AnythingOfType("usecases.Settings")).
 		Return(domain.Ads{{ID: "321", UserID: 465, Currency: "uf"}}, nil)
 	h := GetUserAdsHandler{
-		Interactor:          mInteractor,
-		GetAdInteractor:     mGetAdInteractor,
-		UnitOfAccountSymbol: "UF",
+		Interactor:      mInteractor,
+		GetAdInteractor: mGetAdInteractor,
+		Settings:        usecases.StaticSettings{UnitOfAccountSymbol: "UF"},
 	}
 	var input getUserAdsHandlerInput
 	input.ListID = "123"
@@ -100,22 +129,21 @@ func TestGetUserAdsHandlerWithUF(t *testing.T) {
 func TestGetUserAdsHandlerNoAds(t *testing.T) {
 	mInteractor := &mockGetUserAdsInteractor{}
 	mGetAdInteractor := &mockGetAdInteractor{}
-	mGetAdInteractor.On("GetAd", mock.AnythingOfType("string")).
+	mGetAdInteractor.On("GetAd", mock.Anything, mock.AnythingOfType("string")).
 		Return(domain.Ad{ID: "123", UserID: 465}, nil)
-	mInteractor.On("GetUserAds", mock.AnythingOfType("domain.Ad")).
+	mInteractor.On("GetUserAds", mock.Anything, mock.AnythingOfType("domain.Ad"),
+		mock.AnythingOfType("usecases.Settings")).
 		Return(domain.Ads{}, nil)
 	h := GetUserAdsHandler{
 		Interactor:      mInteractor,
 		GetAdInteractor: mGetAdInteractor,
+		Settings:        usecases.StaticSettings{},
 	}
 	var input getUserAdsHandlerInput
 	input.ListID = "123"
 	getter := MakeMockInputGetter(&input, nil)
 	r := h.Execute(getter)
-	expected := &goutils.Response{
-		Code: http.StatusNoContent,
-	}
-	assert.Equal(t, expected, r)
+	assertProblem(t, r, http.StatusNotFound, usecases.NotEnoughAdsCode, "Not enough ads")
 	mInteractor.AssertExpectations(t)
 	mGetAdInteractor.AssertExpectations(t)
 }
@@ -123,21 +151,22 @@ func TestGetUserAdsHandlerNoAds(t *testing.T) {
 func TestGetUserAdsHandlerErrorGettingUserAds(t *testing.T) {
 	mInteractor := &mockGetUserAdsInteractor{}
 	mGetAdInteractor := &mockGetAdInteractor{}
-	mGetAdInteractor.On("GetAd", mock.AnythingOfType("string")).
+	mGetAdInteractor.On("GetAd", mock.Anything, mock.AnythingOfType("string")).
 		Return(domain.Ad{ID: "123", UserID: 465}, nil)
-	mInteractor.On("GetUserAds", mock.AnythingOfType("domain.Ad")).
-		Return(domain.Ads{}, fmt.Errorf("e"))
+	mInteractor.On("GetUserAds", mock.Anything, mock.AnythingOfType("domain.Ad"),
+		mock.AnythingOfType("usecases.Settings")).
+		Return(domain.Ads{}, usecases.ErrProductNotActive)
 	h := GetUserAdsHandler{
 		Interactor:      mInteractor,
 		GetAdInteractor: mGetAdInteractor,
+		Settings:        usecases.StaticSettings{},
 	}
 	var input getUserAdsHandlerInput
 	input.ListID = "123"
 	getter := MakeMockInputGetter(&input, nil)
 	r := h.Execute(getter)
-	expected := &goutils.Response{
-		Code: http.StatusNoContent,
-	}
+	expected := MakeProblemResponse(http.StatusNotFound,
+		usecases.ProductNotActiveCode, usecases.ErrProductNotActive.Error())
 	assert.Equal(t, expected, r)
 	mInteractor.AssertExpectations(t)
 	mGetAdInteractor.AssertExpectations(t)
@@ -146,19 +175,19 @@ func TestGetUserAdsHandlerErrorGettingUserAds(t *testing.T) {
 func TestGetUserAdsHandlerErrorGettingAd(t *testing.T) {
 	mInteractor := &mockGetUserAdsInteractor{}
 	mGetAdInteractor := &mockGetAdInteractor{}
-	mGetAdInteractor.On("GetAd", mock.AnythingOfType("string")).
-		Return(domain.Ad{ID: "123", UserID: 465}, fmt.Errorf("e"))
+	mGetAdInteractor.On("GetAd", mock.Anything, mock.AnythingOfType("string")).
+		Return(domain.Ad{}, usecases.ErrAdNotFound)
 	h := GetUserAdsHandler{
 		Interactor:      mInteractor,
 		GetAdInteractor: mGetAdInteractor,
+		Settings:        usecases.StaticSettings{},
 	}
 	var input getUserAdsHandlerInput
 	input.ListID = "123"
 	getter := MakeMockInputGetter(&input, nil)
 	r := h.Execute(getter)
-	expected := &goutils.Response{
-		Code: http.StatusNoContent,
-	}
+	expected := MakeProblemResponse(http.StatusNotFound,
+		usecases.AdNotFoundCode, usecases.ErrAdNotFound.Error())
 	assert.Equal(t, expected, r)
 	mInteractor.AssertExpectations(t)
 	mGetAdInteractor.AssertExpectations(t)
@@ -170,6 +199,7 @@ func TestGetUserAdsHandlerErrorBadInput(t *testing.T) {
 	h := GetUserAdsHandler{
 		Interactor:      mInteractor,
 		GetAdInteractor: mGetAdInteractor,
+		Settings:        usecases.StaticSettings{},
 	}
 	var input getUserAdsHandlerInput
 	input.ListID = "123"
@@ -184,3 +214,25 @@ func TestGetUserAdsHandlerErrorBadInput(t *testing.T) {
 	mInteractor.AssertExpectations(t)
 	mGetAdInteractor.AssertExpectations(t)
 }
+
+func TestGetUserAdsHandlerSearchUnavailable(t *testing.T) {
+	mInteractor := &mockGetUserAdsInteractor{}
+	mGetAdInteractor := &mockGetAdInteractor{}
+	err := &usecases.DomainError{Code: usecases.SearchUnavailableCode,
+		Message: "cannot retrieve the ad", Err: fmt.Errorf("e")}
+	mGetAdInteractor.On("GetAd", mock.Anything, mock.AnythingOfType("string")).
+		Return(domain.Ad{}, err)
+	h := GetUserAdsHandler{
+		Interactor:      mInteractor,
+		GetAdInteractor: mGetAdInteractor,
+		Settings:        usecases.StaticSettings{},
+	}
+	var input getUserAdsHandlerInput
+	input.ListID = "123"
+	getter := MakeMockInputGetter(&input, nil)
+	r := h.Execute(getter)
+	assertProblem(t, r, http.StatusServiceUnavailable, usecases.SearchUnavailableCode,
+		"cannot retrieve the ad")
+	mInteractor.AssertExpectations(t)
+	mGetAdInteractor.AssertExpectations(t)
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/Yapo/goutils"
+
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/domain"
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/usecases"
+)
+
+// GetUserProductHandler implements the handler interface and responds to
+// /assigns/{ID} with the requested user product
+type GetUserProductHandler struct {
+	Interactor usecases.GetUserProductInteractor
+}
+
+// GetUserProductLogger logger for GetUserProduct Handler
+type GetUserProductLogger interface{}
+
+// getUserProductHandlerInput is the handler expected input
+type getUserProductHandlerInput struct {
+	RequestContext
+	UserProductID int `path:"ID" validate:"min=1"`
+}
+
+// Input returns a fresh, empty instance of getUserProductHandlerInput
+func (*GetUserProductHandler) Input(ir InputRequest) HandlerInput {
+	input := getUserProductHandlerInput{}
+	ir.Set(&input).FromPath()
+	return &input
+}
+
+// Execute gets a user product for controlpanel, the product version is sent
+// as the ETag header to be used on updates
+func (h *GetUserProductHandler) Execute(ig InputGetter) *goutils.Response {
+	input, response := ig()
+	if response != nil {
+		return response
+	}
+	in := input.(*getUserProductHandlerInput)
+	product, err := h.Interactor.GetUserProduct(in.Context(), in.UserProductID)
+	if err != nil {
+		return MakeErrorResponse(err)
+	}
+	return makeProductResponse(http.StatusOK, product)
+}
+
+// makeProductResponse returns a response holding the product output along
+// with its version as the ETag header
+func makeProductResponse(code int, product domain.Product) *goutils.Response {
+	return &goutils.Response{
+		Code: code,
+		Body: HeadedBody{
+			Headers: map[string]string{"ETag": makeETag(product.Version)},
+			Body:    makeProductOutput(product),
+		},
+	}
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/Yapo/goutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/domain"
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/usecases"
+)
+
+type mockGetUserProductInteractor struct {
+	mock.Mock
+}
+
+func (m *mockGetUserProductInteractor) GetUserProduct(ctx context.Context, userProductID int) (domain.Product, error) {
+	args := m.Called(ctx, userProductID)
+	return args.Get(0).(domain.Product), args.Error(1)
+}
+
+func TestGetUserProductHandlerInput(t *testing.T) {
+	var h GetUserProductHandler
+	mMockInputRequest := &MockInputRequest{}
+	mTargetRequest := &MockTargetRequest{}
+	mMockInputRequest.On("Set",
+		mock.Anything).Return(mTargetRequest)
+	mTargetRequest.On("FromPath").Return(mTargetRequest)
+	input := h.Input(mMockInputRequest)
+	var expected *getUserProductHandlerInput
+	assert.IsType(t, expected, input)
+	mMockInputRequest.AssertExpectations(t)
+	mTargetRequest.AssertExpectations(t)
+}
+
+func TestGetUserProductHandlerOK(t *testing.T) {
+	mInteractor := &mockGetUserProductInteractor{}
+	mInteractor.On("GetUserProduct", mock.Anything, 123).
+		Return(domain.Product{ID: 123, Version: 4,
+			Purchase: domain.Purchase{ID: 1, Type: domain.AdminPurchase}}, nil)
+	h := GetUserProductHandler{
+		Interactor: mInteractor,
+	}
+	input := getUserProductHandlerInput{UserProductID: 123}
+	getter := MakeMockInputGetter(&input, nil)
+	r := h.Execute(getter)
+	expected := &goutils.Response{
+		Code: http.StatusOK,
+		Body: HeadedBody{
+			Headers: map[string]string{"ETag": `"4"`},
+			Body: productsOutput{ID: 123, UserID: "0", PurchaseID: 1,
+				PurchaseType: "ADMIN", ETag: `"4"`},
+		},
+	}
+	assert.Equal(t, expected, r)
+	mInteractor.AssertExpectations(t)
+}
+
+func TestGetUserProductHandlerNotFound(t *testing.T) {
+	mInteractor := &mockGetUserProductInteractor{}
+	mInteractor.On("GetUserProduct", mock.Anything, 123).
+		Return(domain.Product{}, usecases.ErrProductNotFound)
+	h := GetUserProductHandler{
+		Interactor: mInteractor,
+	}
+	input := getUserProductHandlerInput{UserProductID: 123}
+	getter := MakeMockInputGetter(&input, nil)
+	r := h.Execute(getter)
+	expected := MakeProblemResponse(http.StatusNotFound,
+		usecases.ProductNotFoundCode, usecases.ErrProductNotFound.Error())
+	assert.Equal(t, expected, r)
+	mInteractor.AssertExpectations(t)
+}
+
+func TestGetUserProductHandlerError(t *testing.T) {
+	mInteractor := &mockGetUserProductInteractor{}
+	mInteractor.On("GetUserProduct", mock.Anything, 123).
+		Return(domain.Product{}, fmt.Errorf("err"))
+	h := GetUserProductHandler{
+		Interactor: mInteractor,
+	}
+	input := getUserProductHandlerInput{UserProductID: 123}
+	getter := MakeMockInputGetter(&input, nil)
+	r := h.Execute(getter)
+	assertProblem(t, r, http.StatusInternalServerError, InternalErrorCode, "Internal error")
+	mInteractor.AssertExpectations(t)
+}
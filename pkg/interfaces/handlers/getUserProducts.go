@@ -9,6 +9,7 @@ import (
 
 	"github.com/Yapo/goutils"
 
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/domain"
 	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/usecases"
 )
 
@@ -23,8 +24,9 @@ type GetUserProductsLogger interface{}
 
 // getUserProductsHandlerInput is the handler expected input
 type getUserProductsHandlerInput struct {
-	Email string `query:"email"`
-	Page  int    `query:"page"`
+	RequestContext
+	Email string `query:"email" validate:"email"`
+	Page  int    `query:"page" validate:"min=0"`
 }
 
 // getUserRequestOutput is the handler output
@@ -52,6 +54,7 @@ type productsOutput struct {
 	PriceRange         int       `json:"price_range"`
 	Limit              int       `json:"limit"`
 	FillGapsWithRandom bool      `json:"fill_random"`
+	ETag               string    `json:"etag"`
 }
 
 type metadata struct {
@@ -73,39 +76,14 @@ func (h *GetUserProductsHandler) Execute(ig InputGetter) *goutils.Response {
 		return response
 	}
 	in := input.(*getUserProductsHandlerInput)
-	products, currentPage, totalPages, err := h.Interactor.GetUserProducts(in.Email, in.Page)
+	products, currentPage, totalPages, err := h.Interactor.GetUserProducts(in.Context(),
+		in.Email, in.Page)
 	if err != nil {
-		return &goutils.Response{
-			Code: http.StatusBadRequest,
-			Body: goutils.GenericError{
-				ErrorMessage: fmt.Sprintf(`%+v`, err),
-			},
-		}
+		return MakeErrorResponse(err)
 	}
 	productsOut := []productsOutput{}
 	for _, v := range products {
-		p := productsOutput{
-			ID:             v.ID,
-			Email:          v.Email,
-			UserID:         strconv.Itoa(v.UserID),
-			Status:         string(v.Status),
-			Type:           string(v.Type),
-			PurchaseID:     v.Purchase.ID,
-			PurchaseNumber: v.Purchase.Number,
-			PurchasePrice:  v.Purchase.Price,
-			PurchaseStatus: string(v.Purchase.Status),
-			PurchaseType:   string(v.Purchase.Type),
-			ExpiredAt:      v.ExpiredAt,
-			CreatedAt:      v.CreatedAt,
-			Comment:        v.Config.Comment,
-			Keywords:       strings.Join(v.Config.Keywords, ","),
-			PriceRange:     v.Config.PriceRange,
-			Categories: strings.Trim(strings.Join(
-				strings.Fields(fmt.Sprint(v.Config.Categories)), ","), "[]"),
-			Limit:              v.Config.Limit,
-			FillGapsWithRandom: v.Config.FillGapsWithRandom,
-		}
-		productsOut = append(productsOut, p)
+		productsOut = append(productsOut, makeProductOutput(v))
 	}
 	body := getUserProductsRequestOutput{
 		Products: productsOut,
@@ -119,3 +97,29 @@ func (h *GetUserProductsHandler) Execute(ig InputGetter) *goutils.Response {
 		Body: body,
 	}
 }
+
+// makeProductOutput converts a product to its output representation
+func makeProductOutput(v domain.Product) productsOutput {
+	return productsOutput{
+		ID:             v.ID,
+		Email:          v.Email,
+		UserID:         strconv.Itoa(v.UserID),
+		Status:         string(v.Status),
+		Type:           string(v.Type),
+		PurchaseID:     v.Purchase.ID,
+		PurchaseNumber: v.Purchase.Number,
+		PurchasePrice:  v.Purchase.Price,
+		PurchaseStatus: string(v.Purchase.Status),
+		PurchaseType:   string(v.Purchase.Type),
+		ExpiredAt:      v.ExpiredAt,
+		CreatedAt:      v.CreatedAt,
+		Comment:        v.Config.Comment,
+		Keywords:       strings.Join(v.Config.Keywords, ","),
+		PriceRange:     v.Config.PriceRange,
+		Categories: strings.Trim(strings.Join(
+			strings.Fields(fmt.Sprint(v.Config.Categories)), ","), "[]"),
+		Limit:              v.Config.Limit,
+		FillGapsWithRandom: v.Config.FillGapsWithRandom,
+		ETag:               makeETag(v.Version),
+	}
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"testing"
@@ -30,9 +31,9 @@ type mockGetUserProductsInteractor struct {
 	mock.Mock
 }
 
-func (m *mockGetUserProductsInteractor) GetUserProducts(email string,
+func (m *mockGetUserProductsInteractor) GetUserProducts(ctx context.Context, email string,
 	page int) ([]domain.Product, int, int, error) {
-	args := m.Called(email, page)
+	args := m.Called(ctx, email, page)
 	return args.Get(0).([]domain.Product), args.Int(1), args.Int(2), args.Error(3)
 }
 
@@ -56,9 +57,9 @@ func TestGetUserProductsHandlerErrorBadInput(t *testing.T) {
 
 func TestGetUserProductsHandlerOK(t *testing.T) {
 	mInteractor := &mockGetUserProductsInteractor{}
-	mInteractor.On("GetUserProducts", mock.AnythingOfType("string"),
+	mInteractor.On("GetUserProducts", mock.Anything, mock.AnythingOfType("string"),
 		mock.AnythingOfType("int")).
-		Return([]domain.Product{{ID: 123,
+		Return([]domain.Product{{ID: 123, Version: 2,
 			Purchase: domain.Purchase{ID: 1, Type: domain.AdminPurchase}}}, 1, 1, nil)
 	h := GetUserProductsHandler{
 		Interactor: mInteractor,
@@ -73,7 +74,7 @@ func TestGetUserProductsHandlerOK(t *testing.T) {
 		Code: http.StatusOK,
 		Body: getUserProductsRequestOutput{
 			Products: []productsOutput{{ID: 123, UserID: "0", PurchaseID: 1,
-				PurchaseType: "ADMIN"}},
+				PurchaseType: "ADMIN", ETag: `"2"`}},
 			Metadata: metadata{CurrentPage: 1, TotalPages: 1},
 		},
 	}
@@ -84,7 +85,7 @@ func TestGetUserProductsHandlerOK(t *testing.T) {
 func TestGetUserProductsHandlerError(t *testing.T) {
 	mInteractor := &mockGetUserProductsInteractor{}
 	err := fmt.Errorf("err")
-	mInteractor.On("GetUserProducts", mock.AnythingOfType("string"),
+	mInteractor.On("GetUserProducts", mock.Anything, mock.AnythingOfType("string"),
 		mock.AnythingOfType("int")).
 		Return([]domain.Product{}, 0, 0, err)
 	h := GetUserProductsHandler{
@@ -96,12 +97,6 @@ func TestGetUserProductsHandlerError(t *testing.T) {
 	}
 	getter := MakeMockInputGetter(&input, nil)
 	r := h.Execute(getter)
-	expected := &goutils.Response{
-		Code: http.StatusBadRequest,
-		Body: goutils.GenericError{
-			ErrorMessage: fmt.Sprintf(`%+v`, err),
-		},
-	}
-	assert.Equal(t, expected, r)
+	assertProblem(t, r, http.StatusInternalServerError, InternalErrorCode, "Internal error")
 	mInteractor.AssertExpectations(t)
 }
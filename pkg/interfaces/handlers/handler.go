@@ -8,6 +8,8 @@ import (
 	"time"
 
 	"github.com/Yapo/goutils"
+
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/usecases"
 )
 
 // HandlerInput is a placeholder for whatever input a handler may need.
@@ -51,6 +53,7 @@ type TargetRequest interface {
 	FromHeaders() TargetRequest
 	FromCookies() TargetRequest
 	FromForm() TargetRequest
+	FromIdentity() TargetRequest
 }
 
 // Cors methods to configure cache and cors
@@ -70,6 +73,62 @@ type Cache struct {
 	Enabled bool
 }
 
+// HeadedBody wraps a response body along with the extra headers that must
+// be sent with it
+type HeadedBody struct {
+	Headers map[string]string
+	Body    interface{}
+}
+
+// FieldError describes an input field that broke a validation rule
+type FieldError struct {
+	Field string      `json:"field"`
+	Value interface{} `json:"value"`
+	Rule  string      `json:"rule"`
+}
+
+// MakeValidationErrorResponse returns the response for the given invalid fields
+func MakeValidationErrorResponse(fields []FieldError) *goutils.Response {
+	descriptions := make([]string, 0, len(fields))
+	for _, f := range fields {
+		descriptions = append(descriptions, fmt.Sprintf("%s (%s)", f.Field, f.Rule))
+	}
+	return makeProblem(ProblemDetails{
+		Status: http.StatusUnprocessableEntity,
+		Code:   ValidationFailedCode,
+		Detail: "invalid fields: " + strings.Join(descriptions, ", "),
+		Fields: fields,
+	})
+}
+
+// makeETag returns the entity tag that identifies the given version
+func makeETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// parseETag returns the version identified by the given entity tag
+func parseETag(etag string) (int, error) {
+	etag = strings.TrimPrefix(strings.TrimSpace(etag), "W/")
+	return strconv.Atoi(strings.Trim(etag, `"`))
+}
+
+// getIfMatchVersion returns the version required by the If-Match header.
+// Updates without If-Match are rejected with 428 and unknown entity tags
+// with 412
+func getIfMatchVersion(ifMatch string) (int, *goutils.Response) {
+	if ifMatch == "" {
+		return 0, MakeProblemResponse(http.StatusPreconditionRequired,
+			PreconditionRequiredCode, "If-Match header is required")
+	}
+	version, err := parseETag(ifMatch)
+	if err != nil {
+		return 0, MakeProblemResponse(http.StatusPreconditionFailed,
+			usecases.VersionMismatchCode,
+			fmt.Sprintf("If-Match %s does not match", ifMatch))
+	}
+	return version, nil
+}
+
 // MakeJSONHandlerFunc wraps a Handler on a json-over-http context, returning
 // a standard http.HandlerFunc
 func MakeJSONHandlerFunc(h Handler, l JSONHandlerLogger, ih InputHandler, crs Cors, cache *Cache) http.HandlerFunc {
@@ -100,6 +159,33 @@ func (jh *jsonHandler) setupCors(w *http.ResponseWriter) {
 	}
 }
 
+// headedWriter sets its headers again right before writing the status, so
+// they take precedence over the ones set while writing the response
+type headedWriter struct {
+	http.ResponseWriter
+	headers map[string]string
+}
+
+// WriteHeader sets the writer headers and sends the status code
+func (w headedWriter) WriteHeader(statusCode int) {
+	for key, value := range w.headers {
+		w.Header().Set(key, value)
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+// setupHeaders unwraps the body of a HeadedBody and returns a writer that
+// sends its headers
+func (jh *jsonHandler) setupHeaders(w http.ResponseWriter,
+	response *goutils.Response) http.ResponseWriter {
+	body, ok := response.Body.(HeadedBody)
+	if !ok {
+		return w
+	}
+	response.Body = body.Body
+	return headedWriter{ResponseWriter: w, headers: body.Headers}
+}
+
 func (jh *jsonHandler) inBrowserCache(w http.ResponseWriter, r *http.Request) bool {
 	if jh.cache.Enabled {
 		key := strconv.FormatInt(jh.cache.Etag, 10)
@@ -129,11 +215,15 @@ func (jh *jsonHandler) run(w http.ResponseWriter, r *http.Request) {
 	// Function the request can call to retrieve its input
 	ri := jh.inputHandler.NewInputRequest(r)
 	input := jh.handler.Input(ri)
+	if contextInput, ok := input.(ContextInput); ok {
+		contextInput.SetContext(r.Context())
+	}
 	jh.inputHandler.SetInputRequest(ri, input)
 	// Format the output and send it down the writer
 	outputWriter := func() {
+		hw := jh.setupHeaders(w, response)
 		goutils.CreateJSON(response)
-		goutils.WriteJSONResponse(w, response)
+		goutils.WriteJSONResponse(hw, response)
 	}
 	// Handle panicking handlers and report errors
 	errorHandler := func() {
@@ -87,6 +87,10 @@ type DummyInput struct {
 	X int
 }
 
+type DummyContextInput struct {
+	RequestContext
+}
+
 type DummyOutput struct {
 	Y string
 }
@@ -159,6 +163,36 @@ func TestJsonHandlerFuncOK(t *testing.T) {
 	mC.AssertExpectations(t)
 }
 
+func TestJsonHandlerFuncContextInput(t *testing.T) {
+	h := MockHandler{}
+	ih := MockInputHandler{}
+	mMockInputRequest := MockInputRequest{}
+	l := MockLogger{}
+	input := &DummyContextInput{}
+	response := &goutils.Response{Code: http.StatusOK}
+	h.On("Execute", mock.AnythingOfType("handlers.InputGetter")).Return(response).Once()
+	h.On("Input", mock.AnythingOfType("*handlers.MockInputRequest")).Return(input).Once()
+	ih.On("NewInputRequest", mock.AnythingOfType("*http.Request")).Return(&mMockInputRequest)
+	ih.On("Input").Return(input, response)
+	ih.On("SetInputRequest", mock.AnythingOfType("*handlers.MockInputRequest"), input)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/someurl", nil)
+	r = r.WithContext(ContextWithRequestID(r.Context(), "abc"))
+	l.On("LogRequestStart", r)
+	l.On("LogRequestEnd", r, response)
+	mC := MockCors{}
+	mC.On("GetHeaders").Return(map[string]string{})
+	fn := MakeJSONHandlerFunc(&h, &l, &ih, &mC, &Cache{})
+	fn(w, r)
+
+	requestID, _ := RequestIDFromContext(input.Context())
+	assert.Equal(t, "abc", requestID)
+	h.AssertExpectations(t)
+	ih.AssertExpectations(t)
+	l.AssertExpectations(t)
+}
+
 func TestJsonHandlerFuncOK2(t *testing.T) {
 	h := MockHandler{}
 	ih := MockInputHandler{}
@@ -390,3 +424,95 @@ func TestJsonHandlerFuncCache(t *testing.T) {
 	l.AssertExpectations(t)
 	mC.AssertExpectations(t)
 }
+
+func TestJsonHandlerFuncHeadedBody(t *testing.T) {
+	h := MockHandler{}
+	ih := MockInputHandler{}
+	mMockInputRequest := MockInputRequest{}
+	l := MockLogger{}
+	input := &DummyInput{}
+	response := &goutils.Response{
+		Code: http.StatusOK,
+		Body: HeadedBody{
+			Headers: map[string]string{"ETag": `"3"`},
+			Body:    DummyOutput{"That's some bad hat, Harry"},
+		},
+	}
+	getter := mock.AnythingOfType("handlers.InputGetter")
+	h.On("Execute", getter).Return(response).Once()
+	h.On("Input", mock.AnythingOfType("*handlers.MockInputRequest")).Return(input).Once()
+
+	ih.On("NewInputRequest", mock.AnythingOfType("*http.Request")).Return(&mMockInputRequest)
+	ih.On("Input").Return(input, (*goutils.Response)(nil))
+	ih.On(
+		"SetInputRequest",
+		mock.AnythingOfType("*handlers.MockInputRequest"),
+		mock.AnythingOfType("*handlers.DummyInput"),
+	)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/someurl", strings.NewReader("{}"))
+
+	l.On("LogRequestStart", r)
+	l.On("LogRequestEnd", r, response)
+
+	mC := MockCors{}
+	mC.On("GetHeaders").Return(map[string]string{})
+
+	cache := &Cache{}
+	fn := MakeJSONHandlerFunc(&h, &l, &ih, &mC, cache)
+	fn(w, r)
+
+	expectedHeaders := http.Header{
+		"Etag":         []string{`"3"`},
+		"Content-Type": []string{"application/json"}}
+
+	assert.Equal(t, expectedHeaders, w.Result().Header)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"Y":"That's some bad hat, Harry"}`+"\n", w.Body.String())
+	h.AssertExpectations(t)
+	ih.AssertExpectations(t)
+	mMockInputRequest.AssertExpectations(t)
+	l.AssertExpectations(t)
+	mC.AssertExpectations(t)
+}
+
+func TestParseETag(t *testing.T) {
+	for etag, expected := range map[string]int{
+		`"3"`:   3,
+		`W/"4"`: 4,
+		` "5" `: 5,
+	} {
+		version, err := parseETag(etag)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, version)
+	}
+	_, err := parseETag(`"abc"`)
+	assert.Error(t, err)
+	assert.Equal(t, `"7"`, makeETag(7))
+}
+
+func TestMakeValidationErrorResponse(t *testing.T) {
+	r := MakeValidationErrorResponse([]FieldError{
+		{Field: "limit", Value: -1, Rule: "min=0"},
+		{Field: "email", Value: "a", Rule: "email"},
+	})
+	expected := &goutils.Response{
+		Code: http.StatusUnprocessableEntity,
+		Body: HeadedBody{
+			Headers: map[string]string{"Content-Type": ProblemContentType},
+			Body: ProblemDetails{
+				Type:   "about:blank",
+				Title:  "Unprocessable Entity",
+				Status: http.StatusUnprocessableEntity,
+				Detail: "invalid fields: limit (min=0), email (email)",
+				Code:   ValidationFailedCode,
+				Fields: []FieldError{
+					{Field: "limit", Value: -1, Rule: "min=0"},
+					{Field: "email", Value: "a", Rule: "email"},
+				},
+			},
+		},
+	}
+	assert.Equal(t, expected, r)
+}
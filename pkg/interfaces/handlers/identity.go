@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"context"
+)
+
+// Role defines the permissions granted to an authenticated identity
+type Role string
+
+const (
+	// ReaderRole allows to read user products and reports
+	ReaderRole Role = "reader"
+	// EditorRole allows everything a reader does plus editing user products
+	EditorRole Role = "editor"
+	// AdminRole allows every operation
+	AdminRole Role = "admin"
+)
+
+// roleRanks sorts the roles from the least to the most privileged
+var roleRanks = map[Role]int{
+	ReaderRole: 1,
+	EditorRole: 2,
+	AdminRole:  3,
+}
+
+// Valid reports whether the role is a known role
+func (r Role) Valid() bool {
+	_, ok := roleRanks[r]
+	return ok
+}
+
+// Allows reports whether the role grants the permissions of the required role
+func (r Role) Allows(required Role) bool {
+	return r.Valid() && roleRanks[r] >= roleRanks[required]
+}
+
+// Identity describes who is doing an authenticated request
+type Identity struct {
+	Subject string `identity:"subject"`
+	Role    Role   `identity:"role"`
+}
+
+// identityKey is the request context key holding the Identity
+type identityKey struct{}
+
+// ContextWithIdentity returns a copy of ctx holding the given identity
+func ContextWithIdentity(ctx context.Context, identity Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// IdentityFromContext returns the identity held by ctx, if any
+func IdentityFromContext(ctx context.Context) (Identity, bool) {
+	identity, ok := ctx.Value(identityKey{}).(Identity)
+	return identity, ok
+}
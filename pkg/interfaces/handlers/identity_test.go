@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoleAllows(t *testing.T) {
+	assert.True(t, AdminRole.Allows(EditorRole))
+	assert.True(t, EditorRole.Allows(EditorRole))
+	assert.True(t, EditorRole.Allows(ReaderRole))
+	assert.False(t, ReaderRole.Allows(EditorRole))
+	assert.False(t, EditorRole.Allows(AdminRole))
+	assert.False(t, Role("root").Allows(ReaderRole))
+}
+
+func TestIdentityContext(t *testing.T) {
+	_, ok := IdentityFromContext(context.Background())
+	assert.False(t, ok)
+	identity := Identity{Subject: "jane", Role: ReaderRole}
+	got, ok := IdentityFromContext(ContextWithIdentity(context.Background(), identity))
+	assert.True(t, ok)
+	assert.Equal(t, identity, got)
+}
@@ -61,3 +61,9 @@ func (m *MockTargetRequest) FromForm() TargetRequest {
 	m.Called()
 	return m
 }
+
+// FromIdentity is a mocked method
+func (m *MockTargetRequest) FromIdentity() TargetRequest {
+	m.Called()
+	return m
+}
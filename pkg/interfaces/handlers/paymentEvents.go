@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/domain"
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/usecases"
+)
+
+// ErrPoisonMessage tells that a message can never be processed, so it must
+// be set aside instead of retried
+var ErrPoisonMessage = errors.New("poison message")
+
+// ConfirmedPaymentStatus is the status of the payments already charged
+const ConfirmedPaymentStatus = "CONFIRMED"
+
+// paymentKeyPrefix namespaces the idempotency keys of the payments, apart
+// from the keys sent by the clients
+const paymentKeyPrefix = "consumer:payment:"
+
+// PaymentEventsHandler creates the products of the confirmed payments read
+// from the payments topic
+type PaymentEventsHandler struct {
+	Interactor usecases.AddUserProductInteractor
+	// Plans holds the duration of the product bought with each plan
+	Plans map[string]time.Duration
+	// DefaultConfig is the config of the products created from payments
+	DefaultConfig domain.ProductParams
+	// Now returns the current time
+	Now func() time.Time
+}
+
+// PaymentEventsLogger logger for PaymentEvents Handler
+type PaymentEventsLogger interface{}
+
+// paymentEvent is the message published on the payments topic
+type paymentEvent struct {
+	PurchaseNumber int       `json:"purchase_number"`
+	Status         string    `json:"status"`
+	Product        string    `json:"product"`
+	Plan           string    `json:"plan"`
+	UserID         int       `json:"user_id"`
+	Email          string    `json:"email"`
+	Price          int       `json:"price"`
+	PaidAt         time.Time `json:"paid_at"`
+}
+
+// Handle creates the product of a confirmed premium carousel payment. The
+// purchase number is used as idempotency key, so a payment read twice
+// creates a single product. Once the key expires, the payment already stored
+// is skipped as processed. Messages that can't be processed are reported
+// with ErrPoisonMessage, any other error may be retried
+func (h *PaymentEventsHandler) Handle(ctx context.Context, message []byte) error {
+	var event paymentEvent
+	if err := json.Unmarshal(message, &event); err != nil {
+		return fmt.Errorf("%w: %v", ErrPoisonMessage, err)
+	}
+	if event.Status != ConfirmedPaymentStatus ||
+		event.Product != string(domain.PremiumCarousel) {
+		return nil
+	}
+	expiredAt, err := h.getExpiration(event)
+	if err != nil {
+		return fmt.Errorf("%w: purchase %d: %v", ErrPoisonMessage, event.PurchaseNumber, err)
+	}
+	_, err = h.Interactor.AddUserProduct(ctx,
+		paymentKeyPrefix+strconv.Itoa(event.PurchaseNumber), event.UserID,
+		event.Email, event.PurchaseNumber, event.Price,
+		domain.PaymentPurchase, domain.PremiumCarousel, time.Time{}, expiredAt,
+		h.DefaultConfig)
+	if errors.Is(err, usecases.ErrPurchaseAlreadyExists) {
+		return nil
+	}
+	if errors.Is(err, usecases.ErrIdempotencyKeyReused) {
+		return fmt.Errorf("%w: purchase %d: %v", ErrPoisonMessage, event.PurchaseNumber, err)
+	}
+	return err
+}
+
+// getExpiration validates the payment and returns the expiration of its
+// product, given by the plan bought
+func (h *PaymentEventsHandler) getExpiration(event paymentEvent) (time.Time, error) {
+	if event.PurchaseNumber <= 0 || event.UserID <= 0 || event.Email == "" {
+		return time.Time{}, fmt.Errorf("missing purchase number, user id or email")
+	}
+	if event.PaidAt.IsZero() {
+		return time.Time{}, fmt.Errorf("missing payment date")
+	}
+	duration, ok := h.Plans[event.Plan]
+	if !ok {
+		return time.Time{}, fmt.Errorf("unknown plan %q", event.Plan)
+	}
+	expiredAt := event.PaidAt.Add(duration)
+	if expiredAt.Before(h.Now()) {
+		return time.Time{}, fmt.Errorf("plan already expired at %v", expiredAt)
+	}
+	return expiredAt, nil
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/domain"
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/usecases"
+)
+
+func makeTestPaymentEventsHandler(interactor usecases.AddUserProductInteractor) PaymentEventsHandler {
+	return PaymentEventsHandler{
+		Interactor:    interactor,
+		Plans:         map[string]time.Duration{"monthly": 720 * time.Hour},
+		DefaultConfig: domain.ProductParams{Limit: 20},
+		Now: func() time.Time {
+			return time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+		},
+	}
+}
+
+const testPayment = `{"purchase_number": 10, "status": "CONFIRMED",
+	"product": "PREMIUM_CAROUSEL", "plan": "monthly", "user_id": 1,
+	"email": "user@mail.com", "price": 990, "paid_at": "2020-01-01T00:00:00Z"}`
+
+func TestPaymentEventsHandlerOK(t *testing.T) {
+	mInteractor := &mockAddUserProductInteractor{}
+	h := makeTestPaymentEventsHandler(mInteractor)
+	mInteractor.On("AddUserProduct", mock.Anything, "consumer:payment:10", 1, "user@mail.com", 10, 990,
+		domain.PaymentPurchase, domain.PremiumCarousel, time.Time{},
+		time.Date(2020, 1, 31, 0, 0, 0, 0, time.UTC),
+		domain.ProductParams{Limit: 20}).Return(domain.Product{}, nil)
+	err := h.Handle(context.Background(), []byte(testPayment))
+	assert.NoError(t, err)
+	mInteractor.AssertExpectations(t)
+}
+
+func TestPaymentEventsHandlerIgnored(t *testing.T) {
+	mInteractor := &mockAddUserProductInteractor{}
+	h := makeTestPaymentEventsHandler(mInteractor)
+	err := h.Handle(context.Background(), []byte(`{"purchase_number": 10, "status": "PENDING",
+		"product": "PREMIUM_CAROUSEL"}`))
+	assert.NoError(t, err)
+	err = h.Handle(context.Background(), []byte(`{"purchase_number": 10, "status": "CONFIRMED",
+		"product": "BUMP"}`))
+	assert.NoError(t, err)
+	mInteractor.AssertExpectations(t)
+}
+
+func TestPaymentEventsHandlerPoison(t *testing.T) {
+	mInteractor := &mockAddUserProductInteractor{}
+	h := makeTestPaymentEventsHandler(mInteractor)
+	messages := []string{
+		`{`,
+		`{"purchase_number": 10, "status": "CONFIRMED", "product": "PREMIUM_CAROUSEL",
+			"plan": "monthly", "paid_at": "2020-01-01T00:00:00Z"}`,
+		`{"purchase_number": 10, "status": "CONFIRMED", "product": "PREMIUM_CAROUSEL",
+			"plan": "monthly", "user_id": 1, "email": "user@mail.com"}`,
+		`{"purchase_number": 10, "status": "CONFIRMED", "product": "PREMIUM_CAROUSEL",
+			"plan": "yearly", "user_id": 1, "email": "user@mail.com",
+			"paid_at": "2020-01-01T00:00:00Z"}`,
+		`{"purchase_number": 10, "status": "CONFIRMED", "product": "PREMIUM_CAROUSEL",
+			"plan": "monthly", "user_id": 1, "email": "user@mail.com",
+			"paid_at": "2019-01-01T00:00:00Z"}`,
+	}
+	for _, message := range messages {
+		err := h.Handle(context.Background(), []byte(message))
+		assert.True(t, errors.Is(err, ErrPoisonMessage), message)
+	}
+	mInteractor.AssertExpectations(t)
+}
+
+func TestPaymentEventsHandlerReusedPurchase(t *testing.T) {
+	mInteractor := &mockAddUserProductInteractor{}
+	h := makeTestPaymentEventsHandler(mInteractor)
+	mInteractor.On("AddUserProduct", mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything).
+		Return(domain.Product{}, usecases.ErrIdempotencyKeyReused)
+	err := h.Handle(context.Background(), []byte(testPayment))
+	assert.True(t, errors.Is(err, ErrPoisonMessage))
+	mInteractor.AssertExpectations(t)
+}
+
+func TestPaymentEventsHandlerAlreadyProcessed(t *testing.T) {
+	mInteractor := &mockAddUserProductInteractor{}
+	h := makeTestPaymentEventsHandler(mInteractor)
+	mInteractor.On("AddUserProduct", mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything).
+		Return(domain.Product{}, usecases.ErrPurchaseAlreadyExists)
+	err := h.Handle(context.Background(), []byte(testPayment))
+	assert.NoError(t, err)
+	mInteractor.AssertExpectations(t)
+}
+
+func TestPaymentEventsHandlerError(t *testing.T) {
+	mInteractor := &mockAddUserProductInteractor{}
+	h := makeTestPaymentEventsHandler(mInteractor)
+	mInteractor.On("AddUserProduct", mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything).
+		Return(domain.Product{}, fmt.Errorf("err"))
+	err := h.Handle(context.Background(), []byte(testPayment))
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, ErrPoisonMessage))
+	mInteractor.AssertExpectations(t)
+}
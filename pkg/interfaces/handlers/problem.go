@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Yapo/goutils"
+
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/usecases"
+)
+
+// ProblemContentType is the media type of the error responses
+const ProblemContentType = "application/problem+json"
+
+const (
+	// InvalidInputCode is used when the request input can not be parsed
+	InvalidInputCode usecases.ErrorCode = "INVALID_INPUT"
+	// ValidationFailedCode is used when the request input breaks a
+	// validation rule
+	ValidationFailedCode usecases.ErrorCode = "VALIDATION_FAILED"
+	// PreconditionRequiredCode is used when an update is requested without
+	// the If-Match header
+	PreconditionRequiredCode usecases.ErrorCode = "PRECONDITION_REQUIRED"
+	// UnauthorizedCode is used when the request credentials are missing or
+	// invalid
+	UnauthorizedCode usecases.ErrorCode = "UNAUTHORIZED"
+	// ForbiddenCode is used when the request identity lacks the required role
+	ForbiddenCode usecases.ErrorCode = "FORBIDDEN"
+	// InternalErrorCode is used for any error without a known code
+	InternalErrorCode usecases.ErrorCode = "INTERNAL_ERROR"
+)
+
+// problemStatus maps every domain error code to its response status
+var problemStatus = map[usecases.ErrorCode]int{
+	usecases.AdNotFoundCode:            http.StatusNotFound,
+	usecases.ProductNotFoundCode:       http.StatusNotFound,
+	usecases.ProductNotActiveCode:      http.StatusNotFound,
+	usecases.ProductExpiredCode:        http.StatusNotFound,
+	usecases.NotEnoughAdsCode:          http.StatusNotFound,
+	usecases.VersionMismatchCode:       http.StatusPreconditionFailed,
+	usecases.IdempotencyKeyReusedCode:  http.StatusConflict,
+	usecases.RequestInProgressCode:     http.StatusConflict,
+	usecases.PurchaseAlreadyExistsCode: http.StatusConflict,
+	usecases.SearchUnavailableCode:     http.StatusServiceUnavailable,
+	usecases.DatabaseUnavailableCode:   http.StatusServiceUnavailable,
+	usecases.CacheUnavailableCode:      http.StatusServiceUnavailable,
+	usecases.EventsUnavailableCode:     http.StatusServiceUnavailable,
+	JobNotFoundCode:                    http.StatusNotFound,
+	JobRunningCode:                     http.StatusConflict,
+}
+
+// ProblemDetails is the error response body, following RFC 7807. Code holds
+// a stable error code clients may rely on
+type ProblemDetails struct {
+	Type   string             `json:"type"`
+	Title  string             `json:"title"`
+	Status int                `json:"status"`
+	Detail string             `json:"detail,omitempty"`
+	Code   usecases.ErrorCode `json:"code"`
+	Fields []FieldError       `json:"fields,omitempty"`
+	// cause is the error behind the problem, it's logged but never sent
+	cause error
+}
+
+// MakeProblemResponse returns an application/problem+json response
+func MakeProblemResponse(status int, code usecases.ErrorCode, detail string) *goutils.Response {
+	return makeProblem(ProblemDetails{
+		Status: status,
+		Code:   code,
+		Detail: detail,
+	})
+}
+
+// MakeErrorResponse returns the problem response matching the given error.
+// Domain errors get the status of their code and their message as detail,
+// any other error is reported as an internal error. The causes wrapped by the
+// error aren't sent, they are kept to be logged along with the response
+func MakeErrorResponse(err error) *goutils.Response {
+	problem := ProblemDetails{
+		Status: http.StatusInternalServerError,
+		Code:   InternalErrorCode,
+		Detail: "Internal error",
+	}
+	var domainError *usecases.DomainError
+	if errors.As(err, &domainError) {
+		if status, ok := problemStatus[domainError.Code]; ok {
+			problem.Status, problem.Code = status, domainError.Code
+			problem.Detail = domainError.Message
+		}
+	}
+	if err.Error() != problem.Detail {
+		problem.cause = err
+	}
+	return makeProblem(problem)
+}
+
+// ProblemCause returns the error behind a problem response made by
+// MakeErrorResponse, if it tells more than the problem detail
+func ProblemCause(response *goutils.Response) error {
+	body := response.Body
+	if headed, ok := body.(HeadedBody); ok {
+		body = headed.Body
+	}
+	if problem, ok := body.(ProblemDetails); ok {
+		return problem.cause
+	}
+	return nil
+}
+
+// makeProblem fills the problem defaults and wraps it along with its
+// content type
+func makeProblem(problem ProblemDetails) *goutils.Response {
+	problem.Type = "about:blank"
+	problem.Title = http.StatusText(problem.Status)
+	return &goutils.Response{
+		Code: problem.Status,
+		Body: HeadedBody{
+			Headers: map[string]string{"Content-Type": ProblemContentType},
+			Body:    problem,
+		},
+	}
+}
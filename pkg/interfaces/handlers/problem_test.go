@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Yapo/goutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/usecases"
+)
+
+func TestMakeProblemResponse(t *testing.T) {
+	r := MakeProblemResponse(http.StatusBadRequest, InvalidInputCode, "bad input")
+	expected := &goutils.Response{
+		Code: http.StatusBadRequest,
+		Body: HeadedBody{
+			Headers: map[string]string{"Content-Type": ProblemContentType},
+			Body: ProblemDetails{
+				Type:   "about:blank",
+				Title:  "Bad Request",
+				Status: http.StatusBadRequest,
+				Detail: "bad input",
+				Code:   InvalidInputCode,
+			},
+		},
+	}
+	assert.Equal(t, expected, r)
+}
+
+func TestMakeErrorResponse(t *testing.T) {
+	for err, expected := range map[error]int{
+		usecases.ErrAdNotFound:                                        http.StatusNotFound,
+		usecases.ErrProductNotFound:                                   http.StatusNotFound,
+		usecases.ErrProductNotActive:                                  http.StatusNotFound,
+		usecases.ErrProductExpired:                                    http.StatusNotFound,
+		fmt.Errorf("user 1: %w", usecases.ErrNotEnoughAds):            http.StatusNotFound,
+		usecases.ErrVersionMismatch:                                   http.StatusPreconditionFailed,
+		&usecases.DomainError{Code: usecases.SearchUnavailableCode}:   http.StatusServiceUnavailable,
+		&usecases.DomainError{Code: usecases.DatabaseUnavailableCode}: http.StatusServiceUnavailable,
+		&usecases.DomainError{Code: "UNKNOWN"}:                        http.StatusInternalServerError,
+		fmt.Errorf("err"):                                             http.StatusInternalServerError,
+	} {
+		r := MakeErrorResponse(err)
+		assert.Equal(t, expected, r.Code, err.Error())
+	}
+	r := MakeErrorResponse(fmt.Errorf("user 1: %w", usecases.ErrNotEnoughAds))
+	assertProblem(t, r, http.StatusNotFound, usecases.NotEnoughAdsCode, "Not enough ads")
+	assert.EqualError(t, ProblemCause(r), "user 1: Not enough ads")
+}
+
+func TestMakeErrorResponseHidesCauses(t *testing.T) {
+	err := &usecases.DomainError{Code: usecases.DatabaseUnavailableCode,
+		Message: "cannot get the product", Err: fmt.Errorf("pq: connection refused")}
+	r := MakeErrorResponse(err)
+	assertProblem(t, r, http.StatusServiceUnavailable, usecases.DatabaseUnavailableCode,
+		"cannot get the product")
+	assert.Equal(t, err, ProblemCause(r))
+
+	r = MakeErrorResponse(fmt.Errorf("redis: i/o timeout"))
+	assertProblem(t, r, http.StatusInternalServerError, InternalErrorCode, "Internal error")
+	assert.EqualError(t, ProblemCause(r), "redis: i/o timeout")
+
+	// Errors telling nothing more than their message have no cause
+	assert.NoError(t, ProblemCause(MakeErrorResponse(usecases.ErrProductNotFound)))
+	assert.NoError(t, ProblemCause(MakeProblemResponse(http.StatusBadRequest,
+		InvalidInputCode, "bad input")))
+}
+
+// assertProblem asserts that r is a problem response with the given status,
+// code and detail
+func assertProblem(t *testing.T, r *goutils.Response, status int, code usecases.ErrorCode,
+	detail string) {
+	t.Helper()
+	assert.Equal(t, status, r.Code)
+	problem := r.Body.(HeadedBody).Body.(ProblemDetails)
+	assert.Equal(t, status, problem.Status)
+	assert.Equal(t, code, problem.Code)
+	assert.Equal(t, detail, problem.Detail)
+}
+
+func TestJsonHandlerFuncProblem(t *testing.T) {
+	h := MockHandler{}
+	ih := MockInputHandler{}
+	mMockInputRequest := MockInputRequest{}
+	l := MockLogger{}
+	input := &DummyInput{}
+	response := MakeErrorResponse(usecases.ErrProductNotFound)
+	getter := mock.AnythingOfType("handlers.InputGetter")
+	h.On("Execute", getter).Return(response).Once()
+	h.On("Input", mock.AnythingOfType("*handlers.MockInputRequest")).Return(input).Once()
+
+	ih.On("NewInputRequest", mock.AnythingOfType("*http.Request")).Return(&mMockInputRequest)
+	ih.On("Input").Return(input, (*goutils.Response)(nil))
+	ih.On(
+		"SetInputRequest",
+		mock.AnythingOfType("*handlers.MockInputRequest"),
+		mock.AnythingOfType("*handlers.DummyInput"),
+	)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/someurl", strings.NewReader("{}"))
+
+	l.On("LogRequestStart", r)
+	l.On("LogRequestEnd", r, response)
+
+	mC := MockCors{}
+	mC.On("GetHeaders").Return(map[string]string{})
+
+	cache := &Cache{}
+	fn := MakeJSONHandlerFunc(&h, &l, &ih, &mC, cache)
+	fn(w, r)
+
+	assert.Equal(t, ProblemContentType, w.Result().Header.Get("Content-Type"))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, `{"type":"about:blank","title":"Not Found","status":404,`+
+		`"detail":"Product not found","code":"PRODUCT_NOT_FOUND"}`+"\n",
+		w.Body.String())
+	h.AssertExpectations(t)
+	ih.AssertExpectations(t)
+	l.AssertExpectations(t)
+	mC.AssertExpectations(t)
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Yapo/goutils"
+)
+
+const (
+	// DependencyOK is the status of a dependency that answered its probe
+	DependencyOK = "OK"
+	// DependencyFailing is the status of a dependency whose probe failed or
+	// timed out
+	DependencyFailing = "FAILING"
+	// ServiceDraining is the readiness status of a service shutting down
+	ServiceDraining = "DRAINING"
+)
+
+// HealthChecker probes a dependency, returning an error when it's unreachable
+type HealthChecker interface {
+	Check(ctx context.Context) error
+}
+
+// DependencyCheck names the probe of a dependency. A failing critical
+// dependency makes the service not ready, the others are only reported
+type DependencyCheck struct {
+	Name     string
+	Checker  HealthChecker
+	Critical bool
+}
+
+// ReadinessHandler implements the handler interface and responds to /readyz
+// probing every dependency. It answers 503 when a critical dependency fails,
+// so the pod stops receiving traffic until it recovers
+type ReadinessHandler struct {
+	Checks []DependencyCheck
+	// Timeout bounds each probe
+	Timeout time.Duration
+	// draining is set once the service starts shutting down
+	draining int32
+}
+
+// ReadinessLogger logger for Readiness Handler
+type ReadinessLogger interface{}
+
+type readinessHandlerInput struct{}
+
+// dependencyStatus is the outcome of the probe of a dependency
+type dependencyStatus struct {
+	Status    string  `json:"status"`
+	Critical  bool    `json:"critical"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// readinessRequestOutput is the readiness response
+type readinessRequestOutput struct {
+	Status       string                      `json:"status"`
+	Dependencies map[string]dependencyStatus `json:"dependencies"`
+}
+
+// Input returns a fresh, empty instance of readinessHandlerInput
+func (*ReadinessHandler) Input(ir InputRequest) HandlerInput {
+	return &readinessHandlerInput{}
+}
+
+// Drain makes the readiness fail from now on, so the service stops
+// receiving new traffic while it shuts down
+func (h *ReadinessHandler) Drain() {
+	atomic.StoreInt32(&h.draining, 1)
+}
+
+// Execute probes the dependencies and returns their status
+func (h *ReadinessHandler) Execute(ig InputGetter) *goutils.Response {
+	if atomic.LoadInt32(&h.draining) == 1 {
+		return &goutils.Response{
+			Code: http.StatusServiceUnavailable,
+			Body: readinessRequestOutput{Status: ServiceDraining},
+		}
+	}
+	output := h.checkAll()
+	code := http.StatusOK
+	if output.Status != DependencyOK {
+		code = http.StatusServiceUnavailable
+	}
+	return &goutils.Response{
+		Code: code,
+		Body: output,
+	}
+}
+
+// checkAll probes the dependencies concurrently
+func (h *ReadinessHandler) checkAll() readinessRequestOutput {
+	output := readinessRequestOutput{
+		Status:       DependencyOK,
+		Dependencies: make(map[string]dependencyStatus, len(h.Checks)),
+	}
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range h.Checks {
+		wg.Add(1)
+		go func(check DependencyCheck) {
+			defer wg.Done()
+			status := h.probe(check)
+			mutex.Lock()
+			defer mutex.Unlock()
+			output.Dependencies[check.Name] = status
+			if check.Critical && status.Status != DependencyOK {
+				output.Status = DependencyFailing
+			}
+		}(check)
+	}
+	wg.Wait()
+	return output
+}
+
+// probe runs a check, giving up once the timeout is reached even if the
+// checker ignores the context
+func (h *ReadinessHandler) probe(check DependencyCheck) dependencyStatus {
+	ctx, cancel := context.WithTimeout(context.Background(), h.Timeout)
+	defer cancel()
+	start := time.Now()
+	result := make(chan error, 1)
+	go func() {
+		result <- check.Checker.Check(ctx)
+	}()
+	var err error
+	select {
+	case err = <-result:
+	case <-ctx.Done():
+		err = fmt.Errorf("timed out after %v", h.Timeout)
+	}
+	status := dependencyStatus{
+		Status:    DependencyOK,
+		Critical:  check.Critical,
+		LatencyMS: float64(time.Since(start)) / float64(time.Millisecond),
+	}
+	if err != nil {
+		status.Status = DependencyFailing
+		status.Error = err.Error()
+	}
+	return status
+}
+
+// LivenessHandler implements the handler interface and responds to /livez.
+// It answers 200 while the process serves requests without probing the
+// dependencies: it's called often, and restarting the pod won't fix them.
+// Their status is reported by the readiness probe
+type LivenessHandler struct{}
+
+// LivenessLogger logger for Liveness Handler
+type LivenessLogger interface{}
+
+type livenessHandlerInput struct{}
+
+// livenessRequestOutput is the liveness response
+type livenessRequestOutput struct {
+	Status string `json:"status"`
+}
+
+// Input returns a fresh, empty instance of livenessHandlerInput
+func (*LivenessHandler) Input(ir InputRequest) HandlerInput {
+	return &livenessHandlerInput{}
+}
+
+// Execute returns the liveness status
+func (h *LivenessHandler) Execute(ig InputGetter) *goutils.Response {
+	return &goutils.Response{
+		Code: http.StatusOK,
+		Body: livenessRequestOutput{Status: DependencyOK},
+	}
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockHealthChecker struct {
+	mock.Mock
+}
+
+func (m *mockHealthChecker) Check(ctx context.Context) error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+// blockingChecker never answers until its context is done
+type blockingChecker struct{}
+
+func (blockingChecker) Check(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestReadinessHandlerInput(t *testing.T) {
+	var h ReadinessHandler
+	input := h.Input(&MockInputRequest{})
+	var expected *readinessHandlerInput
+	assert.IsType(t, expected, input)
+}
+
+func TestReadinessHandlerOK(t *testing.T) {
+	mPostgres := &mockHealthChecker{}
+	mKafka := &mockHealthChecker{}
+	mPostgres.On("Check").Return(nil)
+	mKafka.On("Check").Return(fmt.Errorf("err"))
+	h := ReadinessHandler{
+		Checks: []DependencyCheck{
+			{Name: "postgres", Checker: mPostgres, Critical: true},
+			{Name: "kafka", Checker: mKafka},
+		},
+		Timeout: time.Second,
+	}
+	var input HandlerInput
+	r := h.Execute(MakeMockInputGetter(&input, nil))
+	assert.Equal(t, http.StatusOK, r.Code)
+	output := r.Body.(readinessRequestOutput)
+	assert.Equal(t, DependencyOK, output.Status)
+	assert.Equal(t, DependencyOK, output.Dependencies["postgres"].Status)
+	assert.Equal(t, DependencyFailing, output.Dependencies["kafka"].Status)
+	assert.Equal(t, "err", output.Dependencies["kafka"].Error)
+	mPostgres.AssertExpectations(t)
+	mKafka.AssertExpectations(t)
+}
+
+func TestReadinessHandlerCriticalTimeout(t *testing.T) {
+	h := ReadinessHandler{
+		Checks: []DependencyCheck{
+			{Name: "elasticsearch", Checker: blockingChecker{}, Critical: true},
+		},
+		Timeout: 10 * time.Millisecond,
+	}
+	var input HandlerInput
+	r := h.Execute(MakeMockInputGetter(&input, nil))
+	assert.Equal(t, http.StatusServiceUnavailable, r.Code)
+	output := r.Body.(readinessRequestOutput)
+	assert.Equal(t, DependencyFailing, output.Status)
+	assert.Equal(t, DependencyFailing, output.Dependencies["elasticsearch"].Status)
+	assert.True(t, output.Dependencies["elasticsearch"].LatencyMS >= 10)
+}
+
+func TestLivenessHandler(t *testing.T) {
+	h := LivenessHandler{}
+	var input HandlerInput
+	r := h.Execute(MakeMockInputGetter(&input, nil))
+	assert.Equal(t, http.StatusOK, r.Code)
+	assert.Equal(t, livenessRequestOutput{Status: DependencyOK}, r.Body)
+}
+
+func TestReadinessHandlerDraining(t *testing.T) {
+	mPostgres := &mockHealthChecker{}
+	h := ReadinessHandler{
+		Checks:  []DependencyCheck{{Name: "postgres", Checker: mPostgres, Critical: true}},
+		Timeout: time.Second,
+	}
+	h.Drain()
+	var input HandlerInput
+	r := h.Execute(MakeMockInputGetter(&input, nil))
+	assert.Equal(t, http.StatusServiceUnavailable, r.Code)
+	assert.Equal(t, ServiceDraining, r.Body.(readinessRequestOutput).Status)
+	mPostgres.AssertExpectations(t)
+}
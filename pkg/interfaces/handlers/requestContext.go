@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"context"
+)
+
+// requestIDKey is the request context key holding the request id
+type requestIDKey struct{}
+
+// ContextWithRequestID returns a copy of ctx holding the given request id
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestIDFromContext returns the request id held by ctx, if any
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	requestID, ok := ctx.Value(requestIDKey{}).(string)
+	return requestID, ok && requestID != ""
+}
+
+// ContextInput is implemented by the handler inputs that need the context
+// of the request, e.g. to correlate the logs of the usecases they run
+type ContextInput interface {
+	SetContext(ctx context.Context)
+}
+
+// RequestContext can be embedded in a handler input to receive the context
+// of the request
+type RequestContext struct {
+	ctx context.Context
+}
+
+// SetContext sets the request context
+func (r *RequestContext) SetContext(ctx context.Context) {
+	r.ctx = ctx
+}
+
+// Context returns the request context, or an empty one when it's not set
+func (r *RequestContext) Context() context.Context {
+	if r.ctx == nil {
+		return context.Background()
+	}
+	return r.ctx
+}
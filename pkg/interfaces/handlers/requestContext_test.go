@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestIDContext(t *testing.T) {
+	_, ok := RequestIDFromContext(context.Background())
+	assert.False(t, ok)
+	_, ok = RequestIDFromContext(ContextWithRequestID(context.Background(), ""))
+	assert.False(t, ok)
+	got, ok := RequestIDFromContext(ContextWithRequestID(context.Background(), "abc"))
+	assert.True(t, ok)
+	assert.Equal(t, "abc", got)
+}
+
+func TestRequestContext(t *testing.T) {
+	input := RequestContext{}
+	assert.Equal(t, context.Background(), input.Context())
+	ctx := ContextWithRequestID(context.Background(), "abc")
+	input.SetContext(ctx)
+	assert.Equal(t, ctx, input.Context())
+}
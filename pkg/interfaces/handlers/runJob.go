@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/Yapo/goutils"
+
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/usecases"
+)
+
+const (
+	// JobNotFoundCode is used when the triggered job does not exist
+	JobNotFoundCode usecases.ErrorCode = "JOB_NOT_FOUND"
+	// JobRunningCode is used when the triggered job is already running
+	JobRunningCode usecases.ErrorCode = "JOB_RUNNING"
+)
+
+var (
+	// ErrJobNotFound is returned by the JobTrigger for unknown jobs
+	ErrJobNotFound error = &usecases.DomainError{Code: JobNotFoundCode,
+		Message: "Job not found"}
+	// ErrJobRunning is returned by the JobTrigger when a run of the job has
+	// not finished yet
+	ErrJobRunning error = &usecases.DomainError{Code: JobRunningCode,
+		Message: "Job already running"}
+)
+
+// JobTrigger starts the background jobs on demand
+type JobTrigger interface {
+	// Trigger starts a run of the named job without waiting for it
+	Trigger(name string) error
+}
+
+// RunJobHandler implements the handler interface and responds to
+// /jobs/{name} starting a run of the job
+type RunJobHandler struct {
+	Jobs JobTrigger
+}
+
+// runJobHandlerInput is the handler expected input
+type runJobHandlerInput struct {
+	RequestContext
+	Name string `path:"name"`
+}
+
+// runJobOutput is the response of a started job
+type runJobOutput struct {
+	Job string `json:"job"`
+}
+
+// Input returns a fresh, empty instance of runJobHandlerInput
+func (*RunJobHandler) Input(ir InputRequest) HandlerInput {
+	input := runJobHandlerInput{}
+	ir.Set(&input).FromPath()
+	return &input
+}
+
+// Execute starts the job, its result is only logged as it runs in background
+func (h *RunJobHandler) Execute(ig InputGetter) *goutils.Response {
+	input, response := ig()
+	if response != nil {
+		return response
+	}
+	in := input.(*runJobHandlerInput)
+	if err := h.Jobs.Trigger(in.Name); err != nil {
+		return MakeErrorResponse(err)
+	}
+	return &goutils.Response{
+		Code: http.StatusAccepted,
+		Body: runJobOutput{Job: in.Name},
+	}
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/Yapo/goutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockJobTrigger struct {
+	mock.Mock
+}
+
+func (m *mockJobTrigger) Trigger(name string) error {
+	args := m.Called(name)
+	return args.Error(0)
+}
+
+func TestRunJobHandlerInput(t *testing.T) {
+	var h RunJobHandler
+	mMockInputRequest := &MockInputRequest{}
+	mTargetRequest := &MockTargetRequest{}
+	mMockInputRequest.On("Set", mock.Anything).Return(mTargetRequest)
+	mTargetRequest.On("FromPath").Return(mTargetRequest)
+	input := h.Input(mMockInputRequest)
+	var expected *runJobHandlerInput
+	assert.IsType(t, expected, input)
+	mMockInputRequest.AssertExpectations(t)
+	mTargetRequest.AssertExpectations(t)
+}
+
+func TestRunJobHandlerOK(t *testing.T) {
+	mJobs := &mockJobTrigger{}
+	mJobs.On("Trigger", "expire-products").Return(nil)
+	h := RunJobHandler{Jobs: mJobs}
+	getter := MakeMockInputGetter(&runJobHandlerInput{Name: "expire-products"}, nil)
+	r := h.Execute(getter)
+	expected := &goutils.Response{
+		Code: http.StatusAccepted,
+		Body: runJobOutput{Job: "expire-products"},
+	}
+	assert.Equal(t, expected, r)
+	mJobs.AssertExpectations(t)
+}
+
+func TestRunJobHandlerErrors(t *testing.T) {
+	cases := map[error]int{
+		ErrJobNotFound: http.StatusNotFound,
+		ErrJobRunning:  http.StatusConflict,
+	}
+	for err, status := range cases {
+		mJobs := &mockJobTrigger{}
+		mJobs.On("Trigger", "job").Return(err)
+		h := RunJobHandler{Jobs: mJobs}
+		getter := MakeMockInputGetter(&runJobHandlerInput{Name: "job"}, nil)
+		r := h.Execute(getter)
+		assert.Equal(t, status, r.Code)
+		mJobs.AssertExpectations(t)
+	}
+}
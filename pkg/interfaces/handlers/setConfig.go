@@ -24,30 +24,28 @@ type SetConfigLogger interface{}
 
 // setConfigHandlerInput is the handler expected input
 type setConfigHandlerInput struct {
+	RequestContext
 	UserProductID      int       `path:"ID"`
-	Categories         string    `json:"categories"`
+	IfMatch            string    `headers:"If-Match"`
+	Categories         string    `json:"categories" validate:"csvint=1000..9999"`
 	Exclude            string    `json:"exclude"`
 	Keywords           string    `json:"keywords"`
 	Comment            string    `json:"comment"`
-	Limit              int       `json:"limit"`
-	PriceRange         int       `json:"price_range"`
+	Limit              int       `json:"limit" validate:"min=0,max=100"`
+	PriceRange         int       `json:"price_range" validate:"min=0"`
 	ExpiredAt          time.Time `json:"expiration"`
 	FillGapsWithRandom bool      `json:"fill_random"`
 }
 
-// getUserRequestOutput is the handler output
-type setConfigRequestOutput struct {
-	response string
-}
-
 // Input returns a fresh, empty instance of setConfigHandlerInput
 func (*SetConfigHandler) Input(ir InputRequest) HandlerInput {
 	input := setConfigHandlerInput{}
-	ir.Set(&input).FromJSONBody().FromPath()
+	ir.Set(&input).FromJSONBody().FromPath().FromHeaders()
 	return &input
 }
 
-// Execute sets configuration for userProduct
+// Execute sets configuration for userProduct. The If-Match header must hold
+// the current userProduct ETag. The updated product is returned
 func (h *SetConfigHandler) Execute(ig InputGetter) *goutils.Response {
 	input, response := ig()
 	if response != nil {
@@ -55,13 +53,12 @@ func (h *SetConfigHandler) Execute(ig InputGetter) *goutils.Response {
 	}
 	in := input.(*setConfigHandlerInput)
 	if in.UserProductID < 1 {
-		return &goutils.Response{
-			Code: http.StatusBadRequest,
-			Body: goutils.GenericError{
-				ErrorMessage: fmt.Sprintf(`error with ProductID: %+v`,
-					in.UserProductID),
-			},
-		}
+		return MakeProblemResponse(http.StatusBadRequest, InvalidInputCode,
+			fmt.Sprintf(`error with ProductID: %+v`, in.UserProductID))
+	}
+	version, response := getIfMatchVersion(in.IfMatch)
+	if response != nil {
+		return response
 	}
 	config := domain.ProductParams{
 		Categories:         h.getCategories(in.Categories),
@@ -72,22 +69,12 @@ func (h *SetConfigHandler) Execute(ig InputGetter) *goutils.Response {
 		PriceRange:         in.PriceRange,
 		FillGapsWithRandom: in.FillGapsWithRandom,
 	}
-	if err := h.Interactor.SetConfig(in.UserProductID,
-		config, in.ExpiredAt); err != nil {
-		return &goutils.Response{
-			Code: http.StatusBadRequest,
-			Body: goutils.GenericError{
-				ErrorMessage: fmt.Sprintf(`%+v`, err),
-			},
-		}
-	}
-	body := setConfigRequestOutput{
-		response: "OK",
-	}
-	return &goutils.Response{
-		Code: http.StatusOK,
-		Body: body,
+	product, err := h.Interactor.SetConfig(in.Context(), in.UserProductID, version,
+		config, in.ExpiredAt)
+	if err != nil {
+		return MakeErrorResponse(err)
 	}
+	return makeProductResponse(http.StatusOK, product)
 }
 
 func (h *SetConfigHandler) getCategories(raw string) (categories []int) {
@@ -96,7 +83,7 @@ func (h *SetConfigHandler) getCategories(raw string) (categories []int) {
 	}
 	categoriesArr := strings.Split(raw, ",")
 	for _, c := range categoriesArr {
-		cat, _ := strconv.Atoi(c)
+		cat, _ := strconv.Atoi(strings.TrimSpace(c))
 		categories = append(categories, cat)
 	}
 	return categories
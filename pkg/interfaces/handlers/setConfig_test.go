@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"testing"
@@ -10,6 +11,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/domain"
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/usecases"
 )
 
 func TestSetConfigHandlerInput(t *testing.T) {
@@ -20,6 +22,7 @@ func TestSetConfigHandlerInput(t *testing.T) {
 		mock.AnythingOfType("*handlers.setConfigHandlerInput")).Return(mTargetRequest)
 	mTargetRequest.On("FromJSONBody").Return(mTargetRequest)
 	mTargetRequest.On("FromPath").Return(mTargetRequest)
+	mTargetRequest.On("FromHeaders").Return(mTargetRequest)
 	input := h.Input(mMockInputRequest)
 	var expected *setConfigHandlerInput
 	assert.IsType(t, expected, input)
@@ -31,10 +34,10 @@ type mockSetConfigInteractor struct {
 	mock.Mock
 }
 
-func (m *mockSetConfigInteractor) SetConfig(userProductID int,
-	config domain.ProductParams, expiredAt time.Time) error {
-	args := m.Called(userProductID, config, expiredAt)
-	return args.Error(0)
+func (m *mockSetConfigInteractor) SetConfig(ctx context.Context, userProductID int, version int,
+	config domain.ProductParams, expiredAt time.Time) (domain.Product, error) {
+	args := m.Called(ctx, userProductID, version, config, expiredAt)
+	return args.Get(0).(domain.Product), args.Error(1)
 }
 
 func TestSetConfigHandlerErrorBadInput(t *testing.T) {
@@ -55,17 +58,20 @@ func TestSetConfigHandlerErrorBadInput(t *testing.T) {
 }
 
 func TestSetConfigHandlerOK(t *testing.T) {
+	product := domain.Product{ID: 123, UserID: 1, Version: 3}
 	mInteractor := &mockSetConfigInteractor{}
-	mInteractor.On("SetConfig",
+	mInteractor.On("SetConfig", mock.Anything,
 		mock.AnythingOfType("int"),
+		2,
 		mock.AnythingOfType("domain.ProductParams"),
 		mock.AnythingOfType("time.Time"),
-	).Return(nil)
+	).Return(product, nil)
 	h := SetConfigHandler{
 		Interactor: mInteractor,
 	}
 	input := setConfigHandlerInput{
 		UserProductID: 123,
+		IfMatch:       `"2"`,
 		Categories:    "2000,1000,3000",
 		ExpiredAt:     time.Now().Add(time.Hour * 24 * 365),
 		Exclude:       "12345",
@@ -74,8 +80,9 @@ func TestSetConfigHandlerOK(t *testing.T) {
 	r := h.Execute(getter)
 	expected := &goutils.Response{
 		Code: http.StatusOK,
-		Body: setConfigRequestOutput{
-			response: "OK",
+		Body: HeadedBody{
+			Headers: map[string]string{"ETag": `"3"`},
+			Body:    makeProductOutput(product),
 		},
 	}
 	assert.Equal(t, expected, r)
@@ -85,27 +92,23 @@ func TestSetConfigHandlerOK(t *testing.T) {
 func TestSetConfigHandlerError(t *testing.T) {
 	err := fmt.Errorf("err")
 	mInteractor := &mockSetConfigInteractor{}
-	mInteractor.On("SetConfig",
+	mInteractor.On("SetConfig", mock.Anything,
 		mock.AnythingOfType("int"),
+		2,
 		mock.AnythingOfType("domain.ProductParams"),
 		mock.AnythingOfType("time.Time"),
-	).Return(err)
+	).Return(domain.Product{}, err)
 	h := SetConfigHandler{
 		Interactor: mInteractor,
 	}
 	input := setConfigHandlerInput{
 		UserProductID: 123,
+		IfMatch:       `"2"`,
 		ExpiredAt:     time.Now().Add(time.Hour * 24 * 365),
 	}
 	getter := MakeMockInputGetter(&input, nil)
 	r := h.Execute(getter)
-	expected := &goutils.Response{
-		Code: http.StatusBadRequest,
-		Body: goutils.GenericError{
-			ErrorMessage: fmt.Sprintf(`%+v`, err),
-		},
-	}
-	assert.Equal(t, expected, r)
+	assertProblem(t, r, http.StatusInternalServerError, InternalErrorCode, "Internal error")
 	mInteractor.AssertExpectations(t)
 }
 
@@ -119,13 +122,65 @@ func TestSetConfigHandlerBadUserProductID(t *testing.T) {
 	}
 	getter := MakeMockInputGetter(&input, nil)
 	r := h.Execute(getter)
-	expected := &goutils.Response{
-		Code: http.StatusBadRequest,
-		Body: goutils.GenericError{
-			ErrorMessage: fmt.Sprintf(`error with ProductID: %+v`,
-				input.UserProductID),
-		},
+	expected := MakeProblemResponse(http.StatusBadRequest,
+		InvalidInputCode, fmt.Sprintf(`error with ProductID: %+v`,
+			input.UserProductID))
+	assert.Equal(t, expected, r)
+	mInteractor.AssertExpectations(t)
+}
+
+func TestSetConfigHandlerVersionMismatch(t *testing.T) {
+	mInteractor := &mockSetConfigInteractor{}
+	mInteractor.On("SetConfig", mock.Anything,
+		123,
+		1,
+		mock.AnythingOfType("domain.ProductParams"),
+		mock.AnythingOfType("time.Time"),
+	).Return(domain.Product{}, usecases.ErrVersionMismatch)
+	h := SetConfigHandler{
+		Interactor: mInteractor,
+	}
+	input := setConfigHandlerInput{
+		UserProductID: 123,
+		IfMatch:       `W/"1"`,
+	}
+	getter := MakeMockInputGetter(&input, nil)
+	r := h.Execute(getter)
+	expected := MakeProblemResponse(http.StatusPreconditionFailed,
+		usecases.VersionMismatchCode, usecases.ErrVersionMismatch.Error())
+	assert.Equal(t, expected, r)
+	mInteractor.AssertExpectations(t)
+}
+
+func TestSetConfigHandlerMissingIfMatch(t *testing.T) {
+	mInteractor := &mockSetConfigInteractor{}
+	h := SetConfigHandler{
+		Interactor: mInteractor,
+	}
+	input := setConfigHandlerInput{
+		UserProductID: 123,
 	}
+	getter := MakeMockInputGetter(&input, nil)
+	r := h.Execute(getter)
+	expected := MakeProblemResponse(http.StatusPreconditionRequired,
+		PreconditionRequiredCode, "If-Match header is required")
+	assert.Equal(t, expected, r)
+	mInteractor.AssertExpectations(t)
+}
+
+func TestSetConfigHandlerInvalidIfMatch(t *testing.T) {
+	mInteractor := &mockSetConfigInteractor{}
+	h := SetConfigHandler{
+		Interactor: mInteractor,
+	}
+	input := setConfigHandlerInput{
+		UserProductID: 123,
+		IfMatch:       "*",
+	}
+	getter := MakeMockInputGetter(&input, nil)
+	r := h.Execute(getter)
+	expected := MakeProblemResponse(http.StatusPreconditionFailed,
+		usecases.VersionMismatchCode, "If-Match * does not match")
 	assert.Equal(t, expected, r)
 	mInteractor.AssertExpectations(t)
 }
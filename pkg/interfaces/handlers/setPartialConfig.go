@@ -2,10 +2,16 @@ package handlers
 
 import (
 	"fmt"
+	"math"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/Yapo/goutils"
 
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/domain"
 	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/usecases"
 )
 
@@ -20,24 +26,23 @@ type SetPartialConfigLogger interface{}
 
 // setPartialConfigHandlerInput is the handler expected input
 type setPartialConfigHandlerInput struct {
+	RequestContext
 	UserProductID int                    `path:"ID"`
+	IfMatch       string                 `headers:"If-Match"`
 	Body          map[string]interface{} `body:"body"`
 }
 
-// getUserRequestOutput is the handler output
-type setPartialConfigRequestOutput struct {
-	response string
-}
-
 // Input returns a fresh, empty instance of setPartialConfigHandlerInput
 func (*SetPartialConfigHandler) Input(ir InputRequest) HandlerInput {
 	input := setPartialConfigHandlerInput{}
-	ir.Set(&input).FromPath()
+	ir.Set(&input).FromPath().FromHeaders()
 	ir.Set(&input.Body).FromJSONBody()
 	return &input
 }
 
-// Execute sets partial configuration for supported params
+// Execute applies the request body as a JSON merge patch (RFC 7396) over
+// the user product. The If-Match header must hold the current user product ETag.
+// The updated product is returned
 func (h *SetPartialConfigHandler) Execute(ig InputGetter) *goutils.Response {
 	input, response := ig()
 	if response != nil {
@@ -45,27 +50,163 @@ func (h *SetPartialConfigHandler) Execute(ig InputGetter) *goutils.Response {
 	}
 	in := input.(*setPartialConfigHandlerInput)
 	if in.UserProductID < 1 {
-		return &goutils.Response{
-			Code: http.StatusBadRequest,
-			Body: goutils.GenericError{
-				ErrorMessage: fmt.Sprintf(`Wrong ProductID: %d`, in.UserProductID),
-			},
+		return MakeProblemResponse(http.StatusBadRequest, InvalidInputCode,
+			fmt.Sprintf(`Wrong ProductID: %d`, in.UserProductID))
+	}
+	version, response := getIfMatchVersion(in.IfMatch)
+	if response != nil {
+		return response
+	}
+	patch, fieldErrors := h.makePatch(in.Body)
+	if len(fieldErrors) > 0 {
+		return MakeValidationErrorResponse(fieldErrors)
+	}
+	product, err := h.Interactor.SetPartialConfig(in.Context(),
+		in.UserProductID, version, patch)
+	if err != nil {
+		return MakeErrorResponse(err)
+	}
+	return makeProductResponse(http.StatusOK, product)
+}
+
+// makePatch translates the merge patch document to a ProductPatch using the
+// same member names, formats and rules of the assigns representation.
+// A null member removes the current value, so it resets the param to its
+// zero value. Returns the errors sorted by member name
+func (h *SetPartialConfigHandler) makePatch( //nolint: funlen
+	body map[string]interface{}) (patch usecases.ProductPatch, fieldErrors []FieldError) {
+	for name, value := range body {
+		var rule string
+		switch name {
+		case "status":
+			var status domain.ProductStatus
+			status, rule = h.getStatus(value)
+			patch.Status = &status
+		case "expiration":
+			var expiredAt time.Time
+			expiredAt, rule = h.getExpiration(value)
+			patch.ExpiredAt = &expiredAt
+		case "categories":
+			var categories []int
+			categories, rule = h.getCategories(value)
+			patch.Categories = &categories
+		case "exclude":
+			var exclude []string
+			exclude, rule = h.getCommaSeparedArr(value)
+			patch.Exclude = &exclude
+		case "keywords":
+			var keywords []string
+			keywords, rule = h.getCommaSeparedArr(value)
+			patch.Keywords = &keywords
+		case "limit":
+			var limit int
+			limit, rule = h.getInt(value, 0, 100)
+			patch.Limit = &limit
+		case "price_range":
+			var priceRange int
+			priceRange, rule = h.getInt(value, 0, math.MaxInt32)
+			patch.PriceRange = &priceRange
+		case "fill_random":
+			var fillGapsWithRandom bool
+			fillGapsWithRandom, rule = h.getBool(value)
+			patch.FillGapsWithRandom = &fillGapsWithRandom
+		case "comment":
+			var comment string
+			comment, rule = h.getString(value)
+			patch.Comment = &comment
+		default:
+			rule = "unsupported"
+		}
+		if rule != "" {
+			fieldErrors = append(fieldErrors, FieldError{Field: name, Value: value, Rule: rule})
 		}
 	}
-	err := h.Interactor.SetPartialConfig(in.UserProductID, in.Body)
+	sort.Slice(fieldErrors, func(i, j int) bool {
+		return fieldErrors[i].Field < fieldErrors[j].Field
+	})
+	return patch, fieldErrors
+}
+
+// getStatus parses a product status. Status can not be removed
+func (h *SetPartialConfigHandler) getStatus(raw interface{}) (domain.ProductStatus, string) {
+	value, _ := raw.(string)
+	switch status := domain.ProductStatus(value); status {
+	case domain.ActiveProduct, domain.InactiveProduct, domain.ExpiredProduct:
+		return status, ""
+	default:
+		return "", "oneof=ACTIVE|INACTIVE|EXPIRED"
+	}
+}
+
+// getExpiration parses a RFC3339 expiration date. Expiration can not be removed
+func (h *SetPartialConfigHandler) getExpiration(raw interface{}) (time.Time, string) {
+	value, _ := raw.(string)
+	expiredAt, err := time.Parse(time.RFC3339, value)
 	if err != nil {
-		return &goutils.Response{
-			Code: http.StatusBadRequest,
-			Body: goutils.GenericError{
-				ErrorMessage: fmt.Sprintf(`%+v`, err),
-			},
+		return time.Time{}, "datetime"
+	}
+	return expiredAt, ""
+}
+
+func (h *SetPartialConfigHandler) getCategories(raw interface{}) ([]int, string) {
+	values, rule := h.getCommaSeparedArr(raw)
+	if rule != "" {
+		return nil, rule
+	}
+	categories := []int{}
+	for _, v := range values {
+		cat, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || cat < 1000 || cat > 9999 {
+			return nil, "csvint=1000..9999"
 		}
+		categories = append(categories, cat)
+	}
+	return categories, ""
+}
+
+func (h *SetPartialConfigHandler) getCommaSeparedArr(raw interface{}) ([]string, string) {
+	value, rule := h.getString(raw)
+	if rule != "" || value == "" {
+		return []string{}, rule
+	}
+	return strings.Split(value, ","), ""
+}
+
+func (h *SetPartialConfigHandler) getString(raw interface{}) (string, string) {
+	if raw == nil {
+		return "", ""
 	}
-	body := setPartialConfigRequestOutput{
-		response: "OK",
+	value, ok := raw.(string)
+	if !ok {
+		return "", "string"
+	}
+	return value, ""
+}
+
+func (h *SetPartialConfigHandler) getInt(raw interface{}, min, max int) (int, string) {
+	if raw == nil {
+		return 0, ""
+	}
+	value, ok := raw.(float64)
+	if !ok || value != math.Trunc(value) {
+		return 0, "int"
+	}
+	if value < float64(min) {
+		return 0, fmt.Sprintf("min=%d", min)
+	}
+	if value > float64(max) {
+		return 0, fmt.Sprintf("max=%d", max)
+	}
+	return int(value), ""
+}
+
+func (h *SetPartialConfigHandler) getBool(raw interface{}) (bool, string) {
+	if raw == nil {
+		return false, ""
 	}
-	return &goutils.Response{
-		Code: http.StatusOK,
-		Body: body,
+	value, ok := raw.(bool)
+	if !ok {
+		return false, "bool"
 	}
+	return value, ""
 }
@@ -1,13 +1,18 @@
 package handlers
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/Yapo/goutils"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/domain"
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/usecases"
 )
 
 func TestSetPartialConfigHandlerInput(t *testing.T) {
@@ -18,6 +23,7 @@ func TestSetPartialConfigHandlerInput(t *testing.T) {
 		mock.Anything).Return(mTargetRequest)
 	mTargetRequest.On("FromJSONBody").Return(mTargetRequest)
 	mTargetRequest.On("FromPath").Return(mTargetRequest)
+	mTargetRequest.On("FromHeaders").Return(mTargetRequest)
 	input := h.Input(mMockInputRequest)
 	var expected *setPartialConfigHandlerInput
 	assert.IsType(t, expected, input)
@@ -29,10 +35,10 @@ type mockSetPartialConfigInteractor struct {
 	mock.Mock
 }
 
-func (m *mockSetPartialConfigInteractor) SetPartialConfig(userProductID int,
-	configMap map[string]interface{}) error {
-	args := m.Called(userProductID, configMap)
-	return args.Error(0)
+func (m *mockSetPartialConfigInteractor) SetPartialConfig(ctx context.Context, userProductID int,
+	version int, patch usecases.ProductPatch) (domain.Product, error) {
+	args := m.Called(ctx, userProductID, version, patch)
+	return args.Get(0).(domain.Product), args.Error(1)
 }
 
 func TestSetPartialConfigHandlerErrorBadInput(t *testing.T) {
@@ -54,23 +60,26 @@ func TestSetPartialConfigHandlerErrorBadInput(t *testing.T) {
 
 func TestSetPartialConfigHandlerOK(t *testing.T) {
 	mInteractor := &mockSetPartialConfigInteractor{}
-	mInteractor.On("SetPartialConfig",
+	mInteractor.On("SetPartialConfig", mock.Anything,
 		mock.AnythingOfType("int"),
-		mock.AnythingOfType("map[string]interface {}"),
-	).Return(nil)
+		2,
+		mock.AnythingOfType("usecases.ProductPatch"),
+	).Return(domain.Product{ID: 123, Version: 3}, nil)
 	h := SetPartialConfigHandler{
 		Interactor: mInteractor,
 	}
 	input := setPartialConfigHandlerInput{
 		UserProductID: 123,
+		IfMatch:       `"2"`,
 		Body:          map[string]interface{}{"status": "ACTIVE"},
 	}
 	getter := MakeMockInputGetter(&input, nil)
 	r := h.Execute(getter)
 	expected := &goutils.Response{
 		Code: http.StatusOK,
-		Body: setPartialConfigRequestOutput{
-			response: "OK",
+		Body: HeadedBody{
+			Headers: map[string]string{"ETag": `"3"`},
+			Body:    makeProductOutput(domain.Product{ID: 123, Version: 3}),
 		},
 	}
 	assert.Equal(t, expected, r)
@@ -80,46 +89,182 @@ func TestSetPartialConfigHandlerOK(t *testing.T) {
 func TestSetPartialConfigHandlerError(t *testing.T) {
 	err := fmt.Errorf("err")
 	mInteractor := &mockSetPartialConfigInteractor{}
-	mInteractor.On("SetPartialConfig",
+	mInteractor.On("SetPartialConfig", mock.Anything,
 		mock.AnythingOfType("int"),
-		mock.AnythingOfType("map[string]interface {}"),
-	).Return(err)
+		2,
+		mock.AnythingOfType("usecases.ProductPatch"),
+	).Return(domain.Product{}, err)
 	h := SetPartialConfigHandler{
 		Interactor: mInteractor,
 	}
 	input := setPartialConfigHandlerInput{
 		UserProductID: 123,
+		IfMatch:       `"2"`,
 		Body:          map[string]interface{}{"status": "ACTIVE"},
 	}
 	getter := MakeMockInputGetter(&input, nil)
 	r := h.Execute(getter)
+	assertProblem(t, r, http.StatusInternalServerError, InternalErrorCode, "Internal error")
+	mInteractor.AssertExpectations(t)
+}
+
+func TestSetPartialConfigHandlerBadUserProductID(t *testing.T) {
+	mInteractor := &mockSetPartialConfigInteractor{}
+	h := SetPartialConfigHandler{
+		Interactor: mInteractor,
+	}
+	input := setPartialConfigHandlerInput{
+		UserProductID: 0,
+	}
+	getter := MakeMockInputGetter(&input, nil)
+	r := h.Execute(getter)
+	expected := MakeProblemResponse(http.StatusBadRequest,
+		InvalidInputCode, fmt.Sprintf(`Wrong ProductID: %d`,
+			input.UserProductID))
+	assert.Equal(t, expected, r)
+	mInteractor.AssertExpectations(t)
+}
+
+func TestSetPartialConfigHandlerFullPatch(t *testing.T) {
+	expiredAt, _ := time.Parse(time.RFC3339, "2020-12-31T00:00:00Z")
+	status := domain.InactiveProduct
+	categories := []int{2020, 1000}
+	exclude := []string{}
+	keywords := []string{"a", "b"}
+	limit := 5
+	priceRange := 0
+	fillRandom := true
+	comment := ""
+	mInteractor := &mockSetPartialConfigInteractor{}
+	mInteractor.On("SetPartialConfig", mock.Anything, 123, 2, usecases.ProductPatch{
+		Status:             &status,
+		ExpiredAt:          &expiredAt,
+		Categories:         &categories,
+		Exclude:            &exclude,
+		Keywords:           &keywords,
+		Limit:              &limit,
+		PriceRange:         &priceRange,
+		FillGapsWithRandom: &fillRandom,
+		Comment:            &comment,
+	}).Return(domain.Product{ID: 123, Version: 3}, nil)
+	h := SetPartialConfigHandler{
+		Interactor: mInteractor,
+	}
+	input := setPartialConfigHandlerInput{
+		UserProductID: 123,
+		IfMatch:       `"2"`,
+		Body: map[string]interface{}{
+			"status":      "INACTIVE",
+			"expiration":  "2020-12-31T00:00:00Z",
+			"categories":  "2020,1000",
+			"exclude":     nil,
+			"keywords":    "a,b",
+			"limit":       float64(5),
+			"price_range": nil,
+			"fill_random": true,
+			"comment":     nil,
+		},
+	}
+	getter := MakeMockInputGetter(&input, nil)
+	r := h.Execute(getter)
 	expected := &goutils.Response{
-		Code: http.StatusBadRequest,
-		Body: goutils.GenericError{
-			ErrorMessage: fmt.Sprintf(`%+v`, err),
+		Code: http.StatusOK,
+		Body: HeadedBody{
+			Headers: map[string]string{"ETag": `"3"`},
+			Body:    makeProductOutput(domain.Product{ID: 123, Version: 3}),
 		},
 	}
 	assert.Equal(t, expected, r)
 	mInteractor.AssertExpectations(t)
 }
 
-func TestSetPartialConfigHandlerBadUserProductID(t *testing.T) {
+func TestSetPartialConfigHandlerInvalidFields(t *testing.T) {
 	mInteractor := &mockSetPartialConfigInteractor{}
 	h := SetPartialConfigHandler{
 		Interactor: mInteractor,
 	}
 	input := setPartialConfigHandlerInput{
-		UserProductID: 0,
+		UserProductID: 123,
+		IfMatch:       `"2"`,
+		Body: map[string]interface{}{
+			"status":     "DELETED",
+			"user_id":    float64(1),
+			"limit":      "five",
+			"expiration": nil,
+			"comment":    "ok",
+		},
 	}
 	getter := MakeMockInputGetter(&input, nil)
 	r := h.Execute(getter)
-	expected := &goutils.Response{
-		Code: http.StatusBadRequest,
-		Body: goutils.GenericError{
-			ErrorMessage: fmt.Sprintf(`Wrong ProductID: %d`,
-				input.UserProductID),
+	expected := MakeValidationErrorResponse([]FieldError{
+		{Field: "expiration", Value: nil, Rule: "datetime"},
+		{Field: "limit", Value: "five", Rule: "int"},
+		{Field: "status", Value: "DELETED", Rule: "oneof=ACTIVE|INACTIVE|EXPIRED"},
+		{Field: "user_id", Value: float64(1), Rule: "unsupported"},
+	})
+	assert.Equal(t, expected, r)
+	mInteractor.AssertExpectations(t)
+}
+
+func TestSetPartialConfigHandlerVersionMismatch(t *testing.T) {
+	mInteractor := &mockSetPartialConfigInteractor{}
+	mInteractor.On("SetPartialConfig", mock.Anything, 123, 1,
+		mock.AnythingOfType("usecases.ProductPatch"),
+	).Return(domain.Product{}, usecases.ErrVersionMismatch)
+	h := SetPartialConfigHandler{
+		Interactor: mInteractor,
+	}
+	input := setPartialConfigHandlerInput{
+		UserProductID: 123,
+		IfMatch:       `"1"`,
+		Body:          map[string]interface{}{"limit": float64(3)},
+	}
+	getter := MakeMockInputGetter(&input, nil)
+	r := h.Execute(getter)
+	expected := MakeProblemResponse(http.StatusPreconditionFailed,
+		usecases.VersionMismatchCode, usecases.ErrVersionMismatch.Error())
+	assert.Equal(t, expected, r)
+	mInteractor.AssertExpectations(t)
+}
+
+func TestSetPartialConfigHandlerMissingIfMatch(t *testing.T) {
+	mInteractor := &mockSetPartialConfigInteractor{}
+	h := SetPartialConfigHandler{
+		Interactor: mInteractor,
+	}
+	input := setPartialConfigHandlerInput{
+		UserProductID: 123,
+		Body:          map[string]interface{}{"limit": float64(3)},
+	}
+	getter := MakeMockInputGetter(&input, nil)
+	r := h.Execute(getter)
+	expected := MakeProblemResponse(http.StatusPreconditionRequired,
+		PreconditionRequiredCode, "If-Match header is required")
+	assert.Equal(t, expected, r)
+	mInteractor.AssertExpectations(t)
+}
+
+func TestSetPartialConfigHandlerOutOfRange(t *testing.T) {
+	mInteractor := &mockSetPartialConfigInteractor{}
+	h := SetPartialConfigHandler{
+		Interactor: mInteractor,
+	}
+	input := setPartialConfigHandlerInput{
+		UserProductID: 123,
+		IfMatch:       `"2"`,
+		Body: map[string]interface{}{
+			"categories":  "2020,abc",
+			"limit":       float64(101),
+			"price_range": float64(-1),
 		},
 	}
+	getter := MakeMockInputGetter(&input, nil)
+	r := h.Execute(getter)
+	expected := MakeValidationErrorResponse([]FieldError{
+		{Field: "categories", Value: "2020,abc", Rule: "csvint=1000..9999"},
+		{Field: "limit", Value: float64(101), Rule: "max=100"},
+		{Field: "price_range", Value: float64(-1), Rule: "min=0"},
+	})
 	assert.Equal(t, expected, r)
 	mInteractor.AssertExpectations(t)
 }
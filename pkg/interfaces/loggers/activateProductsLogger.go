@@ -0,0 +1,23 @@
+package loggers
+
+import "gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/usecases"
+
+type activateProductsLogger struct {
+	logger Logger
+}
+
+func (l *activateProductsLogger) LogErrorActivatingProducts(err error) {
+	l.logger.Error("error activating products: %+v", err)
+}
+
+func (l *activateProductsLogger) LogWarnSettingCache(userID int, err error) {
+	l.logger.Warn("unable to set product cache userID: %d - %+v", userID, err)
+}
+
+// MakeActivateProductsLogger sets up a ActivateProductsLogger instrumented
+// via the provided logger
+func MakeActivateProductsLogger(logger Logger) usecases.ActivateProductsLogger {
+	return &activateProductsLogger{
+		logger: logger,
+	}
+}
@@ -0,0 +1,13 @@
+package loggers
+
+import (
+	"testing"
+)
+
+func TestActivateProductsLogger(t *testing.T) {
+	m := &loggerMock{t: t}
+	l := MakeActivateProductsLogger(m)
+	l.LogErrorActivatingProducts(nil)
+	l.LogWarnSettingCache(0, nil)
+	m.AssertExpectations(t)
+}
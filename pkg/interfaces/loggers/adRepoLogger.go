@@ -0,0 +1,27 @@
+package loggers
+
+import (
+	"context"
+
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/interfaces/repository"
+)
+
+type adRepoLogger struct {
+	logger Logger
+}
+
+func (l *adRepoLogger) LogSearchResults(ctx context.Context, field string, value interface{}, hits int) {
+	WithContext(ctx, l.logger).Debug("search by %s %v returned %d ads", field, value, hits)
+}
+
+func (l *adRepoLogger) LogErrorParsingAd(ctx context.Context, err error) {
+	WithContext(ctx, l.logger).Error("not able to parse search result into an ad: %+v", err)
+}
+
+// MakeAdRepositoryLogger sets up an AdRepositoryLogger instrumented
+// via the provided logger
+func MakeAdRepositoryLogger(logger Logger) repository.AdRepositoryLogger {
+	return &adRepoLogger{
+		logger: logger,
+	}
+}
@@ -0,0 +1,14 @@
+package loggers
+
+import (
+	"context"
+	"testing"
+)
+
+func TestAdRepoLogger(t *testing.T) {
+	m := &loggerMock{t: t}
+	l := MakeAdRepositoryLogger(m)
+	l.LogSearchResults(context.Background(), "", nil, 0)
+	l.LogErrorParsingAd(context.Background(), nil)
+	m.AssertExpectations(t)
+}
@@ -1,21 +1,25 @@
 package loggers
 
-import "gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/usecases"
+import (
+	"context"
+
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/usecases"
+)
 
 type addUserProductLogger struct {
 	logger Logger
 }
 
-func (l *addUserProductLogger) LogWarnSettingCache(userID int, err error) {
-	l.logger.Warn("not able to set product cache userID: %d - %+v", userID, err)
+func (l *addUserProductLogger) LogWarnSettingCache(ctx context.Context, userID int, err error) {
+	WithContext(ctx, l.logger).Warn("not able to set product cache userID: %d - %+v", userID, err)
 }
 
-func (l *addUserProductLogger) LogErrorAddingProduct(userID int, err error) {
-	l.logger.Error("Error adding product to userID: %d error: %+v", userID, err)
+func (l *addUserProductLogger) LogErrorAddingProduct(ctx context.Context, userID int, err error) {
+	WithContext(ctx, l.logger).Error("Error adding product to userID: %d error: %+v", userID, err)
 }
 
-func (l *addUserProductLogger) LogWarnPushingEvent(productID int, err error) {
-	l.logger.Warn("not able to push event to queue productID: %d - %+v", productID, err)
+func (l *addUserProductLogger) LogWarnStoringIdempotencyKey(ctx context.Context, key string, err error) {
+	WithContext(ctx, l.logger).Warn("not able to store idempotency key: %s - %+v", key, err)
 }
 
 // MakeAddUserProductLogger sets up a AddUserProductLogger instrumented
@@ -1,14 +1,15 @@
 package loggers
 
 import (
+	"context"
 	"testing"
 )
 
 func TestAddUserProductLogger(t *testing.T) {
 	m := &loggerMock{t: t}
 	l := MakeAddUserProductLogger(m)
-	l.LogErrorAddingProduct(0, nil)
-	l.LogWarnSettingCache(0, nil)
-	l.LogWarnPushingEvent(0, nil)
+	l.LogErrorAddingProduct(context.Background(), 0, nil)
+	l.LogWarnSettingCache(context.Background(), 0, nil)
+	l.LogWarnStoringIdempotencyKey(context.Background(), "", nil)
 	m.AssertExpectations(t)
 }
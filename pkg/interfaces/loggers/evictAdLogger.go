@@ -0,0 +1,23 @@
+package loggers
+
+import (
+	"context"
+
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/usecases"
+)
+
+type evictAdLogger struct {
+	logger Logger
+}
+
+func (l *evictAdLogger) LogErrorEvictingAd(ctx context.Context, listID string, err error) {
+	WithContext(ctx, l.logger).Error("not able to evict ad cache for listID: %s - %+v", listID, err)
+}
+
+// MakeEvictAdLogger sets up a EvictAdLogger instrumented
+// via the provided logger
+func MakeEvictAdLogger(logger Logger) usecases.EvictAdLogger {
+	return &evictAdLogger{
+		logger: logger,
+	}
+}
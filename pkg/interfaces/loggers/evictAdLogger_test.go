@@ -0,0 +1,13 @@
+package loggers
+
+import (
+	"context"
+	"testing"
+)
+
+func TestEvictAdLogger(t *testing.T) {
+	m := &loggerMock{t: t}
+	l := MakeEvictAdLogger(m)
+	l.LogErrorEvictingAd(context.Background(), "", nil)
+	m.AssertExpectations(t)
+}
@@ -10,6 +10,10 @@ func (l *expireProductsLogger) LogExpireProductsError(err error) {
 	l.logger.Error("error expiring products: %+v", err)
 }
 
+func (l *expireProductsLogger) LogWarnSettingCache(userID int, err error) {
+	l.logger.Warn("unable to set product cache userID: %d - %+v", userID, err)
+}
+
 // MakeExpireProductsLogger sets up a ExpireProductsLogger instrumented
 // via the provided logger
 func MakeExpireProductsLogger(logger Logger) usecases.ExpireProductsLogger {
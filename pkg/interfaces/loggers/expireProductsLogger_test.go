@@ -8,5 +8,6 @@ func TestExpireProductsLogger(t *testing.T) {
 	m := &loggerMock{t: t}
 	l := MakeExpireProductsLogger(m)
 	l.LogExpireProductsError(nil)
+	l.LogWarnSettingCache(0, nil)
 	m.AssertExpectations(t)
 }
@@ -1,14 +1,15 @@
 package loggers
 
 import (
+	"context"
 	"testing"
 )
 
 func TestGetAdLogger(t *testing.T) {
 	m := &loggerMock{t: t}
 	l := MakeGetAdLogger(m)
-	l.LogWarnGettingCache("", nil)
-	l.LogWarnSettingCache("", nil)
-	l.LogErrorGettingAd("", nil)
+	l.LogWarnGettingCache(context.Background(), "", nil)
+	l.LogWarnSettingCache(context.Background(), "", nil)
+	l.LogErrorGettingAd(context.Background(), "", nil)
 	m.AssertExpectations(t)
 }
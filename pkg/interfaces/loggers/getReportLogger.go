@@ -1,13 +1,17 @@
 package loggers
 
-import "gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/usecases"
+import (
+	"context"
+
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/usecases"
+)
 
 type getReportLogger struct {
 	logger Logger
 }
 
-func (l *getReportLogger) LogErrorGettingReport(err error) {
-	l.logger.Error("error getting report data - error: %+v", err)
+func (l *getReportLogger) LogErrorGettingReport(ctx context.Context, err error) {
+	WithContext(ctx, l.logger).Error("error getting report data - error: %+v", err)
 }
 
 // MakeGetReportLogger sets up a GetReportLogger instrumented
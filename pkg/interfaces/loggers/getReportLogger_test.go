@@ -1,12 +1,13 @@
 package loggers
 
 import (
+	"context"
 	"testing"
 )
 
 func TestGetReportLogger(t *testing.T) {
 	m := &loggerMock{t: t}
 	l := MakeGetReportLogger(m)
-	l.LogErrorGettingReport(nil)
+	l.LogErrorGettingReport(context.Background(), nil)
 	m.AssertExpectations(t)
 }
@@ -1,6 +1,8 @@
 package loggers
 
 import (
+	"context"
+
 	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/domain"
 	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/usecases"
 )
@@ -9,30 +11,30 @@ type getUserAdsLogger struct {
 	logger Logger
 }
 
-func (l *getUserAdsLogger) LogWarnGettingCache(userID int, err error) {
-	l.logger.Warn("not able to get cache for user ads: userID %d - %+v", userID, err)
+func (l *getUserAdsLogger) LogWarnGettingCache(ctx context.Context, userID int, err error) {
+	WithContext(ctx, l.logger).Warn("not able to get cache for user ads: userID %d - %+v", userID, err)
 }
 
-func (l *getUserAdsLogger) LogWarnSettingCache(userID int, err error) {
-	l.logger.Warn("not able to set cache for user ads: userID %d - %+v", userID, err)
+func (l *getUserAdsLogger) LogWarnSettingCache(ctx context.Context, userID int, err error) {
+	WithContext(ctx, l.logger).Warn("not able to set cache for user ads: userID %d - %+v", userID, err)
 }
 
-func (l *getUserAdsLogger) LogInfoActiveProductNotFound(userID int, product domain.Product) {
-	l.logger.Info("active product not found for userID: %d. Current product is: %v (id: %d)",
+func (l *getUserAdsLogger) LogInfoActiveProductNotFound(ctx context.Context, userID int, product domain.Product) {
+	WithContext(ctx, l.logger).Info("active product not found for userID: %d. Current product is: %v (id: %d)",
 		userID, product.Status, product.ID)
 }
 
-func (l *getUserAdsLogger) LogInfoProductExpired(userID int, product domain.Product) {
-	l.logger.Info("the requested product %d (userID: %d) is expired at %+v", userID, product.ID,
+func (l *getUserAdsLogger) LogInfoProductExpired(ctx context.Context, userID int, product domain.Product) {
+	WithContext(ctx, l.logger).Info("the requested product %d (userID: %d) is expired at %+v", userID, product.ID,
 		product.ExpiredAt)
 }
 
-func (l *getUserAdsLogger) LogErrorGettingUserAdsData(userID int, err error) {
-	l.logger.Error("error getting user ads data: userID %d, error: %+v", userID, err)
+func (l *getUserAdsLogger) LogErrorGettingUserAdsData(ctx context.Context, userID int, err error) {
+	WithContext(ctx, l.logger).Error("error getting user ads data: userID %d, error: %+v", userID, err)
 }
 
-func (l *getUserAdsLogger) LogNotEnoughAds(userID int) {
-	l.logger.Error("user %s does not have enough active ads", userID)
+func (l *getUserAdsLogger) LogNotEnoughAds(ctx context.Context, userID int) {
+	WithContext(ctx, l.logger).Error("user %s does not have enough active ads", userID)
 }
 
 // MakeGetUserAdsLogger sets up a GetUserAdsLogger instrumented
@@ -1,6 +1,7 @@
 package loggers
 
 import (
+	"context"
 	"testing"
 
 	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/domain"
@@ -9,11 +10,11 @@ import (
 func TestGetUserAdsLogger(t *testing.T) {
 	m := &loggerMock{t: t}
 	l := MakeGetUserAdsLogger(m)
-	l.LogWarnGettingCache(0, nil)
-	l.LogWarnSettingCache(0, nil)
-	l.LogInfoActiveProductNotFound(0, domain.Product{})
-	l.LogInfoProductExpired(0, domain.Product{})
-	l.LogErrorGettingUserAdsData(0, nil)
-	l.LogNotEnoughAds(0)
+	l.LogWarnGettingCache(context.Background(), 0, nil)
+	l.LogWarnSettingCache(context.Background(), 0, nil)
+	l.LogInfoActiveProductNotFound(context.Background(), 0, domain.Product{})
+	l.LogInfoProductExpired(context.Background(), 0, domain.Product{})
+	l.LogErrorGettingUserAdsData(context.Background(), 0, nil)
+	l.LogNotEnoughAds(context.Background(), 0)
 	m.AssertExpectations(t)
 }
@@ -0,0 +1,24 @@
+package loggers
+
+import (
+	"context"
+
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/usecases"
+)
+
+type getUserProductLogger struct {
+	logger Logger
+}
+
+func (l *getUserProductLogger) LogErrorGettingUserProduct(ctx context.Context, userProductID int, err error) {
+	WithContext(ctx, l.logger).Error("error getting user product data: userProductID %d - error: %+v",
+		userProductID, err)
+}
+
+// MakeGetUserProductLogger sets up a GetUserProductLogger instrumented
+// via the provided logger
+func MakeGetUserProductLogger(logger Logger) usecases.GetUserProductLogger {
+	return &getUserProductLogger{
+		logger: logger,
+	}
+}
@@ -0,0 +1,13 @@
+package loggers
+
+import (
+	"context"
+	"testing"
+)
+
+func TestGetUserProductLogger(t *testing.T) {
+	m := &loggerMock{t: t}
+	l := MakeGetUserProductLogger(m)
+	l.LogErrorGettingUserProduct(context.Background(), 0, nil)
+	m.AssertExpectations(t)
+}
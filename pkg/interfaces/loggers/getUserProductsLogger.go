@@ -1,17 +1,21 @@
 package loggers
 
-import "gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/usecases"
+import (
+	"context"
+
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/usecases"
+)
 
 type getUserProductsLogger struct {
 	logger Logger
 }
 
-func (l *getUserProductsLogger) LogErrorGettingUserProducts(err error) {
-	l.logger.Error("error getting user products data - error: %+v", err)
+func (l *getUserProductsLogger) LogErrorGettingUserProducts(ctx context.Context, err error) {
+	WithContext(ctx, l.logger).Error("error getting user products data - error: %+v", err)
 }
 
-func (l *getUserProductsLogger) LogErrorGettingUserProductsByEmail(email string, err error) {
-	l.logger.Error("error getting user products data: email %s - error: %+v", email, err)
+func (l *getUserProductsLogger) LogErrorGettingUserProductsByEmail(ctx context.Context, email string, err error) {
+	WithContext(ctx, l.logger).Error("error getting user products data: email %s - error: %+v", email, err)
 }
 
 // MakeGetUserProductsLogger sets up a GetUserProductsLogger instrumented
@@ -1,13 +1,14 @@
 package loggers
 
 import (
+	"context"
 	"testing"
 )
 
 func TestGetUserProductsLogger(t *testing.T) {
 	m := &loggerMock{t: t}
 	l := MakeGetUserProductsLogger(m)
-	l.LogErrorGettingUserProducts(nil)
-	l.LogErrorGettingUserProductsByEmail("", nil)
+	l.LogErrorGettingUserProducts(context.Background(), nil)
+	l.LogErrorGettingUserProductsByEmail(context.Background(), "", nil)
 	m.AssertExpectations(t)
 }
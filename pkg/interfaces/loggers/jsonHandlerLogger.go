@@ -13,15 +13,20 @@ type jsonHandlerDefaultLogger struct {
 }
 
 func (l *jsonHandlerDefaultLogger) LogRequestStart(r *http.Request) {
-	l.logger.Info("< %s %s %s", r.RemoteAddr, r.Method, r.URL)
+	WithContext(r.Context(), l.logger).Info("< %s %s %s", r.RemoteAddr, r.Method, r.URL)
 }
 
 func (l *jsonHandlerDefaultLogger) LogRequestEnd(r *http.Request, response *goutils.Response) {
-	l.logger.Info("> %s %s %s (%d)", r.RemoteAddr, r.Method, r.URL, response.Code)
+	if cause := handlers.ProblemCause(response); cause != nil {
+		WithContext(r.Context(), l.logger).Error("> %s %s %s (%d): %+v", r.RemoteAddr, r.Method, r.URL,
+			response.Code, cause)
+		return
+	}
+	WithContext(r.Context(), l.logger).Info("> %s %s %s (%d)", r.RemoteAddr, r.Method, r.URL, response.Code)
 }
 
 func (l *jsonHandlerDefaultLogger) LogRequestPanic(r *http.Request, response *goutils.Response, err interface{}) {
-	l.logger.Error("> %s %s %s (%d): %s", r.RemoteAddr, r.Method, r.URL, response.Code, err)
+	WithContext(r.Context(), l.logger).Error("> %s %s %s (%d): %s", r.RemoteAddr, r.Method, r.URL, response.Code, err)
 }
 
 // MakeJSONHandlerLogger sets up a JsonHandlerLogger instrumented
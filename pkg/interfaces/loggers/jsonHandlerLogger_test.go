@@ -1,11 +1,14 @@
 package loggers
 
 import (
+	"fmt"
 	"net/http/httptest"
 	"strings"
 	"testing"
 
 	"github.com/Yapo/goutils"
+
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/interfaces/handlers"
 )
 
 // There are no return values to assert on, as logger only cause side effects
@@ -18,5 +21,6 @@ func TestJSONHandlerLogger(t *testing.T) {
 	l := MakeJSONHandlerLogger(m)
 	l.LogRequestStart(r)
 	l.LogRequestEnd(r, &goutils.Response{})
+	l.LogRequestEnd(r, handlers.MakeErrorResponse(fmt.Errorf("err")))
 	l.LogRequestPanic(r, &goutils.Response{}, nil)
 }
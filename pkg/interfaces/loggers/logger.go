@@ -1,5 +1,14 @@
 package loggers
 
+import (
+	"context"
+
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/interfaces/handlers"
+)
+
+// RequestIDField is the name of the field holding the request id
+const RequestIDField = "request_id"
+
 // Logger is an interface for logging facilities
 type Logger interface {
 	Debug(format string, params ...interface{})
@@ -9,3 +18,21 @@ type Logger interface {
 	Crit(format string, params ...interface{})
 	Success(format string, params ...interface{})
 }
+
+// FieldLogger is a Logger able to tag every line it writes with a field
+type FieldLogger interface {
+	Logger
+	// With returns a copy of the logger that adds the field to its lines
+	With(key, value string) Logger
+}
+
+// WithContext returns a logger that tags its lines with the request id held
+// by ctx. Loggers unable to hold fields are returned as they are
+func WithContext(ctx context.Context, logger Logger) Logger {
+	requestID, ok := handlers.RequestIDFromContext(ctx)
+	fieldLogger, isFieldLogger := logger.(FieldLogger)
+	if !ok || !isFieldLogger {
+		return logger
+	}
+	return fieldLogger.With(RequestIDField, requestID)
+}
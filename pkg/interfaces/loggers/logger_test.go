@@ -1,10 +1,14 @@
 package loggers
 
 import (
+	"context"
 	"fmt"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/interfaces/handlers"
 )
 
 type loggerMock struct {
@@ -30,3 +34,24 @@ func (m *loggerMock) Crit(format string, params ...interface{}) {
 func (m *loggerMock) Success(format string, params ...interface{}) {
 	fmt.Sprintf(format, params...) // nolint: vet,megacheck
 }
+
+type fieldLoggerMock struct {
+	loggerMock
+}
+
+func (m *fieldLoggerMock) With(key, value string) Logger {
+	args := m.Called(key, value)
+	return args.Get(0).(Logger)
+}
+
+func TestWithContext(t *testing.T) {
+	m := &fieldLoggerMock{}
+	tagged := &loggerMock{}
+	m.On("With", RequestIDField, "abc").Return(tagged).Once()
+	ctx := handlers.ContextWithRequestID(context.Background(), "abc")
+	assert.Equal(t, tagged, WithContext(ctx, m))
+	assert.Equal(t, m, WithContext(context.Background(), m))
+	plain := &loggerMock{}
+	assert.Equal(t, plain, WithContext(ctx, plain))
+	m.AssertExpectations(t)
+}
@@ -2,11 +2,13 @@ package loggers
 
 import (
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestProductRepoLogger(t *testing.T) {
 	m := &loggerMock{t: t}
 	l := MakeProductRepositoryLogger(m)
-	l.LogWarnPartialConfigNotSupported("", "")
+	assert.NotNil(t, l)
 	m.AssertExpectations(t)
 }
@@ -0,0 +1,23 @@
+package loggers
+
+import "gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/usecases"
+
+type refreshProductsCacheLogger struct {
+	logger Logger
+}
+
+func (l *refreshProductsCacheLogger) LogErrorRefreshingCache(err error) {
+	l.logger.Error("error refreshing products cache: %+v", err)
+}
+
+func (l *refreshProductsCacheLogger) LogWarnSettingCache(userID int, err error) {
+	l.logger.Warn("unable to set product cache userID: %d - %+v", userID, err)
+}
+
+// MakeRefreshProductsCacheLogger sets up a RefreshProductsCacheLogger
+// instrumented via the provided logger
+func MakeRefreshProductsCacheLogger(logger Logger) usecases.RefreshProductsCacheLogger {
+	return &refreshProductsCacheLogger{
+		logger: logger,
+	}
+}
@@ -0,0 +1,13 @@
+package loggers
+
+import (
+	"testing"
+)
+
+func TestRefreshProductsCacheLogger(t *testing.T) {
+	m := &loggerMock{t: t}
+	l := MakeRefreshProductsCacheLogger(m)
+	l.LogErrorRefreshingCache(nil)
+	l.LogWarnSettingCache(0, nil)
+	m.AssertExpectations(t)
+}
@@ -0,0 +1,32 @@
+package loggers
+
+import "gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/usecases"
+
+type relayBackendEventsLogger struct {
+	logger Logger
+}
+
+func (l *relayBackendEventsLogger) LogErrorGettingPendingEvents(err error) {
+	l.logger.Error("error getting pending backend events: %+v", err)
+}
+
+func (l *relayBackendEventsLogger) LogWarnPublishingEvent(eventID int, attempts int, err error) {
+	l.logger.Warn("not able to publish backend event eventID: %d attempt: %d - %+v",
+		eventID, attempts, err)
+}
+
+func (l *relayBackendEventsLogger) LogErrorMarkingEvent(eventID int, err error) {
+	l.logger.Error("error marking backend event eventID: %d - %+v", eventID, err)
+}
+
+func (l *relayBackendEventsLogger) LogErrorPurgingEvents(err error) {
+	l.logger.Error("error purging sent backend events: %+v", err)
+}
+
+// MakeRelayBackendEventsLogger sets up a RelayBackendEventsLogger
+// instrumented via the provided logger
+func MakeRelayBackendEventsLogger(logger Logger) usecases.RelayBackendEventsLogger {
+	return &relayBackendEventsLogger{
+		logger: logger,
+	}
+}
@@ -0,0 +1,14 @@
+package loggers
+
+import (
+	"testing"
+)
+
+func TestRelayBackendEventsLogger(t *testing.T) {
+	m := &loggerMock{t: t}
+	l := MakeRelayBackendEventsLogger(m)
+	l.LogErrorGettingPendingEvents(nil)
+	l.LogWarnPublishingEvent(0, 0, nil)
+	l.LogErrorMarkingEvent(0, nil)
+	m.AssertExpectations(t)
+}
@@ -0,0 +1,35 @@
+package loggers
+
+import (
+	"context"
+	"time"
+
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/usecases"
+)
+
+type replaySoldProductsLogger struct {
+	logger Logger
+}
+
+func (l *replaySoldProductsLogger) LogErrorGettingReport(ctx context.Context, err error) {
+	WithContext(ctx, l.logger).Error("Error getting report to replay: %+v", err)
+}
+
+func (l *replaySoldProductsLogger) LogReplayingSoldProduct(ctx context.Context, productID int,
+	dryRun bool) {
+	WithContext(ctx, l.logger).Info("Replaying sold product event productID: %d dry run: %t", productID, dryRun)
+}
+
+func (l *replaySoldProductsLogger) LogErrorReplayingSoldProduct(ctx context.Context, productID int,
+	createdAt time.Time, err error) {
+	WithContext(ctx, l.logger).Error("Error replaying sold product event productID: %d created at: %s, "+
+		"resume from that date - %+v", productID, createdAt.Format(time.RFC3339), err)
+}
+
+// MakeReplaySoldProductsLogger sets up a ReplaySoldProductsLogger
+// instrumented via the provided logger
+func MakeReplaySoldProductsLogger(logger Logger) usecases.ReplaySoldProductsLogger {
+	return &replaySoldProductsLogger{
+		logger: logger,
+	}
+}
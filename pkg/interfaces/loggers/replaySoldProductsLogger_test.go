@@ -0,0 +1,16 @@
+package loggers
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestReplaySoldProductsLogger(t *testing.T) {
+	m := &loggerMock{t: t}
+	l := MakeReplaySoldProductsLogger(m)
+	l.LogErrorGettingReport(context.Background(), nil)
+	l.LogReplayingSoldProduct(context.Background(), 0, false)
+	l.LogErrorReplayingSoldProduct(context.Background(), 0, time.Time{}, nil)
+	m.AssertExpectations(t)
+}
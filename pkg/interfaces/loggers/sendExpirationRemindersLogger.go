@@ -0,0 +1,28 @@
+package loggers
+
+import (
+	"context"
+
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/usecases"
+)
+
+type sendExpirationRemindersLogger struct {
+	logger Logger
+}
+
+func (l *sendExpirationRemindersLogger) LogErrorGettingExpiringProducts(ctx context.Context, err error) {
+	WithContext(ctx, l.logger).Error("error getting expiring products: %+v", err)
+}
+
+func (l *sendExpirationRemindersLogger) LogWarnSendingReminder(ctx context.Context, productID int,
+	err error) {
+	WithContext(ctx, l.logger).Warn("unable to send expiration reminder productID: %d - %+v", productID, err)
+}
+
+// MakeSendExpirationRemindersLogger sets up a SendExpirationRemindersLogger
+// instrumented via the provided logger
+func MakeSendExpirationRemindersLogger(logger Logger) usecases.SendExpirationRemindersLogger {
+	return &sendExpirationRemindersLogger{
+		logger: logger,
+	}
+}
@@ -0,0 +1,14 @@
+package loggers
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSendExpirationRemindersLogger(t *testing.T) {
+	m := &loggerMock{t: t}
+	l := MakeSendExpirationRemindersLogger(m)
+	l.LogErrorGettingExpiringProducts(context.Background(), nil)
+	l.LogWarnSendingReminder(context.Background(), 0, nil)
+	m.AssertExpectations(t)
+}
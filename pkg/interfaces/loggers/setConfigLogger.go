@@ -1,17 +1,21 @@
 package loggers
 
-import "gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/usecases"
+import (
+	"context"
+
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/usecases"
+)
 
 type setConfigLogger struct {
 	logger Logger
 }
 
-func (l *setConfigLogger) LogWarnSettingCache(userID int, err error) {
-	l.logger.Warn("unable to set product cache userID: %d - %+v", userID, err)
+func (l *setConfigLogger) LogWarnSettingCache(ctx context.Context, userID int, err error) {
+	WithContext(ctx, l.logger).Warn("unable to set product cache userID: %d - %+v", userID, err)
 }
 
-func (l *setConfigLogger) LogErrorSettingConfig(userProductID int, err error) {
-	l.logger.Error("error setting config for userProductID: %d - %+v", userProductID, err)
+func (l *setConfigLogger) LogErrorSettingConfig(ctx context.Context, userProductID int, err error) {
+	WithContext(ctx, l.logger).Error("error setting config for userProductID: %d - %+v", userProductID, err)
 }
 
 // MakeSetConfigLogger sets up a SetConfigLogger instrumented
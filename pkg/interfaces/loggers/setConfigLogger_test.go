@@ -1,13 +1,14 @@
 package loggers
 
 import (
+	"context"
 	"testing"
 )
 
 func TestSetConfigLogger(t *testing.T) {
 	m := &loggerMock{t: t}
 	l := MakeSetConfigLogger(m)
-	l.LogErrorSettingConfig(1, nil)
-	l.LogWarnSettingCache(0, nil)
+	l.LogErrorSettingConfig(context.Background(), 1, nil)
+	l.LogWarnSettingCache(context.Background(), 0, nil)
 	m.AssertExpectations(t)
 }
@@ -1,17 +1,21 @@
 package loggers
 
-import "gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/usecases"
+import (
+	"context"
+
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/usecases"
+)
 
 type setPartialConfigLogger struct {
 	logger Logger
 }
 
-func (l *setPartialConfigLogger) LogWarnSettingCache(userID int, err error) {
-	l.logger.Warn("unable to set product cache userID: %d - %+v", userID, err)
+func (l *setPartialConfigLogger) LogWarnSettingCache(ctx context.Context, userID int, err error) {
+	WithContext(ctx, l.logger).Warn("unable to set product cache userID: %d - %+v", userID, err)
 }
 
-func (l *setPartialConfigLogger) LogErrorSettingPartialConfig(userProductID int, err error) {
-	l.logger.Error("error setting partial config for userProductID: %s - %+v", userProductID, err)
+func (l *setPartialConfigLogger) LogErrorSettingPartialConfig(ctx context.Context, userProductID int, err error) {
+	WithContext(ctx, l.logger).Error("error setting partial config for userProductID: %s - %+v", userProductID, err)
 }
 
 // MakeSetPartialConfigLogger sets up a SetPartialConfigLogger instrumented
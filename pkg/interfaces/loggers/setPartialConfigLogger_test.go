@@ -1,13 +1,14 @@
 package loggers
 
 import (
+	"context"
 	"testing"
 )
 
 func TestSetPartialConfigLogger(t *testing.T) {
 	m := &loggerMock{t: t}
 	l := MakeSetPartialConfigLogger(m)
-	l.LogWarnSettingCache(0, nil)
-	l.LogErrorSettingPartialConfig(1, nil)
+	l.LogWarnSettingCache(context.Background(), 0, nil)
+	l.LogErrorSettingPartialConfig(context.Background(), 1, nil)
 	m.AssertExpectations(t)
 }
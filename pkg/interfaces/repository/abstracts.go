@@ -1,19 +1,39 @@
 package repository
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"time"
 )
 
 // DbHandler represents a database connection handler
 // it provides basic database capabilities
-// after its use, the connection with the database must be closed
+// after its use, the connection with the database must be closed.
+// Statements are run within the given context, which also carries the
+// trace they are timed in
 type DbHandler interface {
 	io.Closer
-	Insert(statement string, params ...interface{}) error
-	Update(statement string, params ...interface{}) error
-	Query(statement string, params ...interface{}) (DbResult, error)
+	Insert(ctx context.Context, statement string, params ...interface{}) error
+	Update(ctx context.Context, statement string, params ...interface{}) error
+	Query(ctx context.Context, statement string, params ...interface{}) (DbResult, error)
+}
+
+// DbTransaction represents a database transaction. Statements run through it
+// take effect together on Commit, or are discarded on Rollback. Closing a
+// transaction not committed rolls it back
+type DbTransaction interface {
+	DbHandler
+	Commit() error
+	Rollback() error
+}
+
+// TransactionalDbHandler represents a database connection handler able to
+// start transactions
+type TransactionalDbHandler interface {
+	DbHandler
+	Begin(ctx context.Context) (DbTransaction, error)
 }
 
 // DbResult represents a database query result rows
@@ -26,13 +46,31 @@ type DbResult interface {
 	Next() bool
 }
 
+// ErrKeyNotFound is returned by Redis.Get when the key doesn't exist
+var ErrKeyNotFound = errors.New("KEY_NOT_FOUND")
+
 // Redis implements Redis functions
 type Redis interface {
-	HGetAll(key string) (map[string]string, bool)
-	HGet(key, field string) (string, bool)
-	Set(key string, values interface{}, expiration time.Duration) error
-	Get(key string) (RedisResult, error)
-	Del(key string) error
+	HGetAll(ctx context.Context, key string) (map[string]string, bool)
+	HGet(ctx context.Context, key, field string) (string, bool)
+	Set(ctx context.Context, key string, values interface{}, expiration time.Duration) error
+	SetNX(ctx context.Context, key string, values interface{},
+		expiration time.Duration) (bool, error)
+	Get(ctx context.Context, key string) (RedisResult, error)
+	Del(ctx context.Context, key string) error
+}
+
+// RedisPublisher sends messages to the subscribers of a redis channel
+type RedisPublisher interface {
+	Publish(ctx context.Context, channel, message string) error
+}
+
+// LocalCache holds values in the memory of the process, they are dropped
+// once expired or to make room for others
+type LocalCache interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte, expiration time.Duration)
+	Del(key string)
 }
 
 // RedisResult interface for a result obtained from executing a get command in redis
@@ -65,12 +103,18 @@ type Search interface {
 	NewBoolQuery(must, mustNot, should []Query) Query
 	NewIDsQuery(ids ...string) Query
 	NewCategoryFilter(categoryIDs ...int) Query
-	GetDoc(index string, id string) (json.RawMessage, error)
-	Search(index string, query Query, from, size int) (SearchResult, error)
+	GetDoc(ctx context.Context, index string, id string) (json.RawMessage, error)
+	Search(ctx context.Context, index string, query Query, from, size int) (SearchResult, error)
 }
 
 // KafkaProducer allows send messages to kafka
 type KafkaProducer interface {
 	SendMessage(topic string, message []byte) error
+	// SendKeyedMessage sends a message with a key, messages with the same key
+	// are delivered in order to the same partition
+	SendKeyedMessage(topic string, key, message []byte) error
+	// SendMessageAsync enqueues a keyed message without waiting for kafka to
+	// acknowledge it, onDelivery is called with the delivery result
+	SendMessageAsync(topic string, key, message []byte, onDelivery func(error)) error
 	io.Closer
 }
@@ -1,9 +1,9 @@
 package repository
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"log"
 	"math/rand"
 	"regexp"
 	"strconv"
@@ -19,25 +19,32 @@ type adRepo struct {
 	regionsConf     Config
 	imageServerLink string
 	index           string
-	maxAdsToDisplay int
+	logger          AdRepositoryLogger
+}
+
+// AdRepositoryLogger logs ad repository events
+type AdRepositoryLogger interface {
+	LogSearchResults(ctx context.Context, field string, value interface{}, hits int)
+	LogErrorParsingAd(ctx context.Context, err error)
 }
 
 // MakeAdRepository returns a fresh instance of AdRepository
 func MakeAdRepository(handler Search, regionsConf Config, index,
-	imageServerLink string, maxAdsToDisplay int) usecases.AdRepository {
+	imageServerLink string, logger AdRepositoryLogger) usecases.AdRepository {
 	return &adRepo{
 		handler:         handler,
 		index:           index,
 		imageServerLink: imageServerLink,
 		regionsConf:     regionsConf,
-		maxAdsToDisplay: maxAdsToDisplay,
+		logger:          logger,
 	}
 }
 
 // GetUserAds gets user active ads from search repository using config to
 // match similar ads
-func (repo *adRepo) GetUserAds(userID int, productParams domain.ProductParams) (domain.Ads, error) {
-	limit := repo.makeLimit(productParams)
+func (repo *adRepo) GetUserAds(ctx context.Context, userID int,
+	productParams domain.ProductParams, maxAds int) (domain.Ads, error) {
+	limit := repo.makeLimit(productParams, maxAds)
 	termQuery := repo.handler.NewTermQuery("userId", userID)
 	must, mustNot := []Query{termQuery}, []Query{}
 
@@ -72,20 +79,21 @@ func (repo *adRepo) GetUserAds(userID int, productParams domain.ProductParams) (
 
 	boolQuery := repo.handler.NewBoolQuery(must, mustNot, []Query{})
 	scoreQuery := repo.handler.NewFunctionScoreQuery(boolQuery, 5, "multiply", true)
-	result, err := repo.handler.Search(repo.index, scoreQuery, 0, limit)
+	result, err := repo.handler.Search(ctx, repo.index, scoreQuery, 0, limit)
 	if err != nil {
 		return domain.Ads{}, err
 	}
 
-	ads := repo.parseToAds(result.GetResults())
+	ads := repo.parseToAds(ctx, result.GetResults())
+	repo.logger.LogSearchResults(ctx, "userId", userID, len(ads))
 	if len(ads) < limit && productParams.FillGapsWithRandom {
-		ads = repo.fillGapsWithRandom(userID, (limit - len(ads)), ads, productParams)
+		ads = repo.fillGapsWithRandom(ctx, userID, (limit - len(ads)), ads, productParams, maxAds)
 	}
 
 	if len(ads) == 0 {
 		return domain.Ads{}, fmt.Errorf("The specified "+
-			"userID: %d don't return results elasticsearch",
-			userID)
+			"userID: %d don't return results elasticsearch: %w",
+			userID, usecases.ErrNotEnoughAds)
 	}
 
 	return ads, nil
@@ -93,18 +101,18 @@ func (repo *adRepo) GetUserAds(userID int, productParams domain.ProductParams) (
 
 // fillGapsWithRandom fill gaps in case of the limit is less than required ads by config.
 // This method only works if config 'fillGapsWithRandom' is enabled
-func (repo *adRepo) fillGapsWithRandom(userID int, delta int, ads domain.Ads,
-	productParams domain.ProductParams) domain.Ads {
+func (repo *adRepo) fillGapsWithRandom(ctx context.Context, userID int, delta int, ads domain.Ads,
+	productParams domain.ProductParams, maxAds int) domain.Ads {
 	exclude := []string{}
 	for _, ad := range ads {
 		exclude = append(exclude, ad.ID)
 	}
-	extraAds, _ := repo.GetUserAds(userID, domain.ProductParams{
+	extraAds, _ := repo.GetUserAds(ctx, userID, domain.ProductParams{
 		Exclude:            append(exclude, productParams.Exclude...),
 		Categories:         productParams.Categories,
 		FillGapsWithRandom: false,
 		Limit:              delta,
-	})
+	}, maxAds)
 	for i, ad := range extraAds {
 		ad.IsRelated = false
 		extraAds[i] = ad
@@ -125,15 +133,15 @@ var notAlphaNumbericRegex, _ = regexp.Compile("[^a-zA-Z0-9]+")
 var specialCases = strings.NewReplacer("á", "a", "é", "e", "í", "i", "ó", "o",
 	"ú", "u", "'", "", "ñ", "n")
 
-// parseToAds parses raw searchRepository response to domain object
-func (repo *adRepo) parseToAds(results []json.RawMessage) (ads domain.Ads) {
-	fmt.Printf("results %+v\n", results)
-	log.Printf("results %+v\n", results)
+// parseToAds parses raw searchRepository response to domain object. Hits
+// that can't be parsed are logged and left out
+func (repo *adRepo) parseToAds(ctx context.Context, results []json.RawMessage) (ads domain.Ads) {
 	for _, hit := range results {
 		result := usecases.Ad{}
-		err := json.Unmarshal(hit, &result) // nolint
-		log.Printf("result Unmarshal err %+v\n", err)
-		fmt.Printf("result Unmarshal err %+v\n", err)
+		if err := json.Unmarshal(hit, &result); err != nil {
+			repo.logger.LogErrorParsingAd(ctx, err)
+			continue
+		}
 		ads = append(ads, repo.fillAd(result))
 	}
 	return
@@ -196,24 +204,24 @@ func (repo *adRepo) fillImage(ID int) domain.Image {
 }
 
 // makeLimit determines the real limit based on configuration
-func (repo *adRepo) makeLimit(productParams domain.ProductParams) int {
-	if productParams.Limit > 0 && productParams.Limit < repo.maxAdsToDisplay {
+func (repo *adRepo) makeLimit(productParams domain.ProductParams, maxAds int) int {
+	if productParams.Limit > 0 && productParams.Limit < maxAds {
 		return productParams.Limit
 	}
-	return repo.maxAdsToDisplay
+	return maxAds
 }
 
 // GetAd gets ad in search Repository using listID
-func (repo *adRepo) GetAd(listID string) (domain.Ad, error) {
+func (repo *adRepo) GetAd(ctx context.Context, listID string) (domain.Ad, error) {
 	termQuery := repo.handler.NewTermQuery("listId", listID)
-	log.Printf("termQuery %s\n", termQuery)
-	fmt.Printf("termQuery %s\n", termQuery)
-	res, err := repo.handler.Search(repo.index, termQuery, 0, 10)
-	log.Printf("Search res:%+v err:%+v\n", res, err)
-	fmt.Printf("Search res:%+v err:%+v\n", res, err)
+	res, err := repo.handler.Search(ctx, repo.index, termQuery, 0, 10)
 	if err != nil {
 		return domain.Ad{}, err
 	}
-	ads := repo.parseToAds(res.GetResults())
+	ads := repo.parseToAds(ctx, res.GetResults())
+	repo.logger.LogSearchResults(ctx, "listId", listID, len(ads))
+	if len(ads) == 0 {
+		return domain.Ad{}, usecases.ErrAdNotFound
+	}
 	return ads[0], nil
 }
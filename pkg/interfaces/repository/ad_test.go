@@ -1,13 +1,16 @@
 package repository
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/domain"
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/usecases"
 )
 
 type mockSearch struct {
@@ -52,14 +55,14 @@ func (m *mockSearch) NewCategoryFilter(categoryIDs ...int) Query {
 	return args.Get(0).(Query)
 }
 
-func (m *mockSearch) GetDoc(index string, id string) (json.RawMessage, error) {
-	args := m.Called(index, id)
+func (m *mockSearch) GetDoc(ctx context.Context, index string, id string) (json.RawMessage, error) {
+	args := m.Called(ctx, index, id)
 	return args.Get(0).(json.RawMessage), args.Error(1)
 }
 
-func (m *mockSearch) Search(index string, query Query, from,
+func (m *mockSearch) Search(ctx context.Context, index string, query Query, from,
 	size int) (SearchResult, error) {
-	args := m.Called(index, query, from, size)
+	args := m.Called(ctx, index, query, from, size)
 	return args.Get(0).(SearchResult), args.Error(1)
 }
 
@@ -86,6 +89,19 @@ func (m *mockQuery) Source() (interface{}, error) {
 	return args.Get(0), args.Error(1)
 }
 
+type mockAdRepositoryLogger struct {
+	mock.Mock
+}
+
+func (m *mockAdRepositoryLogger) LogSearchResults(ctx context.Context, field string,
+	value interface{}, hits int) {
+	m.Called(ctx, field, value, hits)
+}
+
+func (m *mockAdRepositoryLogger) LogErrorParsingAd(ctx context.Context, err error) {
+	m.Called(ctx, err)
+}
+
 type mockConfig struct {
 	mock.Mock
 }
@@ -102,7 +118,7 @@ func TestMakeAdRepository(t *testing.T) {
 		handler:     mSearch,
 		regionsConf: mConfig,
 	}
-	result := MakeAdRepository(mSearch, mConfig, "", "", 0)
+	result := MakeAdRepository(mSearch, mConfig, "", "", nil)
 	assert.Equal(t, &expected, result)
 	mSearch.AssertExpectations(t)
 	mConfig.AssertExpectations(t)
@@ -144,25 +160,28 @@ func TestGetUserAdsOK(t *testing.T) {
 		[]byte(`{"ListID": 123, "UserID": 2, "CategoryID": 2020, "Subject": "Autito"}`),
 	}
 
-	mSearch.On("Search", mock.AnythingOfType("string"),
+	mSearch.On("Search", mock.Anything, mock.AnythingOfType("string"),
 		mock.Anything,
 		mock.AnythingOfType("int"),
 		mock.AnythingOfType("int")).Return(mResults, nil)
 
 	mResults.On("GetResults").Return(results)
-	mConfig.On("Get", mock.AnythingOfType("string")).Return("something")
+	mConfig.On("Get", mock.Anything, mock.AnythingOfType("string")).Return("something")
+	mLogger := &mockAdRepositoryLogger{}
+	mLogger.On("LogSearchResults", mock.Anything, "userId", 0, 1)
 	interactor := adRepo{
+		logger:      mLogger,
 		handler:     mSearch,
 		regionsConf: mConfig,
 	}
 
-	userAds, err := interactor.GetUserAds(0,
+	userAds, err := interactor.GetUserAds(context.Background(), 0,
 		domain.ProductParams{
 			Categories: []int{1234, 2345},
 			Exclude:    []string{"123"},
 			Keywords:   []string{"key1"},
 			PriceRange: 1,
-		})
+		}, 0)
 
 	expected := domain.Ads{
 		{ID: "123", UserID: 2, CategoryID: 2020,
@@ -171,6 +190,7 @@ func TestGetUserAdsOK(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, expected, userAds)
 	mSearch.AssertExpectations(t)
+	mLogger.AssertExpectations(t)
 	mConfig.AssertExpectations(t)
 	mResults.AssertExpectations(t)
 	mQuery.AssertExpectations(t)
@@ -214,21 +234,23 @@ func TestGetUserAdsWithFilledGaps(t *testing.T) {
 		[]byte(`{"ListID": 123, "UserID": 2, "CategoryID": 2020, "Subject": "Autito"}`),
 	}
 
-	mSearch.On("Search", mock.AnythingOfType("string"),
+	mSearch.On("Search", mock.Anything, mock.AnythingOfType("string"),
 		mock.Anything,
 		mock.AnythingOfType("int"),
 		mock.AnythingOfType("int")).Return(mResults, nil)
 	mResults.On("GetResults").Return(results1).Once()
 	mResults.On("GetResults").Return(results2).Once()
 
-	mConfig.On("Get", mock.AnythingOfType("string")).Return("something")
+	mConfig.On("Get", mock.Anything, mock.AnythingOfType("string")).Return("something")
+	mLogger := &mockAdRepositoryLogger{}
+	mLogger.On("LogSearchResults", mock.Anything, "userId", 0, 1).Twice()
 	interactor := adRepo{
-		handler:         mSearch,
-		regionsConf:     mConfig,
-		maxAdsToDisplay: 20,
+		logger:      mLogger,
+		handler:     mSearch,
+		regionsConf: mConfig,
 	}
 
-	userAds, err := interactor.GetUserAds(0,
+	userAds, err := interactor.GetUserAds(context.Background(), 0,
 		domain.ProductParams{
 			Categories:         []int{1234, 2345},
 			Exclude:            []string{"123"},
@@ -236,7 +258,7 @@ func TestGetUserAdsWithFilledGaps(t *testing.T) {
 			PriceRange:         1,
 			FillGapsWithRandom: true,
 			Limit:              2,
-		})
+		}, 20)
 
 	expected := domain.Ads{
 		{ID: "1234", UserID: 2, CategoryID: 2020,
@@ -247,6 +269,7 @@ func TestGetUserAdsWithFilledGaps(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, expected, userAds)
 	mSearch.AssertExpectations(t)
+	mLogger.AssertExpectations(t)
 	mConfig.AssertExpectations(t)
 	mResults.AssertExpectations(t)
 	mQuery.AssertExpectations(t)
@@ -286,28 +309,31 @@ func TestGetUserAdsZeroResults(t *testing.T) {
 
 	results := []json.RawMessage{}
 
-	mSearch.On("Search", mock.AnythingOfType("string"),
+	mSearch.On("Search", mock.Anything, mock.AnythingOfType("string"),
 		mock.Anything,
 		mock.AnythingOfType("int"),
 		mock.AnythingOfType("int")).Return(mResults, nil)
 
 	mResults.On("GetResults").Return(results)
+	mLogger := &mockAdRepositoryLogger{}
+	mLogger.On("LogSearchResults", mock.Anything, "userId", 0, 0)
 	interactor := adRepo{
-		handler:         mSearch,
-		regionsConf:     mConfig,
-		maxAdsToDisplay: 20,
+		logger:      mLogger,
+		handler:     mSearch,
+		regionsConf: mConfig,
 	}
 
-	_, err := interactor.GetUserAds(0,
+	_, err := interactor.GetUserAds(context.Background(), 0,
 		domain.ProductParams{
 			Categories: []int{1234, 2345},
 			Exclude:    []string{"123"},
 			Keywords:   []string{"key1"},
 			PriceRange: 1,
-		})
+		}, 20)
 
-	assert.Error(t, err)
+	assert.True(t, errors.Is(err, usecases.ErrNotEnoughAds))
 	mSearch.AssertExpectations(t)
+	mLogger.AssertExpectations(t)
 	mConfig.AssertExpectations(t)
 	mResults.AssertExpectations(t)
 	mQuery.AssertExpectations(t)
@@ -345,26 +371,29 @@ func TestGetUserAdsSearchError(t *testing.T) {
 		mock.AnythingOfType("string"),
 		mock.AnythingOfType("bool")).Return(mQuery)
 
-	mSearch.On("Search", mock.AnythingOfType("string"),
+	mSearch.On("Search", mock.Anything, mock.AnythingOfType("string"),
 		mock.Anything,
 		mock.AnythingOfType("int"),
 		mock.AnythingOfType("int")).Return(mResults, fmt.Errorf("e"))
 
+	mLogger := &mockAdRepositoryLogger{}
 	interactor := adRepo{
+		logger:      mLogger,
 		handler:     mSearch,
 		regionsConf: mConfig,
 	}
 
-	_, err := interactor.GetUserAds(0,
+	_, err := interactor.GetUserAds(context.Background(), 0,
 		domain.ProductParams{
 			Categories: []int{1234, 2345},
 			Exclude:    []string{"123"},
 			Keywords:   []string{"key1"},
 			PriceRange: 1,
-		})
+		}, 0)
 
 	assert.Error(t, err)
 	mSearch.AssertExpectations(t)
+	mLogger.AssertExpectations(t)
 	mConfig.AssertExpectations(t)
 	mResults.AssertExpectations(t)
 	mQuery.AssertExpectations(t)
@@ -399,21 +428,22 @@ func TestGetMainImage(t *testing.T) {
 func TestGetAdOK(t *testing.T) {
 	mSearch := &mockSearch{}
 	mConfig := &mockConfig{}
+	mLogger := &mockAdRepositoryLogger{}
 	interactor := adRepo{
-		handler:         mSearch,
-		regionsConf:     mConfig,
-		maxAdsToDisplay: 20,
+		logger:      mLogger,
+		handler:     mSearch,
+		regionsConf: mConfig,
 	}
 	var result json.RawMessage
 	result = []byte(`{"ListID": 123,
 	 "UserID": 2, "CategoryID": 2020, "Subject": "Autito"}`)
-	mSearch.On("GetDoc",
+	mSearch.On("GetDoc", mock.Anything,
 		mock.AnythingOfType("string"),
 		mock.AnythingOfType("string"),
 	).Return(result, nil)
-	mConfig.On("Get", mock.AnythingOfType("string")).Return("something")
+	mConfig.On("Get", mock.Anything, mock.AnythingOfType("string")).Return("something")
 
-	userAds, err := interactor.GetAd("123")
+	userAds, err := interactor.GetAd(context.Background(), "123")
 
 	expected := domain.Ad{ID: "123", UserID: 2, CategoryID: 2020,
 		Subject: "Autito", URL: "/something/autito_123", IsRelated: true}
@@ -421,48 +451,75 @@ func TestGetAdOK(t *testing.T) {
 	assert.Equal(t, expected, userAds)
 	mConfig.AssertExpectations(t)
 	mSearch.AssertExpectations(t)
+	mLogger.AssertExpectations(t)
 }
 
 func TestGetAdGetDocError(t *testing.T) {
 	mSearch := &mockSearch{}
 	mConfig := &mockConfig{}
+	mLogger := &mockAdRepositoryLogger{}
 	interactor := adRepo{
+		logger:      mLogger,
 		handler:     mSearch,
 		regionsConf: mConfig,
 	}
 	var result json.RawMessage
 	result = []byte(`{"ListID": 123,
 	 "UserID": 2, "CategoryID": 2020, "Subject": "Autito"}`)
-	mSearch.On("GetDoc",
+	mSearch.On("GetDoc", mock.Anything,
 		mock.AnythingOfType("string"),
 		mock.AnythingOfType("string"),
 	).Return(result, fmt.Errorf("e"))
 
-	_, err := interactor.GetAd("123")
+	_, err := interactor.GetAd(context.Background(), "123")
 
 	assert.Error(t, err)
 	mConfig.AssertExpectations(t)
 	mSearch.AssertExpectations(t)
+	mLogger.AssertExpectations(t)
 }
 
 func TestGetAdUnmarshalError(t *testing.T) {
 	mSearch := &mockSearch{}
 	mConfig := &mockConfig{}
+	mLogger := &mockAdRepositoryLogger{}
 	interactor := adRepo{
+		logger:      mLogger,
 		handler:     mSearch,
 		regionsConf: mConfig,
 	}
 	var result json.RawMessage
 	result = []byte(`{"ListID": 123,
 	 "UserID": 2, "CategoryID": 2020, "Subject": "`)
-	mSearch.On("GetDoc",
+	mSearch.On("GetDoc", mock.Anything,
 		mock.AnythingOfType("string"),
 		mock.AnythingOfType("string"),
 	).Return(result, nil)
 
-	_, err := interactor.GetAd("123")
+	_, err := interactor.GetAd(context.Background(), "123")
 
 	assert.Error(t, err)
 	mConfig.AssertExpectations(t)
 	mSearch.AssertExpectations(t)
+	mLogger.AssertExpectations(t)
+}
+
+func TestGetAdParseError(t *testing.T) {
+	mSearch := &mockSearch{}
+	mQuery := &mockQuery{}
+	mResults := &mockSearchResult{}
+	mLogger := &mockAdRepositoryLogger{}
+	mSearch.On("NewTermQuery", "listId", "123").Return(mQuery)
+	mSearch.On("Search", mock.Anything, mock.AnythingOfType("string"), mQuery, 0, 10).Return(mResults, nil)
+	mResults.On("GetResults").Return([]json.RawMessage{[]byte(`{"ListID": "`)})
+	mLogger.On("LogErrorParsingAd", mock.Anything, mock.Anything)
+	mLogger.On("LogSearchResults", mock.Anything, "listId", "123", 0)
+	repo := adRepo{handler: mSearch, logger: mLogger}
+
+	_, err := repo.GetAd(context.Background(), "123")
+
+	assert.Equal(t, usecases.ErrAdNotFound, err)
+	mSearch.AssertExpectations(t)
+	mResults.AssertExpectations(t)
+	mLogger.AssertExpectations(t)
 }
@@ -1,8 +1,11 @@
 package repository
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
+	"time"
 
 	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/domain"
 	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/usecases"
@@ -10,21 +13,42 @@ import (
 
 // producer allows to push events to queue
 type producer struct {
-	handler              KafkaProducer
+	sender               eventSender
 	premiumProductsTopic string
 }
 
+// eventSender delivers the encoded events, key tells the events that must
+// keep their order
+type eventSender interface {
+	send(ctx context.Context, topic, key string, message []byte) error
+}
+
+// kafkaSender sends the events straight to kafka
+type kafkaSender struct {
+	handler KafkaProducer
+}
+
+func (s kafkaSender) send(ctx context.Context, topic, key string, message []byte) error {
+	return s.handler.SendKeyedMessage(topic, []byte(key), message)
+}
+
 // MakeBackendEventsProducer creates new instance of Producer for backend events
 func MakeBackendEventsProducer(handler KafkaProducer, premiumProductsTopic string) usecases.BackendEventsRepository {
 	return &producer{
-		handler:              handler,
+		sender:               kafkaSender{handler: handler},
 		premiumProductsTopic: premiumProductsTopic,
 	}
 }
 
+// eventsVersion is the version of the events payload. It must be increased
+// on every change that is not backwards compatible, so consumers can tell the
+// payloads apart
+const eventsVersion = 1
+
 // kafkaMessage defines the valid input supported by backend events
 type kafkaMessage struct {
 	Type      EventType   `json:"type"`
+	Version   int         `json:"version"`
 	Date      string      `json:"date"`
 	Timestamp string      `json:"ts_utc"`
 	Content   interface{} `json:"content"`
@@ -36,35 +60,146 @@ type EventType string
 const (
 	// PremiumCarouselPurchase represents a premium carousel purchase event type
 	PremiumCarouselPurchase EventType = "premium_carousel_purchase"
+	// PremiumCarouselExpirationReminder represents a reminder sent before a
+	// premium carousel expires
+	PremiumCarouselExpirationReminder EventType = "premium_carousel_expiration_reminder"
+	// PremiumCarouselConfigChange represents a change of the premium carousel
+	// configuration
+	PremiumCarouselConfigChange EventType = "premium_carousel_config_change"
+	// PremiumCarouselStatusChange represents a change of the premium carousel
+	// status
+	PremiumCarouselStatusChange EventType = "premium_carousel_status_change"
+	// PremiumCarouselExpiration represents a premium carousel reaching its
+	// expiration date
+	PremiumCarouselExpiration EventType = "premium_carousel_expiration"
+	// PremiumCarouselExtension represents a premium carousel whose expiration
+	// was postponed
+	PremiumCarouselExtension EventType = "premium_carousel_extension"
+	// PremiumCarouselCancellation represents an active premium carousel
+	// stopped before its expiration
+	PremiumCarouselCancellation EventType = "premium_carousel_cancellation"
 )
 
 // Push pushes given product to backend events through purchases topic
-func (p *producer) PushSoldProduct(product domain.Product) error {
+func (p *producer) PushSoldProduct(ctx context.Context, product domain.Product) error {
+	switch product.Type {
+	case domain.PremiumCarousel:
+		return p.push(ctx, PremiumCarouselPurchase, product.ID, product.CreatedAt,
+			makeProductContent(product))
+	default:
+		return fmt.Errorf("Product not supported")
+	}
+}
+
+// PushExpirationReminder pushes a reminder of the product expiration, window
+// is how long before the expiration the reminder is sent
+func (p *producer) PushExpirationReminder(ctx context.Context, product domain.Product,
+	window time.Duration) error {
+	switch product.Type {
+	case domain.PremiumCarousel:
+		content := makeProductContent(product)
+		content["window_hours"] = int(window.Hours())
+		return p.push(ctx, PremiumCarouselExpirationReminder, product.ID, time.Now(), content)
+	default:
+		return fmt.Errorf("Product not supported")
+	}
+}
+
+// PushConfigChange pushes the new product configuration along with the
+// previous one
+func (p *producer) PushConfigChange(ctx context.Context, product domain.Product,
+	previous domain.ProductParams) error {
+	content := makeProductContent(product)
+	content["config"] = makeConfigContent(product.Config)
+	content["previous_config"] = makeConfigContent(previous)
+	return p.pushProductEvent(ctx, PremiumCarouselConfigChange, product, content)
+}
+
+// PushStatusChange pushes the new product status along with the previous one
+func (p *producer) PushStatusChange(ctx context.Context, product domain.Product,
+	previous domain.ProductStatus) error {
+	content := makeProductContent(product)
+	content["previous_status"] = previous
+	return p.pushProductEvent(ctx, PremiumCarouselStatusChange, product, content)
+}
+
+// PushExpiration pushes the expiration of the product
+func (p *producer) PushExpiration(ctx context.Context, product domain.Product) error {
+	return p.pushProductEvent(ctx, PremiumCarouselExpiration, product,
+		makeProductContent(product))
+}
+
+// PushExtension pushes the new product expiration along with the previous one
+func (p *producer) PushExtension(ctx context.Context, product domain.Product,
+	previous time.Time) error {
+	content := makeProductContent(product)
+	content["previous_expired_at"] = previous.String()
+	return p.pushProductEvent(ctx, PremiumCarouselExtension, product, content)
+}
+
+// PushCancellation pushes the cancellation of the product, previous is the
+// status it had before
+func (p *producer) PushCancellation(ctx context.Context, product domain.Product,
+	previous domain.ProductStatus) error {
+	content := makeProductContent(product)
+	content["previous_status"] = previous
+	return p.pushProductEvent(ctx, PremiumCarouselCancellation, product, content)
+}
+
+// pushProductEvent pushes a lifecycle event of the product dated now
+func (p *producer) pushProductEvent(ctx context.Context, eventType EventType,
+	product domain.Product,
+	content map[string]interface{}) error {
 	switch product.Type {
 	case domain.PremiumCarousel:
-		content := map[string]interface{}{
-			"id":              product.ID,
-			"type":            product.Type,
-			"user_id":         product.UserID,
-			"email":           product.Email,
-			"purchase_id":     product.Purchase.ID,
-			"purchase_number": product.Purchase.Number,
-			"purchase_price":  product.Purchase.Price,
-			"purchase_status": product.Purchase.Status,
-			"purchase_type":   product.Purchase.Type,
-			"status":          product.Status,
-			"expired_at":      product.ExpiredAt.String(),
-			"created_at":      product.CreatedAt.String(),
-		}
-		message := kafkaMessage{
-			Type:      PremiumCarouselPurchase,
-			Date:      product.CreatedAt.Format("2006-01-02 15:04:05"),
-			Timestamp: fmt.Sprintf("%d", product.CreatedAt.Unix()),
-			Content:   content,
-		}
-		bytes, _ := json.Marshal(message) // nolint
-		return p.handler.SendMessage(p.premiumProductsTopic, bytes)
+		return p.push(ctx, eventType, product.ID, time.Now(), content)
 	default:
 		return fmt.Errorf("Product not supported")
 	}
 }
+
+// push sends an event of the given type through the purchases topic, keyed
+// by the product so its events keep their order
+func (p *producer) push(ctx context.Context, eventType EventType, productID int,
+	date time.Time, content map[string]interface{}) error {
+	message := kafkaMessage{
+		Type:      eventType,
+		Version:   eventsVersion,
+		Date:      date.Format("2006-01-02 15:04:05"),
+		Timestamp: fmt.Sprintf("%d", date.Unix()),
+		Content:   content,
+	}
+	bytes, _ := json.Marshal(message) // nolint
+	return p.sender.send(ctx, p.premiumProductsTopic, strconv.Itoa(productID), bytes)
+}
+
+// makeProductContent returns the event content describing the product
+func makeProductContent(product domain.Product) map[string]interface{} {
+	return map[string]interface{}{
+		"id":              product.ID,
+		"type":            product.Type,
+		"user_id":         product.UserID,
+		"email":           product.Email,
+		"purchase_id":     product.Purchase.ID,
+		"purchase_number": product.Purchase.Number,
+		"purchase_price":  product.Purchase.Price,
+		"purchase_status": product.Purchase.Status,
+		"purchase_type":   product.Purchase.Type,
+		"status":          product.Status,
+		"expired_at":      product.ExpiredAt.String(),
+		"created_at":      product.CreatedAt.String(),
+	}
+}
+
+// makeConfigContent returns the event content describing the product config
+func makeConfigContent(config domain.ProductParams) map[string]interface{} {
+	return map[string]interface{}{
+		"categories":  config.Categories,
+		"exclude":     config.Exclude,
+		"keywords":    config.Keywords,
+		"limit":       config.Limit,
+		"price_range": config.PriceRange,
+		"fill_random": config.FillGapsWithRandom,
+		"comment":     config.Comment,
+	}
+}
@@ -1,12 +1,16 @@
 package repository
 
 import (
+	"context"
+	"encoding/json"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 
 	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/domain"
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/usecases"
 )
 
 type mockKafkaProducer struct {
@@ -18,6 +22,22 @@ func (m *mockKafkaProducer) SendMessage(topic string, bytes []byte) error {
 	return args.Error(0)
 }
 
+func (m *mockKafkaProducer) SendKeyedMessage(topic string, key, bytes []byte) error {
+	args := m.Called(topic, key, bytes)
+	return args.Error(0)
+}
+
+// SendMessageAsync returns the first error and reports the second one on
+// delivery when the message is enqueued
+func (m *mockKafkaProducer) SendMessageAsync(topic string, key, bytes []byte, onDelivery func(error)) error {
+	args := m.Called(topic, key, bytes)
+	if err := args.Error(0); err != nil {
+		return err
+	}
+	go onDelivery(args.Error(1))
+	return nil
+}
+
 func (m *mockKafkaProducer) Close() error {
 	args := m.Called()
 	return args.Error(0)
@@ -25,10 +45,10 @@ func (m *mockKafkaProducer) Close() error {
 
 func TestPushSoldProductOK(t *testing.T) {
 	mProducer := &mockKafkaProducer{}
-	mProducer.On("SendMessage", mock.AnythingOfType("string"),
-		mock.AnythingOfType("[]uint8")).Return(nil)
+	mProducer.On("SendKeyedMessage", mock.AnythingOfType("string"),
+		[]byte("0"), mock.AnythingOfType("[]uint8")).Return(nil)
 	repo := MakeBackendEventsProducer(mProducer, "")
-	err := repo.PushSoldProduct(domain.Product{Type: domain.PremiumCarousel})
+	err := repo.PushSoldProduct(context.Background(), domain.Product{Type: domain.PremiumCarousel})
 	assert.NoError(t, err)
 	mProducer.AssertExpectations(t)
 }
@@ -37,7 +57,99 @@ func TestPushSoldProductErrorProductNotSupoorted(t *testing.T) {
 	mProducer := &mockKafkaProducer{}
 
 	repo := MakeBackendEventsProducer(mProducer, "")
-	err := repo.PushSoldProduct(domain.Product{Type: "arepa"})
+	err := repo.PushSoldProduct(context.Background(), domain.Product{Type: "arepa"})
+	assert.Error(t, err)
+	mProducer.AssertExpectations(t)
+}
+
+func TestPushExpirationReminderOK(t *testing.T) {
+	mProducer := &mockKafkaProducer{}
+	var message map[string]interface{}
+	mProducer.On("SendKeyedMessage", "topic", mock.Anything,
+		mock.AnythingOfType("[]uint8")).
+		Run(func(args mock.Arguments) {
+			json.Unmarshal(args.Get(2).([]byte), &message) // nolint
+		}).Return(nil)
+	repo := MakeBackendEventsProducer(mProducer, "topic")
+	err := repo.PushExpirationReminder(context.Background(), domain.Product{ID: 7,
+		Type: domain.PremiumCarousel}, 24*time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, string(PremiumCarouselExpirationReminder), message["type"])
+	content := message["content"].(map[string]interface{})
+	assert.Equal(t, float64(7), content["id"])
+	assert.Equal(t, float64(24), content["window_hours"])
+	mProducer.AssertExpectations(t)
+}
+
+func TestPushExpirationReminderErrorProductNotSupported(t *testing.T) {
+	mProducer := &mockKafkaProducer{}
+	repo := MakeBackendEventsProducer(mProducer, "")
+	err := repo.PushExpirationReminder(context.Background(), domain.Product{Type: "arepa"}, time.Hour)
+	assert.Error(t, err)
+	mProducer.AssertExpectations(t)
+}
+
+func TestPushLifecycleEventsOK(t *testing.T) {
+	product := domain.Product{ID: 7, Type: domain.PremiumCarousel,
+		Status: domain.ExpiredProduct}
+	pushes := map[EventType]func(repo usecases.BackendEventsRepository) error{
+		PremiumCarouselConfigChange: func(repo usecases.BackendEventsRepository) error {
+			return repo.PushConfigChange(context.Background(), product, domain.ProductParams{Limit: 5})
+		},
+		PremiumCarouselStatusChange: func(repo usecases.BackendEventsRepository) error {
+			return repo.PushStatusChange(context.Background(), product, domain.ActiveProduct)
+		},
+		PremiumCarouselExpiration: func(repo usecases.BackendEventsRepository) error {
+			return repo.PushExpiration(context.Background(), product)
+		},
+		PremiumCarouselExtension: func(repo usecases.BackendEventsRepository) error {
+			return repo.PushExtension(context.Background(), product, time.Now())
+		},
+		PremiumCarouselCancellation: func(repo usecases.BackendEventsRepository) error {
+			return repo.PushCancellation(context.Background(), product, domain.ActiveProduct)
+		},
+	}
+	for eventType, push := range pushes {
+		mProducer := &mockKafkaProducer{}
+		var message map[string]interface{}
+		mProducer.On("SendKeyedMessage", "topic", []byte("7"),
+			mock.AnythingOfType("[]uint8")).
+			Run(func(args mock.Arguments) {
+				json.Unmarshal(args.Get(2).([]byte), &message) // nolint
+			}).Return(nil)
+		err := push(MakeBackendEventsProducer(mProducer, "topic"))
+		assert.NoError(t, err)
+		assert.Equal(t, string(eventType), message["type"])
+		assert.Equal(t, float64(eventsVersion), message["version"])
+		content := message["content"].(map[string]interface{})
+		assert.Equal(t, float64(7), content["id"])
+		assert.Equal(t, string(domain.ExpiredProduct), content["status"])
+		mProducer.AssertExpectations(t)
+	}
+}
+
+func TestPushConfigChangeContent(t *testing.T) {
+	mProducer := &mockKafkaProducer{}
+	var message map[string]interface{}
+	mProducer.On("SendKeyedMessage", "topic", mock.Anything,
+		mock.AnythingOfType("[]uint8")).
+		Run(func(args mock.Arguments) {
+			json.Unmarshal(args.Get(2).([]byte), &message) // nolint
+		}).Return(nil)
+	repo := MakeBackendEventsProducer(mProducer, "topic")
+	err := repo.PushConfigChange(context.Background(), domain.Product{Type: domain.PremiumCarousel,
+		Config: domain.ProductParams{Limit: 20}}, domain.ProductParams{Limit: 10})
+	assert.NoError(t, err)
+	content := message["content"].(map[string]interface{})
+	assert.Equal(t, float64(20), content["config"].(map[string]interface{})["limit"])
+	assert.Equal(t, float64(10), content["previous_config"].(map[string]interface{})["limit"])
+	mProducer.AssertExpectations(t)
+}
+
+func TestPushExpirationErrorProductNotSupported(t *testing.T) {
+	mProducer := &mockKafkaProducer{}
+	repo := MakeBackendEventsProducer(mProducer, "")
+	err := repo.PushExpiration(context.Background(), domain.Product{Type: "arepa"})
 	assert.Error(t, err)
 	mProducer.AssertExpectations(t)
 }
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"encoding/json"
 	"strings"
 	"time"
@@ -31,9 +32,10 @@ func (repo *cacheRepository) makeRedisKey(key string, cacheType usecases.CacheTy
 }
 
 // GetCache returns the response of a cached request
-func (repo *cacheRepository) GetCache(key string, cacheType usecases.CacheType) ([]byte, error) {
+func (repo *cacheRepository) GetCache(ctx context.Context, key string,
+	cacheType usecases.CacheType) ([]byte, error) {
 	k := repo.makeRedisKey(key, cacheType)
-	res, err := repo.handler.Get(k)
+	res, err := repo.handler.Get(ctx, k)
 	if err != nil {
 		return nil, err
 	}
@@ -41,7 +43,7 @@ func (repo *cacheRepository) GetCache(key string, cacheType usecases.CacheType)
 }
 
 // SetCache saves the response of request in redis
-func (repo *cacheRepository) SetCache(key string, cacheType usecases.CacheType,
+func (repo *cacheRepository) SetCache(ctx context.Context, key string, cacheType usecases.CacheType,
 	data interface{}, expiration time.Duration) error {
 	if expiration <= 0 {
 		expiration = repo.defaultExpiration
@@ -49,7 +51,13 @@ func (repo *cacheRepository) SetCache(key string, cacheType usecases.CacheType,
 	k := repo.makeRedisKey(key, cacheType)
 	data = repo.minifyCache(cacheType, data)
 	bytes, _ := json.Marshal(data) // nolint
-	return repo.handler.Set(k, bytes, expiration)
+	return repo.handler.Set(ctx, k, bytes, expiration)
+}
+
+// DelCache removes a cached response from redis
+func (repo *cacheRepository) DelCache(ctx context.Context, key string,
+	cacheType usecases.CacheType) error {
+	return repo.handler.Del(ctx, repo.makeRedisKey(key, cacheType))
 }
 
 // minifyCache tries to reduce known cache types
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"fmt"
 	"testing"
 	"time"
@@ -25,28 +26,33 @@ type mockRedis struct {
 	mock.Mock
 }
 
-func (m *mockRedis) HGetAll(key string) (map[string]string, bool) {
-	args := m.Called(key)
+func (m *mockRedis) HGetAll(ctx context.Context, key string) (map[string]string, bool) {
+	args := m.Called(ctx, key)
 	return args.Get(0).(map[string]string), args.Bool(1)
 }
 
-func (m *mockRedis) HGet(key, field string) (string, bool) {
-	args := m.Called(key)
+func (m *mockRedis) HGet(ctx context.Context, key, field string) (string, bool) {
+	args := m.Called(ctx, key)
 	return args.String(0), args.Bool(1)
 }
 
-func (m *mockRedis) Set(key string, value interface{}, expiration time.Duration) error {
-	args := m.Called(key, value, expiration)
+func (m *mockRedis) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	args := m.Called(ctx, key, value, expiration)
 	return args.Error(0)
 }
 
-func (m *mockRedis) Get(key string) (RedisResult, error) {
-	args := m.Called(key)
+func (m *mockRedis) SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) (bool, error) {
+	args := m.Called(ctx, key, value, expiration)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *mockRedis) Get(ctx context.Context, key string) (RedisResult, error) {
+	args := m.Called(ctx, key)
 	return args.Get(0).(RedisResult), args.Error(1)
 }
 
-func (m *mockRedis) Del(key string) error {
-	args := m.Called(key)
+func (m *mockRedis) Del(ctx context.Context, key string) error {
+	args := m.Called(ctx, key)
 	return args.Error(0)
 }
 
@@ -69,8 +75,8 @@ func TestGetCache(t *testing.T) {
 		defaultExpiration: time.Hour,
 	}
 	mResult.On("Bytes").Return([]byte{}, nil)
-	m.On("Get", mock.AnythingOfType("string")).Return(mResult, nil)
-	result, err := repo.GetCache(`some-key`, usecases.ProductCacheType)
+	m.On("Get", mock.Anything, mock.AnythingOfType("string")).Return(mResult, nil)
+	result, err := repo.GetCache(context.Background(), `some-key`, usecases.ProductCacheType)
 	assert.NoError(t, err)
 	assert.Equal(t, []byte{}, result)
 	m.AssertExpectations(t)
@@ -84,8 +90,8 @@ func TestGetCacheErrorInRedis(t *testing.T) {
 		handler:           m,
 		defaultExpiration: time.Hour,
 	}
-	m.On("Get", mock.AnythingOfType("string")).Return(mResult, fmt.Errorf("err"))
-	_, err := repo.GetCache(`some-key`, usecases.ProductCacheType)
+	m.On("Get", mock.Anything, mock.AnythingOfType("string")).Return(mResult, fmt.Errorf("err"))
+	_, err := repo.GetCache(context.Background(), `some-key`, usecases.ProductCacheType)
 	assert.Error(t, err)
 	m.AssertExpectations(t)
 	mResult.AssertExpectations(t)
@@ -98,9 +104,9 @@ func TestSetCacheOK(t *testing.T) {
 		handler:           m,
 		defaultExpiration: time.Hour,
 	}
-	m.On("Set", mock.AnythingOfType("string"), mock.AnythingOfType("[]uint8"),
+	m.On("Set", mock.Anything, mock.AnythingOfType("string"), mock.AnythingOfType("[]uint8"),
 		mock.AnythingOfType("time.Duration")).Return(nil)
-	err := repo.SetCache(`some-key`, usecases.MinifiedAdDataType, domain.Ad{}, 0)
+	err := repo.SetCache(context.Background(), `some-key`, usecases.MinifiedAdDataType, domain.Ad{}, 0)
 	assert.NoError(t, err)
 	m.AssertExpectations(t)
 	mResult.AssertExpectations(t)
@@ -134,3 +140,12 @@ func TestMinifyCacheOK(t *testing.T) {
 	m.AssertExpectations(t)
 	mResult.AssertExpectations(t)
 }
+
+func TestDelCacheOK(t *testing.T) {
+	m := &mockRedis{}
+	repo := NewCacheRepository(m, "", time.Hour)
+	m.On("Del", mock.Anything, "ad:1:cache-minified-ad-data").Return(nil)
+	err := repo.DelCache(context.Background(), "ad:1", usecases.MinifiedAdDataType)
+	assert.NoError(t, err)
+	m.AssertExpectations(t)
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/usecases"
+)
+
+// idempotencyRepository stores idempotency records using redis handler
+type idempotencyRepository struct {
+	handler        Redis
+	prefix         string
+	lockExpiration time.Duration
+	expiration     time.Duration
+}
+
+// MakeIdempotencyRepository returns an instance of IdempotencyRepository.
+// Reserved keys are locked during lockExpiration, so they are freed soon when
+// the operation never ends, and saved records are kept during expiration
+func MakeIdempotencyRepository(handler Redis, prefix string,
+	lockExpiration, expiration time.Duration) usecases.IdempotencyRepository {
+	return &idempotencyRepository{
+		handler:        handler,
+		prefix:         prefix,
+		lockExpiration: lockExpiration,
+		expiration:     expiration,
+	}
+}
+
+// makeRedisKey generates key for redis
+func (repo *idempotencyRepository) makeRedisKey(key string) string {
+	return strings.Join([]string{repo.prefix, "idempotency", key}, ":")
+}
+
+// Reserve stores the record only if the key is not already taken, locking
+// the key during the lock expiration
+func (repo *idempotencyRepository) Reserve(ctx context.Context, key string,
+	record usecases.IdempotencyRecord) (bool, error) {
+	bytes, _ := json.Marshal(record) // nolint
+	return repo.handler.SetNX(ctx, repo.makeRedisKey(key), bytes, repo.lockExpiration)
+}
+
+// Get returns the record stored with the key
+func (repo *idempotencyRepository) Get(ctx context.Context,
+	key string) (usecases.IdempotencyRecord, error) {
+	record := usecases.IdempotencyRecord{}
+	res, err := repo.handler.Get(ctx, repo.makeRedisKey(key))
+	if errors.Is(err, ErrKeyNotFound) {
+		return record, usecases.ErrIdempotencyRecordNotFound
+	}
+	if err != nil {
+		return record, err
+	}
+	bytes, err := res.Bytes()
+	if err != nil {
+		return record, err
+	}
+	err = json.Unmarshal(bytes, &record)
+	return record, err
+}
+
+// Save stores the record overwriting the previous one, keeping it during the
+// whole expiration
+func (repo *idempotencyRepository) Save(ctx context.Context, key string,
+	record usecases.IdempotencyRecord) error {
+	bytes, _ := json.Marshal(record) // nolint
+	return repo.handler.Set(ctx, repo.makeRedisKey(key), bytes, repo.expiration)
+}
+
+// Release deletes the record so the key may be used again
+func (repo *idempotencyRepository) Release(ctx context.Context, key string) error {
+	return repo.handler.Del(ctx, repo.makeRedisKey(key))
+}
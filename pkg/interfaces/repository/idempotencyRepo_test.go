@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/domain"
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/usecases"
+)
+
+func TestIdempotencyRepositoryReserve(t *testing.T) {
+	m := &mockRedis{}
+	repo := MakeIdempotencyRepository(m, "cache", time.Minute, time.Hour)
+	record := usecases.IdempotencyRecord{Fingerprint: "abc"}
+	bytes, _ := json.Marshal(record)
+	m.On("SetNX", mock.Anything, "cache:idempotency:key-1", bytes, time.Minute).Return(true, nil)
+	reserved, err := repo.Reserve(context.Background(), "key-1", record)
+	assert.NoError(t, err)
+	assert.True(t, reserved)
+	m.AssertExpectations(t)
+}
+
+func TestIdempotencyRepositoryGet(t *testing.T) {
+	m := &mockRedis{}
+	mResult := &mockRedisResult{}
+	repo := MakeIdempotencyRepository(m, "cache", time.Minute, time.Hour)
+	record := usecases.IdempotencyRecord{Fingerprint: "abc", Completed: true,
+		Product: domain.Product{ID: 7}}
+	bytes, _ := json.Marshal(record)
+	m.On("Get", mock.Anything, "cache:idempotency:key-1").Return(mResult, nil)
+	mResult.On("Bytes").Return(bytes, nil)
+	result, err := repo.Get(context.Background(), "key-1")
+	assert.NoError(t, err)
+	assert.Equal(t, record, result)
+	m.AssertExpectations(t)
+	mResult.AssertExpectations(t)
+}
+
+func TestIdempotencyRepositoryGetError(t *testing.T) {
+	m := &mockRedis{}
+	repo := MakeIdempotencyRepository(m, "cache", time.Minute, time.Hour)
+	m.On("Get", mock.Anything, "cache:idempotency:key-1").
+		Return(&mockRedisResult{}, fmt.Errorf("KEY_NOT_FOUND"))
+	_, err := repo.Get(context.Background(), "key-1")
+	assert.Error(t, err)
+	m.AssertExpectations(t)
+}
+
+func TestIdempotencyRepositoryGetNotFound(t *testing.T) {
+	m := &mockRedis{}
+	repo := MakeIdempotencyRepository(m, "cache", time.Minute, time.Hour)
+	m.On("Get", mock.Anything, "cache:idempotency:key-1").
+		Return(&mockRedisResult{}, fmt.Errorf("%w: cache:idempotency:key-1", ErrKeyNotFound))
+	_, err := repo.Get(context.Background(), "key-1")
+	assert.Equal(t, usecases.ErrIdempotencyRecordNotFound, err)
+	m.AssertExpectations(t)
+}
+
+func TestIdempotencyRepositorySaveAndRelease(t *testing.T) {
+	m := &mockRedis{}
+	repo := MakeIdempotencyRepository(m, "cache", time.Minute, time.Hour)
+	record := usecases.IdempotencyRecord{Fingerprint: "abc", Completed: true}
+	bytes, _ := json.Marshal(record)
+	m.On("Set", mock.Anything, "cache:idempotency:key-1", bytes, time.Hour).Return(nil)
+	m.On("Del", mock.Anything, "cache:idempotency:key-1").Return(nil)
+	assert.NoError(t, repo.Save(context.Background(), "key-1", record))
+	assert.NoError(t, repo.Release(context.Background(), "key-1"))
+	m.AssertExpectations(t)
+}
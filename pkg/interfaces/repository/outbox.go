@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/usecases"
+)
+
+// outboxSender stores the events in the outbox table, to be published later
+// by the relay
+type outboxSender struct {
+	handler DbHandler
+}
+
+func (s outboxSender) send(ctx context.Context, topic, key string, message []byte) error {
+	return s.handler.Insert(ctx,
+		`INSERT INTO backend_event_outbox(event_key, topic, payload)
+			VALUES ($1, $2, $3)`, key, topic, string(message))
+}
+
+// MakeBackendEventsOutbox creates a new instance of BackendEventsRepository
+// that stores the events in the outbox. When the handler is a transaction the
+// events are stored only if it is committed
+func MakeBackendEventsOutbox(handler DbHandler,
+	premiumProductsTopic string) usecases.BackendEventsRepository {
+	return &producer{
+		sender:               outboxSender{handler: handler},
+		premiumProductsTopic: premiumProductsTopic,
+	}
+}
+
+// outboxRepo holds connections to the outbox table
+type outboxRepo struct {
+	handler DbHandler
+}
+
+// MakeOutboxRepository creates a new instance of OutboxRepository
+func MakeOutboxRepository(handler DbHandler) usecases.OutboxRepository {
+	return &outboxRepo{
+		handler: handler,
+	}
+}
+
+// GetPendingEvents returns the oldest events not sent yet whose attempt is
+// due. Events after one of the same key waiting for a retry are left out so
+// each key keeps its order
+func (repo *outboxRepo) GetPendingEvents(ctx context.Context, limit int) ([]usecases.OutboxEvent, error) {
+	result, err := repo.handler.Query(ctx,
+		`SELECT o.id, o.event_key, o.topic, o.payload, o.attempts
+			FROM backend_event_outbox o
+			WHERE o.sent_at IS NULL AND o.next_attempt_at <= NOW()
+				AND NOT EXISTS (
+					SELECT 1 FROM backend_event_outbox b
+					WHERE b.sent_at IS NULL AND b.event_key = o.event_key
+						AND b.id < o.id AND b.next_attempt_at > NOW()
+				)
+			ORDER BY o.id
+			LIMIT $1`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer result.Close()
+	events := []usecases.OutboxEvent{}
+	for result.Next() {
+		var event usecases.OutboxEvent
+		var payload string
+		result.Scan(&event.ID, &event.Key, &event.Topic, &payload, &event.Attempts)
+		event.Payload = []byte(payload)
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// MarkSent records the event as published
+func (repo *outboxRepo) MarkSent(ctx context.Context, eventID int) error {
+	return repo.handler.Update(ctx,
+		`UPDATE backend_event_outbox SET sent_at = NOW() WHERE id = $1`, eventID)
+}
+
+// MarkFailed records a failed attempt to publish the event, scheduling the
+// next one
+func (repo *outboxRepo) MarkFailed(ctx context.Context, eventID int, cause error,
+	next time.Time) error {
+	return repo.handler.Update(ctx,
+		`UPDATE backend_event_outbox
+			SET attempts = attempts + 1, last_error = $2, next_attempt_at = $3
+			WHERE id = $1`, eventID, cause.Error(), next)
+}
+
+// PurgeSent deletes the events sent before the given date
+func (repo *outboxRepo) PurgeSent(ctx context.Context, before time.Time) error {
+	return repo.handler.Update(ctx,
+		`DELETE FROM backend_event_outbox WHERE sent_at < $1`, before)
+}
+
+// errHeldBack is returned for the events not sent because a previous one of
+// the same key failed
+var errHeldBack = fmt.Errorf("held back by a previous event of the same key")
+
+// outboxPublisher publishes the outbox events to kafka one by one, waiting
+// for each of them
+type outboxPublisher struct {
+	handler KafkaProducer
+}
+
+// MakeOutboxPublisher creates a new instance of EventsPublisher
+func MakeOutboxPublisher(handler KafkaProducer) usecases.EventsPublisher {
+	return &outboxPublisher{
+		handler: handler,
+	}
+}
+
+// Publish sends the events keyed so events of the same key land on the same
+// partition. Once an event fails the next ones of its key are not sent
+func (p *outboxPublisher) Publish(ctx context.Context, events []usecases.OutboxEvent) []error {
+	errs := make([]error, len(events))
+	failed := map[string]bool{}
+	for i, event := range events {
+		if failed[event.Key] {
+			errs[i] = errHeldBack
+			continue
+		}
+		errs[i] = p.handler.SendKeyedMessage(event.Topic, []byte(event.Key), event.Payload)
+		if errs[i] != nil {
+			failed[event.Key] = true
+		}
+	}
+	return errs
+}
+
+// asyncOutboxPublisher publishes the outbox events to kafka enqueueing the
+// whole batch before waiting for the delivery reports
+type asyncOutboxPublisher struct {
+	handler KafkaProducer
+}
+
+// MakeAsyncOutboxPublisher creates a new instance of EventsPublisher that
+// doesn't wait for each event before sending the next one
+func MakeAsyncOutboxPublisher(handler KafkaProducer) usecases.EventsPublisher {
+	return &asyncOutboxPublisher{
+		handler: handler,
+	}
+}
+
+// Publish enqueues the events keyed and waits until kafka reports all of
+// them, so the events are only marked as sent once they are delivered
+func (p *asyncOutboxPublisher) Publish(ctx context.Context, events []usecases.OutboxEvent) []error {
+	errs := make([]error, len(events))
+	var wg sync.WaitGroup
+	for i, event := range events {
+		i := i
+		wg.Add(1)
+		err := p.handler.SendMessageAsync(event.Topic, []byte(event.Key), event.Payload,
+			func(err error) {
+				errs[i] = err
+				wg.Done()
+			})
+		if err != nil {
+			errs[i] = err
+			wg.Done()
+		}
+	}
+	wg.Wait()
+	return errs
+}
@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/domain"
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/usecases"
+)
+
+func TestBackendEventsOutboxPushSoldProductOK(t *testing.T) {
+	mockDB := &dbHandlerMock{}
+	repo := MakeBackendEventsOutbox(mockDB, "topic")
+	mockDB.On("Insert", mock.Anything, mock.AnythingOfType("string"),
+		mock.MatchedBy(func(params []interface{}) bool {
+			return len(params) == 3 && params[0] == "7" && params[1] == "topic"
+		})).Return(nil)
+	err := repo.PushSoldProduct(context.Background(), domain.Product{ID: 7, Type: domain.PremiumCarousel})
+	assert.NoError(t, err)
+	mockDB.AssertExpectations(t)
+}
+
+func TestBackendEventsOutboxPushError(t *testing.T) {
+	mockDB := &dbHandlerMock{}
+	repo := MakeBackendEventsOutbox(mockDB, "topic")
+	mockDB.On("Insert", mock.Anything, mock.AnythingOfType("string"), mock.Anything).
+		Return(fmt.Errorf("err"))
+	err := repo.PushExpiration(context.Background(), domain.Product{ID: 7, Type: domain.PremiumCarousel})
+	assert.Error(t, err)
+	mockDB.AssertExpectations(t)
+}
+
+func TestGetPendingEventsOK(t *testing.T) {
+	mockDB := &dbHandlerMock{}
+	mResult := &mockResult{}
+	repo := MakeOutboxRepository(mockDB)
+	mockDB.On("Query", mock.Anything, mock.AnythingOfType("string"), []interface{}{10}).
+		Return(mResult, nil)
+	mResult.On("Next").Return(true).Once()
+	mResult.On("Next").Return(false).Once()
+	mResult.On("Scan", mock.Anything).Return([]interface{}{1, "7", "topic", "{}", 2})
+	mResult.On("Close").Return(nil)
+	events, err := repo.GetPendingEvents(context.Background(), 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []usecases.OutboxEvent{{ID: 1, Key: "7", Topic: "topic",
+		Payload: []byte("{}"), Attempts: 2}}, events)
+	mockDB.AssertExpectations(t)
+	mResult.AssertExpectations(t)
+}
+
+func TestGetPendingEventsError(t *testing.T) {
+	mockDB := &dbHandlerMock{}
+	mResult := &mockResult{}
+	repo := MakeOutboxRepository(mockDB)
+	mockDB.On("Query", mock.Anything, mock.AnythingOfType("string"), mock.Anything).
+		Return(mResult, fmt.Errorf("err"))
+	_, err := repo.GetPendingEvents(context.Background(), 10)
+	assert.Error(t, err)
+	mockDB.AssertExpectations(t)
+}
+
+func TestMarkEventSentOK(t *testing.T) {
+	mockDB := &dbHandlerMock{}
+	repo := MakeOutboxRepository(mockDB)
+	mockDB.On("Update", mock.Anything, mock.AnythingOfType("string"), []interface{}{1}).Return(nil)
+	err := repo.MarkSent(context.Background(), 1)
+	assert.NoError(t, err)
+	mockDB.AssertExpectations(t)
+}
+
+func TestMarkEventFailedOK(t *testing.T) {
+	mockDB := &dbHandlerMock{}
+	repo := MakeOutboxRepository(mockDB)
+	next := time.Now()
+	mockDB.On("Update", mock.Anything, mock.AnythingOfType("string"),
+		[]interface{}{1, "err", next}).Return(nil)
+	err := repo.MarkFailed(context.Background(), 1, fmt.Errorf("err"), next)
+	assert.NoError(t, err)
+	mockDB.AssertExpectations(t)
+}
+
+func TestPurgeSentEventsOK(t *testing.T) {
+	mockDB := &dbHandlerMock{}
+	repo := MakeOutboxRepository(mockDB)
+	before := time.Now()
+	mockDB.On("Update", mock.Anything, mock.AnythingOfType("string"),
+		[]interface{}{before}).Return(nil)
+	err := repo.PurgeSent(context.Background(), before)
+	assert.NoError(t, err)
+	mockDB.AssertExpectations(t)
+}
+
+func TestOutboxPublisherOK(t *testing.T) {
+	mProducer := &mockKafkaProducer{}
+	publisher := MakeOutboxPublisher(mProducer)
+	mProducer.On("SendKeyedMessage", "topic", []byte("7"), []byte("{}")).Return(nil)
+	errs := publisher.Publish(context.Background(), []usecases.OutboxEvent{{Key: "7", Topic: "topic",
+		Payload: []byte("{}")}})
+	assert.Equal(t, []error{nil}, errs)
+	mProducer.AssertExpectations(t)
+}
+
+func TestOutboxPublisherHoldsKeyOnFailure(t *testing.T) {
+	mProducer := &mockKafkaProducer{}
+	publisher := MakeOutboxPublisher(mProducer)
+	mProducer.On("SendKeyedMessage", "topic", []byte("7"), []byte("1")).Return(fmt.Errorf("err"))
+	mProducer.On("SendKeyedMessage", "topic", []byte("8"), []byte("2")).Return(nil)
+	errs := publisher.Publish(context.Background(), []usecases.OutboxEvent{
+		{Key: "7", Topic: "topic", Payload: []byte("1")},
+		{Key: "8", Topic: "topic", Payload: []byte("2")},
+		{Key: "7", Topic: "topic", Payload: []byte("3")},
+	})
+	assert.Equal(t, []error{fmt.Errorf("err"), nil, errHeldBack}, errs)
+	mProducer.AssertExpectations(t)
+}
+
+func TestAsyncOutboxPublisherOK(t *testing.T) {
+	mProducer := &mockKafkaProducer{}
+	publisher := MakeAsyncOutboxPublisher(mProducer)
+	mProducer.On("SendMessageAsync", "topic", []byte("7"), []byte("1")).Return(nil, nil)
+	mProducer.On("SendMessageAsync", "topic", []byte("8"), []byte("2")).Return(nil, fmt.Errorf("err"))
+	mProducer.On("SendMessageAsync", "topic", []byte("9"), []byte("3")).Return(fmt.Errorf("full"))
+	errs := publisher.Publish(context.Background(), []usecases.OutboxEvent{
+		{Key: "7", Topic: "topic", Payload: []byte("1")},
+		{Key: "8", Topic: "topic", Payload: []byte("2")},
+		{Key: "9", Topic: "topic", Payload: []byte("3")},
+	})
+	assert.Equal(t, []error{nil, fmt.Errorf("err"), fmt.Errorf("full")}, errs)
+	mProducer.AssertExpectations(t)
+}
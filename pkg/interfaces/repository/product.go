@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 	"strings"
@@ -20,9 +21,7 @@ type productRepo struct {
 }
 
 // ProductRepositoryLogger logs product repository events
-type ProductRepositoryLogger interface {
-	LogWarnPartialConfigNotSupported(name, value string)
-}
+type ProductRepositoryLogger interface{}
 
 // MakeProductRepository creates a new instance of ProductRepository
 func MakeProductRepository(handler DbHandler, resultsPerPage int,
@@ -35,8 +34,8 @@ func MakeProductRepository(handler DbHandler, resultsPerPage int,
 }
 
 // GetUserProductsTotal get the total of user products
-func (repo *productRepo) GetUserProductsTotal() (total int) {
-	result, err := repo.handler.Query(`SELECT COUNT(*) as total FROM
+func (repo *productRepo) GetUserProductsTotal(ctx context.Context) (total int) {
+	result, err := repo.handler.Query(ctx, `SELECT COUNT(*) as total FROM
 		user_product`)
 	if err != nil {
 		return 0
@@ -49,8 +48,9 @@ func (repo *productRepo) GetUserProductsTotal() (total int) {
 }
 
 // GetUserProductsTotal get the total of user products
-func (repo *productRepo) GetUserProductsTotalByEmail(email string) (total int) {
-	result, err := repo.handler.Query(`SELECT COUNT(*) as total FROM
+func (repo *productRepo) GetUserProductsTotalByEmail(ctx context.Context,
+	email string) (total int) {
+	result, err := repo.handler.Query(ctx, `SELECT COUNT(*) as total FROM
 			user_product WHERE user_email=$1`, email)
 	if err != nil {
 		return 0
@@ -63,13 +63,13 @@ func (repo *productRepo) GetUserProductsTotalByEmail(email string) (total int) {
 }
 
 // GetUserProducts get a list of user products with pagination
-func (repo *productRepo) GetUserProducts(
+func (repo *productRepo) GetUserProducts(ctx context.Context,
 	page int) (products []domain.Product, currentPage int,
 	totalPages int, err error) {
 	if page < 1 {
 		page = 1
 	}
-	total := repo.GetUserProductsTotal()
+	total := repo.GetUserProductsTotal(ctx)
 	if total < 1 {
 		return []domain.Product{}, page, 0, nil
 	}
@@ -77,7 +77,7 @@ func (repo *productRepo) GetUserProducts(
 	if (total % repo.resultsPerPage) > 0 {
 		totalPages++
 	}
-	result, err := repo.makeUserProductQuery(`
+	result, err := repo.makeUserProductQuery(ctx, `
 		WHERE TRUE
 		ORDER BY p.id DESC
 		OFFSET $1 LIMIT $2`,
@@ -93,7 +93,7 @@ func (repo *productRepo) GetUserProducts(
 			&product.Status, &product.ExpiredAt, &product.CreatedAt,
 			&product.Purchase.ID, &product.Purchase.Number, &product.Purchase.Type,
 			&product.Purchase.Status, &product.Purchase.Price, &product.Purchase.CreatedAt,
-			(*pq.StringArray)(&rawConfig))
+			(*pq.StringArray)(&rawConfig), &product.Version)
 		config, _ := repo.parseConfig(rawConfig)
 		product.Config = config
 		products = append(products, product)
@@ -103,9 +103,9 @@ func (repo *productRepo) GetUserProducts(
 
 // GetReport gets sales report using interval between start date and end date.
 // Returns sold products between given interval.
-func (repo *productRepo) GetReport(startDate,
+func (repo *productRepo) GetReport(ctx context.Context, startDate,
 	endDate time.Time) (soldProducts []domain.Product, err error) {
-	result, err := repo.makeUserProductQuery(`
+	result, err := repo.makeUserProductQuery(ctx, `
 		WHERE  p.created_at BETWEEN $1 AND $2
 		ORDER BY p.id DESC`, startDate, endDate)
 	if err != nil {
@@ -119,7 +119,7 @@ func (repo *productRepo) GetReport(startDate,
 			&product.Status, &product.ExpiredAt, &product.CreatedAt,
 			&product.Purchase.ID, &product.Purchase.Number, &product.Purchase.Type,
 			&product.Purchase.Status, &product.Purchase.Price, &product.Purchase.CreatedAt,
-			(*pq.StringArray)(&rawConfig))
+			(*pq.StringArray)(&rawConfig), &product.Version)
 		config, _ := repo.parseConfig(rawConfig)
 		product.Config = config
 		soldProducts = append(soldProducts, product)
@@ -127,17 +127,21 @@ func (repo *productRepo) GetReport(startDate,
 	return soldProducts, nil
 }
 
-func (repo *productRepo) makeUserProductQuery(conditions string,
-	params ...interface{}) (DbResult, error) {
-	return repo.handler.Query(`
-		SELECT
+// userProductColumns are the columns scanned into a product, read from the
+// products as p joined with their purchases as pur
+const userProductColumns = `
 			p.id, p.product_type, p.user_id, p.user_email, p.status, p.expired_at,
 			p.created_at, pur.id, pur.purchase_number, pur.purchase_type,
 			pur.purchase_status, pur.price, pur.created_at,
 			ARRAY(
 				SELECT user_product_param.name || '=' || user_product_param.value
 				FROM user_product_param WHERE user_product_id = p.id
-			) AS config_params
+			) AS config_params, p.version`
+
+func (repo *productRepo) makeUserProductQuery(ctx context.Context, conditions string,
+	params ...interface{}) (DbResult, error) {
+	return repo.handler.Query(ctx, `
+		SELECT`+userProductColumns+`
 		FROM user_product as p
 		JOIN purchase as pur ON (p.purchase_id = pur.id) `+
 		conditions, params...,
@@ -145,13 +149,13 @@ func (repo *productRepo) makeUserProductQuery(conditions string,
 }
 
 // GetUserProducts get a list of user products by email with pagination
-func (repo *productRepo) GetUserProductsByEmail(email string,
+func (repo *productRepo) GetUserProductsByEmail(ctx context.Context, email string,
 	page int) (products []domain.Product, currentPage int,
 	totalPages int, err error) {
 	if page < 1 {
 		page = 1
 	}
-	total := repo.GetUserProductsTotalByEmail(email)
+	total := repo.GetUserProductsTotalByEmail(ctx, email)
 	if total < 1 {
 		return []domain.Product{}, page, 0, nil
 	}
@@ -159,7 +163,7 @@ func (repo *productRepo) GetUserProductsByEmail(email string,
 	if (total % repo.resultsPerPage) > 0 {
 		totalPages++
 	}
-	result, err := repo.makeUserProductQuery(
+	result, err := repo.makeUserProductQuery(ctx,
 		`WHERE user_email = $1
 		ORDER BY p.id DESC
 		OFFSET $2 LIMIT $3`,
@@ -176,7 +180,7 @@ func (repo *productRepo) GetUserProductsByEmail(email string,
 			&product.Status, &product.ExpiredAt, &product.CreatedAt,
 			&product.Purchase.ID, &product.Purchase.Number, &product.Purchase.Type,
 			&product.Purchase.Status, &product.Purchase.Price, &product.Purchase.CreatedAt,
-			(*pq.StringArray)(&rawConfig))
+			(*pq.StringArray)(&rawConfig), &product.Version)
 		config, _ := repo.parseConfig(rawConfig)
 		product.Config = config
 		products = append(products, product)
@@ -185,9 +189,9 @@ func (repo *productRepo) GetUserProductsByEmail(email string,
 }
 
 // GetUserActiveProduct gets active product for an specific userID
-func (repo *productRepo) GetUserActiveProduct(userID int,
+func (repo *productRepo) GetUserActiveProduct(ctx context.Context, userID int,
 	productType domain.ProductType) (domain.Product, error) {
-	result, err := repo.makeUserProductQuery(`
+	result, err := repo.makeUserProductQuery(ctx, `
 		WHERE  p.status = 'ACTIVE'
 		AND p.user_id = $1 AND p.product_type = $2
 		ORDER BY p.expired_at, p.start_at LIMIT 1`,
@@ -203,7 +207,7 @@ func (repo *productRepo) GetUserActiveProduct(userID int,
 			&product.Status, &product.ExpiredAt, &product.CreatedAt,
 			&product.Purchase.ID, &product.Purchase.Number, &product.Purchase.Type,
 			&product.Purchase.Status, &product.Purchase.Price, &product.Purchase.CreatedAt,
-			(*pq.StringArray)(&configArr))
+			(*pq.StringArray)(&configArr), &product.Version)
 	} else {
 		return domain.Product{}, usecases.ErrProductNotFound
 	}
@@ -216,8 +220,9 @@ func (repo *productRepo) GetUserActiveProduct(userID int,
 }
 
 // GetUserActiveProduct gets active product for an specific userProductID
-func (repo *productRepo) GetUserProductByID(userProductID int) (domain.Product, error) {
-	result, err := repo.makeUserProductQuery(`
+func (repo *productRepo) GetUserProductByID(ctx context.Context,
+	userProductID int) (domain.Product, error) {
+	result, err := repo.makeUserProductQuery(ctx, `
 		WHERE  p.id = $1`, userProductID)
 	if err != nil {
 		return domain.Product{}, err
@@ -230,9 +235,10 @@ func (repo *productRepo) GetUserProductByID(userProductID int) (domain.Product,
 			&product.Status, &product.ExpiredAt, &product.CreatedAt,
 			&product.Purchase.ID, &product.Purchase.Number, &product.Purchase.Type,
 			&product.Purchase.Status, &product.Purchase.Price, &product.Purchase.CreatedAt,
-			(*pq.StringArray)(&configArr))
+			(*pq.StringArray)(&configArr), &product.Version)
+	} else {
+		return domain.Product{}, usecases.ErrProductNotFound
 	}
-
 	config, err := repo.parseConfig(configArr)
 	if err != nil {
 		return domain.Product{}, err
@@ -241,6 +247,34 @@ func (repo *productRepo) GetUserProductByID(userProductID int) (domain.Product,
 	return product, nil
 }
 
+// GetProductsExpiringBefore gets the active products not yet expired whose
+// expiration is before the given date
+func (repo *productRepo) GetProductsExpiringBefore(ctx context.Context,
+	date time.Time) ([]domain.Product, error) {
+	result, err := repo.makeUserProductQuery(ctx, `
+		WHERE p.status = 'ACTIVE'
+		AND p.expired_at > NOW() AND p.expired_at <= $1
+		ORDER BY p.expired_at`, date)
+	if err != nil {
+		return []domain.Product{}, err
+	}
+	defer result.Close()
+	products := []domain.Product{}
+	for result.Next() {
+		product := domain.Product{}
+		rawConfig := []string{}
+		result.Scan(&product.ID, &product.Type, &product.UserID, &product.Email,
+			&product.Status, &product.ExpiredAt, &product.CreatedAt,
+			&product.Purchase.ID, &product.Purchase.Number, &product.Purchase.Type,
+			&product.Purchase.Status, &product.Purchase.Price, &product.Purchase.CreatedAt,
+			(*pq.StringArray)(&rawConfig), &product.Version)
+		config, _ := repo.parseConfig(rawConfig)
+		product.Config = config
+		products = append(products, product)
+	}
+	return products, nil
+}
+
 // parseConfig parses rawConfiguration slice to domain.ProductParams  struct
 func (repo *productRepo) parseConfig(rawConfig []string) (domain.ProductParams, error) {
 	if len(rawConfig) == 0 {
@@ -282,30 +316,42 @@ func (repo *productRepo) parseConfig(rawConfig []string) (domain.ProductParams,
 	}, nil
 }
 
-// CreateUserProduct creates a new product for user
-func (repo *productRepo) CreateUserProduct(userID int, email string,
-	purchase domain.Purchase, productType domain.ProductType, expiredAt time.Time,
+// CreateUserProduct creates a new product for user. A product whose startAt
+// is in the future is created inactive, to be activated by ActivateProducts
+// once it's reached. A zero startAt starts it right away
+func (repo *productRepo) CreateUserProduct(ctx context.Context, userID int, email string,
+	purchase domain.Purchase, productType domain.ProductType, startAt, expiredAt time.Time,
 	config domain.ProductParams) (domain.Product, error) {
-	result, err := repo.handler.Query(
+	var start interface{}
+	if !startAt.IsZero() {
+		start = startAt
+	}
+	result, err := repo.handler.Query(ctx,
 		`INSERT INTO user_product(product_type, status, user_id, user_email,
-			purchase_id, expired_at)
-			VALUES (
-				$1, 'ACTIVE', $2, $3, $4, $5
-			) RETURNING id, created_at`, productType, userID, email, purchase.ID,
-		expiredAt)
+			purchase_id, expired_at, start_at, activated_at)
+			SELECT $1, CASE WHEN s.start_at > NOW() THEN 'INACTIVE' ELSE 'ACTIVE' END,
+				$2, $3, $4, $5, s.start_at,
+				CASE WHEN s.start_at > NOW() THEN NULL ELSE NOW() END
+			FROM (SELECT COALESCE($6::timestamp, NOW()) AS start_at) AS s
+			RETURNING id, status, created_at`, productType, userID, email, purchase.ID,
+		expiredAt, start)
 	if err != nil {
 		return domain.Product{}, err
 	}
-	defer result.Close()
 	var userProductID int
+	var status domain.ProductStatus
 	var createdAt time.Time
-	if result.Next() {
-		result.Scan(&userProductID, &createdAt)
-	} else {
+	found := result.Next()
+	if found {
+		result.Scan(&userProductID, &status, &createdAt)
+	}
+	// rows must be closed before the next statement when in a transaction
+	result.Close()
+	if !found {
 		return domain.Product{},
 			fmt.Errorf("next error: getting userProductID from database")
 	}
-	err = repo.SetConfig(userProductID, config)
+	err = repo.SetConfig(ctx, userProductID, config)
 	if err != nil {
 		return domain.Product{}, err
 	}
@@ -317,14 +363,41 @@ func (repo *productRepo) CreateUserProduct(userID int, email string,
 		ExpiredAt: expiredAt,
 		CreatedAt: createdAt,
 		Config:    config,
-		Status:    domain.ActiveProduct,
+		Status:    status,
 		Purchase:  purchase,
+		Version:   1,
 	}, nil
 }
 
+// IncrementVersion increments the product version only when the current
+// version matches the given one, otherwise returns ErrVersionMismatch.
+// Returns the new product version
+func (repo *productRepo) IncrementVersion(ctx context.Context, userProductID int,
+	version int) (int, error) {
+	result, err := repo.handler.Query(ctx,
+		`UPDATE user_product SET version = version + 1
+			WHERE id = $1 AND version = $2
+			RETURNING version`, userProductID, version)
+	if err != nil {
+		return 0, err
+	}
+	defer result.Close()
+	if !result.Next() {
+		return 0, usecases.ErrVersionMismatch
+	}
+	var newVersion int
+	result.Scan(&newVersion)
+	return newVersion, nil
+}
+
 // SetConfig adds configuration to Product
-func (repo *productRepo) SetConfig(userProductID int, config domain.ProductParams) error {
-	values := makeConfigValues(userProductID, config)
+func (repo *productRepo) SetConfig(ctx context.Context, userProductID int,
+	config domain.ProductParams) error {
+	return repo.upsertConfigValues(ctx, makeConfigValues(userProductID, config))
+}
+
+// upsertConfigValues inserts or replaces the given product params
+func (repo *productRepo) upsertConfigValues(ctx context.Context, values [][]interface{}) error {
 	insertValues, positions := []interface{}{}, []string{}
 	counter := 0
 	for _, v := range values {
@@ -336,7 +409,7 @@ func (repo *productRepo) SetConfig(userProductID int, config domain.ProductParam
 		positions = append(positions, "("+strings.Join(temp, ",")+")")
 		insertValues = append(insertValues, []interface{}{v[0], v[1], v[2]}...)
 	}
-	return repo.handler.Insert(
+	return repo.handler.Insert(ctx,
 		fmt.Sprintf(`INSERT INTO user_product_param(user_product_id, name, value) VALUES %s
 			ON CONFLICT (user_product_id, name) DO UPDATE set value=excluded.value`,
 			strings.Join(positions, ", ")),
@@ -356,27 +429,66 @@ func makeConfigValues(userProductID int, config domain.ProductParams) (values []
 	}
 }
 
-// SetConfig adds configuration to Product
-func (repo *productRepo) SetPartialConfig(userProductID int, configMap map[string]interface{}) error {
-	for name, value := range configMap {
-		switch name {
-		case "status":
-			if err := repo.SetStatus(userProductID,
-				domain.ProductStatus(value.(string))); err != nil {
-				return err
-			}
-		default:
-			repo.logger.LogWarnPartialConfigNotSupported(name,
-				fmt.Sprintf("%+v", value))
+// SetPartialConfig persists only the members present on the given patch
+func (repo *productRepo) SetPartialConfig(ctx context.Context, userProductID int,
+	patch usecases.ProductPatch) error {
+	if patch.Status != nil {
+		if err := repo.SetStatus(ctx, userProductID, *patch.Status); err != nil {
+			return err
+		}
+	}
+	if patch.ExpiredAt != nil {
+		if err := repo.SetExpiration(ctx, userProductID, *patch.ExpiredAt); err != nil {
+			return err
+		}
+	}
+	values := makePartialConfigValues(userProductID, patch)
+	if len(values) == 0 {
+		return nil
+	}
+	return repo.upsertConfigValues(ctx, values)
+}
+
+// makePartialConfigValues makes the config values for the params present
+// on the given patch
+func makePartialConfigValues(userProductID int,
+	patch usecases.ProductPatch) (values [][]interface{}) {
+	config, present := domain.ProductParams{}, map[string]bool{}
+	if patch.Categories != nil {
+		config.Categories, present["categories"] = *patch.Categories, true
+	}
+	if patch.Limit != nil {
+		config.Limit, present["limit"] = *patch.Limit, true
+	}
+	if patch.Keywords != nil {
+		config.Keywords, present["keywords"] = *patch.Keywords, true
+	}
+	if patch.Exclude != nil {
+		config.Exclude, present["exclude"] = *patch.Exclude, true
+	}
+	if patch.PriceRange != nil {
+		config.PriceRange, present["price_range"] = *patch.PriceRange, true
+	}
+	if patch.Comment != nil {
+		config.Comment, present["comment"] = *patch.Comment, true
+	}
+	if patch.FillGapsWithRandom != nil {
+		config.FillGapsWithRandom, present["fill_random"] = *patch.FillGapsWithRandom, true
+	}
+	for _, v := range makeConfigValues(userProductID, config) {
+		if present[v[1].(string)] {
+			values = append(values, v)
 		}
 	}
-	return nil
+	return values
 }
 
-// SetStatus sets the user product status
-func (repo *productRepo) SetStatus(userProductID int, status domain.ProductStatus) error {
+// SetStatus sets the user product status. The version is left to
+// IncrementVersion
+func (repo *productRepo) SetStatus(ctx context.Context, userProductID int,
+	status domain.ProductStatus) error {
 	result, err := repo.handler.
-		Query(
+		Query(ctx,
 			`UPDATE user_product SET status=$1 WHERE id=$2`,
 			status,
 			userProductID,
@@ -387,10 +499,12 @@ func (repo *productRepo) SetStatus(userProductID int, status domain.ProductStatu
 	return result.Close()
 }
 
-// SetExpiration sets the expiration for product
-func (repo *productRepo) SetExpiration(userProductID int, expiredAt time.Time) error {
+// SetExpiration sets the expiration for product. The version is left to
+// IncrementVersion
+func (repo *productRepo) SetExpiration(ctx context.Context, userProductID int,
+	expiredAt time.Time) error {
 	result, err := repo.handler.
-		Query(
+		Query(ctx,
 			`UPDATE user_product SET expired_at=$1 WHERE id=$2`,
 			expiredAt,
 			userProductID,
@@ -401,16 +515,120 @@ func (repo *productRepo) SetExpiration(userProductID int, expiredAt time.Time) e
 	return result.Close()
 }
 
-// ExpireProductds sets expired status for all expired products
-func (repo *productRepo) ExpireProducts() error {
-	return repo.handler.Update(
+// ExpireProducts sets expired status for all expired products.
+// Returns the expired products
+func (repo *productRepo) ExpireProducts(ctx context.Context) ([]domain.Product, error) {
+	return repo.updateProductsStatus(ctx,
 		`UPDATE
 			user_product
 		SET
-			status = 'EXPIRED'
+			status = 'EXPIRED',
+			version = version + 1
 		WHERE
 			expired_at < NOW()
 		AND
 			status = 'ACTIVE'`,
 	)
 }
+
+// ActivateProducts sets active status for the inactive products whose start
+// date was reached and were never active before. Returns the activated products
+func (repo *productRepo) ActivateProducts(ctx context.Context) ([]domain.Product, error) {
+	return repo.updateProductsStatus(ctx,
+		`UPDATE
+			user_product
+		SET
+			status = 'ACTIVE',
+			activated_at = NOW(),
+			version = version + 1
+		WHERE
+			start_at <= NOW()
+		AND
+			expired_at > NOW()
+		AND
+			activated_at IS NULL
+		AND
+			status = 'INACTIVE'`,
+	)
+}
+
+// updateProductsStatus runs a status update of user_product returning the
+// updated products along with their purchase and config
+func (repo *productRepo) updateProductsStatus(ctx context.Context,
+	update string) ([]domain.Product, error) {
+	// The products are read from the update, the rest of the statement
+	// doesn't see its changes
+	result, err := repo.handler.Query(ctx, `
+		WITH p AS (`+update+`
+		RETURNING
+			id, product_type, user_id, user_email, status, expired_at,
+			created_at, purchase_id, version
+		)
+		SELECT`+userProductColumns+`
+		FROM p
+		JOIN purchase as pur ON (p.purchase_id = pur.id)
+		ORDER BY p.id`)
+	if err != nil {
+		return []domain.Product{}, err
+	}
+	defer result.Close()
+	products := []domain.Product{}
+	for result.Next() {
+		product := domain.Product{}
+		rawConfig := []string{}
+		result.Scan(&product.ID, &product.Type, &product.UserID, &product.Email,
+			&product.Status, &product.ExpiredAt, &product.CreatedAt,
+			&product.Purchase.ID, &product.Purchase.Number, &product.Purchase.Type,
+			&product.Purchase.Status, &product.Purchase.Price, &product.Purchase.CreatedAt,
+			(*pq.StringArray)(&rawConfig), &product.Version)
+		config, _ := repo.parseConfig(rawConfig)
+		product.Config = config
+		products = append(products, product)
+	}
+	return products, nil
+}
+
+// GetActiveProducts gets every active product, sorted by user in the same
+// order GetUserActiveProduct picks them
+func (repo *productRepo) GetActiveProducts(ctx context.Context) ([]domain.Product, error) {
+	result, err := repo.makeUserProductQuery(ctx, `
+		WHERE p.status = 'ACTIVE'
+		ORDER BY p.user_id, p.expired_at, p.start_at`)
+	if err != nil {
+		return []domain.Product{}, err
+	}
+	defer result.Close()
+	products := []domain.Product{}
+	for result.Next() {
+		product := domain.Product{}
+		rawConfig := []string{}
+		result.Scan(&product.ID, &product.Type, &product.UserID, &product.Email,
+			&product.Status, &product.ExpiredAt, &product.CreatedAt,
+			&product.Purchase.ID, &product.Purchase.Number, &product.Purchase.Type,
+			&product.Purchase.Status, &product.Purchase.Price, &product.Purchase.CreatedAt,
+			(*pq.StringArray)(&rawConfig), &product.Version)
+		config, _ := repo.parseConfig(rawConfig)
+		product.Config = config
+		products = append(products, product)
+	}
+	return products, nil
+}
+
+// CountActiveProducts returns how many active products there are of each type
+func (repo *productRepo) CountActiveProducts(ctx context.Context) (map[domain.ProductType]int, error) {
+	result, err := repo.handler.Query(ctx,
+		`SELECT product_type, COUNT(*) FROM user_product
+			WHERE status = 'ACTIVE' GROUP BY product_type`)
+	if err != nil {
+		return nil, err
+	}
+	defer result.Close()
+	totals := map[domain.ProductType]int{}
+	for result.Next() {
+		var productType domain.ProductType
+		var total int
+		result.Scan(&productType, &total)
+		totals[productType] = total
+	}
+	return totals, nil
+}
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"reflect"
@@ -10,6 +11,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/domain"
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/usecases"
 )
 
 type mockResult struct {
@@ -47,18 +49,18 @@ func (m *dbHandlerMock) Close() error {
 	return args.Error(0)
 }
 
-func (m *dbHandlerMock) Query(statement string, params ...interface{}) (DbResult, error) {
-	args := m.Called(statement, params)
+func (m *dbHandlerMock) Query(ctx context.Context, statement string, params ...interface{}) (DbResult, error) {
+	args := m.Called(ctx, statement, params)
 	return args.Get(0).(DbResult), args.Error(1)
 }
 
-func (m *dbHandlerMock) Insert(statement string, params ...interface{}) error {
-	args := m.Called(statement, params)
+func (m *dbHandlerMock) Insert(ctx context.Context, statement string, params ...interface{}) error {
+	args := m.Called(ctx, statement, params)
 	return args.Error(0)
 }
 
-func (m *dbHandlerMock) Update(statement string, params ...interface{}) error {
-	args := m.Called(statement, params)
+func (m *dbHandlerMock) Update(ctx context.Context, statement string, params ...interface{}) error {
+	args := m.Called(ctx, statement, params)
 	return args.Error(0)
 }
 
@@ -66,10 +68,6 @@ type mockProductRepoLogger struct {
 	mock.Mock
 }
 
-func (m *mockProductRepoLogger) LogWarnPartialConfigNotSupported(name, value string) {
-	m.Called(name, value)
-}
-
 func TestMakeProductRepositoryOK(t *testing.T) {
 	mockDB := &dbHandlerMock{}
 	mLogger := &mockProductRepoLogger{}
@@ -87,7 +85,7 @@ func TestGetUserProductsTotalOk(t *testing.T) {
 	mockDB := &dbHandlerMock{}
 	mResult := &mockResult{}
 	mLogger := &mockProductRepoLogger{}
-	mockDB.On("Query",
+	mockDB.On("Query", mock.Anything,
 		mock.AnythingOfType("string"),
 		mock.AnythingOfType("[]interface {}"),
 	).Return(mResult, nil)
@@ -95,7 +93,7 @@ func TestGetUserProductsTotalOk(t *testing.T) {
 	mResult.On("Next").Return(true).Once()
 	mResult.On("Scan", mock.Anything).Return([]interface{}{123})
 	repo := MakeProductRepository(mockDB, 10, mLogger)
-	result := repo.GetUserProductsTotal()
+	result := repo.GetUserProductsTotal(context.Background())
 	assert.Equal(t, 123, result)
 	mockDB.AssertExpectations(t)
 	mResult.AssertExpectations(t)
@@ -106,12 +104,12 @@ func TestGetUserProductsTotalError(t *testing.T) {
 	mockDB := &dbHandlerMock{}
 	mResult := &mockResult{}
 	mLogger := &mockProductRepoLogger{}
-	mockDB.On("Query",
+	mockDB.On("Query", mock.Anything,
 		mock.AnythingOfType("string"),
 		mock.AnythingOfType("[]interface {}"),
 	).Return(mResult, fmt.Errorf("e"))
 	repo := MakeProductRepository(mockDB, 10, mLogger)
-	result := repo.GetUserProductsTotal()
+	result := repo.GetUserProductsTotal(context.Background())
 	assert.Equal(t, 0, result)
 	mockDB.AssertExpectations(t)
 	mResult.AssertExpectations(t)
@@ -122,7 +120,7 @@ func TestGetUserProductsTotalByEmailOk(t *testing.T) {
 	mockDB := &dbHandlerMock{}
 	mResult := &mockResult{}
 	mLogger := &mockProductRepoLogger{}
-	mockDB.On("Query",
+	mockDB.On("Query", mock.Anything,
 		mock.AnythingOfType("string"),
 		mock.AnythingOfType("[]interface {}"),
 	).Return(mResult, nil)
@@ -130,7 +128,7 @@ func TestGetUserProductsTotalByEmailOk(t *testing.T) {
 	mResult.On("Next").Return(true).Once()
 	mResult.On("Scan", mock.Anything).Return([]interface{}{123})
 	repo := MakeProductRepository(mockDB, 10, mLogger)
-	result := repo.GetUserProductsTotalByEmail("123@123.cl")
+	result := repo.GetUserProductsTotalByEmail(context.Background(), "123@123.cl")
 	assert.Equal(t, 123, result)
 	mockDB.AssertExpectations(t)
 	mResult.AssertExpectations(t)
@@ -141,12 +139,12 @@ func TestGetUserProductsTotalByEmailError(t *testing.T) {
 	mockDB := &dbHandlerMock{}
 	mResult := &mockResult{}
 	mLogger := &mockProductRepoLogger{}
-	mockDB.On("Query",
+	mockDB.On("Query", mock.Anything,
 		mock.AnythingOfType("string"),
 		mock.AnythingOfType("[]interface {}"),
 	).Return(mResult, fmt.Errorf("e"))
 	repo := MakeProductRepository(mockDB, 10, mLogger)
-	result := repo.GetUserProductsTotalByEmail("123@test.cl")
+	result := repo.GetUserProductsTotalByEmail(context.Background(), "123@test.cl")
 	assert.Equal(t, 0, result)
 	mockDB.AssertExpectations(t)
 	mResult.AssertExpectations(t)
@@ -161,14 +159,14 @@ func TestGetUserProductsByEmailOk(t *testing.T) {
 
 	mResult.On("Close").Return(nil)
 	// Get Products Total mocks
-	mockDB.On("Query",
+	mockDB.On("Query", mock.Anything,
 		mock.AnythingOfType("string"),
 		mock.AnythingOfType("[]interface {}"),
 	).Return(mResult, nil).Once()
 	mResult.On("Next").Return(true).Once()
 	mResult.On("Scan", mock.Anything).Return([]interface{}{11}).Once()
 	// get products query
-	mockDB.On("Query",
+	mockDB.On("Query", mock.Anything,
 		mock.AnythingOfType("string"),
 		mock.Anything,
 	).Return(mResult, nil).Once()
@@ -179,10 +177,10 @@ func TestGetUserProductsByEmailOk(t *testing.T) {
 		11, domain.PremiumCarousel, 1, "test@mail.com", domain.ActiveProduct,
 		testTime, testTime, 0, 0, domain.AdminPurchase, domain.AcceptedPurchase,
 		100, testTime, []string{"categories=2020,1020",
-			"keywords=a,b,c", "comment=comentario"}}).Once()
+			"keywords=a,b,c", "comment=comentario"}, 3}).Once()
 
 	result, currentPage,
-		totalPages, err := repo.GetUserProductsByEmail("test@email.com", 0)
+		totalPages, err := repo.GetUserProductsByEmail(context.Background(), "test@email.com", 0)
 	expected := []domain.Product{
 		{
 			ID:        11,
@@ -206,6 +204,7 @@ func TestGetUserProductsByEmailOk(t *testing.T) {
 				Keywords:   []string{"a", "b", "c"},
 				Comment:    "comentario",
 			},
+			Version: 3,
 		},
 	}
 	assert.Equal(t, 1, currentPage)
@@ -225,14 +224,14 @@ func TestGetUserProductsOk(t *testing.T) {
 
 	mResult.On("Close").Return(nil)
 	// Get Products Total mocks
-	mockDB.On("Query",
+	mockDB.On("Query", mock.Anything,
 		mock.AnythingOfType("string"),
 		mock.AnythingOfType("[]interface {}"),
 	).Return(mResult, nil).Once()
 	mResult.On("Next").Return(true).Once()
 	mResult.On("Scan", mock.Anything).Return([]interface{}{11}).Once()
 	// get products query
-	mockDB.On("Query",
+	mockDB.On("Query", mock.Anything,
 		mock.AnythingOfType("string"),
 		mock.Anything,
 	).Return(mResult, nil).Once()
@@ -243,9 +242,9 @@ func TestGetUserProductsOk(t *testing.T) {
 		11, domain.PremiumCarousel, 1, "test@mail.com", domain.ActiveProduct,
 		testTime, testTime, 0, 0, domain.AdminPurchase, domain.AcceptedPurchase,
 		100, testTime, []string{"categories=2020,1020",
-			"keywords=a,b,c", "comment=comentario"}}).Once()
+			"keywords=a,b,c", "comment=comentario"}, 3}).Once()
 	result, currentPage,
-		totalPages, err := repo.GetUserProducts(0)
+		totalPages, err := repo.GetUserProducts(context.Background(), 0)
 	expected := []domain.Product{
 		{
 			ID:        11,
@@ -269,6 +268,7 @@ func TestGetUserProductsOk(t *testing.T) {
 				Keywords:   []string{"a", "b", "c"},
 				Comment:    "comentario",
 			},
+			Version: 3,
 		},
 	}
 	assert.Equal(t, 1, currentPage)
@@ -288,7 +288,7 @@ func TestGetReportOk(t *testing.T) {
 	mResult.On("Close").Return(nil)
 
 	// get products query
-	mockDB.On("Query",
+	mockDB.On("Query", mock.Anything,
 		mock.AnythingOfType("string"),
 		mock.Anything,
 	).Return(mResult, nil).Once()
@@ -299,8 +299,8 @@ func TestGetReportOk(t *testing.T) {
 		11, domain.PremiumCarousel, 1, "test@mail.com", domain.ActiveProduct,
 		testTime, testTime, 0, 0, domain.AdminPurchase, domain.AcceptedPurchase,
 		100, testTime, []string{"categories=2020,1020",
-			"keywords=a,b,c", "comment=comentario"}}).Once()
-	result, err := repo.GetReport(testTime, testTime)
+			"keywords=a,b,c", "comment=comentario"}, 3}).Once()
+	result, err := repo.GetReport(context.Background(), testTime, testTime)
 	expected := []domain.Product{
 		{
 			ID:        11,
@@ -324,6 +324,7 @@ func TestGetReportOk(t *testing.T) {
 				Keywords:   []string{"a", "b", "c"},
 				Comment:    "comentario",
 			},
+			Version: 3,
 		},
 	}
 
@@ -339,11 +340,11 @@ func TestGetReportQueryError(t *testing.T) {
 	mResult := &mockResult{}
 	mLogger := &mockProductRepoLogger{}
 	repo := MakeProductRepository(mockDB, 10, mLogger)
-	mockDB.On("Query",
+	mockDB.On("Query", mock.Anything,
 		mock.AnythingOfType("string"),
 		mock.Anything,
 	).Return(mResult, fmt.Errorf("err")).Once()
-	_, err := repo.GetReport(time.Now(), time.Now())
+	_, err := repo.GetReport(context.Background(), time.Now(), time.Now())
 	assert.Error(t, err)
 	mockDB.AssertExpectations(t)
 	mResult.AssertExpectations(t)
@@ -355,12 +356,12 @@ func TestGetUserProductsZeroResults(t *testing.T) {
 	mResult := &mockResult{}
 	mLogger := &mockProductRepoLogger{}
 	repo := MakeProductRepository(mockDB, 10, mLogger)
-	mockDB.On("Query",
+	mockDB.On("Query", mock.Anything,
 		mock.AnythingOfType("string"),
 		mock.AnythingOfType("[]interface {}"),
 	).Return(mResult, fmt.Errorf("e")).Once()
 	result, currentPage,
-		totalPages, err := repo.GetUserProducts(0)
+		totalPages, err := repo.GetUserProducts(context.Background(), 0)
 	expected := []domain.Product{}
 	assert.Equal(t, 1, currentPage)
 	assert.Equal(t, 0, totalPages)
@@ -376,12 +377,12 @@ func TestGetUserProductsByEmailZeroResults(t *testing.T) {
 	mResult := &mockResult{}
 	mLogger := &mockProductRepoLogger{}
 	repo := MakeProductRepository(mockDB, 10, mLogger)
-	mockDB.On("Query",
+	mockDB.On("Query", mock.Anything,
 		mock.AnythingOfType("string"),
 		mock.AnythingOfType("[]interface {}"),
 	).Return(mResult, fmt.Errorf("e")).Once()
 	result, currentPage,
-		totalPages, err := repo.GetUserProductsByEmail("test@email.com", 0)
+		totalPages, err := repo.GetUserProductsByEmail(context.Background(), "test@email.com", 0)
 	expected := []domain.Product{}
 	assert.Equal(t, 1, currentPage)
 	assert.Equal(t, 0, totalPages)
@@ -398,17 +399,17 @@ func TestGetUserProductsByEmailQueryError(t *testing.T) {
 	mLogger := &mockProductRepoLogger{}
 	repo := MakeProductRepository(mockDB, 10, mLogger)
 	mResult.On("Close").Return(nil)
-	mockDB.On("Query",
+	mockDB.On("Query", mock.Anything,
 		mock.AnythingOfType("string"),
 		mock.AnythingOfType("[]interface {}"),
 	).Return(mResult, nil).Once()
 	mResult.On("Next").Return(true).Once()
 	mResult.On("Scan", mock.Anything).Return([]interface{}{11}).Once()
-	mockDB.On("Query",
+	mockDB.On("Query", mock.Anything,
 		mock.AnythingOfType("string"),
 		mock.Anything,
 	).Return(mResult, fmt.Errorf("err")).Once()
-	_, _, _, err := repo.GetUserProductsByEmail("test@email.com", 0)
+	_, _, _, err := repo.GetUserProductsByEmail(context.Background(), "test@email.com", 0)
 	assert.Error(t, err)
 	mockDB.AssertExpectations(t)
 	mResult.AssertExpectations(t)
@@ -421,17 +422,17 @@ func TestGetUserProductsQueryError(t *testing.T) {
 	mLogger := &mockProductRepoLogger{}
 	repo := MakeProductRepository(mockDB, 10, mLogger)
 	mResult.On("Close").Return(nil)
-	mockDB.On("Query",
+	mockDB.On("Query", mock.Anything,
 		mock.AnythingOfType("string"),
 		mock.AnythingOfType("[]interface {}"),
 	).Return(mResult, nil).Once()
 	mResult.On("Next").Return(true).Once()
 	mResult.On("Scan", mock.Anything).Return([]interface{}{11}).Once()
-	mockDB.On("Query",
+	mockDB.On("Query", mock.Anything,
 		mock.AnythingOfType("string"),
 		mock.Anything,
 	).Return(mResult, fmt.Errorf("err")).Once()
-	_, _, _, err := repo.GetUserProducts(0)
+	_, _, _, err := repo.GetUserProducts(context.Background(), 0)
 	assert.Error(t, err)
 	mockDB.AssertExpectations(t)
 	mResult.AssertExpectations(t)
@@ -444,7 +445,7 @@ func TestGetUserActiveProductOk(t *testing.T) {
 	mLogger := &mockProductRepoLogger{}
 	repo := MakeProductRepository(mockDB, 10, mLogger)
 	mResult.On("Close").Return(nil)
-	mockDB.On("Query",
+	mockDB.On("Query", mock.Anything,
 		mock.AnythingOfType("string"),
 		mock.Anything,
 	).Return(mResult, nil).Once()
@@ -454,8 +455,8 @@ func TestGetUserActiveProductOk(t *testing.T) {
 		11, domain.PremiumCarousel, 1, "test@mail.com", domain.ActiveProduct,
 		testTime, testTime, 0, 0, domain.AdminPurchase, domain.AcceptedPurchase,
 		100, testTime, []string{"categories=2020,1020",
-			"keywords=a,b,c", "comment=comentario"}}).Once()
-	result, err := repo.GetUserActiveProduct(1,
+			"keywords=a,b,c", "comment=comentario"}, 3}).Once()
+	result, err := repo.GetUserActiveProduct(context.Background(), 1,
 		domain.PremiumCarousel)
 	expected := domain.Product{
 		ID:        11,
@@ -479,6 +480,7 @@ func TestGetUserActiveProductOk(t *testing.T) {
 			Keywords:   []string{"a", "b", "c"},
 			Comment:    "comentario",
 		},
+		Version: 3,
 	}
 	assert.Equal(t, expected, result)
 	assert.NoError(t, err)
@@ -493,12 +495,12 @@ func TestGetUserActiveProductErrorProductNotFound(t *testing.T) {
 	mLogger := &mockProductRepoLogger{}
 	repo := MakeProductRepository(mockDB, 10, mLogger)
 	mResult.On("Close").Return(nil)
-	mockDB.On("Query",
+	mockDB.On("Query", mock.Anything,
 		mock.AnythingOfType("string"),
 		mock.Anything,
 	).Return(mResult, nil).Once()
 	mResult.On("Next").Return(false).Once()
-	_, err := repo.GetUserActiveProduct(1,
+	_, err := repo.GetUserActiveProduct(context.Background(), 1,
 		domain.PremiumCarousel)
 
 	assert.Error(t, err)
@@ -512,11 +514,11 @@ func TestGetUserActiveProductError(t *testing.T) {
 	mResult := &mockResult{}
 	mLogger := &mockProductRepoLogger{}
 	repo := MakeProductRepository(mockDB, 10, mLogger)
-	mockDB.On("Query",
+	mockDB.On("Query", mock.Anything,
 		mock.AnythingOfType("string"),
 		mock.Anything,
 	).Return(mResult, fmt.Errorf("err")).Once()
-	_, err := repo.GetUserActiveProduct(1,
+	_, err := repo.GetUserActiveProduct(context.Background(), 1,
 		domain.PremiumCarousel)
 	assert.Error(t, err)
 	mockDB.AssertExpectations(t)
@@ -530,7 +532,7 @@ func TestGetUserActiveProductErrorNoConfig(t *testing.T) {
 	mLogger := &mockProductRepoLogger{}
 	repo := MakeProductRepository(mockDB, 10, mLogger)
 	mResult.On("Close").Return(nil)
-	mockDB.On("Query",
+	mockDB.On("Query", mock.Anything,
 		mock.AnythingOfType("string"),
 		mock.Anything,
 	).Return(mResult, nil).Once()
@@ -539,8 +541,8 @@ func TestGetUserActiveProductErrorNoConfig(t *testing.T) {
 	mResult.On("Scan", mock.Anything).Return([]interface{}{
 		11, domain.PremiumCarousel, 1, "test@mail.com", domain.ActiveProduct,
 		testTime, testTime, 0, 0, domain.AdminPurchase, domain.AcceptedPurchase,
-		100, testTime, []string{}}).Once()
-	_, err := repo.GetUserActiveProduct(1,
+		100, testTime, []string{}, 3}).Once()
+	_, err := repo.GetUserActiveProduct(context.Background(), 1,
 		domain.PremiumCarousel)
 	assert.Error(t, err)
 	mockDB.AssertExpectations(t)
@@ -554,20 +556,20 @@ func TestCreateUserProductOk(t *testing.T) {
 	mLogger := &mockProductRepoLogger{}
 	repo := MakeProductRepository(mockDB, 10, mLogger)
 	mResult.On("Close").Return(nil).Once()
-	mockDB.On("Query",
+	mockDB.On("Query", mock.Anything,
 		mock.AnythingOfType("string"),
 		mock.Anything,
 	).Return(mResult, nil).Once()
 	mResult.On("Next").Return(true).Once()
-	mockDB.On("Insert",
+	mockDB.On("Insert", mock.Anything,
 		mock.AnythingOfType("string"),
 		mock.Anything,
 	).Return(nil).Once()
 	testTime := time.Now()
 	mResult.On("Scan", mock.Anything).Return([]interface{}{
-		11, testTime}).Once()
-	result, err := repo.CreateUserProduct(1, "test@mail.com", domain.Purchase{},
-		domain.PremiumCarousel, testTime, domain.ProductParams{
+		11, domain.ActiveProduct, testTime}).Once()
+	result, err := repo.CreateUserProduct(context.Background(), 1, "test@mail.com", domain.Purchase{},
+		domain.PremiumCarousel, time.Time{}, testTime, domain.ProductParams{
 			Categories: []int{2020, 1020},
 			Exclude:    []string{"11111", "22222"},
 		})
@@ -584,6 +586,7 @@ func TestCreateUserProductOk(t *testing.T) {
 			Categories: []int{2020, 1020},
 			Exclude:    []string{"11111", "22222"},
 		},
+		Version: 1,
 	}
 	assert.Equal(t, expected, result)
 	assert.NoError(t, err)
@@ -592,18 +595,41 @@ func TestCreateUserProductOk(t *testing.T) {
 	mLogger.AssertExpectations(t)
 }
 
+func TestCreateUserProductStartsLater(t *testing.T) {
+	mockDB := &dbHandlerMock{}
+	mResult := &mockResult{}
+	repo := MakeProductRepository(mockDB, 10, &mockProductRepoLogger{})
+	startAt := time.Now().Add(time.Hour)
+	expiredAt := startAt.Add(24 * time.Hour)
+	mResult.On("Close").Return(nil)
+	mockDB.On("Query", mock.Anything, mock.AnythingOfType("string"),
+		[]interface{}{domain.PremiumCarousel, 1, "test@mail.com", 0, expiredAt, startAt},
+	).Return(mResult, nil).Once()
+	mResult.On("Next").Return(true).Once()
+	mResult.On("Scan", mock.Anything).Return([]interface{}{
+		11, domain.InactiveProduct, time.Now()}).Once()
+	mockDB.On("Insert", mock.Anything, mock.AnythingOfType("string"), mock.Anything).
+		Return(nil).Once()
+	result, err := repo.CreateUserProduct(context.Background(), 1, "test@mail.com", domain.Purchase{},
+		domain.PremiumCarousel, startAt, expiredAt, domain.ProductParams{})
+	assert.NoError(t, err)
+	assert.Equal(t, domain.InactiveProduct, result.Status)
+	mockDB.AssertExpectations(t)
+	mResult.AssertExpectations(t)
+}
+
 func TestCreateUserProductQueryError(t *testing.T) {
 	mockDB := &dbHandlerMock{}
 	mResult := &mockResult{}
 	mLogger := &mockProductRepoLogger{}
 	repo := MakeProductRepository(mockDB, 10, mLogger)
-	mockDB.On("Query",
+	mockDB.On("Query", mock.Anything,
 		mock.AnythingOfType("string"),
 		mock.Anything,
 	).Return(mResult, fmt.Errorf("err")).Once()
 	testTime := time.Now()
-	_, err := repo.CreateUserProduct(1, "test@mail.com", domain.Purchase{},
-		domain.PremiumCarousel, testTime, domain.ProductParams{
+	_, err := repo.CreateUserProduct(context.Background(), 1, "test@mail.com", domain.Purchase{},
+		domain.PremiumCarousel, time.Time{}, testTime, domain.ProductParams{
 			Categories: []int{2020, 1020},
 			Exclude:    []string{"11111", "22222"},
 		})
@@ -618,15 +644,15 @@ func TestCreateUserProductNextError(t *testing.T) {
 	mResult := &mockResult{}
 	mLogger := &mockProductRepoLogger{}
 	repo := MakeProductRepository(mockDB, 10, mLogger)
-	mockDB.On("Query",
+	mockDB.On("Query", mock.Anything,
 		mock.AnythingOfType("string"),
 		mock.Anything,
 	).Return(mResult, nil).Once()
 	mResult.On("Close").Return(nil).Once()
 	mResult.On("Next").Return(false).Once()
 	testTime := time.Now()
-	_, err := repo.CreateUserProduct(1, "test@mail.com", domain.Purchase{},
-		domain.PremiumCarousel, testTime, domain.ProductParams{
+	_, err := repo.CreateUserProduct(context.Background(), 1, "test@mail.com", domain.Purchase{},
+		domain.PremiumCarousel, time.Time{}, testTime, domain.ProductParams{
 			Categories: []int{2020, 1020},
 			Exclude:    []string{"11111", "22222"},
 		})
@@ -642,20 +668,20 @@ func TestCreateUserProductAddConfigError(t *testing.T) {
 	mLogger := &mockProductRepoLogger{}
 	repo := MakeProductRepository(mockDB, 10, mLogger)
 	mResult.On("Close").Return(nil).Once()
-	mockDB.On("Query",
+	mockDB.On("Query", mock.Anything,
 		mock.AnythingOfType("string"),
 		mock.Anything,
 	).Return(mResult, nil).Once()
 	mResult.On("Next").Return(true).Once()
-	mockDB.On("Insert",
+	mockDB.On("Insert", mock.Anything,
 		mock.AnythingOfType("string"),
 		mock.Anything,
 	).Return(fmt.Errorf("e")).Once()
 	testTime := time.Now()
 	mResult.On("Scan", mock.Anything).Return([]interface{}{
-		11, testTime}).Once()
-	_, err := repo.CreateUserProduct(1, "test@mail.com", domain.Purchase{},
-		domain.PremiumCarousel, testTime, domain.ProductParams{
+		11, domain.ActiveProduct, testTime}).Once()
+	_, err := repo.CreateUserProduct(context.Background(), 1, "test@mail.com", domain.Purchase{},
+		domain.PremiumCarousel, time.Time{}, testTime, domain.ProductParams{
 			Categories: []int{2020, 1020},
 			Exclude:    []string{"11111", "22222"},
 		})
@@ -671,7 +697,7 @@ func TestGetUserProductByIDOK(t *testing.T) {
 	mLogger := &mockProductRepoLogger{}
 	repo := MakeProductRepository(mockDB, 10, mLogger)
 	mResult.On("Close").Return(nil)
-	mockDB.On("Query",
+	mockDB.On("Query", mock.Anything,
 		mock.AnythingOfType("string"),
 		mock.Anything,
 	).Return(mResult, nil).Once()
@@ -681,8 +707,8 @@ func TestGetUserProductByIDOK(t *testing.T) {
 		11, domain.PremiumCarousel, 1, "test@mail.com", domain.ActiveProduct,
 		testTime, testTime, 0, 0, domain.AdminPurchase, domain.AcceptedPurchase,
 		100, testTime, []string{"categories=2020,1020",
-			"keywords=a,b,c", "exclude=1,2,3", "comment=comentario"}}).Once()
-	result, err := repo.GetUserProductByID(11)
+			"keywords=a,b,c", "exclude=1,2,3", "comment=comentario"}, 3}).Once()
+	result, err := repo.GetUserProductByID(context.Background(), 11)
 	expected := domain.Product{
 		ID:        11,
 		Type:      domain.PremiumCarousel,
@@ -705,6 +731,7 @@ func TestGetUserProductByIDOK(t *testing.T) {
 			Keywords:   []string{"a", "b", "c"},
 			Comment:    "comentario",
 		},
+		Version: 3,
 	}
 	assert.Equal(t, expected, result)
 	assert.NoError(t, err)
@@ -713,16 +740,34 @@ func TestGetUserProductByIDOK(t *testing.T) {
 	mLogger.AssertExpectations(t)
 }
 
+func TestGetUserProductByIDNotFound(t *testing.T) {
+	mockDB := &dbHandlerMock{}
+	mResult := &mockResult{}
+	mLogger := &mockProductRepoLogger{}
+	repo := MakeProductRepository(mockDB, 10, mLogger)
+	mResult.On("Close").Return(nil)
+	mockDB.On("Query", mock.Anything,
+		mock.AnythingOfType("string"),
+		mock.Anything,
+	).Return(mResult, nil).Once()
+	mResult.On("Next").Return(false).Once()
+	_, err := repo.GetUserProductByID(context.Background(), 11)
+	assert.Equal(t, usecases.ErrProductNotFound, err)
+	mockDB.AssertExpectations(t)
+	mResult.AssertExpectations(t)
+	mLogger.AssertExpectations(t)
+}
+
 func TestGetUserProductByIDQueryError(t *testing.T) {
 	mockDB := &dbHandlerMock{}
 	mResult := &mockResult{}
 	mLogger := &mockProductRepoLogger{}
 	repo := MakeProductRepository(mockDB, 10, mLogger)
-	mockDB.On("Query",
+	mockDB.On("Query", mock.Anything,
 		mock.AnythingOfType("string"),
 		mock.Anything,
 	).Return(mResult, fmt.Errorf("err")).Once()
-	_, err := repo.GetUserProductByID(11)
+	_, err := repo.GetUserProductByID(context.Background(), 11)
 	assert.Error(t, err)
 	mockDB.AssertExpectations(t)
 	mResult.AssertExpectations(t)
@@ -735,7 +780,7 @@ func TestGetUserProductByIDParseConfigError(t *testing.T) {
 	mLogger := &mockProductRepoLogger{}
 	repo := MakeProductRepository(mockDB, 10, mLogger)
 	mResult.On("Close").Return(nil)
-	mockDB.On("Query",
+	mockDB.On("Query", mock.Anything,
 		mock.AnythingOfType("string"),
 		mock.Anything,
 	).Return(mResult, nil).Once()
@@ -744,8 +789,8 @@ func TestGetUserProductByIDParseConfigError(t *testing.T) {
 	mResult.On("Scan", mock.Anything).Return([]interface{}{
 		11, domain.PremiumCarousel, 1, "test@mail.com", domain.ActiveProduct,
 		testTime, testTime, 0, 0, domain.AdminPurchase, domain.AcceptedPurchase,
-		100, testTime, []string{}}).Once()
-	_, err := repo.GetUserProductByID(11)
+		100, testTime, []string{}, 3}).Once()
+	_, err := repo.GetUserProductByID(context.Background(), 11)
 
 	assert.Error(t, err)
 	mockDB.AssertExpectations(t)
@@ -758,16 +803,22 @@ func TestSetPartialConfigOK(t *testing.T) {
 	mResult := &mockResult{}
 	mLogger := &mockProductRepoLogger{}
 	repo := MakeProductRepository(mockDB, 10, mLogger)
+	status, expiredAt := domain.ActiveProduct, time.Now()
+	limit, comment := 5, ""
 	mResult.On("Close").Return(nil)
-	mockDB.On("Query",
+	mockDB.On("Query", mock.Anything,
 		mock.AnythingOfType("string"),
 		mock.Anything,
-	).Return(mResult, nil).Once()
-	mLogger.On("LogWarnPartialConfigNotSupported",
-		mock.Anything, mock.Anything)
-	err := repo.SetPartialConfig(11, map[string]interface{}{
-		"status": "ACTIVE",
-		"other":  "not supported",
+	).Return(mResult, nil).Twice()
+	mockDB.On("Insert", mock.Anything,
+		mock.AnythingOfType("string"),
+		[]interface{}{11, "limit", "5", 11, "comment", ""},
+	).Return(nil).Once()
+	err := repo.SetPartialConfig(context.Background(), 11, usecases.ProductPatch{
+		Status:    &status,
+		ExpiredAt: &expiredAt,
+		Limit:     &limit,
+		Comment:   &comment,
 	})
 	assert.NoError(t, err)
 	mockDB.AssertExpectations(t)
@@ -775,18 +826,29 @@ func TestSetPartialConfigOK(t *testing.T) {
 	mLogger.AssertExpectations(t)
 }
 
+func TestSetPartialConfigEmptyPatch(t *testing.T) {
+	mockDB := &dbHandlerMock{}
+	mLogger := &mockProductRepoLogger{}
+	repo := MakeProductRepository(mockDB, 10, mLogger)
+	err := repo.SetPartialConfig(context.Background(), 11, usecases.ProductPatch{})
+	assert.NoError(t, err)
+	mockDB.AssertExpectations(t)
+	mLogger.AssertExpectations(t)
+}
+
 func TestSetPartialConfigError(t *testing.T) {
 	mockDB := &dbHandlerMock{}
 	mResult := &mockResult{}
 	mLogger := &mockProductRepoLogger{}
 	repo := MakeProductRepository(mockDB, 10, mLogger)
-	mockDB.On("Query",
+	status := domain.ActiveProduct
+	mockDB.On("Query", mock.Anything,
 		mock.AnythingOfType("string"),
 		mock.Anything,
 	).Return(mResult, fmt.Errorf("err")).Once()
 
-	err := repo.SetPartialConfig(11, map[string]interface{}{
-		"status": "ACTIVE",
+	err := repo.SetPartialConfig(context.Background(), 11, usecases.ProductPatch{
+		Status: &status,
 	})
 	assert.Error(t, err)
 	mockDB.AssertExpectations(t)
@@ -794,17 +856,34 @@ func TestSetPartialConfigError(t *testing.T) {
 	mLogger.AssertExpectations(t)
 }
 
+func TestSetPartialConfigInsertError(t *testing.T) {
+	mockDB := &dbHandlerMock{}
+	mLogger := &mockProductRepoLogger{}
+	repo := MakeProductRepository(mockDB, 10, mLogger)
+	categories := []int{1000, 2020}
+	mockDB.On("Insert", mock.Anything,
+		mock.AnythingOfType("string"),
+		[]interface{}{11, "categories", "1000,2020"},
+	).Return(fmt.Errorf("err")).Once()
+	err := repo.SetPartialConfig(context.Background(), 11, usecases.ProductPatch{
+		Categories: &categories,
+	})
+	assert.Error(t, err)
+	mockDB.AssertExpectations(t)
+	mLogger.AssertExpectations(t)
+}
+
 func TestSetExpirationOK(t *testing.T) {
 	mockDB := &dbHandlerMock{}
 	mResult := &mockResult{}
 	mLogger := &mockProductRepoLogger{}
 	repo := MakeProductRepository(mockDB, 10, mLogger)
 	mResult.On("Close").Return(nil)
-	mockDB.On("Query",
+	mockDB.On("Query", mock.Anything,
 		mock.AnythingOfType("string"),
 		mock.Anything,
 	).Return(mResult, nil).Once()
-	err := repo.SetExpiration(11, time.Now())
+	err := repo.SetExpiration(context.Background(), 11, time.Now())
 	assert.NoError(t, err)
 	mockDB.AssertExpectations(t)
 	mResult.AssertExpectations(t)
@@ -816,40 +895,294 @@ func TestSetExpirationError(t *testing.T) {
 	mResult := &mockResult{}
 	mLogger := &mockProductRepoLogger{}
 	repo := MakeProductRepository(mockDB, 10, mLogger)
-	mockDB.On("Query",
+	mockDB.On("Query", mock.Anything,
 		mock.AnythingOfType("string"),
 		mock.Anything,
 	).Return(mResult, fmt.Errorf("err")).Once()
-	err := repo.SetExpiration(11, time.Now())
+	err := repo.SetExpiration(context.Background(), 11, time.Now())
+	assert.Error(t, err)
+	mockDB.AssertExpectations(t)
+	mResult.AssertExpectations(t)
+	mLogger.AssertExpectations(t)
+}
+
+func TestExpireProductsOK(t *testing.T) {
+	mockDB := &dbHandlerMock{}
+	mResult := &mockResult{}
+	mLogger := &mockProductRepoLogger{}
+	repo := MakeProductRepository(mockDB, 10, mLogger)
+	mResult.On("Close").Return(nil)
+	mockDB.On("Query", mock.Anything,
+		mock.AnythingOfType("string"),
+		mock.Anything,
+	).Return(mResult, nil).Once()
+	mResult.On("Next").Return(true).Once()
+	mResult.On("Next").Return(false).Once()
+	testTime := time.Now()
+	mResult.On("Scan", mock.Anything).Return([]interface{}{
+		11, domain.PremiumCarousel, 1, "test@mail.com", domain.ExpiredProduct,
+		testTime, testTime, 7, 30, domain.PaymentPurchase, domain.AcceptedPurchase,
+		990, testTime, []string{"limit=5"}, 4}).Once()
+	products, err := repo.ExpireProducts(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.Product{{
+		ID:        11,
+		Type:      domain.PremiumCarousel,
+		UserID:    1,
+		Email:     "test@mail.com",
+		Status:    domain.ExpiredProduct,
+		ExpiredAt: testTime,
+		CreatedAt: testTime,
+		Purchase: domain.Purchase{
+			ID:        7,
+			Number:    30,
+			Price:     990,
+			Type:      domain.PaymentPurchase,
+			Status:    domain.AcceptedPurchase,
+			CreatedAt: testTime,
+		},
+		Config: domain.ProductParams{
+			Categories: []int{},
+			Exclude:    []string{},
+			Keywords:   []string{},
+			Limit:      5,
+		},
+		Version: 4,
+	}}, products)
+	mockDB.AssertExpectations(t)
+	mResult.AssertExpectations(t)
+	mLogger.AssertExpectations(t)
+}
+
+func TestExpireProductsError(t *testing.T) {
+	mockDB := &dbHandlerMock{}
+	mResult := &mockResult{}
+	mLogger := &mockProductRepoLogger{}
+	repo := MakeProductRepository(mockDB, 10, mLogger)
+	mockDB.On("Query", mock.Anything,
+		mock.AnythingOfType("string"),
+		mock.Anything,
+	).Return(mResult, fmt.Errorf("err"))
+	_, err := repo.ExpireProducts(context.Background())
+	assert.Error(t, err)
+	mockDB.AssertExpectations(t)
+	mLogger.AssertExpectations(t)
+}
+
+func TestActivateProductsOK(t *testing.T) {
+	mockDB := &dbHandlerMock{}
+	mResult := &mockResult{}
+	mLogger := &mockProductRepoLogger{}
+	repo := MakeProductRepository(mockDB, 10, mLogger)
+	mResult.On("Close").Return(nil)
+	mockDB.On("Query", mock.Anything,
+		mock.AnythingOfType("string"),
+		mock.Anything,
+	).Return(mResult, nil).Once()
+	mResult.On("Next").Return(false).Once()
+	products, err := repo.ActivateProducts(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.Product{}, products)
+	mockDB.AssertExpectations(t)
+	mResult.AssertExpectations(t)
+	mLogger.AssertExpectations(t)
+}
+
+func TestActivateProductsError(t *testing.T) {
+	mockDB := &dbHandlerMock{}
+	mResult := &mockResult{}
+	mLogger := &mockProductRepoLogger{}
+	repo := MakeProductRepository(mockDB, 10, mLogger)
+	mockDB.On("Query", mock.Anything,
+		mock.AnythingOfType("string"),
+		mock.Anything,
+	).Return(mResult, fmt.Errorf("err"))
+	_, err := repo.ActivateProducts(context.Background())
+	assert.Error(t, err)
+	mockDB.AssertExpectations(t)
+	mLogger.AssertExpectations(t)
+}
+
+func TestGetActiveProductsOK(t *testing.T) {
+	mockDB := &dbHandlerMock{}
+	mResult := &mockResult{}
+	mLogger := &mockProductRepoLogger{}
+	repo := MakeProductRepository(mockDB, 10, mLogger)
+	mResult.On("Close").Return(nil)
+	mockDB.On("Query", mock.Anything,
+		mock.AnythingOfType("string"),
+		mock.Anything,
+	).Return(mResult, nil).Once()
+	mResult.On("Next").Return(true).Once()
+	mResult.On("Next").Return(false).Once()
+	testTime := time.Now()
+	mResult.On("Scan", mock.Anything).Return([]interface{}{
+		11, domain.PremiumCarousel, 1, "test@mail.com", domain.ActiveProduct,
+		testTime, testTime, 0, 0, domain.AdminPurchase, domain.AcceptedPurchase,
+		100, testTime, []string{"categories=2020", "limit=5"}, 3}).Once()
+	products, err := repo.GetActiveProducts(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.Product{{
+		ID:        11,
+		Type:      domain.PremiumCarousel,
+		UserID:    1,
+		Email:     "test@mail.com",
+		Status:    domain.ActiveProduct,
+		ExpiredAt: testTime,
+		CreatedAt: testTime,
+		Purchase: domain.Purchase{
+			Price:     100,
+			Type:      domain.AdminPurchase,
+			Status:    domain.AcceptedPurchase,
+			CreatedAt: testTime,
+		},
+		Config: domain.ProductParams{
+			Categories: []int{2020},
+			Exclude:    []string{},
+			Keywords:   []string{},
+			Limit:      5,
+		},
+		Version: 3,
+	}}, products)
+	mockDB.AssertExpectations(t)
+	mResult.AssertExpectations(t)
+	mLogger.AssertExpectations(t)
+}
+
+func TestGetActiveProductsError(t *testing.T) {
+	mockDB := &dbHandlerMock{}
+	mResult := &mockResult{}
+	mLogger := &mockProductRepoLogger{}
+	repo := MakeProductRepository(mockDB, 10, mLogger)
+	mockDB.On("Query", mock.Anything,
+		mock.AnythingOfType("string"),
+		mock.Anything,
+	).Return(mResult, fmt.Errorf("err"))
+	_, err := repo.GetActiveProducts(context.Background())
+	assert.Error(t, err)
+	mockDB.AssertExpectations(t)
+	mLogger.AssertExpectations(t)
+}
+
+func TestCountActiveProductsOK(t *testing.T) {
+	mockDB := &dbHandlerMock{}
+	mResult := &mockResult{}
+	repo := MakeProductRepository(mockDB, 10, &mockProductRepoLogger{})
+	mResult.On("Close").Return(nil)
+	mockDB.On("Query", mock.Anything, mock.AnythingOfType("string"), mock.Anything).
+		Return(mResult, nil).Once()
+	mResult.On("Next").Return(true).Once()
+	mResult.On("Next").Return(false).Once()
+	mResult.On("Scan", mock.Anything).Return([]interface{}{domain.PremiumCarousel, 4}).Once()
+	totals, err := repo.CountActiveProducts(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, map[domain.ProductType]int{domain.PremiumCarousel: 4}, totals)
+	mockDB.AssertExpectations(t)
+	mResult.AssertExpectations(t)
+}
+
+func TestCountActiveProductsError(t *testing.T) {
+	mockDB := &dbHandlerMock{}
+	repo := MakeProductRepository(mockDB, 10, &mockProductRepoLogger{})
+	mockDB.On("Query", mock.Anything, mock.AnythingOfType("string"), mock.Anything).
+		Return(&mockResult{}, fmt.Errorf("err"))
+	_, err := repo.CountActiveProducts(context.Background())
 	assert.Error(t, err)
 	mockDB.AssertExpectations(t)
+}
+
+func TestIncrementVersionOK(t *testing.T) {
+	mockDB := &dbHandlerMock{}
+	mResult := &mockResult{}
+	mLogger := &mockProductRepoLogger{}
+	repo := MakeProductRepository(mockDB, 10, mLogger)
+	mResult.On("Close").Return(nil)
+	mockDB.On("Query", mock.Anything,
+		mock.AnythingOfType("string"),
+		[]interface{}{11, 3},
+	).Return(mResult, nil).Once()
+	mResult.On("Next").Return(true).Once()
+	mResult.On("Scan", mock.Anything).Return([]interface{}{4}).Once()
+	version, err := repo.IncrementVersion(context.Background(), 11, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, version)
+	mockDB.AssertExpectations(t)
 	mResult.AssertExpectations(t)
 	mLogger.AssertExpectations(t)
 }
 
-func TestSetExpirateProductsOK(t *testing.T) {
+func TestIncrementVersionMismatch(t *testing.T) {
 	mockDB := &dbHandlerMock{}
+	mResult := &mockResult{}
 	mLogger := &mockProductRepoLogger{}
 	repo := MakeProductRepository(mockDB, 10, mLogger)
-	mockDB.On("Update",
+	mResult.On("Close").Return(nil)
+	mockDB.On("Query", mock.Anything,
+		mock.AnythingOfType("string"),
+		[]interface{}{11, 2},
+	).Return(mResult, nil).Once()
+	mResult.On("Next").Return(false).Once()
+	_, err := repo.IncrementVersion(context.Background(), 11, 2)
+	assert.Equal(t, usecases.ErrVersionMismatch, err)
+	mockDB.AssertExpectations(t)
+	mResult.AssertExpectations(t)
+	mLogger.AssertExpectations(t)
+}
+
+func TestIncrementVersionQueryError(t *testing.T) {
+	mockDB := &dbHandlerMock{}
+	mResult := &mockResult{}
+	mLogger := &mockProductRepoLogger{}
+	repo := MakeProductRepository(mockDB, 10, mLogger)
+	mockDB.On("Query", mock.Anything,
 		mock.AnythingOfType("string"),
 		mock.Anything,
-	).Return(nil)
-	err := repo.ExpireProducts()
+	).Return(mResult, fmt.Errorf("err")).Once()
+	_, err := repo.IncrementVersion(context.Background(), 11, 2)
+	assert.Error(t, err)
+	mockDB.AssertExpectations(t)
+	mResult.AssertExpectations(t)
+	mLogger.AssertExpectations(t)
+}
+
+func TestGetProductsExpiringBeforeOK(t *testing.T) {
+	mockDB := &dbHandlerMock{}
+	mResult := &mockResult{}
+	mLogger := &mockProductRepoLogger{}
+	repo := MakeProductRepository(mockDB, 10, mLogger)
+	date := time.Now().Add(24 * time.Hour)
+	mResult.On("Close").Return(nil)
+	mockDB.On("Query", mock.Anything,
+		mock.AnythingOfType("string"),
+		[]interface{}{date},
+	).Return(mResult, nil).Once()
+	mResult.On("Next").Return(true).Once()
+	mResult.On("Next").Return(false).Once()
+	testTime := time.Now()
+	mResult.On("Scan", mock.Anything).Return([]interface{}{
+		11, domain.PremiumCarousel, 1, "test@mail.com", domain.ActiveProduct,
+		testTime, testTime, 0, 0, domain.AdminPurchase, domain.AcceptedPurchase,
+		100, testTime, []string{"limit=5"}, 3}).Once()
+	products, err := repo.GetProductsExpiringBefore(context.Background(), date)
 	assert.NoError(t, err)
+	assert.Len(t, products, 1)
+	assert.Equal(t, 11, products[0].ID)
+	assert.Equal(t, 5, products[0].Config.Limit)
 	mockDB.AssertExpectations(t)
+	mResult.AssertExpectations(t)
 	mLogger.AssertExpectations(t)
 }
 
-func TestSetExpirateProductsError(t *testing.T) {
+func TestGetProductsExpiringBeforeError(t *testing.T) {
 	mockDB := &dbHandlerMock{}
+	mResult := &mockResult{}
 	mLogger := &mockProductRepoLogger{}
 	repo := MakeProductRepository(mockDB, 10, mLogger)
-	mockDB.On("Update",
+	mockDB.On("Query", mock.Anything,
 		mock.AnythingOfType("string"),
 		mock.Anything,
-	).Return(fmt.Errorf("err"))
-	err := repo.ExpireProducts()
+	).Return(mResult, fmt.Errorf("err"))
+	_, err := repo.GetProductsExpiringBefore(context.Background(), time.Now())
 	assert.Error(t, err)
 	mockDB.AssertExpectations(t)
 	mLogger.AssertExpectations(t)
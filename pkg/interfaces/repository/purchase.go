@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"fmt"
 
 	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/domain"
@@ -19,20 +20,26 @@ func MakePurchaseRepository(handler DbHandler) usecases.PurchaseRepository {
 	}
 }
 
-// CreatePurchase creates a new purchase
-func (repo *purchaseRepo) CreatePurchase(purchaseNumber, price int,
+// CreatePurchase creates a new purchase. A payment already stored with the
+// same number is reported with usecases.ErrPurchaseAlreadyExists
+func (repo *purchaseRepo) CreatePurchase(ctx context.Context, purchaseNumber, price int,
 	purchaseType domain.PurchaseType) (purchase domain.Purchase, err error) {
-	result, err := repo.handler.Query(
+	result, err := repo.handler.Query(ctx,
 		`INSERT INTO purchase(purchase_number, price, purchase_type)
 			VALUES (
 				$1, $2, $3
-			) RETURNING id, created_at, purchase_status`, purchaseNumber, price, purchaseType)
+			)
+			ON CONFLICT (purchase_type, purchase_number) WHERE purchase_type = 'PAYMENT'
+			DO NOTHING
+			RETURNING id, created_at, purchase_status`, purchaseNumber, price, purchaseType)
 	if err != nil {
 		return domain.Purchase{}, err
 	}
 	defer result.Close()
 	if result.Next() {
 		result.Scan(&purchase.ID, &purchase.CreatedAt, &purchase.Status)
+	} else if purchaseType == domain.PaymentPurchase {
+		return domain.Purchase{}, usecases.ErrPurchaseAlreadyExists
 	} else {
 		return domain.Purchase{},
 			fmt.Errorf("next error: getting purchaseID from database")
@@ -48,8 +55,9 @@ func (repo *purchaseRepo) CreatePurchase(purchaseNumber, price int,
 }
 
 // AcceptePurchase changes the purchase status to Accepted
-func (repo *purchaseRepo) AcceptPurchase(purchase domain.Purchase) (domain.Purchase, error) {
-	if err := repo.setStatus(purchase.ID, domain.AcceptedPurchase); err != nil {
+func (repo *purchaseRepo) AcceptPurchase(ctx context.Context,
+	purchase domain.Purchase) (domain.Purchase, error) {
+	if err := repo.setStatus(ctx, purchase.ID, domain.AcceptedPurchase); err != nil {
 		return domain.Purchase{}, err
 	}
 	purchase.Status = domain.AcceptedPurchase
@@ -57,9 +65,10 @@ func (repo *purchaseRepo) AcceptPurchase(purchase domain.Purchase) (domain.Purch
 }
 
 // setStatus sets the purchase status
-func (repo *purchaseRepo) setStatus(purchaseID int, status domain.PurchaseStatus) error {
+func (repo *purchaseRepo) setStatus(ctx context.Context, purchaseID int,
+	status domain.PurchaseStatus) error {
 	result, err := repo.handler.
-		Query(
+		Query(ctx,
 			`UPDATE purchase SET purchase_status=$1 WHERE id=$2`,
 			status,
 			purchaseID,
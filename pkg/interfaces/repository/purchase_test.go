@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"fmt"
 	"testing"
 	"time"
@@ -8,6 +9,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/domain"
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/usecases"
 )
 
 func TestMakePurchaseRepositoryOK(t *testing.T) {
@@ -23,7 +25,7 @@ func TestCreatePurchaseOK(t *testing.T) {
 	mockDB := &dbHandlerMock{}
 	mResult := &mockResult{}
 	testTime := time.Now()
-	mockDB.On("Query",
+	mockDB.On("Query", mock.Anything,
 		mock.AnythingOfType("string"),
 		mock.AnythingOfType("[]interface {}"),
 	).Return(mResult, nil)
@@ -32,7 +34,7 @@ func TestCreatePurchaseOK(t *testing.T) {
 	mResult.On("Scan", mock.Anything).
 		Return([]interface{}{123, testTime, domain.PendingPurchase})
 	repo := MakePurchaseRepository(mockDB)
-	result, err := repo.CreatePurchase(10, 100, domain.AdminPurchase)
+	result, err := repo.CreatePurchase(context.Background(), 10, 100, domain.AdminPurchase)
 	assert.NoError(t, err)
 	expected := domain.Purchase{
 		ID:        123,
@@ -50,12 +52,12 @@ func TestCreatePurchaseOK(t *testing.T) {
 func TestCreatePurchaseQueryError(t *testing.T) {
 	mockDB := &dbHandlerMock{}
 	mResult := &mockResult{}
-	mockDB.On("Query",
+	mockDB.On("Query", mock.Anything,
 		mock.AnythingOfType("string"),
 		mock.AnythingOfType("[]interface {}"),
 	).Return(mResult, fmt.Errorf("err"))
 	repo := MakePurchaseRepository(mockDB)
-	_, err := repo.CreatePurchase(10, 100, domain.AdminPurchase)
+	_, err := repo.CreatePurchase(context.Background(), 10, 100, domain.AdminPurchase)
 	assert.Error(t, err)
 	mockDB.AssertExpectations(t)
 	mResult.AssertExpectations(t)
@@ -64,24 +66,40 @@ func TestCreatePurchaseQueryError(t *testing.T) {
 func TestCreatePurchaseNextError(t *testing.T) {
 	mockDB := &dbHandlerMock{}
 	mResult := &mockResult{}
-	mockDB.On("Query",
+	mockDB.On("Query", mock.Anything,
 		mock.AnythingOfType("string"),
 		mock.AnythingOfType("[]interface {}"),
 	).Return(mResult, nil)
 	mResult.On("Close").Return(nil)
 	mResult.On("Next").Return(false).Once()
 	repo := MakePurchaseRepository(mockDB)
-	_, err := repo.CreatePurchase(10, 100, domain.AdminPurchase)
+	_, err := repo.CreatePurchase(context.Background(), 10, 100, domain.AdminPurchase)
 	assert.Error(t, err)
 	mockDB.AssertExpectations(t)
 	mResult.AssertExpectations(t)
 }
 
+func TestCreatePurchasePaymentExists(t *testing.T) {
+	mockDB := &dbHandlerMock{}
+	mResult := &mockResult{}
+	mockDB.On("Query", mock.Anything,
+		mock.AnythingOfType("string"),
+		mock.AnythingOfType("[]interface {}"),
+	).Return(mResult, nil)
+	mResult.On("Close").Return(nil)
+	mResult.On("Next").Return(false).Once()
+	repo := MakePurchaseRepository(mockDB)
+	_, err := repo.CreatePurchase(context.Background(), 10, 100, domain.PaymentPurchase)
+	assert.Equal(t, usecases.ErrPurchaseAlreadyExists, err)
+	mockDB.AssertExpectations(t)
+	mResult.AssertExpectations(t)
+}
+
 func TestAcceptPurchaseOK(t *testing.T) {
 	mockDB := &dbHandlerMock{}
 	mResult := &mockResult{}
 	testTime := time.Now()
-	mockDB.On("Query",
+	mockDB.On("Query", mock.Anything,
 		mock.AnythingOfType("string"),
 		mock.AnythingOfType("[]interface {}"),
 	).Return(mResult, nil)
@@ -95,7 +113,7 @@ func TestAcceptPurchaseOK(t *testing.T) {
 		Status:    domain.PendingPurchase,
 		CreatedAt: testTime,
 	}
-	newPurchase, err := repo.AcceptPurchase(prevPurchase)
+	newPurchase, err := repo.AcceptPurchase(context.Background(), prevPurchase)
 	expected := prevPurchase
 	expected.Status = domain.AcceptedPurchase
 	assert.NoError(t, err)
@@ -107,12 +125,12 @@ func TestAcceptPurchaseOK(t *testing.T) {
 func TestAcceptPurchaseError(t *testing.T) {
 	mockDB := &dbHandlerMock{}
 	mResult := &mockResult{}
-	mockDB.On("Query",
+	mockDB.On("Query", mock.Anything,
 		mock.AnythingOfType("string"),
 		mock.AnythingOfType("[]interface {}"),
 	).Return(mResult, fmt.Errorf("err"))
 	repo := MakePurchaseRepository(mockDB)
-	_, err := repo.AcceptPurchase(domain.Purchase{})
+	_, err := repo.AcceptPurchase(context.Background(), domain.Purchase{})
 	assert.Error(t, err)
 	mockDB.AssertExpectations(t)
 	mResult.AssertExpectations(t)
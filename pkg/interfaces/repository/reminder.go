@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/domain"
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/usecases"
+)
+
+// reminderRepo holds connections to record expiration reminders
+type reminderRepo struct {
+	handler DbHandler
+}
+
+// MakeExpirationReminderRepository creates a new instance of
+// ExpirationReminderRepository
+func MakeExpirationReminderRepository(handler DbHandler) usecases.ExpirationReminderRepository {
+	return &reminderRepo{
+		handler: handler,
+	}
+}
+
+// Claim records the reminder of the product expiration for the window.
+// Returns false when the reminder was already recorded
+func (repo *reminderRepo) Claim(ctx context.Context, product domain.Product,
+	window time.Duration) (bool, error) {
+	result, err := repo.handler.Query(ctx,
+		`INSERT INTO user_product_reminder(user_product_id, window_minutes, expired_at)
+			VALUES (
+				$1, $2, $3
+			) ON CONFLICT DO NOTHING RETURNING user_product_id`,
+		product.ID, int(window.Minutes()), product.ExpiredAt)
+	if err != nil {
+		return false, err
+	}
+	defer result.Close()
+	return result.Next(), nil
+}
@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/domain"
+)
+
+func TestClaimReminderOK(t *testing.T) {
+	mockDB := &dbHandlerMock{}
+	mResult := &mockResult{}
+	repo := MakeExpirationReminderRepository(mockDB)
+	expiredAt := time.Now()
+	mockDB.On("Query", mock.Anything, mock.AnythingOfType("string"),
+		[]interface{}{11, 1440, expiredAt}).Return(mResult, nil)
+	mResult.On("Next").Return(true).Once()
+	mResult.On("Close").Return(nil)
+	claimed, err := repo.Claim(context.Background(), domain.Product{ID: 11, ExpiredAt: expiredAt}, 24*time.Hour)
+	assert.NoError(t, err)
+	assert.True(t, claimed)
+	mockDB.AssertExpectations(t)
+	mResult.AssertExpectations(t)
+}
+
+func TestClaimReminderAlreadyClaimed(t *testing.T) {
+	mockDB := &dbHandlerMock{}
+	mResult := &mockResult{}
+	repo := MakeExpirationReminderRepository(mockDB)
+	mockDB.On("Query", mock.Anything, mock.AnythingOfType("string"), mock.Anything).
+		Return(mResult, nil)
+	mResult.On("Next").Return(false).Once()
+	mResult.On("Close").Return(nil)
+	claimed, err := repo.Claim(context.Background(), domain.Product{ID: 11}, 24*time.Hour)
+	assert.NoError(t, err)
+	assert.False(t, claimed)
+	mockDB.AssertExpectations(t)
+	mResult.AssertExpectations(t)
+}
+
+func TestClaimReminderError(t *testing.T) {
+	mockDB := &dbHandlerMock{}
+	mResult := &mockResult{}
+	repo := MakeExpirationReminderRepository(mockDB)
+	mockDB.On("Query", mock.Anything, mock.AnythingOfType("string"), mock.Anything).
+		Return(mResult, fmt.Errorf("err"))
+	_, err := repo.Claim(context.Background(), domain.Product{ID: 11}, 24*time.Hour)
+	assert.Error(t, err)
+	mockDB.AssertExpectations(t)
+}
@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/usecases"
+)
+
+// transactionRunner runs operations over repositories sharing a transaction
+type transactionRunner struct {
+	handler              TransactionalDbHandler
+	resultsPerPage       int
+	logger               ProductRepositoryLogger
+	premiumProductsTopic string
+}
+
+// MakeTransactionRunner creates a new instance of TransactionRunner. The
+// backend events of the operations are stored in the outbox
+func MakeTransactionRunner(handler TransactionalDbHandler, resultsPerPage int,
+	logger ProductRepositoryLogger, premiumProductsTopic string) usecases.TransactionRunner {
+	return &transactionRunner{
+		handler:              handler,
+		resultsPerPage:       resultsPerPage,
+		logger:               logger,
+		premiumProductsTopic: premiumProductsTopic,
+	}
+}
+
+// RunInTransaction runs the operation in a new transaction, committed only
+// when the operation succeeds
+func (runner *transactionRunner) RunInTransaction(ctx context.Context,
+	operation func(repos usecases.TransactionalRepositories) error) error {
+	tx, err := runner.handler.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Close()
+	err = operation(usecases.TransactionalRepositories{
+		Products:      MakeProductRepository(tx, runner.resultsPerPage, runner.logger),
+		Purchases:     MakePurchaseRepository(tx),
+		BackendEvents: MakeBackendEventsOutbox(tx, runner.premiumProductsTopic),
+		Reminders:     MakeExpirationReminderRepository(tx),
+	})
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/domain"
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/usecases"
+)
+
+type dbTransactionMock struct {
+	dbHandlerMock
+}
+
+func (m *dbTransactionMock) Commit() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+func (m *dbTransactionMock) Rollback() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+type transactionalDbHandlerMock struct {
+	dbHandlerMock
+}
+
+func (m *transactionalDbHandlerMock) Begin(ctx context.Context) (DbTransaction, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(DbTransaction), args.Error(1)
+}
+
+func TestRunInTransactionCommit(t *testing.T) {
+	mockDB := &transactionalDbHandlerMock{}
+	mockTx := &dbTransactionMock{}
+	runner := MakeTransactionRunner(mockDB, 10, nil, "topic")
+	mockDB.On("Begin", mock.Anything, mock.Anything).Return(mockTx, nil)
+	mockTx.On("Insert", mock.Anything, mock.AnythingOfType("string"), mock.Anything).Return(nil)
+	mockTx.On("Commit").Return(nil)
+	mockTx.On("Close").Return(nil)
+	err := runner.RunInTransaction(context.Background(), func(repos usecases.TransactionalRepositories) error {
+		return repos.BackendEvents.PushExpiration(context.Background(), domain.Product{ID: 1,
+			Type: domain.PremiumCarousel})
+	})
+	assert.NoError(t, err)
+	mockDB.AssertExpectations(t)
+	mockTx.AssertExpectations(t)
+}
+
+func TestRunInTransactionRollback(t *testing.T) {
+	mockDB := &transactionalDbHandlerMock{}
+	mockTx := &dbTransactionMock{}
+	runner := MakeTransactionRunner(mockDB, 10, nil, "topic")
+	mockDB.On("Begin", mock.Anything, mock.Anything).Return(mockTx, nil)
+	mockTx.On("Close").Return(nil)
+	err := runner.RunInTransaction(context.Background(), func(repos usecases.TransactionalRepositories) error {
+		return fmt.Errorf("err")
+	})
+	assert.Error(t, err)
+	mockDB.AssertExpectations(t)
+	mockTx.AssertExpectations(t)
+}
+
+func TestRunInTransactionBeginError(t *testing.T) {
+	mockDB := &transactionalDbHandlerMock{}
+	runner := MakeTransactionRunner(mockDB, 10, nil, "topic")
+	mockDB.On("Begin", mock.Anything, mock.Anything).Return(&dbTransactionMock{}, fmt.Errorf("err"))
+	err := runner.RunInTransaction(context.Background(), func(repos usecases.TransactionalRepositories) error {
+		return nil
+	})
+	assert.Error(t, err)
+	mockDB.AssertExpectations(t)
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/usecases"
+)
+
+// TwoTierCacheRepository keeps the cached responses read from a shared cache
+// in a local cache for a short time, by cache type, saving the round-trips to
+// the shared one. The writes go to the shared cache and are announced on a
+// redis channel, every pod subscribed to it drops its local copy through
+// Invalidate. A copy read while it's being replaced may be kept stale until
+// its local ttl ends
+type TwoTierCacheRepository struct {
+	shared    usecases.CacheRepository
+	local     LocalCache
+	publisher RedisPublisher
+	channel   string
+	ttls      map[usecases.CacheType]time.Duration
+}
+
+// NewTwoTierCacheRepository returns a TwoTierCacheRepository over shared.
+// The cache types without ttl aren't kept locally
+func NewTwoTierCacheRepository(shared usecases.CacheRepository, local LocalCache,
+	publisher RedisPublisher, channel string,
+	ttls map[usecases.CacheType]time.Duration) *TwoTierCacheRepository {
+	return &TwoTierCacheRepository{
+		shared:    shared,
+		local:     local,
+		publisher: publisher,
+		channel:   channel,
+		ttls:      ttls,
+	}
+}
+
+// makeLocalKey generates the key of the local cache, also sent on the
+// invalidations
+func (repo *TwoTierCacheRepository) makeLocalKey(key string, cacheType usecases.CacheType) string {
+	return strings.Join([]string{key, string(cacheType)}, ":")
+}
+
+// GetCache returns the response of a cached request, from the local cache
+// when it's held there
+func (repo *TwoTierCacheRepository) GetCache(ctx context.Context, key string,
+	cacheType usecases.CacheType) ([]byte, error) {
+	ttl := repo.ttls[cacheType]
+	if ttl <= 0 {
+		return repo.shared.GetCache(ctx, key, cacheType)
+	}
+	k := repo.makeLocalKey(key, cacheType)
+	if value, ok := repo.local.Get(k); ok {
+		return value, nil
+	}
+	value, err := repo.shared.GetCache(ctx, key, cacheType)
+	if err != nil {
+		return nil, err
+	}
+	repo.local.Set(k, value, ttl)
+	return value, nil
+}
+
+// SetCache saves the response of request in the shared cache and invalidates
+// the local copies
+func (repo *TwoTierCacheRepository) SetCache(ctx context.Context, key string, cacheType usecases.CacheType,
+	data interface{}, expiration time.Duration) error {
+	if err := repo.shared.SetCache(ctx, key, cacheType, data, expiration); err != nil {
+		return err
+	}
+	return repo.invalidateAll(ctx, key, cacheType)
+}
+
+// DelCache removes a cached response from the shared cache and invalidates
+// the local copies
+func (repo *TwoTierCacheRepository) DelCache(ctx context.Context, key string,
+	cacheType usecases.CacheType) error {
+	if err := repo.shared.DelCache(ctx, key, cacheType); err != nil {
+		return err
+	}
+	return repo.invalidateAll(ctx, key, cacheType)
+}
+
+// invalidateAll drops the local copy of key and tells the other pods to drop
+// theirs
+func (repo *TwoTierCacheRepository) invalidateAll(ctx context.Context, key string,
+	cacheType usecases.CacheType) error {
+	if repo.ttls[cacheType] <= 0 {
+		return nil
+	}
+	k := repo.makeLocalKey(key, cacheType)
+	repo.local.Del(k)
+	if err := repo.publisher.Publish(ctx, repo.channel, k); err != nil {
+		return fmt.Errorf("error publishing the invalidation of %s: %w", k, err)
+	}
+	return nil
+}
+
+// Invalidate drops the local copy of the key received from the channel
+func (repo *TwoTierCacheRepository) Invalidate(key string) {
+	repo.local.Del(key)
+}
@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/usecases"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockCacheRepo struct {
+	mock.Mock
+}
+
+func (m *mockCacheRepo) SetCache(ctx context.Context, key string, typ usecases.CacheType,
+	data interface{}, expiration time.Duration) error {
+	args := m.Called(ctx, key, typ, data, expiration)
+	return args.Error(0)
+}
+
+func (m *mockCacheRepo) GetCache(ctx context.Context, key string, typ usecases.CacheType) ([]byte, error) {
+	args := m.Called(ctx, key, typ)
+	return args.Get(0).([]byte), args.Error(1)
+}
+
+func (m *mockCacheRepo) DelCache(ctx context.Context, key string, typ usecases.CacheType) error {
+	args := m.Called(ctx, key, typ)
+	return args.Error(0)
+}
+
+type mockLocalCache struct {
+	mock.Mock
+}
+
+func (m *mockLocalCache) Get(key string) ([]byte, bool) {
+	args := m.Called(key)
+	return args.Get(0).([]byte), args.Bool(1)
+}
+
+func (m *mockLocalCache) Set(key string, value []byte, expiration time.Duration) {
+	m.Called(key, value, expiration)
+}
+
+func (m *mockLocalCache) Del(key string) {
+	m.Called(key)
+}
+
+type mockRedisPublisher struct {
+	mock.Mock
+}
+
+func (m *mockRedisPublisher) Publish(ctx context.Context, channel, message string) error {
+	args := m.Called(ctx, channel, message)
+	return args.Error(0)
+}
+
+func makeTestTwoTierCache() (*TwoTierCacheRepository, *mockCacheRepo, *mockLocalCache, *mockRedisPublisher) {
+	shared, local, publisher := &mockCacheRepo{}, &mockLocalCache{}, &mockRedisPublisher{}
+	repo := NewTwoTierCacheRepository(shared, local, publisher, "invalidations",
+		map[usecases.CacheType]time.Duration{usecases.ProductCacheType: time.Second})
+	return repo, shared, local, publisher
+}
+
+func TestTwoTierGetCacheLocalHit(t *testing.T) {
+	repo, shared, local, publisher := makeTestTwoTierCache()
+	local.On("Get", "user:1:cache-product").Return([]byte("1"), true)
+	result, err := repo.GetCache(context.Background(), "user:1", usecases.ProductCacheType)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("1"), result)
+	shared.AssertExpectations(t)
+	local.AssertExpectations(t)
+	publisher.AssertExpectations(t)
+}
+
+func TestTwoTierGetCacheLocalMiss(t *testing.T) {
+	repo, shared, local, _ := makeTestTwoTierCache()
+	local.On("Get", "user:1:cache-product").Return([]byte(nil), false)
+	shared.On("GetCache", mock.Anything, "user:1", usecases.ProductCacheType).
+		Return([]byte("1"), nil)
+	local.On("Set", "user:1:cache-product", []byte("1"), time.Second)
+	result, err := repo.GetCache(context.Background(), "user:1", usecases.ProductCacheType)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("1"), result)
+	shared.AssertExpectations(t)
+	local.AssertExpectations(t)
+}
+
+func TestTwoTierGetCacheSharedError(t *testing.T) {
+	repo, shared, local, _ := makeTestTwoTierCache()
+	local.On("Get", "user:1:cache-product").Return([]byte(nil), false)
+	shared.On("GetCache", mock.Anything, "user:1", usecases.ProductCacheType).
+		Return([]byte(nil), fmt.Errorf("KEY_NOT_FOUND"))
+	_, err := repo.GetCache(context.Background(), "user:1", usecases.ProductCacheType)
+	assert.Error(t, err)
+	shared.AssertExpectations(t)
+	local.AssertExpectations(t)
+}
+
+func TestTwoTierGetCacheNotKeptLocally(t *testing.T) {
+	repo, shared, local, _ := makeTestTwoTierCache()
+	shared.On("GetCache", mock.Anything, "1", usecases.MinifiedAdDataType).
+		Return([]byte("1"), nil)
+	result, err := repo.GetCache(context.Background(), "1", usecases.MinifiedAdDataType)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("1"), result)
+	shared.AssertExpectations(t)
+	local.AssertExpectations(t)
+}
+
+func TestTwoTierSetCache(t *testing.T) {
+	repo, shared, local, publisher := makeTestTwoTierCache()
+	shared.On("SetCache", mock.Anything, "user:1", usecases.ProductCacheType, "data", time.Hour).
+		Return(nil)
+	local.On("Del", "user:1:cache-product")
+	publisher.On("Publish", mock.Anything, "invalidations", "user:1:cache-product").Return(nil)
+	err := repo.SetCache(context.Background(), "user:1", usecases.ProductCacheType, "data", time.Hour)
+	assert.NoError(t, err)
+	shared.AssertExpectations(t)
+	local.AssertExpectations(t)
+	publisher.AssertExpectations(t)
+}
+
+func TestTwoTierSetCacheError(t *testing.T) {
+	repo, shared, local, publisher := makeTestTwoTierCache()
+	shared.On("SetCache", mock.Anything, "user:1", usecases.ProductCacheType, "data", time.Hour).
+		Return(fmt.Errorf("err"))
+	err := repo.SetCache(context.Background(), "user:1", usecases.ProductCacheType, "data", time.Hour)
+	assert.Error(t, err)
+	shared.AssertExpectations(t)
+	local.AssertExpectations(t)
+	publisher.AssertExpectations(t)
+}
+
+func TestTwoTierDelCachePublishError(t *testing.T) {
+	repo, shared, local, publisher := makeTestTwoTierCache()
+	shared.On("DelCache", mock.Anything, "user:1", usecases.ProductCacheType).Return(nil)
+	local.On("Del", "user:1:cache-product")
+	publisher.On("Publish", mock.Anything, "invalidations", "user:1:cache-product").
+		Return(fmt.Errorf("err"))
+	err := repo.DelCache(context.Background(), "user:1", usecases.ProductCacheType)
+	assert.Error(t, err)
+	shared.AssertExpectations(t)
+	local.AssertExpectations(t)
+	publisher.AssertExpectations(t)
+}
+
+func TestTwoTierInvalidate(t *testing.T) {
+	repo, _, local, _ := makeTestTwoTierCache()
+	local.On("Del", "user:1:cache-product")
+	repo.Invalidate("user:1:cache-product")
+	local.AssertExpectations(t)
+}
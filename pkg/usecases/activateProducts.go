@@ -0,0 +1,88 @@
+package usecases
+
+import (
+	"context"
+
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/domain"
+)
+
+// ActivateProductsInteractor wraps ActivateProducts operations
+type ActivateProductsInteractor interface {
+	ActivateProducts(ctx context.Context) error
+}
+
+// activateProductsInteractor defines the interactor for ActivateProducts usecase
+type activateProductsInteractor struct {
+	productRepo          ProductRepository
+	cacheRepo            CacheRepository
+	logger               ActivateProductsLogger
+	settings             SettingsProvider
+	transactions         TransactionRunner
+	backendEventsEnabled bool
+	tracer               Tracer
+}
+
+// ActivateProductsLogger logs ActivateProducts events
+type ActivateProductsLogger interface {
+	LogErrorActivatingProducts(err error)
+	LogWarnSettingCache(userID int, err error)
+}
+
+// MakeActivateProductsInteractor creates a new instance of ActivateProductsInteractor
+func MakeActivateProductsInteractor(
+	productRepo ProductRepository,
+	cacheRepo CacheRepository,
+	logger ActivateProductsLogger,
+	settings SettingsProvider,
+	transactions TransactionRunner,
+	backendEventsEnabled bool,
+	tracer Tracer,
+) ActivateProductsInteractor {
+	return &activateProductsInteractor{
+		productRepo:          productRepo,
+		cacheRepo:            cacheRepo,
+		logger:               logger,
+		settings:             settings,
+		transactions:         transactions,
+		backendEventsEnabled: backendEventsEnabled,
+		tracer:               tracer,
+	}
+}
+
+// ActivateProducts set active status for the products whose start date was
+// reached, also refreshes the cache of their users. Their change of status
+// events are stored in the same transaction
+func (interactor *activateProductsInteractor) ActivateProducts(ctx context.Context) (err error) {
+	ctx, span := interactor.tracer.Start(ctx, "ActivateProducts")
+	defer func() { span.End(err) }()
+	var products []domain.Product
+	err = runInTransaction(ctx, interactor.transactions, "error activating products",
+		func(repos TransactionalRepositories) error {
+			var err error
+			if products, err = repos.Products.ActivateProducts(ctx); err != nil {
+				return newDatabaseError("error activating products", err)
+			}
+			if !interactor.backendEventsEnabled {
+				return nil
+			}
+			for _, product := range products {
+				if err = repos.BackendEvents.PushStatusChange(ctx, product,
+					domain.InactiveProduct); err != nil {
+					return newDatabaseError("cannot store the activation events", err)
+				}
+			}
+			return nil
+		})
+	if err != nil {
+		interactor.logger.LogErrorActivatingProducts(err)
+		return err
+	}
+	for _, product := range products {
+		err := refreshUserProductCache(ctx, interactor.productRepo,
+			interactor.cacheRepo, product.UserID, interactor.settings.Settings().CacheTTL)
+		if err != nil {
+			interactor.logger.LogWarnSettingCache(product.UserID, err)
+		}
+	}
+	return nil
+}
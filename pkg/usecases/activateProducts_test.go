@@ -0,0 +1,100 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/domain"
+)
+
+type mockActivateProductsLogger struct {
+	mock.Mock
+}
+
+func (m *mockActivateProductsLogger) LogErrorActivatingProducts(err error) {
+	m.Called(err)
+}
+
+func (m *mockActivateProductsLogger) LogWarnSettingCache(userID int, err error) {
+	m.Called(userID, err)
+}
+
+func TestActivateProductsOk(t *testing.T) {
+	mProductRepo := &mockProductRepo{}
+	mCacheRepo := &mockCacheRepo{}
+	mLogger := &mockActivateProductsLogger{}
+	interactor := MakeActivateProductsInteractor(mProductRepo, mCacheRepo,
+		mLogger, StaticSettings{CacheTTL: time.Hour}, makeMockTransactionRunner(mProductRepo, nil, nil), false, &mockTracer{})
+	product := domain.Product{ID: 1, UserID: 123, Status: domain.ActiveProduct}
+	mProductRepo.On("ActivateProducts", mock.Anything, mock.Anything).Return([]domain.Product{product}, nil)
+	mProductRepo.On("GetUserActiveProduct", mock.Anything, 123, domain.PremiumCarousel).
+		Return(product, nil)
+	mCacheRepo.On("SetCache", mock.Anything, "user:123:PREMIUM_CAROUSEL", ProductCacheType,
+		product, time.Hour).Return(nil)
+	err := interactor.ActivateProducts(context.Background())
+	assert.NoError(t, err)
+	mProductRepo.AssertExpectations(t)
+	mCacheRepo.AssertExpectations(t)
+	mLogger.AssertExpectations(t)
+}
+
+func TestActivateProductsErrorGettingActiveProduct(t *testing.T) {
+	mProductRepo := &mockProductRepo{}
+	mCacheRepo := &mockCacheRepo{}
+	mLogger := &mockActivateProductsLogger{}
+	interactor := MakeActivateProductsInteractor(mProductRepo, mCacheRepo,
+		mLogger, StaticSettings{CacheTTL: time.Hour}, makeMockTransactionRunner(mProductRepo, nil, nil), false, &mockTracer{})
+	mProductRepo.On("ActivateProducts", mock.Anything, mock.Anything).Return([]domain.Product{{ID: 1, UserID: 123}}, nil)
+	mProductRepo.On("GetUserActiveProduct", mock.Anything, 123, domain.PremiumCarousel).
+		Return(domain.Product{}, fmt.Errorf("err"))
+	mLogger.On("LogWarnSettingCache", 123, mock.Anything)
+	err := interactor.ActivateProducts(context.Background())
+	assert.NoError(t, err)
+	mProductRepo.AssertExpectations(t)
+	mCacheRepo.AssertExpectations(t)
+	mLogger.AssertExpectations(t)
+}
+
+func TestActivateProductsRepoError(t *testing.T) {
+	mProductRepo := &mockProductRepo{}
+	mCacheRepo := &mockCacheRepo{}
+	mLogger := &mockActivateProductsLogger{}
+	interactor := MakeActivateProductsInteractor(mProductRepo, mCacheRepo,
+		mLogger, StaticSettings{CacheTTL: time.Hour}, makeMockTransactionRunner(mProductRepo, nil, nil), false, &mockTracer{})
+	mProductRepo.On("ActivateProducts", mock.Anything, mock.Anything).Return([]domain.Product{}, fmt.Errorf("err"))
+	mLogger.On("LogErrorActivatingProducts", mock.Anything)
+	err := interactor.ActivateProducts(context.Background())
+	assert.Error(t, err)
+	mProductRepo.AssertExpectations(t)
+	mCacheRepo.AssertExpectations(t)
+	mLogger.AssertExpectations(t)
+}
+
+func TestActivateProductsPushesEvents(t *testing.T) {
+	mProductRepo := &mockProductRepo{}
+	mCacheRepo := &mockCacheRepo{}
+	mLogger := &mockActivateProductsLogger{}
+	mBackendEventRepo := &mockBackendEventRepo{}
+	interactor := MakeActivateProductsInteractor(mProductRepo, mCacheRepo,
+		mLogger, StaticSettings{CacheTTL: time.Hour},
+		makeMockTransactionRunner(mProductRepo, nil, mBackendEventRepo), true, &mockTracer{})
+	product := domain.Product{ID: 1, UserID: 123, Status: domain.ActiveProduct}
+	mProductRepo.On("ActivateProducts", mock.Anything, mock.Anything).Return([]domain.Product{product}, nil)
+	mProductRepo.On("GetUserActiveProduct", mock.Anything, 123, domain.PremiumCarousel).
+		Return(product, nil)
+	mCacheRepo.On("SetCache", mock.Anything, "user:123:PREMIUM_CAROUSEL", ProductCacheType,
+		product, time.Hour).Return(nil)
+	mBackendEventRepo.On("PushStatusChange", mock.Anything, product, domain.InactiveProduct).
+		Return(nil)
+	err := interactor.ActivateProducts(context.Background())
+	assert.NoError(t, err)
+	mProductRepo.AssertExpectations(t)
+	mCacheRepo.AssertExpectations(t)
+	mLogger.AssertExpectations(t)
+	mBackendEventRepo.AssertExpectations(t)
+}
@@ -1,7 +1,11 @@
 package usecases
 
 import (
-	"fmt"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"strconv"
 	"strings"
 	"time"
@@ -11,82 +15,197 @@ import (
 
 // AddUserProductInteractor wraps AddUserProduct operations
 type AddUserProductInteractor interface {
-	AddUserProduct(userID int, email string,
+	AddUserProduct(ctx context.Context, idempotencyKey string, userID int, email string,
 		purchaseNumber, purchasePrice int, purchaseType domain.PurchaseType,
-		productType domain.ProductType, expiredAt time.Time,
-		config domain.ProductParams) error
+		productType domain.ProductType, startAt, expiredAt time.Time,
+		config domain.ProductParams) (domain.Product, error)
 }
 
 // addUserProductInteractor defines the interactor for addUserProduct usecase
 type addUserProductInteractor struct {
-	productRepo          ProductRepository
-	purchaseRepo         PurchaseRepository
+	transactions         TransactionRunner
 	cacheRepo            CacheRepository
 	logger               AddUserProductLogger
-	cacheTTL             time.Duration
-	backendEventsRepo    BackendEventsRepository
+	settings             SettingsProvider
 	backendEventsEnabled bool
+	idempotencyRepo      IdempotencyRepository
+	tracer               Tracer
 }
 
 // AddUserProductLogger logs AddUserProduct events
 type AddUserProductLogger interface {
-	LogErrorAddingProduct(userID int, err error)
-	LogWarnSettingCache(userID int, err error)
-	LogWarnPushingEvent(productID int, err error)
+	LogErrorAddingProduct(ctx context.Context, userID int, err error)
+	LogWarnSettingCache(ctx context.Context, userID int, err error)
+	LogWarnStoringIdempotencyKey(ctx context.Context, key string, err error)
 }
 
 // MakeAddUserProductInteractor creates a new instance of AddUserProductInteractor
-func MakeAddUserProductInteractor(productRepo ProductRepository,
-	purchaseRepo PurchaseRepository,
+func MakeAddUserProductInteractor(transactions TransactionRunner,
 	cacheRepo CacheRepository, logger AddUserProductLogger,
-	cacheTTL time.Duration, BackendEventsRepo BackendEventsRepository,
-	backendEventsEnabled bool) AddUserProductInteractor {
-	return &addUserProductInteractor{productRepo: productRepo,
-		purchaseRepo: purchaseRepo, cacheRepo: cacheRepo,
-		logger: logger, cacheTTL: cacheTTL,
-		backendEventsRepo:    BackendEventsRepo,
-		backendEventsEnabled: backendEventsEnabled}
+	settings SettingsProvider, backendEventsEnabled bool,
+	idempotencyRepo IdempotencyRepository, tracer Tracer) AddUserProductInteractor {
+	return &addUserProductInteractor{transactions: transactions,
+		cacheRepo: cacheRepo, logger: logger, settings: settings,
+		backendEventsEnabled: backendEventsEnabled,
+		idempotencyRepo:      idempotencyRepo, tracer: tracer}
 }
 
-// AddUserProduct associates a new product to user
-func (interactor *addUserProductInteractor) AddUserProduct(userID int, email string,
+// addUserProductParams holds the params that identify an AddUserProduct call
+type addUserProductParams struct {
+	UserID         int
+	Email          string
+	PurchaseNumber int
+	PurchasePrice  int
+	PurchaseType   domain.PurchaseType
+	ProductType    domain.ProductType
+	// StartAt is left out of the fingerprint when not given, so the keys
+	// stored before it existed still match
+	StartAt   *time.Time `json:",omitempty"`
+	ExpiredAt time.Time
+	Config    domain.ProductParams
+}
+
+// AddUserProduct associates a new product to user and returns it. When an
+// idempotency key is given, calls repeated with the same key and params return
+// the original product instead of creating it again
+func (interactor *addUserProductInteractor) AddUserProduct(ctx context.Context,
+	idempotencyKey string, userID int, email string,
 	purchaseNumber, purchasePrice int, purchaseType domain.PurchaseType,
-	productType domain.ProductType, expiredAt time.Time,
-	config domain.ProductParams) error {
-	purchase, err := interactor.purchaseRepo.CreatePurchase(purchaseNumber,
-		purchasePrice, purchaseType)
-	if err != nil {
-		interactor.logger.LogErrorAddingProduct(userID, err)
-		return fmt.Errorf("cannot create purchase: %+v", err)
+	productType domain.ProductType, startAt, expiredAt time.Time,
+	config domain.ProductParams) (product domain.Product, err error) {
+	ctx, span := interactor.tracer.Start(ctx, "AddUserProduct")
+	defer func() { span.End(err) }()
+	params := addUserProductParams{UserID: userID, Email: email,
+		PurchaseNumber: purchaseNumber, PurchasePrice: purchasePrice,
+		PurchaseType: purchaseType, ProductType: productType,
+		ExpiredAt: expiredAt, Config: config}
+	if !startAt.IsZero() {
+		params.StartAt = &startAt
 	}
-	product, err := interactor.productRepo.CreateUserProduct(userID, email,
-		purchase, productType, expiredAt, config)
+	if idempotencyKey == "" {
+		return interactor.addUserProduct(ctx, params)
+	}
+	fingerprint := params.fingerprint()
+	var reserved bool
+	reserved, product, err = interactor.reserve(ctx, userID, idempotencyKey, fingerprint)
+	if !reserved {
+		return product, err
+	}
+	product, err = interactor.addUserProduct(ctx, params)
 	if err != nil {
-		interactor.logger.LogErrorAddingProduct(userID, err)
-		return fmt.Errorf("cannot set control-panel configuration: %+v", err)
+		if releaseErr := interactor.idempotencyRepo.Release(ctx, idempotencyKey); releaseErr != nil {
+			interactor.logger.LogWarnStoringIdempotencyKey(ctx, idempotencyKey, releaseErr)
+		}
+		return domain.Product{}, err
 	}
-	product.Purchase, err = interactor.purchaseRepo.AcceptPurchase(product.Purchase)
+	err = interactor.idempotencyRepo.Save(ctx, idempotencyKey, IdempotencyRecord{
+		Fingerprint: fingerprint, Completed: true, Product: product})
 	if err != nil {
-		interactor.logger.LogErrorAddingProduct(userID, err)
-		return fmt.Errorf("cannot set control-panel configuration: %+v", err)
+		interactor.logger.LogWarnStoringIdempotencyKey(ctx, idempotencyKey, err)
 	}
-	interactor.refreshCache(product)
-	if interactor.backendEventsEnabled {
-		if err := interactor.backendEventsRepo.
-			PushSoldProduct(product); err != nil {
-			interactor.logger.LogWarnPushingEvent(product.ID, err)
+	return product, nil
+}
+
+// reserve takes the idempotency key for the request. When the key is taken,
+// the request that took it is replayed instead, unless its lock expired
+// meanwhile: the key is free again then, so it's reserved once more
+func (interactor *addUserProductInteractor) reserve(ctx context.Context, userID int,
+	idempotencyKey, fingerprint string) (bool, domain.Product, error) {
+	for retried := false; ; retried = true {
+		reserved, err := interactor.idempotencyRepo.Reserve(ctx, idempotencyKey,
+			IdempotencyRecord{Fingerprint: fingerprint})
+		if err != nil {
+			interactor.logger.LogErrorAddingProduct(ctx, userID, err)
+			return false, domain.Product{}, newCacheError("cannot reserve idempotency key", err)
+		}
+		if reserved {
+			return true, domain.Product{}, nil
 		}
+		product, err := interactor.replay(ctx, idempotencyKey, fingerprint)
+		if retried || !errors.Is(err, ErrIdempotencyRecordNotFound) {
+			return false, product, err
+		}
+	}
+}
+
+// replay returns the product created by the operation already made with the key
+func (interactor *addUserProductInteractor) replay(ctx context.Context,
+	idempotencyKey, fingerprint string) (domain.Product, error) {
+	record, err := interactor.idempotencyRepo.Get(ctx, idempotencyKey)
+	if err != nil {
+		return domain.Product{}, newCacheError("cannot get idempotency key", err)
+	}
+	if record.Fingerprint != fingerprint {
+		return domain.Product{}, ErrIdempotencyKeyReused
+	}
+	if !record.Completed {
+		return domain.Product{}, ErrRequestInProgress
+	}
+	return record.Product, nil
+}
+
+// fingerprint returns a digest that identifies the params
+func (params addUserProductParams) fingerprint() string {
+	raw, _ := json.Marshal(params) // nolint
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// addUserProduct creates the purchase and the product of the params, along
+// with its sold event in the same transaction
+func (interactor *addUserProductInteractor) addUserProduct(ctx context.Context,
+	params addUserProductParams) (domain.Product, error) {
+	var product domain.Product
+	err := runInTransaction(ctx, interactor.transactions, "cannot add the product",
+		func(repos TransactionalRepositories) error {
+			purchase, err := repos.Purchases.CreatePurchase(ctx, params.PurchaseNumber,
+				params.PurchasePrice, params.PurchaseType)
+			if errors.Is(err, ErrPurchaseAlreadyExists) {
+				return err
+			}
+			if err != nil {
+				return newDatabaseError("cannot create purchase", err)
+			}
+			var startAt time.Time
+			if params.StartAt != nil {
+				startAt = *params.StartAt
+			}
+			product, err = repos.Products.CreateUserProduct(ctx, params.UserID, params.Email,
+				purchase, params.ProductType, startAt, params.ExpiredAt, params.Config)
+			if err != nil {
+				return newDatabaseError("cannot set control-panel configuration", err)
+			}
+			product.Purchase, err = repos.Purchases.AcceptPurchase(ctx, product.Purchase)
+			if err != nil {
+				return newDatabaseError("cannot set control-panel configuration", err)
+			}
+			if !interactor.backendEventsEnabled {
+				return nil
+			}
+			if err = repos.BackendEvents.PushSoldProduct(ctx, product); err != nil {
+				return newDatabaseError("cannot store the sold product event", err)
+			}
+			return nil
+		})
+	if err != nil {
+		interactor.logger.LogErrorAddingProduct(ctx, params.UserID, err)
+		return domain.Product{}, err
+	}
+	// a product starting later is cached by the activation job, until then
+	// the user is served their current product
+	if product.Status == domain.ActiveProduct {
+		interactor.refreshCache(ctx, product)
 	}
-	return nil
+	return product, nil
 }
 
 // refreshCache updates cache in repository for user product
-func (interactor *addUserProductInteractor) refreshCache(product domain.Product) {
+func (interactor *addUserProductInteractor) refreshCache(ctx context.Context, product domain.Product) {
 	cacheError := interactor.cacheRepo.
-		SetCache(strings.Join([]string{"user",
+		SetCache(ctx, strings.Join([]string{"user",
 			strconv.Itoa(product.UserID), string(domain.PremiumCarousel)}, ":"),
-			ProductCacheType, product, interactor.cacheTTL)
+			ProductCacheType, product, interactor.settings.Settings().CacheTTL)
 	if cacheError != nil {
-		interactor.logger.LogWarnSettingCache(product.UserID, cacheError)
+		interactor.logger.LogWarnSettingCache(ctx, product.UserID, cacheError)
 	}
 }
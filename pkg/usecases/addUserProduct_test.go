@@ -1,6 +1,8 @@
 package usecases
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"testing"
 	"time"
@@ -15,14 +17,14 @@ type mockPurchaseRepo struct {
 	mock.Mock
 }
 
-func (m *mockPurchaseRepo) CreatePurchase(purchaseNumber, price int,
+func (m *mockPurchaseRepo) CreatePurchase(ctx context.Context, purchaseNumber, price int,
 	purchaseType domain.PurchaseType) (domain.Purchase, error) {
-	args := m.Called(purchaseNumber, price, purchaseType)
+	args := m.Called(ctx, purchaseNumber, price, purchaseType)
 	return args.Get(0).(domain.Purchase), args.Error(1)
 }
 
-func (m *mockPurchaseRepo) AcceptPurchase(purchase domain.Purchase) (domain.Purchase, error) {
-	args := m.Called(purchase)
+func (m *mockPurchaseRepo) AcceptPurchase(ctx context.Context, purchase domain.Purchase) (domain.Purchase, error) {
+	args := m.Called(ctx, purchase)
 	return args.Get(0).(domain.Purchase), args.Error(1)
 }
 
@@ -30,60 +32,175 @@ type mockAddUserProductLogger struct {
 	mock.Mock
 }
 
-func (m *mockAddUserProductLogger) LogErrorAddingProduct(userID int, err error) {
-	m.Called(userID, err)
+func (m *mockAddUserProductLogger) LogErrorAddingProduct(ctx context.Context, userID int, err error) {
+	m.Called(ctx, userID, err)
 }
 
-func (m *mockAddUserProductLogger) LogWarnSettingCache(userID int, err error) {
-	m.Called(userID, err)
+func (m *mockAddUserProductLogger) LogWarnSettingCache(ctx context.Context, userID int, err error) {
+	m.Called(ctx, userID, err)
 }
 
-func (m *mockAddUserProductLogger) LogWarnPushingEvent(productID int, err error) {
-	m.Called(productID, err)
+func (m *mockAddUserProductLogger) LogWarnStoringIdempotencyKey(ctx context.Context, key string, err error) {
+	m.Called(ctx, key, err)
+}
+
+type mockIdempotencyRepo struct {
+	mock.Mock
+}
+
+func (m *mockIdempotencyRepo) Reserve(ctx context.Context, key string, record IdempotencyRecord) (bool, error) {
+	args := m.Called(ctx, key, record)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *mockIdempotencyRepo) Get(ctx context.Context, key string) (IdempotencyRecord, error) {
+	args := m.Called(ctx, key)
+	return args.Get(0).(IdempotencyRecord), args.Error(1)
+}
+
+func (m *mockIdempotencyRepo) Save(ctx context.Context, key string, record IdempotencyRecord) error {
+	args := m.Called(ctx, key, record)
+	return args.Error(0)
+}
+
+func (m *mockIdempotencyRepo) Release(ctx context.Context, key string) error {
+	args := m.Called(ctx, key)
+	return args.Error(0)
+}
+
+type mockTransactionRunner struct {
+	mock.Mock
+	repos TransactionalRepositories
+}
+
+// RunInTransaction runs the operation over the mocked repositories, unless
+// an error is set to be returned
+func (m *mockTransactionRunner) RunInTransaction(ctx context.Context,
+	operation func(repos TransactionalRepositories) error) error {
+	if len(m.ExpectedCalls) > 0 {
+		if err := m.Called(ctx).Error(0); err != nil {
+			return err
+		}
+	}
+	return operation(m.repos)
+}
+
+func makeMockTransactionRunner(products ProductRepository, purchases PurchaseRepository,
+	backendEvents BackendEventsRepository) *mockTransactionRunner {
+	return &mockTransactionRunner{repos: TransactionalRepositories{Products: products,
+		Purchases: purchases, BackendEvents: backendEvents}}
 }
 
 type mockBackendEventRepo struct {
 	mock.Mock
 }
 
-func (m *mockBackendEventRepo) PushSoldProduct(product domain.Product) error {
-	args := m.Called(product)
+func (m *mockBackendEventRepo) PushSoldProduct(ctx context.Context, product domain.Product) error {
+	args := m.Called(ctx, product)
+	return args.Error(0)
+}
+
+func (m *mockBackendEventRepo) PushExpirationReminder(ctx context.Context, product domain.Product,
+	window time.Duration) error {
+	args := m.Called(ctx, product, window)
+	return args.Error(0)
+}
+
+func (m *mockBackendEventRepo) PushConfigChange(ctx context.Context, product domain.Product,
+	previous domain.ProductParams) error {
+	args := m.Called(ctx, product, previous)
+	return args.Error(0)
+}
+
+func (m *mockBackendEventRepo) PushStatusChange(ctx context.Context, product domain.Product,
+	previous domain.ProductStatus) error {
+	args := m.Called(ctx, product, previous)
+	return args.Error(0)
+}
+
+func (m *mockBackendEventRepo) PushExpiration(ctx context.Context, product domain.Product) error {
+	args := m.Called(ctx, product)
+	return args.Error(0)
+}
+
+func (m *mockBackendEventRepo) PushExtension(ctx context.Context, product domain.Product,
+	previous time.Time) error {
+	args := m.Called(ctx, product, previous)
+	return args.Error(0)
+}
+
+func (m *mockBackendEventRepo) PushCancellation(ctx context.Context, product domain.Product,
+	previous domain.ProductStatus) error {
+	args := m.Called(ctx, product, previous)
 	return args.Error(0)
 }
 
 func TestAddProductOk(t *testing.T) {
-	product := domain.Product{}
+	product := domain.Product{Status: domain.ActiveProduct}
 	mProductRepo := &mockProductRepo{}
 	mPurchaseRepo := &mockPurchaseRepo{}
 	mCacheRepo := &mockCacheRepo{}
 	mLogger := &mockAddUserProductLogger{}
 	mBackendEventRepo := &mockBackendEventRepo{}
-	interactor := MakeAddUserProductInteractor(mProductRepo, mPurchaseRepo,
-		mCacheRepo, mLogger, 0, mBackendEventRepo, true)
-	mCacheRepo.On("SetCache", mock.AnythingOfType("string"),
+	interactor := MakeAddUserProductInteractor(
+		makeMockTransactionRunner(mProductRepo, mPurchaseRepo, mBackendEventRepo),
+		mCacheRepo, mLogger, StaticSettings{}, true, nil, &mockTracer{})
+	mCacheRepo.On("SetCache", mock.Anything, mock.AnythingOfType("string"),
 		ProductCacheType,
 		mock.AnythingOfType("domain.Product"),
 		mock.Anything).
 		Return(nil)
-	mPurchaseRepo.On("CreatePurchase",
+	mPurchaseRepo.On("CreatePurchase", mock.Anything,
 		mock.AnythingOfType("int"),
 		mock.AnythingOfType("int"),
 		mock.AnythingOfType("domain.PurchaseType")).Return(domain.Purchase{}, nil)
-	mProductRepo.On("CreateUserProduct",
+	mProductRepo.On("CreateUserProduct", mock.Anything,
 		mock.AnythingOfType("int"),
 		mock.AnythingOfType("string"),
 		mock.AnythingOfType("domain.Purchase"),
 		domain.PremiumCarousel,
 		mock.AnythingOfType("time.Time"),
+		mock.AnythingOfType("time.Time"),
 		mock.AnythingOfType("domain.ProductParams"),
 	).Return(product, nil)
-	mPurchaseRepo.On("AcceptPurchase",
+	mPurchaseRepo.On("AcceptPurchase", mock.Anything,
 		mock.AnythingOfType("domain.Purchase")).Return(domain.Purchase{}, nil)
-	mBackendEventRepo.On("PushSoldProduct",
+	mBackendEventRepo.On("PushSoldProduct", mock.Anything,
 		mock.AnythingOfType("domain.Product")).Return(nil)
-	err := interactor.AddUserProduct(0, "", 0, 0, domain.AdminPurchase,
-		domain.PremiumCarousel, time.Time{}, domain.ProductParams{})
+	_, err := interactor.AddUserProduct(context.Background(), "", 0, "", 0, 0, domain.AdminPurchase,
+		domain.PremiumCarousel, time.Time{}, time.Time{}, domain.ProductParams{})
+	assert.NoError(t, err)
+	mProductRepo.AssertExpectations(t)
+	mPurchaseRepo.AssertExpectations(t)
+	mCacheRepo.AssertExpectations(t)
+	mLogger.AssertExpectations(t)
+	mBackendEventRepo.AssertExpectations(t)
+}
+
+func TestAddProductStartingLater(t *testing.T) {
+	startAt := time.Now().Add(24 * time.Hour)
+	expiredAt := startAt.Add(30 * 24 * time.Hour)
+	product := domain.Product{ID: 7, Status: domain.InactiveProduct}
+	mProductRepo := &mockProductRepo{}
+	mPurchaseRepo := &mockPurchaseRepo{}
+	mCacheRepo := &mockCacheRepo{}
+	mLogger := &mockAddUserProductLogger{}
+	mBackendEventRepo := &mockBackendEventRepo{}
+	interactor := MakeAddUserProductInteractor(
+		makeMockTransactionRunner(mProductRepo, mPurchaseRepo, mBackendEventRepo),
+		mCacheRepo, mLogger, StaticSettings{}, true, nil, &mockTracer{})
+	mPurchaseRepo.On("CreatePurchase", mock.Anything, 1, 100, domain.AdminPurchase).
+		Return(domain.Purchase{}, nil)
+	mProductRepo.On("CreateUserProduct", mock.Anything, 123, "a@b.cl",
+		domain.Purchase{}, domain.PremiumCarousel, startAt, expiredAt,
+		mock.AnythingOfType("domain.ProductParams"),
+	).Return(product, nil)
+	mPurchaseRepo.On("AcceptPurchase", mock.Anything, domain.Purchase{}).Return(domain.Purchase{}, nil)
+	mBackendEventRepo.On("PushSoldProduct", mock.Anything, product).Return(nil)
+	created, err := interactor.AddUserProduct(context.Background(), "", 123, "a@b.cl", 1, 100,
+		domain.AdminPurchase, domain.PremiumCarousel, startAt, expiredAt, domain.ProductParams{})
 	assert.NoError(t, err)
+	assert.Equal(t, product, created)
 	mProductRepo.AssertExpectations(t)
 	mPurchaseRepo.AssertExpectations(t)
 	mCacheRepo.AssertExpectations(t)
@@ -97,17 +214,18 @@ func TestAddProductCreatePurchaseError(t *testing.T) {
 	mCacheRepo := &mockCacheRepo{}
 	mLogger := &mockAddUserProductLogger{}
 	mBackendEventRepo := &mockBackendEventRepo{}
-	interactor := MakeAddUserProductInteractor(mProductRepo, mPurchaseRepo,
-		mCacheRepo, mLogger, 0, mBackendEventRepo, false)
-	mLogger.On("LogErrorAddingProduct", mock.Anything, mock.Anything)
-	mPurchaseRepo.On("CreatePurchase",
+	interactor := MakeAddUserProductInteractor(
+		makeMockTransactionRunner(mProductRepo, mPurchaseRepo, mBackendEventRepo),
+		mCacheRepo, mLogger, StaticSettings{}, false, nil, &mockTracer{})
+	mLogger.On("LogErrorAddingProduct", mock.Anything, mock.Anything, mock.Anything)
+	mPurchaseRepo.On("CreatePurchase", mock.Anything,
 		mock.AnythingOfType("int"),
 		mock.AnythingOfType("int"),
 		mock.AnythingOfType("domain.PurchaseType")).
 		Return(domain.Purchase{}, fmt.Errorf("err"))
 
-	err := interactor.AddUserProduct(0, "", 0, 0, domain.AdminPurchase,
-		domain.PremiumCarousel, time.Time{}, domain.ProductParams{})
+	_, err := interactor.AddUserProduct(context.Background(), "", 0, "", 0, 0, domain.AdminPurchase,
+		domain.PremiumCarousel, time.Time{}, time.Time{}, domain.ProductParams{})
 	assert.Error(t, err)
 	mProductRepo.AssertExpectations(t)
 	mPurchaseRepo.AssertExpectations(t)
@@ -116,6 +234,25 @@ func TestAddProductCreatePurchaseError(t *testing.T) {
 	mBackendEventRepo.AssertExpectations(t)
 }
 
+func TestAddProductPurchaseAlreadyExists(t *testing.T) {
+	mProductRepo := &mockProductRepo{}
+	mPurchaseRepo := &mockPurchaseRepo{}
+	mLogger := &mockAddUserProductLogger{}
+	interactor := MakeAddUserProductInteractor(
+		makeMockTransactionRunner(mProductRepo, mPurchaseRepo, &mockBackendEventRepo{}),
+		&mockCacheRepo{}, mLogger, StaticSettings{}, false, nil, &mockTracer{})
+	mLogger.On("LogErrorAddingProduct", mock.Anything, mock.Anything, mock.Anything)
+	mPurchaseRepo.On("CreatePurchase", mock.Anything, 10, 990, domain.PaymentPurchase).
+		Return(domain.Purchase{}, ErrPurchaseAlreadyExists)
+
+	_, err := interactor.AddUserProduct(context.Background(), "", 1, "", 10, 990,
+		domain.PaymentPurchase, domain.PremiumCarousel, time.Time{}, time.Time{}, domain.ProductParams{})
+	assert.Equal(t, ErrPurchaseAlreadyExists, err)
+	mProductRepo.AssertExpectations(t)
+	mPurchaseRepo.AssertExpectations(t)
+	mLogger.AssertExpectations(t)
+}
+
 func TestAddProductAcceptPurchaseError(t *testing.T) {
 	product := domain.Product{}
 	mProductRepo := &mockProductRepo{}
@@ -123,27 +260,29 @@ func TestAddProductAcceptPurchaseError(t *testing.T) {
 	mCacheRepo := &mockCacheRepo{}
 	mLogger := &mockAddUserProductLogger{}
 	mBackendEventRepo := &mockBackendEventRepo{}
-	interactor := MakeAddUserProductInteractor(mProductRepo, mPurchaseRepo,
-		mCacheRepo, mLogger, 0, mBackendEventRepo, false)
-	mLogger.On("LogErrorAddingProduct", mock.Anything, mock.Anything)
+	interactor := MakeAddUserProductInteractor(
+		makeMockTransactionRunner(mProductRepo, mPurchaseRepo, mBackendEventRepo),
+		mCacheRepo, mLogger, StaticSettings{}, false, nil, &mockTracer{})
+	mLogger.On("LogErrorAddingProduct", mock.Anything, mock.Anything, mock.Anything)
 
-	mPurchaseRepo.On("CreatePurchase",
+	mPurchaseRepo.On("CreatePurchase", mock.Anything,
 		mock.AnythingOfType("int"),
 		mock.AnythingOfType("int"),
 		mock.AnythingOfType("domain.PurchaseType")).Return(domain.Purchase{}, nil)
-	mProductRepo.On("CreateUserProduct",
+	mProductRepo.On("CreateUserProduct", mock.Anything,
 		mock.AnythingOfType("int"),
 		mock.AnythingOfType("string"),
 		mock.AnythingOfType("domain.Purchase"),
 		domain.PremiumCarousel,
 		mock.AnythingOfType("time.Time"),
+		mock.AnythingOfType("time.Time"),
 		mock.AnythingOfType("domain.ProductParams"),
 	).Return(product, nil)
-	mPurchaseRepo.On("AcceptPurchase",
+	mPurchaseRepo.On("AcceptPurchase", mock.Anything,
 		mock.AnythingOfType("domain.Purchase")).
 		Return(domain.Purchase{}, fmt.Errorf("err"))
-	err := interactor.AddUserProduct(0, "", 0, 0, domain.AdminPurchase,
-		domain.PremiumCarousel, time.Time{}, domain.ProductParams{})
+	_, err := interactor.AddUserProduct(context.Background(), "", 0, "", 0, 0, domain.AdminPurchase,
+		domain.PremiumCarousel, time.Time{}, time.Time{}, domain.ProductParams{})
 	assert.Error(t, err)
 	mProductRepo.AssertExpectations(t)
 	mPurchaseRepo.AssertExpectations(t)
@@ -159,23 +298,25 @@ func TestAddProductErrorAddingProduct(t *testing.T) {
 	mCacheRepo := &mockCacheRepo{}
 	mLogger := &mockAddUserProductLogger{}
 	mBackendEventRepo := &mockBackendEventRepo{}
-	interactor := MakeAddUserProductInteractor(mProductRepo, mPurchaseRepo,
-		mCacheRepo, mLogger, 0, mBackendEventRepo, false)
-	mLogger.On("LogErrorAddingProduct", mock.Anything, mock.Anything)
-	mPurchaseRepo.On("CreatePurchase",
+	interactor := MakeAddUserProductInteractor(
+		makeMockTransactionRunner(mProductRepo, mPurchaseRepo, mBackendEventRepo),
+		mCacheRepo, mLogger, StaticSettings{}, false, nil, &mockTracer{})
+	mLogger.On("LogErrorAddingProduct", mock.Anything, mock.Anything, mock.Anything)
+	mPurchaseRepo.On("CreatePurchase", mock.Anything,
 		mock.AnythingOfType("int"),
 		mock.AnythingOfType("int"),
 		mock.AnythingOfType("domain.PurchaseType")).Return(domain.Purchase{}, nil)
-	mProductRepo.On("CreateUserProduct",
+	mProductRepo.On("CreateUserProduct", mock.Anything,
 		mock.AnythingOfType("int"),
 		mock.AnythingOfType("string"),
 		mock.AnythingOfType("domain.Purchase"),
 		domain.PremiumCarousel,
 		mock.AnythingOfType("time.Time"),
+		mock.AnythingOfType("time.Time"),
 		mock.AnythingOfType("domain.ProductParams"),
 	).Return(product, fmt.Errorf("err"))
-	err := interactor.AddUserProduct(0, "", 0, 0, domain.AdminPurchase,
-		domain.PremiumCarousel, time.Time{}, domain.ProductParams{})
+	_, err := interactor.AddUserProduct(context.Background(), "", 0, "", 0, 0, domain.AdminPurchase,
+		domain.PremiumCarousel, time.Time{}, time.Time{}, domain.ProductParams{})
 	assert.Error(t, err)
 	mProductRepo.AssertExpectations(t)
 	mPurchaseRepo.AssertExpectations(t)
@@ -184,36 +325,38 @@ func TestAddProductErrorAddingProduct(t *testing.T) {
 }
 
 func TestAddProductOkErrorSettingCache(t *testing.T) {
-	product := domain.Product{}
+	product := domain.Product{Status: domain.ActiveProduct}
 	mProductRepo := &mockProductRepo{}
 	mPurchaseRepo := &mockPurchaseRepo{}
 	mCacheRepo := &mockCacheRepo{}
 	mLogger := &mockAddUserProductLogger{}
 	mBackendEventRepo := &mockBackendEventRepo{}
-	interactor := MakeAddUserProductInteractor(mProductRepo, mPurchaseRepo,
-		mCacheRepo, mLogger, 0, mBackendEventRepo, false)
-	mLogger.On("LogWarnSettingCache", mock.Anything, mock.Anything)
-	mCacheRepo.On("SetCache", mock.AnythingOfType("string"),
+	interactor := MakeAddUserProductInteractor(
+		makeMockTransactionRunner(mProductRepo, mPurchaseRepo, mBackendEventRepo),
+		mCacheRepo, mLogger, StaticSettings{}, false, nil, &mockTracer{})
+	mLogger.On("LogWarnSettingCache", mock.Anything, mock.Anything, mock.Anything)
+	mCacheRepo.On("SetCache", mock.Anything, mock.AnythingOfType("string"),
 		ProductCacheType,
 		mock.AnythingOfType("domain.Product"),
 		mock.Anything).
 		Return(fmt.Errorf("err"))
-	mPurchaseRepo.On("CreatePurchase",
+	mPurchaseRepo.On("CreatePurchase", mock.Anything,
 		mock.AnythingOfType("int"),
 		mock.AnythingOfType("int"),
 		mock.AnythingOfType("domain.PurchaseType")).Return(domain.Purchase{}, nil)
-	mProductRepo.On("CreateUserProduct",
+	mProductRepo.On("CreateUserProduct", mock.Anything,
 		mock.AnythingOfType("int"),
 		mock.AnythingOfType("string"),
 		mock.AnythingOfType("domain.Purchase"),
 		domain.PremiumCarousel,
 		mock.AnythingOfType("time.Time"),
+		mock.AnythingOfType("time.Time"),
 		mock.AnythingOfType("domain.ProductParams"),
 	).Return(product, nil)
-	mPurchaseRepo.On("AcceptPurchase",
+	mPurchaseRepo.On("AcceptPurchase", mock.Anything,
 		mock.AnythingOfType("domain.Purchase")).Return(domain.Purchase{}, nil)
-	err := interactor.AddUserProduct(0, "", 0, 0, domain.AdminPurchase,
-		domain.PremiumCarousel, time.Time{}, domain.ProductParams{})
+	_, err := interactor.AddUserProduct(context.Background(), "", 0, "", 0, 0, domain.AdminPurchase,
+		domain.PremiumCarousel, time.Time{}, time.Time{}, domain.ProductParams{})
 	assert.NoError(t, err)
 	mProductRepo.AssertExpectations(t)
 	mPurchaseRepo.AssertExpectations(t)
@@ -222,44 +365,238 @@ func TestAddProductOkErrorSettingCache(t *testing.T) {
 	mBackendEventRepo.AssertExpectations(t)
 }
 
-func TestAddProductOkBackendEventError(t *testing.T) {
+func TestAddProductBackendEventError(t *testing.T) {
 	product := domain.Product{}
 	mProductRepo := &mockProductRepo{}
 	mPurchaseRepo := &mockPurchaseRepo{}
 	mCacheRepo := &mockCacheRepo{}
 	mLogger := &mockAddUserProductLogger{}
 	mBackendEventRepo := &mockBackendEventRepo{}
-	interactor := MakeAddUserProductInteractor(mProductRepo, mPurchaseRepo,
-		mCacheRepo, mLogger, 0, mBackendEventRepo, true)
-	mCacheRepo.On("SetCache", mock.AnythingOfType("string"),
-		ProductCacheType,
-		mock.AnythingOfType("domain.Product"),
-		mock.Anything).
-		Return(nil)
-	mPurchaseRepo.On("CreatePurchase",
+	interactor := MakeAddUserProductInteractor(
+		makeMockTransactionRunner(mProductRepo, mPurchaseRepo, mBackendEventRepo),
+		mCacheRepo, mLogger, StaticSettings{}, true, nil, &mockTracer{})
+	mPurchaseRepo.On("CreatePurchase", mock.Anything,
 		mock.AnythingOfType("int"),
 		mock.AnythingOfType("int"),
 		mock.AnythingOfType("domain.PurchaseType")).Return(domain.Purchase{}, nil)
-	mProductRepo.On("CreateUserProduct",
+	mProductRepo.On("CreateUserProduct", mock.Anything,
 		mock.AnythingOfType("int"),
 		mock.AnythingOfType("string"),
 		mock.AnythingOfType("domain.Purchase"),
 		domain.PremiumCarousel,
 		mock.AnythingOfType("time.Time"),
+		mock.AnythingOfType("time.Time"),
 		mock.AnythingOfType("domain.ProductParams"),
 	).Return(product, nil)
-	mPurchaseRepo.On("AcceptPurchase",
+	mPurchaseRepo.On("AcceptPurchase", mock.Anything,
 		mock.AnythingOfType("domain.Purchase")).Return(domain.Purchase{}, nil)
-	mBackendEventRepo.On("PushSoldProduct",
+	mBackendEventRepo.On("PushSoldProduct", mock.Anything,
 		mock.AnythingOfType("domain.Product")).Return(fmt.Errorf("err"))
-	mLogger.On("LogWarnPushingEvent", mock.Anything, mock.Anything)
+	mLogger.On("LogErrorAddingProduct", mock.Anything, mock.Anything, mock.Anything)
 
-	err := interactor.AddUserProduct(0, "", 0, 0, domain.AdminPurchase,
-		domain.PremiumCarousel, time.Time{}, domain.ProductParams{})
-	assert.NoError(t, err)
+	_, err := interactor.AddUserProduct(context.Background(), "", 0, "", 0, 0, domain.AdminPurchase,
+		domain.PremiumCarousel, time.Time{}, time.Time{}, domain.ProductParams{})
+	var domainErr *DomainError
+	assert.True(t, errors.As(err, &domainErr))
+	assert.Equal(t, DatabaseUnavailableCode, domainErr.Code)
 	mProductRepo.AssertExpectations(t)
 	mPurchaseRepo.AssertExpectations(t)
 	mCacheRepo.AssertExpectations(t)
 	mLogger.AssertExpectations(t)
 	mBackendEventRepo.AssertExpectations(t)
 }
+
+func TestAddProductCommitError(t *testing.T) {
+	mLogger := &mockAddUserProductLogger{}
+	mCacheRepo := &mockCacheRepo{}
+	runner := makeMockTransactionRunner(&mockProductRepo{}, &mockPurchaseRepo{}, nil)
+	runner.On("RunInTransaction", mock.Anything, mock.Anything).Return(fmt.Errorf("err"))
+	interactor := MakeAddUserProductInteractor(runner, mCacheRepo, mLogger, StaticSettings{}, false, nil, &mockTracer{})
+	mLogger.On("LogErrorAddingProduct", mock.Anything, 0, mock.Anything)
+
+	_, err := interactor.AddUserProduct(context.Background(), "", 0, "", 0, 0, domain.AdminPurchase,
+		domain.PremiumCarousel, time.Time{}, time.Time{}, domain.ProductParams{})
+	var domainErr *DomainError
+	assert.True(t, errors.As(err, &domainErr))
+	assert.Equal(t, DatabaseUnavailableCode, domainErr.Code)
+	mCacheRepo.AssertExpectations(t)
+	mLogger.AssertExpectations(t)
+	runner.AssertExpectations(t)
+}
+
+func TestAddProductIdempotentOk(t *testing.T) {
+	product := domain.Product{ID: 7, UserID: 123, Status: domain.ActiveProduct}
+	mProductRepo := &mockProductRepo{}
+	mPurchaseRepo := &mockPurchaseRepo{}
+	mCacheRepo := &mockCacheRepo{}
+	mLogger := &mockAddUserProductLogger{}
+	mIdempotencyRepo := &mockIdempotencyRepo{}
+	interactor := MakeAddUserProductInteractor(
+		makeMockTransactionRunner(mProductRepo, mPurchaseRepo, nil),
+		mCacheRepo, mLogger, StaticSettings{}, false, mIdempotencyRepo, &mockTracer{})
+	mIdempotencyRepo.On("Reserve", mock.Anything, "key-1", mock.AnythingOfType("IdempotencyRecord")).
+		Return(true, nil)
+	mCacheRepo.On("SetCache", mock.Anything, mock.AnythingOfType("string"),
+		ProductCacheType, product, mock.Anything).Return(nil)
+	mPurchaseRepo.On("CreatePurchase", mock.Anything, 1, 100, domain.AdminPurchase).
+		Return(domain.Purchase{}, nil)
+	mProductRepo.On("CreateUserProduct", mock.Anything, 123, "a@b.cl",
+		domain.Purchase{}, domain.PremiumCarousel,
+		mock.AnythingOfType("time.Time"),
+		mock.AnythingOfType("time.Time"),
+		mock.AnythingOfType("domain.ProductParams"),
+	).Return(product, nil)
+	mPurchaseRepo.On("AcceptPurchase", mock.Anything, domain.Purchase{}).Return(domain.Purchase{}, nil)
+	mIdempotencyRepo.On("Save", mock.Anything, "key-1", mock.MatchedBy(func(r IdempotencyRecord) bool {
+		return r.Completed && r.Product.ID == 7 && r.Fingerprint != ""
+	})).Return(nil)
+	created, err := interactor.AddUserProduct(context.Background(), "key-1", 123, "a@b.cl", 1, 100,
+		domain.AdminPurchase, domain.PremiumCarousel, time.Time{}, time.Time{}, domain.ProductParams{})
+	assert.NoError(t, err)
+	assert.Equal(t, product, created)
+	mProductRepo.AssertExpectations(t)
+	mPurchaseRepo.AssertExpectations(t)
+	mCacheRepo.AssertExpectations(t)
+	mLogger.AssertExpectations(t)
+	mIdempotencyRepo.AssertExpectations(t)
+}
+
+func TestAddProductIdempotentReplay(t *testing.T) {
+	mProductRepo := &mockProductRepo{}
+	mPurchaseRepo := &mockPurchaseRepo{}
+	mCacheRepo := &mockCacheRepo{}
+	mLogger := &mockAddUserProductLogger{}
+	mIdempotencyRepo := &mockIdempotencyRepo{}
+	interactor := MakeAddUserProductInteractor(
+		makeMockTransactionRunner(mProductRepo, mPurchaseRepo, nil),
+		mCacheRepo, mLogger, StaticSettings{}, false, mIdempotencyRepo, &mockTracer{})
+	fingerprint := addUserProductParams{UserID: 123, Email: "a@b.cl",
+		PurchaseNumber: 1, PurchasePrice: 100, PurchaseType: domain.AdminPurchase,
+		ProductType: domain.PremiumCarousel}.fingerprint()
+	mIdempotencyRepo.On("Reserve", mock.Anything, "key-1",
+		IdempotencyRecord{Fingerprint: fingerprint}).Return(false, nil)
+	product := domain.Product{ID: 7, UserID: 123}
+	mIdempotencyRepo.On("Get", mock.Anything, "key-1").Return(IdempotencyRecord{
+		Fingerprint: fingerprint, Completed: true, Product: product}, nil)
+	replayed, err := interactor.AddUserProduct(context.Background(), "key-1", 123, "a@b.cl", 1, 100,
+		domain.AdminPurchase, domain.PremiumCarousel, time.Time{}, time.Time{}, domain.ProductParams{})
+	assert.NoError(t, err)
+	assert.Equal(t, product, replayed)
+	mProductRepo.AssertExpectations(t)
+	mPurchaseRepo.AssertExpectations(t)
+	mIdempotencyRepo.AssertExpectations(t)
+}
+
+func TestAddProductIdempotentReserveAgainWhenFreed(t *testing.T) {
+	product := domain.Product{ID: 7, UserID: 123, Status: domain.ActiveProduct}
+	mProductRepo := &mockProductRepo{}
+	mPurchaseRepo := &mockPurchaseRepo{}
+	mCacheRepo := &mockCacheRepo{}
+	mLogger := &mockAddUserProductLogger{}
+	mIdempotencyRepo := &mockIdempotencyRepo{}
+	interactor := MakeAddUserProductInteractor(
+		makeMockTransactionRunner(mProductRepo, mPurchaseRepo, nil),
+		mCacheRepo, mLogger, StaticSettings{}, false, mIdempotencyRepo, &mockTracer{})
+	mIdempotencyRepo.On("Reserve", mock.Anything, "key-1", mock.AnythingOfType("IdempotencyRecord")).
+		Return(false, nil).Once()
+	mIdempotencyRepo.On("Get", mock.Anything, "key-1").
+		Return(IdempotencyRecord{}, ErrIdempotencyRecordNotFound).Once()
+	mIdempotencyRepo.On("Reserve", mock.Anything, "key-1", mock.AnythingOfType("IdempotencyRecord")).
+		Return(true, nil).Once()
+	mCacheRepo.On("SetCache", mock.Anything, mock.AnythingOfType("string"),
+		ProductCacheType, product, mock.Anything).Return(nil)
+	mPurchaseRepo.On("CreatePurchase", mock.Anything, 1, 100, domain.AdminPurchase).
+		Return(domain.Purchase{}, nil)
+	mProductRepo.On("CreateUserProduct", mock.Anything, 123, "a@b.cl",
+		domain.Purchase{}, domain.PremiumCarousel,
+		mock.AnythingOfType("time.Time"),
+		mock.AnythingOfType("time.Time"),
+		mock.AnythingOfType("domain.ProductParams"),
+	).Return(product, nil)
+	mPurchaseRepo.On("AcceptPurchase", mock.Anything, domain.Purchase{}).Return(domain.Purchase{}, nil)
+	mIdempotencyRepo.On("Save", mock.Anything, "key-1", mock.AnythingOfType("IdempotencyRecord")).
+		Return(nil)
+	created, err := interactor.AddUserProduct(context.Background(), "key-1", 123, "a@b.cl", 1, 100,
+		domain.AdminPurchase, domain.PremiumCarousel, time.Time{}, time.Time{}, domain.ProductParams{})
+	assert.NoError(t, err)
+	assert.Equal(t, product, created)
+	mProductRepo.AssertExpectations(t)
+	mPurchaseRepo.AssertExpectations(t)
+	mCacheRepo.AssertExpectations(t)
+	mIdempotencyRepo.AssertExpectations(t)
+}
+
+func TestAddProductIdempotentKeyFreedTwice(t *testing.T) {
+	mLogger := &mockAddUserProductLogger{}
+	mIdempotencyRepo := &mockIdempotencyRepo{}
+	interactor := MakeAddUserProductInteractor(
+		makeMockTransactionRunner(&mockProductRepo{}, &mockPurchaseRepo{}, nil),
+		&mockCacheRepo{}, mLogger, StaticSettings{}, false, mIdempotencyRepo, &mockTracer{})
+	mIdempotencyRepo.On("Reserve", mock.Anything, "key-1", mock.AnythingOfType("IdempotencyRecord")).
+		Return(false, nil).Twice()
+	mIdempotencyRepo.On("Get", mock.Anything, "key-1").
+		Return(IdempotencyRecord{}, ErrIdempotencyRecordNotFound).Twice()
+	_, err := interactor.AddUserProduct(context.Background(), "key-1", 123, "a@b.cl", 1, 100,
+		domain.AdminPurchase, domain.PremiumCarousel, time.Time{}, time.Time{}, domain.ProductParams{})
+	assert.True(t, errors.Is(err, &DomainError{Code: CacheUnavailableCode}))
+	mIdempotencyRepo.AssertExpectations(t)
+}
+
+func TestAddProductIdempotencyConflicts(t *testing.T) {
+	params := addUserProductParams{UserID: 123, Email: "a@b.cl",
+		PurchaseNumber: 1, PurchasePrice: 100, PurchaseType: domain.AdminPurchase,
+		ProductType: domain.PremiumCarousel}
+	for expected, record := range map[error]IdempotencyRecord{
+		ErrIdempotencyKeyReused: {Fingerprint: "other", Completed: true},
+		ErrRequestInProgress:    {Fingerprint: params.fingerprint()},
+	} {
+		mIdempotencyRepo := &mockIdempotencyRepo{}
+		interactor := MakeAddUserProductInteractor(
+			makeMockTransactionRunner(&mockProductRepo{}, &mockPurchaseRepo{}, nil),
+			&mockCacheRepo{}, &mockAddUserProductLogger{}, StaticSettings{}, false, mIdempotencyRepo, &mockTracer{})
+		mIdempotencyRepo.On("Reserve", mock.Anything, "key-1", mock.AnythingOfType("IdempotencyRecord")).
+			Return(false, nil)
+		mIdempotencyRepo.On("Get", mock.Anything, "key-1").Return(record, nil)
+		_, err := interactor.AddUserProduct(context.Background(), "key-1", 123, "a@b.cl", 1, 100,
+			domain.AdminPurchase, domain.PremiumCarousel, time.Time{}, time.Time{}, domain.ProductParams{})
+		assert.Equal(t, expected, err)
+		mIdempotencyRepo.AssertExpectations(t)
+	}
+}
+
+func TestAddProductIdempotentReleaseOnError(t *testing.T) {
+	mPurchaseRepo := &mockPurchaseRepo{}
+	mLogger := &mockAddUserProductLogger{}
+	mIdempotencyRepo := &mockIdempotencyRepo{}
+	interactor := MakeAddUserProductInteractor(
+		makeMockTransactionRunner(&mockProductRepo{}, mPurchaseRepo, nil),
+		&mockCacheRepo{}, mLogger, StaticSettings{}, false, mIdempotencyRepo, &mockTracer{})
+	mIdempotencyRepo.On("Reserve", mock.Anything, "key-1", mock.AnythingOfType("IdempotencyRecord")).
+		Return(true, nil)
+	mPurchaseRepo.On("CreatePurchase", mock.Anything, 1, 100, domain.AdminPurchase).
+		Return(domain.Purchase{}, fmt.Errorf("err"))
+	mLogger.On("LogErrorAddingProduct", mock.Anything, 123, mock.Anything)
+	mIdempotencyRepo.On("Release", mock.Anything, "key-1").Return(nil)
+	_, err := interactor.AddUserProduct(context.Background(), "key-1", 123, "a@b.cl", 1, 100,
+		domain.AdminPurchase, domain.PremiumCarousel, time.Time{}, time.Time{}, domain.ProductParams{})
+	assert.True(t, errors.Is(err, &DomainError{Code: DatabaseUnavailableCode}))
+	mPurchaseRepo.AssertExpectations(t)
+	mLogger.AssertExpectations(t)
+	mIdempotencyRepo.AssertExpectations(t)
+}
+
+func TestAddProductIdempotencyRepoError(t *testing.T) {
+	mLogger := &mockAddUserProductLogger{}
+	mIdempotencyRepo := &mockIdempotencyRepo{}
+	interactor := MakeAddUserProductInteractor(
+		makeMockTransactionRunner(&mockProductRepo{}, &mockPurchaseRepo{}, nil),
+		&mockCacheRepo{}, mLogger, StaticSettings{}, false, mIdempotencyRepo, &mockTracer{})
+	mIdempotencyRepo.On("Reserve", mock.Anything, "key-1", mock.AnythingOfType("IdempotencyRecord")).
+		Return(false, fmt.Errorf("err"))
+	mLogger.On("LogErrorAddingProduct", mock.Anything, 123, mock.Anything)
+	_, err := interactor.AddUserProduct(context.Background(), "key-1", 123, "a@b.cl", 1, 100,
+		domain.AdminPurchase, domain.PremiumCarousel, time.Time{}, time.Time{}, domain.ProductParams{})
+	assert.True(t, errors.Is(err, &DomainError{Code: CacheUnavailableCode}))
+	mLogger.AssertExpectations(t)
+	mIdempotencyRepo.AssertExpectations(t)
+}
@@ -1,6 +1,7 @@
 package usecases
 
 import (
+	"context"
 	"errors"
 	"time"
 
@@ -67,40 +68,46 @@ type GomsRepository interface {
 
 // AdRepository allows get ads data
 type AdRepository interface {
-	GetUserAds(userID int,
-		productParams domain.ProductParams) (domain.Ads, error)
-	GetAd(listID string) (domain.Ad, error)
+	// GetUserAds returns up to maxAds ads of the user, fewer when the
+	// product sets a lower limit
+	GetUserAds(ctx context.Context, userID int,
+		productParams domain.ProductParams, maxAds int) (domain.Ads, error)
+	GetAd(ctx context.Context, listID string) (domain.Ad, error)
 }
 
 // PurchaseRepository interface to allows purchase repository operations
 type PurchaseRepository interface {
-	CreatePurchase(purchaseNumber, price int,
+	CreatePurchase(ctx context.Context, purchaseNumber, price int,
 		purchaseType domain.PurchaseType) (domain.Purchase, error)
-	AcceptPurchase(purchase domain.Purchase) (domain.Purchase, error)
+	AcceptPurchase(ctx context.Context, purchase domain.Purchase) (domain.Purchase, error)
 }
 
-// ErrProductNotFound defines error for product not found
-var ErrProductNotFound error = errors.New("Product not found")
-
 // ProductRepository interface to allows product repository operations
 type ProductRepository interface {
-	GetUserProducts(page int) ([]domain.Product, int, int, error)
-	GetUserProductsByEmail(email string, page int) ([]domain.Product,
+	GetUserProducts(ctx context.Context, page int) ([]domain.Product, int, int, error)
+	GetUserProductsByEmail(ctx context.Context, email string, page int) ([]domain.Product,
 		int, int, error)
-	CreateUserProduct(userID int, email string,
+	// CreateUserProduct creates the product active, or inactive until startAt
+	// when it's in the future
+	CreateUserProduct(ctx context.Context, userID int, email string,
 		purchase domain.Purchase, productType domain.ProductType,
-		expiredAt time.Time, config domain.ProductParams) (domain.Product, error)
-	GetUserActiveProduct(userID int,
+		startAt, expiredAt time.Time, config domain.ProductParams) (domain.Product, error)
+	GetUserActiveProduct(ctx context.Context, userID int,
 		productType domain.ProductType) (domain.Product, error)
-	GetUserProductsTotal() (total int)
-	GetUserProductsTotalByEmail(email string) (total int)
-	GetUserProductByID(userProductID int) (domain.Product, error)
-	SetConfig(userProductID int, config domain.ProductParams) error
-	SetPartialConfig(userProductID int, configMap map[string]interface{}) error
-	SetExpiration(userProductID int, expiredAt time.Time) error
-	SetStatus(userProductID int, status domain.ProductStatus) error
-	GetReport(startDate, endDate time.Time) ([]domain.Product, error)
-	ExpireProducts() error
+	GetUserProductsTotal(ctx context.Context) (total int)
+	GetUserProductsTotalByEmail(ctx context.Context, email string) (total int)
+	GetUserProductByID(ctx context.Context, userProductID int) (domain.Product, error)
+	IncrementVersion(ctx context.Context, userProductID int, version int) (int, error)
+	SetConfig(ctx context.Context, userProductID int, config domain.ProductParams) error
+	SetPartialConfig(ctx context.Context, userProductID int, patch ProductPatch) error
+	SetExpiration(ctx context.Context, userProductID int, expiredAt time.Time) error
+	SetStatus(ctx context.Context, userProductID int, status domain.ProductStatus) error
+	GetReport(ctx context.Context, startDate, endDate time.Time) ([]domain.Product, error)
+	GetActiveProducts(ctx context.Context) ([]domain.Product, error)
+	CountActiveProducts(ctx context.Context) (map[domain.ProductType]int, error)
+	GetProductsExpiringBefore(ctx context.Context, date time.Time) ([]domain.Product, error)
+	ExpireProducts(ctx context.Context) ([]domain.Product, error)
+	ActivateProducts(ctx context.Context) ([]domain.Product, error)
 }
 
 // CacheType defines the user cache type
@@ -115,12 +122,98 @@ const (
 
 // CacheRepository implements cache repository operations
 type CacheRepository interface {
-	SetCache(key string, typ CacheType, data interface{},
+	SetCache(ctx context.Context, key string, typ CacheType, data interface{},
 		expiration time.Duration) error
-	GetCache(key string, typ CacheType) ([]byte, error)
+	GetCache(ctx context.Context, key string, typ CacheType) ([]byte, error)
+	DelCache(ctx context.Context, key string, typ CacheType) error
+}
+
+// IdempotencyRecord holds the outcome of an operation requested with an
+// idempotency key. Fingerprint identifies the operation params
+type IdempotencyRecord struct {
+	Fingerprint string
+	Completed   bool
+	Product     domain.Product
+}
+
+// ErrIdempotencyRecordNotFound is returned by IdempotencyRepository.Get when
+// the key is free
+var ErrIdempotencyRecordNotFound = errors.New("idempotency record not found")
+
+// IdempotencyRepository stores the records of idempotent operations
+type IdempotencyRepository interface {
+	// Reserve stores the record only if the key is not already taken. The
+	// reservation expires soon unless the record is saved
+	Reserve(ctx context.Context, key string, record IdempotencyRecord) (bool, error)
+	Get(ctx context.Context, key string) (IdempotencyRecord, error)
+	Save(ctx context.Context, key string, record IdempotencyRecord) error
+	Release(ctx context.Context, key string) error
+}
+
+// ExpirationReminderRepository records the expiration reminders sent, so
+// each product gets a single reminder per window and expiration date
+type ExpirationReminderRepository interface {
+	// Claim records the reminder, returns false if it was already recorded
+	Claim(ctx context.Context, product domain.Product, window time.Duration) (bool, error)
 }
 
 // BackendEventsRepository allows push events to backend events queue
 type BackendEventsRepository interface {
-	PushSoldProduct(product domain.Product) error
+	PushSoldProduct(ctx context.Context, product domain.Product) error
+	PushExpirationReminder(ctx context.Context, product domain.Product,
+		window time.Duration) error
+	PushConfigChange(ctx context.Context, product domain.Product,
+		previous domain.ProductParams) error
+	PushStatusChange(ctx context.Context, product domain.Product,
+		previous domain.ProductStatus) error
+	PushExpiration(ctx context.Context, product domain.Product) error
+	PushExtension(ctx context.Context, product domain.Product, previous time.Time) error
+	PushCancellation(ctx context.Context, product domain.Product,
+		previous domain.ProductStatus) error
+}
+
+// TransactionalRepositories holds the repositories sharing a transaction
+type TransactionalRepositories struct {
+	Products      ProductRepository
+	Purchases     PurchaseRepository
+	BackendEvents BackendEventsRepository
+	Reminders     ExpirationReminderRepository
+}
+
+// TransactionRunner runs operations atomically, either every change made
+// through the given repositories takes place or none does
+type TransactionRunner interface {
+	// RunInTransaction commits the changes when the operation returns no
+	// error and rolls them back otherwise
+	RunInTransaction(ctx context.Context,
+		operation func(repos TransactionalRepositories) error) error
+}
+
+// OutboxEvent is a backend event waiting to be published. Events of the same
+// key are published in order
+type OutboxEvent struct {
+	ID       int
+	Key      string
+	Topic    string
+	Payload  []byte
+	Attempts int
+}
+
+// OutboxRepository holds the backend events to be published
+type OutboxRepository interface {
+	// GetPendingEvents returns the oldest events due to be published,
+	// leaving out those after an event of the same key waiting for a retry
+	GetPendingEvents(ctx context.Context, limit int) ([]OutboxEvent, error)
+	MarkSent(ctx context.Context, eventID int) error
+	// MarkFailed records a failed attempt, the event is retried at next
+	MarkFailed(ctx context.Context, eventID int, cause error, next time.Time) error
+	// PurgeSent deletes the events sent before the given date
+	PurgeSent(ctx context.Context, before time.Time) error
+}
+
+// EventsPublisher publishes the outbox events to backend events
+type EventsPublisher interface {
+	// Publish publishes the events and returns the error of each one, in
+	// order. The events after a failed one of the same key may be skipped
+	Publish(ctx context.Context, events []OutboxEvent) []error
 }
@@ -0,0 +1,116 @@
+package usecases
+
+// ErrorCode defines a stable machine readable error code
+type ErrorCode string
+
+const (
+	// AdNotFoundCode is used when the requested ad does not exist
+	AdNotFoundCode ErrorCode = "AD_NOT_FOUND"
+	// ProductNotFoundCode is used when the requested product does not exist
+	ProductNotFoundCode ErrorCode = "PRODUCT_NOT_FOUND"
+	// ProductNotActiveCode is used when the ad user has no active product
+	ProductNotActiveCode ErrorCode = "PRODUCT_NOT_ACTIVE"
+	// ProductExpiredCode is used when the ad user product has expired
+	ProductExpiredCode ErrorCode = "PRODUCT_EXPIRED"
+	// NotEnoughAdsCode is used when the ad user has not enough ads to fill
+	// the carousel
+	NotEnoughAdsCode ErrorCode = "NOT_ENOUGH_ADS"
+	// VersionMismatchCode is used when a product update is made over an
+	// outdated product version
+	VersionMismatchCode ErrorCode = "VERSION_MISMATCH"
+	// IdempotencyKeyReusedCode is used when an idempotency key is sent
+	// again along with different params
+	IdempotencyKeyReusedCode ErrorCode = "IDEMPOTENCY_KEY_REUSED"
+	// RequestInProgressCode is used when an idempotency key is sent again
+	// while its first request is still running
+	RequestInProgressCode ErrorCode = "REQUEST_IN_PROGRESS"
+	// PurchaseAlreadyExistsCode is used when a payment was already stored
+	PurchaseAlreadyExistsCode ErrorCode = "PURCHASE_ALREADY_EXISTS"
+	// SearchUnavailableCode is used when the search repository fails
+	SearchUnavailableCode ErrorCode = "SEARCH_UNAVAILABLE"
+	// DatabaseUnavailableCode is used when the product or purchase
+	// repositories fail
+	DatabaseUnavailableCode ErrorCode = "DATABASE_UNAVAILABLE"
+	// CacheUnavailableCode is used when the cache repositories fail
+	CacheUnavailableCode ErrorCode = "CACHE_UNAVAILABLE"
+	// EventsUnavailableCode is used when the backend events repository fails
+	EventsUnavailableCode ErrorCode = "EVENTS_UNAVAILABLE"
+)
+
+// DomainError is an error that tells its kind through a stable code
+type DomainError struct {
+	Code    ErrorCode
+	Message string
+	Err     error
+}
+
+// Error returns the error message along with its cause
+func (e *DomainError) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+// Unwrap returns the cause of the error
+func (e *DomainError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is a DomainError of the same kind
+func (e *DomainError) Is(target error) bool {
+	t, ok := target.(*DomainError)
+	return ok && t.Code == e.Code
+}
+
+var (
+	// ErrAdNotFound defines error for ad not found
+	ErrAdNotFound error = &DomainError{Code: AdNotFoundCode, Message: "Ad not found"}
+	// ErrProductNotFound defines error for product not found
+	ErrProductNotFound error = &DomainError{Code: ProductNotFoundCode,
+		Message: "Product not found"}
+	// ErrProductNotActive defines error for users without an active product
+	ErrProductNotActive error = &DomainError{Code: ProductNotActiveCode,
+		Message: "Product not active"}
+	// ErrProductExpired defines error for users with an expired product
+	ErrProductExpired error = &DomainError{Code: ProductExpiredCode,
+		Message: "Product expired"}
+	// ErrNotEnoughAds defines error for users without enough ads
+	ErrNotEnoughAds error = &DomainError{Code: NotEnoughAdsCode,
+		Message: "Not enough ads"}
+	// ErrVersionMismatch defines error for product updates made over an
+	// outdated product version
+	ErrVersionMismatch error = &DomainError{Code: VersionMismatchCode,
+		Message: "Product version mismatch"}
+	// ErrIdempotencyKeyReused defines error for idempotency keys reused
+	// with different params
+	ErrIdempotencyKeyReused error = &DomainError{Code: IdempotencyKeyReusedCode,
+		Message: "Idempotency key already used with different params"}
+	// ErrRequestInProgress defines error for idempotency keys whose first
+	// request has not finished
+	ErrRequestInProgress error = &DomainError{Code: RequestInProgressCode,
+		Message: "A request with the same idempotency key is in progress"}
+	// ErrPurchaseAlreadyExists defines error for payments already stored
+	ErrPurchaseAlreadyExists error = &DomainError{Code: PurchaseAlreadyExistsCode,
+		Message: "Purchase already exists"}
+)
+
+// newSearchError wraps a search repository failure
+func newSearchError(message string, err error) error {
+	return &DomainError{Code: SearchUnavailableCode, Message: message, Err: err}
+}
+
+// newDatabaseError wraps a product or purchase repository failure
+func newDatabaseError(message string, err error) error {
+	return &DomainError{Code: DatabaseUnavailableCode, Message: message, Err: err}
+}
+
+// newCacheError wraps a cache repository failure
+func newCacheError(message string, err error) error {
+	return &DomainError{Code: CacheUnavailableCode, Message: message, Err: err}
+}
+
+// newEventsError wraps a backend events repository failure
+func newEventsError(message string, err error) error {
+	return &DomainError{Code: EventsUnavailableCode, Message: message, Err: err}
+}
@@ -0,0 +1,46 @@
+package usecases
+
+import (
+	"context"
+	"strings"
+)
+
+// EvictAdInteractor wraps EvictAd operations
+type EvictAdInteractor interface {
+	EvictAd(ctx context.Context, listID string) error
+}
+
+// evictAdInteractor defines the interactor for evictAd usecase
+type evictAdInteractor struct {
+	cacheRepo CacheRepository
+	logger    EvictAdLogger
+	tracer    Tracer
+}
+
+// EvictAdLogger logs EvictAd events
+type EvictAdLogger interface {
+	LogErrorEvictingAd(ctx context.Context, listID string, err error)
+}
+
+// MakeEvictAdInteractor creates a new instance of EvictAdInteractor
+func MakeEvictAdInteractor(cacheRepo CacheRepository,
+	logger EvictAdLogger, tracer Tracer) EvictAdInteractor {
+	return &evictAdInteractor{cacheRepo: cacheRepo, logger: logger, tracer: tracer}
+}
+
+// EvictAd removes the cached data of an ad, so the next request reads it
+// again from the ad repository. Carousels are built from the ad repository
+// on each request and never cached by the service, so the ad is the only
+// entry that can hold stale data
+func (interactor *evictAdInteractor) EvictAd(ctx context.Context,
+	listID string) (err error) {
+	ctx, span := interactor.tracer.Start(ctx, "EvictAd")
+	defer func() { span.End(err) }()
+	err = interactor.cacheRepo.DelCache(ctx,
+		strings.Join([]string{"ad", listID}, ":"), MinifiedAdDataType)
+	if err != nil {
+		interactor.logger.LogErrorEvictingAd(ctx, listID, err)
+		return newCacheError("cannot evict the ad", err)
+	}
+	return nil
+}
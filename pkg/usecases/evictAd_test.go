@@ -0,0 +1,44 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockEvictAdLogger struct {
+	mock.Mock
+}
+
+func (m *mockEvictAdLogger) LogErrorEvictingAd(ctx context.Context, listID string, err error) {
+	m.Called(ctx, listID, err)
+}
+
+func TestEvictAdOK(t *testing.T) {
+	mCacheRepo := &mockCacheRepo{}
+	mLogger := &mockEvictAdLogger{}
+	interactor := MakeEvictAdInteractor(mCacheRepo, mLogger, &mockTracer{})
+	mCacheRepo.On("DelCache", mock.Anything, "ad:1", MinifiedAdDataType).Return(nil)
+	err := interactor.EvictAd(context.Background(), "1")
+	assert.NoError(t, err)
+	mCacheRepo.AssertExpectations(t)
+	mLogger.AssertExpectations(t)
+}
+
+func TestEvictAdError(t *testing.T) {
+	mCacheRepo := &mockCacheRepo{}
+	mLogger := &mockEvictAdLogger{}
+	interactor := MakeEvictAdInteractor(mCacheRepo, mLogger, &mockTracer{})
+	mCacheRepo.On("DelCache", mock.Anything, "ad:1", MinifiedAdDataType).Return(fmt.Errorf("err"))
+	mLogger.On("LogErrorEvictingAd", mock.Anything, "1", mock.Anything)
+	err := interactor.EvictAd(context.Background(), "1")
+	var domainError *DomainError
+	assert.True(t, errors.As(err, &domainError))
+	assert.Equal(t, CacheUnavailableCode, domainError.Code)
+	mCacheRepo.AssertExpectations(t)
+	mLogger.AssertExpectations(t)
+}
@@ -1,42 +1,87 @@
 package usecases
 
 import (
-	"fmt"
+	"context"
+
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/domain"
 )
 
 // ExpireProductsInteractor wraps ExpireProducts operations
 type ExpireProductsInteractor interface {
-	ExpireProducts() error
+	ExpireProducts(ctx context.Context) error
 }
 
 // expireProductsInteractor defines the interactor for ExpireProducts usecase
 type expireProductsInteractor struct {
-	productRepo ProductRepository
-	logger      ExpireProductsLogger
+	productRepo          ProductRepository
+	cacheRepo            CacheRepository
+	logger               ExpireProductsLogger
+	settings             SettingsProvider
+	transactions         TransactionRunner
+	backendEventsEnabled bool
+	tracer               Tracer
 }
 
 // ExpireProductsLogger logs ExpireProducts events
 type ExpireProductsLogger interface {
 	LogExpireProductsError(err error)
+	LogWarnSettingCache(userID int, err error)
 }
 
 // MakeExpireProductsInteractor creates a new instance of ExpireProductsInteractor
 func MakeExpireProductsInteractor(
 	productRepo ProductRepository,
+	cacheRepo CacheRepository,
 	logger ExpireProductsLogger,
+	settings SettingsProvider,
+	transactions TransactionRunner,
+	backendEventsEnabled bool,
+	tracer Tracer,
 ) ExpireProductsInteractor {
 	return &expireProductsInteractor{
-		productRepo: productRepo,
-		logger:      logger,
+		productRepo:          productRepo,
+		cacheRepo:            cacheRepo,
+		logger:               logger,
+		settings:             settings,
+		transactions:         transactions,
+		backendEventsEnabled: backendEventsEnabled,
+		tracer:               tracer,
 	}
 }
 
-// ExpireProducts set expired status for all expired products
-func (interactor *expireProductsInteractor) ExpireProducts() error {
-	err := interactor.productRepo.ExpireProducts()
+// ExpireProducts set expired status for all expired products, also refreshes
+// the cache of their users. Their expiration events are stored in the same
+// transaction
+func (interactor *expireProductsInteractor) ExpireProducts(ctx context.Context) (err error) {
+	ctx, span := interactor.tracer.Start(ctx, "ExpireProducts")
+	defer func() { span.End(err) }()
+	var products []domain.Product
+	err = runInTransaction(ctx, interactor.transactions, "error expiring products",
+		func(repos TransactionalRepositories) error {
+			var err error
+			if products, err = repos.Products.ExpireProducts(ctx); err != nil {
+				return newDatabaseError("error expiring products", err)
+			}
+			if !interactor.backendEventsEnabled {
+				return nil
+			}
+			for _, product := range products {
+				if err = repos.BackendEvents.PushExpiration(ctx, product); err != nil {
+					return newDatabaseError("cannot store the expiration events", err)
+				}
+			}
+			return nil
+		})
 	if err != nil {
 		interactor.logger.LogExpireProductsError(err)
-		return fmt.Errorf("error expiring products: %+v", err)
+		return err
+	}
+	for _, product := range products {
+		err := refreshUserProductCache(ctx, interactor.productRepo,
+			interactor.cacheRepo, product.UserID, interactor.settings.Settings().CacheTTL)
+		if err != nil {
+			interactor.logger.LogWarnSettingCache(product.UserID, err)
+		}
 	}
 	return nil
 }
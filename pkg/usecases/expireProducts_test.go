@@ -1,11 +1,15 @@
 package usecases
 
 import (
+	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/domain"
 )
 
 type mockExpireProductsLogger struct {
@@ -16,18 +20,35 @@ func (m *mockExpireProductsLogger) LogExpireProductsError(err error) {
 	m.Called(err)
 }
 
-func (m *mockExpireProductsLogger) LogErrorSettingCache(err error) {
-	m.Called(err)
+func (m *mockExpireProductsLogger) LogWarnSettingCache(userID int, err error) {
+	m.Called(userID, err)
 }
 
 func TestExpireProductsOk(t *testing.T) {
 	mProductRepo := &mockProductRepo{}
+	mCacheRepo := &mockCacheRepo{}
 	mLogger := &mockExpireProductsLogger{}
-	interactor := MakeExpireProductsInteractor(mProductRepo, mLogger)
-	mProductRepo.On("ExpireProducts").Return(nil)
-	err := interactor.ExpireProducts()
+	interactor := MakeExpireProductsInteractor(mProductRepo, mCacheRepo,
+		mLogger, StaticSettings{CacheTTL: time.Hour}, makeMockTransactionRunner(mProductRepo, nil, nil), false, &mockTracer{})
+	next := domain.Product{ID: 2, UserID: 123, Status: domain.ActiveProduct}
+	mProductRepo.On("ExpireProducts", mock.Anything, mock.Anything).Return([]domain.Product{
+		{ID: 1, UserID: 123, Status: domain.ExpiredProduct},
+		{ID: 3, UserID: 456, Status: domain.ExpiredProduct},
+	}, nil)
+	mProductRepo.On("GetUserActiveProduct", mock.Anything, 123, domain.PremiumCarousel).
+		Return(next, nil)
+	mProductRepo.On("GetUserActiveProduct", mock.Anything, 456, domain.PremiumCarousel).
+		Return(domain.Product{}, ErrProductNotFound)
+	mCacheRepo.On("SetCache", mock.Anything, "user:123:PREMIUM_CAROUSEL", ProductCacheType,
+		next, time.Hour).Return(nil)
+	mCacheRepo.On("SetCache", mock.Anything, "user:456:PREMIUM_CAROUSEL", ProductCacheType,
+		domain.Product{UserID: 456, Status: domain.InactiveProduct}, time.Hour).
+		Return(fmt.Errorf("err"))
+	mLogger.On("LogWarnSettingCache", 456, mock.Anything)
+	err := interactor.ExpireProducts(context.Background())
 	assert.NoError(t, err)
 	mProductRepo.AssertExpectations(t)
+	mCacheRepo.AssertExpectations(t)
 	mLogger.AssertExpectations(t)
 }
 
@@ -35,12 +56,56 @@ func TestExpireProductsRepoError(t *testing.T) {
 	mProductRepo := &mockProductRepo{}
 	mCacheRepo := &mockCacheRepo{}
 	mLogger := &mockExpireProductsLogger{}
-	interactor := MakeExpireProductsInteractor(mProductRepo, mLogger)
-	mProductRepo.On("ExpireProducts").Return(fmt.Errorf("err"))
+	interactor := MakeExpireProductsInteractor(mProductRepo, mCacheRepo,
+		mLogger, StaticSettings{CacheTTL: time.Hour}, makeMockTransactionRunner(mProductRepo, nil, nil), false, &mockTracer{})
+	mProductRepo.On("ExpireProducts", mock.Anything, mock.Anything).Return([]domain.Product{}, fmt.Errorf("err"))
 	mLogger.On("LogExpireProductsError", mock.Anything)
-	err := interactor.ExpireProducts()
+	err := interactor.ExpireProducts(context.Background())
 	assert.Error(t, err)
 	mProductRepo.AssertExpectations(t)
 	mLogger.AssertExpectations(t)
 	mCacheRepo.AssertExpectations(t)
 }
+
+func TestExpireProductsStoresEvents(t *testing.T) {
+	mProductRepo := &mockProductRepo{}
+	mCacheRepo := &mockCacheRepo{}
+	mLogger := &mockExpireProductsLogger{}
+	mBackendEventRepo := &mockBackendEventRepo{}
+	interactor := MakeExpireProductsInteractor(mProductRepo, mCacheRepo,
+		mLogger, StaticSettings{CacheTTL: time.Hour},
+		makeMockTransactionRunner(mProductRepo, nil, mBackendEventRepo), true, &mockTracer{})
+	expired := domain.Product{ID: 1, UserID: 123, Status: domain.ExpiredProduct}
+	mProductRepo.On("ExpireProducts", mock.Anything, mock.Anything).Return([]domain.Product{expired}, nil)
+	mProductRepo.On("GetUserActiveProduct", mock.Anything, 123, domain.PremiumCarousel).
+		Return(domain.Product{}, ErrProductNotFound)
+	mCacheRepo.On("SetCache", mock.Anything, "user:123:PREMIUM_CAROUSEL", ProductCacheType,
+		mock.Anything, time.Hour).Return(nil)
+	mBackendEventRepo.On("PushExpiration", mock.Anything, expired).Return(nil)
+	err := interactor.ExpireProducts(context.Background())
+	assert.NoError(t, err)
+	mProductRepo.AssertExpectations(t)
+	mCacheRepo.AssertExpectations(t)
+	mLogger.AssertExpectations(t)
+	mBackendEventRepo.AssertExpectations(t)
+}
+
+func TestExpireProductsEventError(t *testing.T) {
+	mProductRepo := &mockProductRepo{}
+	mCacheRepo := &mockCacheRepo{}
+	mLogger := &mockExpireProductsLogger{}
+	mBackendEventRepo := &mockBackendEventRepo{}
+	interactor := MakeExpireProductsInteractor(mProductRepo, mCacheRepo,
+		mLogger, StaticSettings{CacheTTL: time.Hour},
+		makeMockTransactionRunner(mProductRepo, nil, mBackendEventRepo), true, &mockTracer{})
+	expired := domain.Product{ID: 1, UserID: 123, Status: domain.ExpiredProduct}
+	mProductRepo.On("ExpireProducts", mock.Anything, mock.Anything).Return([]domain.Product{expired}, nil)
+	mBackendEventRepo.On("PushExpiration", mock.Anything, expired).Return(fmt.Errorf("err"))
+	mLogger.On("LogExpireProductsError", mock.Anything)
+	err := interactor.ExpireProducts(context.Background())
+	assert.Error(t, err)
+	mProductRepo.AssertExpectations(t)
+	mCacheRepo.AssertExpectations(t)
+	mLogger.AssertExpectations(t)
+	mBackendEventRepo.AssertExpectations(t)
+}
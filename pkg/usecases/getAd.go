@@ -1,16 +1,17 @@
 package usecases
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"strings"
-	"time"
 
 	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/domain"
 )
 
 // GetAdInteractor wraps GetAd operations
 type GetAdInteractor interface {
-	GetAd(listID string) (domain.Ad, error)
+	GetAd(ctx context.Context, listID string) (domain.Ad, error)
 }
 
 // getAdInteractor defines the interactor for getAd usecase
@@ -18,43 +19,53 @@ type getAdInteractor struct {
 	adRepo    AdRepository
 	cacheRepo CacheRepository
 	logger    GetAdLogger
-	cacheTTL  time.Duration
+	settings  SettingsProvider
+	tracer    Tracer
+	metrics   Metrics
 }
 
 // GetAdLogger logs GetAd events
 type GetAdLogger interface {
-	LogWarnGettingCache(listID string, err error)
-	LogWarnSettingCache(listID string, err error)
-	LogErrorGettingAd(listID string, err error)
+	LogWarnGettingCache(ctx context.Context, listID string, err error)
+	LogWarnSettingCache(ctx context.Context, listID string, err error)
+	LogErrorGettingAd(ctx context.Context, listID string, err error)
 }
 
 // MakeGetAdInteractor creates a new instance of GetAdInteractor
 func MakeGetAdInteractor(adRepo AdRepository,
 	cacheRepo CacheRepository, logger GetAdLogger,
-	cacheTTL time.Duration) GetAdInteractor {
+	settings SettingsProvider, tracer Tracer, metrics Metrics) GetAdInteractor {
 	return &getAdInteractor{adRepo: adRepo, cacheRepo: cacheRepo,
-		logger: logger, cacheTTL: cacheTTL}
+		logger: logger, settings: settings, tracer: tracer, metrics: metrics}
 }
 
 // GetAd gets ad by given listID
-func (interactor *getAdInteractor) GetAd(listID string) (ad domain.Ad, err error) {
-	ad, cacheError := interactor.getCache(listID)
+func (interactor *getAdInteractor) GetAd(ctx context.Context,
+	listID string) (ad domain.Ad, err error) {
+	ctx, span := interactor.tracer.Start(ctx, "GetAd")
+	defer func() { span.End(err) }()
+	ad, cacheError := interactor.getCache(ctx, listID)
 	if cacheError == nil {
 		return ad, nil
 	}
-	interactor.logger.LogWarnGettingCache(listID, cacheError)
-	ad, err = interactor.adRepo.GetAd(listID)
-	if err != nil {
-		interactor.logger.LogErrorGettingAd(listID, err)
+	interactor.logger.LogWarnGettingCache(ctx, listID, cacheError)
+	ad, err = interactor.adRepo.GetAd(ctx, listID)
+	if errors.Is(err, ErrAdNotFound) {
 		return domain.Ad{}, err
 	}
-	interactor.refreshCache(ad)
+	if err != nil {
+		interactor.logger.LogErrorGettingAd(ctx, listID, err)
+		return domain.Ad{}, newSearchError("cannot retrieve the ad", err)
+	}
+	interactor.refreshCache(ctx, ad)
 	return ad, nil
 }
 
-func (interactor *getAdInteractor) getCache(listID string) (ad domain.Ad, cacheError error) {
-	rawCachedAd, cacheError := interactor.cacheRepo.GetCache(
+func (interactor *getAdInteractor) getCache(ctx context.Context,
+	listID string) (ad domain.Ad, cacheError error) {
+	rawCachedAd, cacheError := interactor.cacheRepo.GetCache(ctx,
 		strings.Join([]string{"ad", listID}, ":"), MinifiedAdDataType)
+	interactor.metrics.CollectCacheLookup(MinifiedAdDataType, cacheError == nil)
 	if cacheError == nil {
 		cacheError = json.Unmarshal(rawCachedAd, &ad)
 	}
@@ -64,11 +75,11 @@ func (interactor *getAdInteractor) getCache(listID string) (ad domain.Ad, cacheE
 	return ad, nil
 }
 
-func (interactor *getAdInteractor) refreshCache(ad domain.Ad) {
-	cacheError := interactor.cacheRepo.SetCache(
+func (interactor *getAdInteractor) refreshCache(ctx context.Context, ad domain.Ad) {
+	cacheError := interactor.cacheRepo.SetCache(ctx,
 		strings.Join([]string{"ad", ad.ID}, ":"),
-		MinifiedAdDataType, ad, interactor.cacheTTL)
+		MinifiedAdDataType, ad, interactor.settings.Settings().CacheTTL)
 	if cacheError != nil {
-		interactor.logger.LogWarnSettingCache(ad.ID, cacheError)
+		interactor.logger.LogWarnSettingCache(ctx, ad.ID, cacheError)
 	}
 }
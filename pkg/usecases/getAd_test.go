@@ -1,7 +1,9 @@
 package usecases
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"testing"
 
@@ -15,95 +17,134 @@ type mockGetAdLogger struct {
 	mock.Mock
 }
 
-func (m *mockGetAdLogger) LogWarnGettingCache(listID string, err error) {
-	m.Called(listID, err)
+func (m *mockGetAdLogger) LogWarnGettingCache(ctx context.Context, listID string, err error) {
+	m.Called(ctx, listID, err)
 }
 
-func (m *mockGetAdLogger) LogWarnSettingCache(listID string, err error) {
-	m.Called(listID, err)
+func (m *mockGetAdLogger) LogWarnSettingCache(ctx context.Context, listID string, err error) {
+	m.Called(ctx, listID, err)
 }
 
-func (m *mockGetAdLogger) LogErrorGettingAd(listID string, err error) {
-	m.Called(listID, err)
+func (m *mockGetAdLogger) LogErrorGettingAd(ctx context.Context, listID string, err error) {
+	m.Called(ctx, listID, err)
 }
 
 func TestGetAdOkWithoutCache(t *testing.T) {
 	mAdRepo := &mockAdRepo{}
 	mCacheRepo := &mockCacheRepo{}
 	mLogger := &mockGetAdLogger{}
-	interactor := MakeGetAdInteractor(mAdRepo, mCacheRepo, mLogger, 0)
+	mMetrics := &mockMetrics{}
+	interactor := MakeGetAdInteractor(mAdRepo, mCacheRepo, mLogger, StaticSettings{}, &mockTracer{}, mMetrics)
 	tAd := domain.Ad{ID: "1", Subject: "Mi auto", UserID: 123}
-	mLogger.On("LogWarnGettingCache", mock.Anything, mock.Anything)
-	mCacheRepo.On("GetCache", mock.AnythingOfType("string"), mock.Anything).
+	mLogger.On("LogWarnGettingCache", mock.Anything, mock.Anything, mock.Anything)
+	mCacheRepo.On("GetCache", mock.Anything, mock.AnythingOfType("string"), mock.Anything).
 		Return([]byte{}, fmt.Errorf("cache not found"))
-	mCacheRepo.On("SetCache", mock.AnythingOfType("string"),
+	mCacheRepo.On("SetCache", mock.Anything, mock.AnythingOfType("string"),
 		MinifiedAdDataType,
 		mock.AnythingOfType("domain.Ad"),
 		mock.Anything).
 		Return(nil)
-	mAdRepo.On("GetAd", mock.AnythingOfType("string")).Return(tAd, nil)
-	ads, err := interactor.GetAd("1")
+	mAdRepo.On("GetAd", mock.Anything, mock.AnythingOfType("string")).Return(tAd, nil)
+	ads, err := interactor.GetAd(context.Background(), "1")
 	expected := tAd
 	assert.NoError(t, err)
 	assert.Equal(t, expected, ads)
 	mAdRepo.AssertExpectations(t)
 	mCacheRepo.AssertExpectations(t)
+	assert.Equal(t, 1, mMetrics.misses[MinifiedAdDataType])
 }
 
 func TestGetAdOkWithCache(t *testing.T) {
 	mAdRepo := &mockAdRepo{}
 	mCacheRepo := &mockCacheRepo{}
 	mLogger := &mockGetAdLogger{}
-	interactor := MakeGetAdInteractor(mAdRepo, mCacheRepo, mLogger, 0)
+	mMetrics := &mockMetrics{}
+	interactor := MakeGetAdInteractor(mAdRepo, mCacheRepo, mLogger, StaticSettings{}, &mockTracer{}, mMetrics)
 	tAd := domain.Ad{ID: "1", Subject: "Mi auto", UserID: 123}
 	tAdBytes, _ := json.Marshal(tAd)
-	mCacheRepo.On("GetCache", mock.AnythingOfType("string"), mock.Anything).
+	mCacheRepo.On("GetCache", mock.Anything, mock.AnythingOfType("string"), mock.Anything).
 		Return(tAdBytes, nil)
-	ads, err := interactor.GetAd("1")
+	ads, err := interactor.GetAd(context.Background(), "1")
 	expected := tAd
 	assert.NoError(t, err)
 	assert.Equal(t, expected, ads)
 	mAdRepo.AssertExpectations(t)
 	mCacheRepo.AssertExpectations(t)
+	assert.Equal(t, 1, mMetrics.hits[MinifiedAdDataType])
 }
 
 func TestGetAdErrorGettingAd(t *testing.T) {
 	mAdRepo := &mockAdRepo{}
 	mCacheRepo := &mockCacheRepo{}
 	mLogger := &mockGetAdLogger{}
-	interactor := MakeGetAdInteractor(mAdRepo, mCacheRepo, mLogger, 0)
+	interactor := MakeGetAdInteractor(mAdRepo, mCacheRepo, mLogger, StaticSettings{}, &mockTracer{}, &mockMetrics{})
 	tAd := domain.Ad{ID: "1", Subject: "Mi auto", UserID: 123}
-	mLogger.On("LogWarnGettingCache", mock.Anything, mock.Anything)
-	mCacheRepo.On("GetCache", mock.AnythingOfType("string"), mock.Anything).
+	mLogger.On("LogWarnGettingCache", mock.Anything, mock.Anything, mock.Anything)
+	mCacheRepo.On("GetCache", mock.Anything, mock.AnythingOfType("string"), mock.Anything).
 		Return([]byte{}, fmt.Errorf("cache not found"))
-	mLogger.On("LogErrorGettingAd", mock.Anything, mock.Anything)
-	mAdRepo.On("GetAd", mock.AnythingOfType("string")).Return(tAd, fmt.Errorf("err"))
-	_, err := interactor.GetAd("1")
-	assert.Error(t, err)
+	mLogger.On("LogErrorGettingAd", mock.Anything, mock.Anything, mock.Anything)
+	mAdRepo.On("GetAd", mock.Anything, mock.AnythingOfType("string")).Return(tAd, fmt.Errorf("err"))
+	_, err := interactor.GetAd(context.Background(), "1")
+	assert.True(t, errors.Is(err, &DomainError{Code: SearchUnavailableCode}))
 	mAdRepo.AssertExpectations(t)
 	mCacheRepo.AssertExpectations(t)
 }
 
+func TestGetAdNotFound(t *testing.T) {
+	mAdRepo := &mockAdRepo{}
+	mCacheRepo := &mockCacheRepo{}
+	mLogger := &mockGetAdLogger{}
+	interactor := MakeGetAdInteractor(mAdRepo, mCacheRepo, mLogger, StaticSettings{}, &mockTracer{}, &mockMetrics{})
+	mLogger.On("LogWarnGettingCache", mock.Anything, mock.Anything, mock.Anything)
+	mCacheRepo.On("GetCache", mock.Anything, mock.AnythingOfType("string"), mock.Anything).
+		Return([]byte{}, fmt.Errorf("cache not found"))
+	mAdRepo.On("GetAd", mock.Anything, mock.AnythingOfType("string")).
+		Return(domain.Ad{}, ErrAdNotFound)
+	_, err := interactor.GetAd(context.Background(), "1")
+	assert.True(t, errors.Is(err, ErrAdNotFound))
+	mAdRepo.AssertExpectations(t)
+	mCacheRepo.AssertExpectations(t)
+	mLogger.AssertExpectations(t)
+}
+
 func TestGetAdErrorSettingCache(t *testing.T) {
 	mAdRepo := &mockAdRepo{}
 	mCacheRepo := &mockCacheRepo{}
 	mLogger := &mockGetAdLogger{}
-	interactor := MakeGetAdInteractor(mAdRepo, mCacheRepo, mLogger, 0)
+	interactor := MakeGetAdInteractor(mAdRepo, mCacheRepo, mLogger, StaticSettings{}, &mockTracer{}, &mockMetrics{})
 	tAd := domain.Ad{ID: "1", Subject: "Mi auto", UserID: 123}
-	mLogger.On("LogWarnGettingCache", mock.Anything, mock.Anything)
-	mCacheRepo.On("GetCache", mock.AnythingOfType("string"), mock.Anything).
+	mLogger.On("LogWarnGettingCache", mock.Anything, mock.Anything, mock.Anything)
+	mCacheRepo.On("GetCache", mock.Anything, mock.AnythingOfType("string"), mock.Anything).
 		Return([]byte{}, fmt.Errorf("cache not found"))
-	mCacheRepo.On("SetCache", mock.AnythingOfType("string"),
+	mCacheRepo.On("SetCache", mock.Anything, mock.AnythingOfType("string"),
 		MinifiedAdDataType,
 		mock.AnythingOfType("domain.Ad"),
 		mock.Anything).
 		Return(fmt.Errorf("err"))
-	mLogger.On("LogWarnSettingCache", mock.Anything, mock.Anything)
-	mAdRepo.On("GetAd", mock.AnythingOfType("string")).Return(tAd, nil)
-	ads, err := interactor.GetAd("1")
+	mLogger.On("LogWarnSettingCache", mock.Anything, mock.Anything, mock.Anything)
+	mAdRepo.On("GetAd", mock.Anything, mock.AnythingOfType("string")).Return(tAd, nil)
+	ads, err := interactor.GetAd(context.Background(), "1")
 	expected := tAd
 	assert.NoError(t, err)
 	assert.Equal(t, expected, ads)
 	mAdRepo.AssertExpectations(t)
 	mCacheRepo.AssertExpectations(t)
 }
+
+func TestGetAdTraced(t *testing.T) {
+	mAdRepo := &mockAdRepo{}
+	mCacheRepo := &mockCacheRepo{}
+	mLogger := &mockGetAdLogger{}
+	mTracer := &mockTracer{}
+	interactor := MakeGetAdInteractor(mAdRepo, mCacheRepo, mLogger, StaticSettings{}, mTracer, &mockMetrics{})
+	mLogger.On("LogWarnGettingCache", mock.Anything, mock.Anything, mock.Anything)
+	mCacheRepo.On("GetCache", mock.Anything, mock.AnythingOfType("string"), mock.Anything).
+		Return([]byte{}, fmt.Errorf("cache not found"))
+	mAdRepo.On("GetAd", mock.Anything, mock.AnythingOfType("string")).
+		Return(domain.Ad{}, ErrAdNotFound)
+	_, err := interactor.GetAd(context.Background(), "1")
+	assert.Len(t, mTracer.spans, 1)
+	assert.Equal(t, "GetAd", mTracer.spans[0].name)
+	assert.True(t, mTracer.spans[0].ended)
+	assert.Equal(t, err, mTracer.spans[0].err)
+}
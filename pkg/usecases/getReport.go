@@ -1,7 +1,7 @@
 package usecases
 
 import (
-	"fmt"
+	"context"
 	"time"
 
 	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/domain"
@@ -9,7 +9,7 @@ import (
 
 // GetReportInteractor wraps GetReport operations
 type GetReportInteractor interface {
-	GetReport(startDate,
+	GetReport(ctx context.Context, startDate,
 		endDate time.Time) (products []domain.Product, err error)
 }
 
@@ -17,26 +17,30 @@ type GetReportInteractor interface {
 type getReportInteractor struct {
 	productRepo ProductRepository
 	logger      GetReportLogger
+	tracer      Tracer
 }
 
 // GetReportLogger logs GetReport events
 type GetReportLogger interface {
-	LogErrorGettingReport(err error)
+	LogErrorGettingReport(ctx context.Context, err error)
 }
 
 // MakeGetReportInteractor creates a new instance of GetReportInteractor
 func MakeGetReportInteractor(productRepo ProductRepository,
-	logger GetReportLogger) GetReportInteractor {
-	return &getReportInteractor{productRepo: productRepo, logger: logger}
+	logger GetReportLogger, tracer Tracer) GetReportInteractor {
+	return &getReportInteractor{productRepo: productRepo, logger: logger,
+		tracer: tracer}
 }
 
 // GetReport gets sales report using start & end date
-func (interactor *getReportInteractor) GetReport(startDate,
-	endDate time.Time) (products []domain.Product, err error) {
-	products, err = interactor.productRepo.GetReport(startDate, endDate)
+func (interactor *getReportInteractor) GetReport(ctx context.Context,
+	startDate, endDate time.Time) (products []domain.Product, err error) {
+	ctx, span := interactor.tracer.Start(ctx, "GetReport")
+	defer func() { span.End(err) }()
+	products, err = interactor.productRepo.GetReport(ctx, startDate, endDate)
 	if err != nil {
-		interactor.logger.LogErrorGettingReport(err)
-		return []domain.Product{}, fmt.Errorf("error loading report: %+v", err)
+		interactor.logger.LogErrorGettingReport(ctx, err)
+		return []domain.Product{}, newDatabaseError("error loading report", err)
 	}
 	return
 }
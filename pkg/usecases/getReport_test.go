@@ -1,6 +1,7 @@
 package usecases
 
 import (
+	"context"
 	"fmt"
 	"testing"
 	"time"
@@ -14,21 +15,21 @@ type mockGetReportLogger struct {
 	mock.Mock
 }
 
-func (m *mockGetReportLogger) LogErrorGettingReport(err error) {
-	m.Called(err)
+func (m *mockGetReportLogger) LogErrorGettingReport(ctx context.Context, err error) {
+	m.Called(ctx, err)
 }
 
 func TestGetReportOk(t *testing.T) {
 	mProductRepo := &mockProductRepo{}
 	mLogger := &mockGetReportLogger{}
 	testTime := time.Now()
-	interactor := MakeGetReportInteractor(mProductRepo, mLogger)
+	interactor := MakeGetReportInteractor(mProductRepo, mLogger, &mockTracer{})
 	products := []domain.Product{}
-	mProductRepo.On("GetReport",
+	mProductRepo.On("GetReport", mock.Anything,
 		testTime,
 		testTime,
 	).Return(products, nil)
-	res, err := interactor.GetReport(testTime, testTime)
+	res, err := interactor.GetReport(context.Background(), testTime, testTime)
 	assert.NoError(t, err)
 	assert.Equal(t, products, res)
 	mProductRepo.AssertExpectations(t)
@@ -39,14 +40,14 @@ func TestGetReportError(t *testing.T) {
 	mProductRepo := &mockProductRepo{}
 	mLogger := &mockGetReportLogger{}
 	testTime := time.Now()
-	interactor := MakeGetReportInteractor(mProductRepo, mLogger)
-	mLogger.On("LogErrorGettingReport",
+	interactor := MakeGetReportInteractor(mProductRepo, mLogger, &mockTracer{})
+	mLogger.On("LogErrorGettingReport", mock.Anything,
 		mock.Anything, mock.Anything)
-	mProductRepo.On("GetReport",
+	mProductRepo.On("GetReport", mock.Anything,
 		testTime,
 		testTime,
 	).Return([]domain.Product{}, fmt.Errorf("err"))
-	_, err := interactor.GetReport(testTime, testTime)
+	_, err := interactor.GetReport(context.Background(), testTime, testTime)
 	assert.Error(t, err)
 	mProductRepo.AssertExpectations(t)
 	mLogger.AssertExpectations(t)
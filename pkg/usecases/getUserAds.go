@@ -1,7 +1,9 @@
 package usecases
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -10,61 +12,79 @@ import (
 	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/domain"
 )
 
-// GetUserAdsInteractor wraps GetUserAds operations
+// GetUserAdsInteractor wraps GetUserAds operations. The settings are the
+// snapshot taken once for the whole request
 type GetUserAdsInteractor interface {
-	GetUserAds(currentAdview domain.Ad) (domain.Ads, error)
+	GetUserAds(ctx context.Context, currentAdview domain.Ad, settings Settings) (domain.Ads, error)
 }
 
 // getUserAdsInteractor defines the interactor for GetUserAds usecase
 type getUserAdsInteractor struct {
-	adRepo          AdRepository
-	productRepo     ProductRepository
-	cacheRepo       CacheRepository
-	logger          GetUserAdsLogger
-	cacheTTL        time.Duration
-	minAdsToDisplay int
+	adRepo               AdRepository
+	productRepo          ProductRepository
+	cacheRepo            CacheRepository
+	logger               GetUserAdsLogger
+	transactions         TransactionRunner
+	backendEventsEnabled bool
+	tracer               Tracer
+	metrics              Metrics
 }
 
 // GetUserAdsLogger logs getUserAds events
 type GetUserAdsLogger interface {
-	LogNotEnoughAds(userID int)
-	LogWarnGettingCache(userID int, err error)
-	LogWarnSettingCache(userID int, err error)
-	LogErrorGettingUserAdsData(userID int, err error)
-	LogInfoProductExpired(userID int, product domain.Product)
-	LogInfoActiveProductNotFound(userID int, product domain.Product)
+	LogNotEnoughAds(ctx context.Context, userID int)
+	LogWarnGettingCache(ctx context.Context, userID int, err error)
+	LogWarnSettingCache(ctx context.Context, userID int, err error)
+	LogErrorGettingUserAdsData(ctx context.Context, userID int, err error)
+	LogInfoProductExpired(ctx context.Context, userID int, product domain.Product)
+	LogInfoActiveProductNotFound(ctx context.Context, userID int, product domain.Product)
 }
 
 // MakeGetUserAdsInteractor creates a new instance of GetUserAdsInteractor
 func MakeGetUserAdsInteractor(adRepo AdRepository, productRepo ProductRepository,
 	cacheRepo CacheRepository, logger GetUserAdsLogger,
-	cacheTTL time.Duration, minAdsToDisplay int) GetUserAdsInteractor {
+	transactions TransactionRunner,
+	backendEventsEnabled bool, tracer Tracer, metrics Metrics) GetUserAdsInteractor {
 	return &getUserAdsInteractor{adRepo: adRepo,
-		productRepo: productRepo, cacheRepo: cacheRepo,
-		logger: logger, cacheTTL: cacheTTL, minAdsToDisplay: minAdsToDisplay}
+		productRepo: productRepo, cacheRepo: cacheRepo, logger: logger,
+		transactions: transactions, backendEventsEnabled: backendEventsEnabled,
+		tracer: tracer, metrics: metrics}
 }
 
 // GetUserAds retrieves user ads based on product configurations
-func (interactor *getUserAdsInteractor) GetUserAds(currentAdview domain.Ad) (ads domain.Ads, err error) {
+func (interactor *getUserAdsInteractor) GetUserAds(ctx context.Context,
+	currentAdview domain.Ad, settings Settings) (ads domain.Ads, err error) {
+	ctx, span := interactor.tracer.Start(ctx, "GetUserAds")
+	defer func() { span.End(err) }()
+	defer func() { interactor.collectCarousel(ads, err) }()
 	userID := currentAdview.UserID
-	product, cacheError := interactor.getCache(userID)
+	product, cacheError := interactor.getCache(ctx, userID)
 	if cacheError != nil {
-		product, err = interactor.productRepo.GetUserActiveProduct(userID,
+		product, err = interactor.productRepo.GetUserActiveProduct(ctx, userID,
 			domain.PremiumCarousel)
+		if err != nil && !errors.Is(err, ErrProductNotFound) {
+			interactor.logger.LogErrorGettingUserAdsData(ctx, userID, err)
+			return domain.Ads{}, newDatabaseError("cannot retrieve the user's product", err)
+		}
 		if err != nil {
 			product = domain.Product{UserID: userID, Status: domain.InactiveProduct}
 		}
-		interactor.refreshCache(product)
+		interactor.refreshCache(ctx, product, settings.CacheTTL)
 	}
 	if product.Status != domain.ActiveProduct {
-		interactor.logger.LogInfoActiveProductNotFound(userID, product)
-		return domain.Ads{}, fmt.Errorf("Product %v for user %d", product.Status, userID)
+		interactor.logger.LogInfoActiveProductNotFound(ctx, userID, product)
+		return domain.Ads{}, fmt.Errorf("product %v for user %d: %w",
+			product.Status, userID, ErrProductNotActive)
 	}
 	if product.ExpiredAt.Before(time.Now()) {
 		product.Status = domain.ExpiredProduct
-		interactor.logger.LogInfoProductExpired(userID, product)
-		interactor.refreshCache(product)
-		return domain.Ads{}, interactor.productRepo.SetStatus(product.ID, product.Status)
+		interactor.logger.LogInfoProductExpired(ctx, userID, product)
+		interactor.refreshCache(ctx, product, settings.CacheTTL)
+		if err = interactor.expire(ctx, product); err != nil {
+			return domain.Ads{}, err
+		}
+		return domain.Ads{}, fmt.Errorf("product %d for user %d: %w",
+			product.ID, userID, ErrProductExpired)
 	}
 	product.Config.Categories = append([]int{currentAdview.CategoryID},
 		product.Config.Categories...)
@@ -73,41 +93,96 @@ func (interactor *getUserAdsInteractor) GetUserAds(currentAdview domain.Ad) (ads
 		product.Config.PriceFrom = int(currentAdview.Price) - product.Config.PriceRange
 		product.Config.PriceTo = int(currentAdview.Price) + product.Config.PriceRange
 	}
-	ads, err = interactor.adRepo.GetUserAds(userID, product.Config)
+	ads, err = interactor.adRepo.GetUserAds(ctx, userID, product.Config,
+		settings.MaxAdsToDisplay)
+	if errors.Is(err, ErrNotEnoughAds) {
+		interactor.logger.LogNotEnoughAds(ctx, userID)
+		return domain.Ads{}, err
+	}
 	if err != nil {
-		interactor.logger.LogErrorGettingUserAdsData(userID, err)
-		return domain.Ads{}, fmt.Errorf("cannot retrieve the user's ads: %+v", err)
+		interactor.logger.LogErrorGettingUserAdsData(ctx, userID, err)
+		return domain.Ads{}, newSearchError("cannot retrieve the user's ads", err)
 	}
-	if interactor.minAdsToDisplay > 0 && len(ads) < interactor.minAdsToDisplay {
-		interactor.logger.LogNotEnoughAds(userID)
-		return domain.Ads{}, fmt.Errorf("user %d does not have enough active ads", userID)
+	if settings.MinAdsToDisplay > 0 && len(ads) < settings.MinAdsToDisplay {
+		interactor.logger.LogNotEnoughAds(ctx, userID)
+		return domain.Ads{}, fmt.Errorf("user %d has %d active ads: %w",
+			userID, len(ads), ErrNotEnoughAds)
 	}
 	return ads, nil
 }
 
-func (interactor *getUserAdsInteractor) refreshCache(product domain.Product) {
-	cacheError := interactor.cacheRepo.SetCache(
+// collectCarousel counts the carousel served, telling why it's empty when
+// the ads could not be retrieved
+func (interactor *getUserAdsInteractor) collectCarousel(ads domain.Ads, err error) {
+	var domainErr *DomainError
+	switch {
+	case err == nil:
+		interactor.metrics.CollectCarouselServed(len(ads))
+	case errors.Is(err, ErrProductNotActive):
+		interactor.metrics.CollectEmptyCarousel(NoProductReason)
+	case errors.Is(err, ErrProductExpired):
+		interactor.metrics.CollectEmptyCarousel(ProductExpiredReason)
+	case errors.Is(err, ErrNotEnoughAds):
+		interactor.metrics.CollectEmptyCarousel(NotEnoughAdsReason)
+	case errors.As(err, &domainErr) && domainErr.Code == SearchUnavailableCode:
+		interactor.metrics.CollectEmptyCarousel(SearchErrorReason)
+	default:
+		interactor.metrics.CollectEmptyCarousel(DatabaseErrorReason)
+	}
+}
+
+// expire stores the expired status of a product found expired on read, along
+// with its expiration event in the same transaction. When the product was
+// changed meanwhile it's left to the expiration job
+func (interactor *getUserAdsInteractor) expire(ctx context.Context,
+	product domain.Product) error {
+	return runInTransaction(ctx, interactor.transactions, "cannot expire the user's product",
+		func(repos TransactionalRepositories) error {
+			_, err := repos.Products.IncrementVersion(ctx, product.ID, product.Version)
+			if errors.Is(err, ErrVersionMismatch) {
+				return nil
+			}
+			if err != nil {
+				return newDatabaseError("cannot expire the user's product", err)
+			}
+			if err = repos.Products.SetStatus(ctx, product.ID, product.Status); err != nil {
+				return newDatabaseError("cannot expire the user's product", err)
+			}
+			if !interactor.backendEventsEnabled {
+				return nil
+			}
+			if err = repos.BackendEvents.PushExpiration(ctx, product); err != nil {
+				return newDatabaseError("cannot store the expiration event", err)
+			}
+			return nil
+		})
+}
+
+func (interactor *getUserAdsInteractor) refreshCache(ctx context.Context, product domain.Product,
+	ttl time.Duration) {
+	cacheError := interactor.cacheRepo.SetCache(ctx,
 		strings.Join([]string{"user", strconv.Itoa(product.UserID),
 			string(domain.PremiumCarousel)}, ":"),
 		ProductCacheType,
 		product,
-		interactor.cacheTTL)
+		ttl)
 	if cacheError != nil {
-		interactor.logger.LogWarnSettingCache(product.UserID, cacheError)
+		interactor.logger.LogWarnSettingCache(ctx, product.UserID, cacheError)
 	}
 }
 
-func (interactor *getUserAdsInteractor) getCache(userID int) (product domain.Product,
+func (interactor *getUserAdsInteractor) getCache(ctx context.Context, userID int) (product domain.Product,
 	cacheError error) {
-	rawCachedProduct, cacheError := interactor.cacheRepo.GetCache(
+	rawCachedProduct, cacheError := interactor.cacheRepo.GetCache(ctx,
 		strings.Join([]string{"user", strconv.Itoa(userID),
 			string(domain.PremiumCarousel)}, ":"),
 		ProductCacheType)
+	interactor.metrics.CollectCacheLookup(ProductCacheType, cacheError == nil)
 	if cacheError == nil {
 		cacheError = json.Unmarshal(rawCachedProduct, &product)
 	}
 	if cacheError != nil {
-		interactor.logger.LogWarnGettingCache(userID, cacheError)
+		interactor.logger.LogWarnGettingCache(ctx, userID, cacheError)
 		return domain.Product{}, cacheError
 	}
 	return product, nil
@@ -1,7 +1,9 @@
 package usecases
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"testing"
 	"time"
@@ -16,89 +18,114 @@ type mockProductRepo struct {
 	mock.Mock
 }
 
-func (m *mockProductRepo) GetUserActiveProduct(userID int,
+func (m *mockProductRepo) GetUserActiveProduct(ctx context.Context, userID int,
 	productType domain.ProductType) (domain.Product, error) {
-	args := m.Called(userID, productType)
+	args := m.Called(ctx, userID, productType)
 	return args.Get(0).(domain.Product), args.Error(1)
 }
 
-func (m *mockProductRepo) GetUserProducts(page int) ([]domain.Product, int, int, error) {
-	args := m.Called(page)
+func (m *mockProductRepo) GetUserProducts(ctx context.Context, page int) ([]domain.Product, int, int, error) {
+	args := m.Called(ctx, page)
 	return args.Get(0).([]domain.Product), args.Int(1), args.Int(2), args.Error(3)
 }
 
-func (m *mockProductRepo) GetReport(start, end time.Time) ([]domain.Product, error) {
-	args := m.Called(start, end)
+func (m *mockProductRepo) GetReport(ctx context.Context, start, end time.Time) ([]domain.Product, error) {
+	args := m.Called(ctx, start, end)
 	return args.Get(0).([]domain.Product), args.Error(1)
 }
 
-func (m *mockProductRepo) GetUserProductsByEmail(email string, page int) ([]domain.Product, int, int, error) {
-	args := m.Called(email, page)
+func (m *mockProductRepo) GetUserProductsByEmail(ctx context.Context, email string, page int) ([]domain.Product, int, int, error) {
+	args := m.Called(ctx, email, page)
 	return args.Get(0).([]domain.Product), args.Int(1), args.Int(2), args.Error(3)
 }
 
-func (m *mockProductRepo) CreateUserProduct(userID int, email string, purchase domain.Purchase,
-	productType domain.ProductType, expiredAt time.Time,
+func (m *mockProductRepo) CreateUserProduct(ctx context.Context, userID int, email string, purchase domain.Purchase,
+	productType domain.ProductType, startAt, expiredAt time.Time,
 	config domain.ProductParams) (domain.Product, error) {
-	args := m.Called(userID, email, purchase, productType, expiredAt, config)
+	args := m.Called(ctx, userID, email, purchase, productType, startAt, expiredAt, config)
 	return args.Get(0).(domain.Product), args.Error(1)
 }
 
-func (m *mockProductRepo) GetUserProductsTotal() (total int) {
-	args := m.Called()
+func (m *mockProductRepo) GetUserProductsTotal(ctx context.Context) (total int) {
+	args := m.Called(ctx)
 	return args.Int(0)
 }
 
-func (m *mockProductRepo) GetUserProductsTotalByEmail(email string) (total int) {
-	args := m.Called(email)
+func (m *mockProductRepo) GetUserProductsTotalByEmail(ctx context.Context, email string) (total int) {
+	args := m.Called(ctx, email)
 	return args.Int(0)
 }
 
-func (m *mockProductRepo) GetUserProductByID(userProductID int) (domain.Product, error) {
-	args := m.Called(userProductID)
+func (m *mockProductRepo) GetUserProductByID(ctx context.Context, userProductID int) (domain.Product, error) {
+	args := m.Called(ctx, userProductID)
 	return args.Get(0).(domain.Product), args.Error(1)
 }
 
-func (m *mockProductRepo) SetConfig(userProductID int, config domain.ProductParams) error {
-	args := m.Called(userProductID, config)
+func (m *mockProductRepo) IncrementVersion(ctx context.Context, userProductID int, version int) (int, error) {
+	args := m.Called(ctx, userProductID, version)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *mockProductRepo) SetConfig(ctx context.Context, userProductID int, config domain.ProductParams) error {
+	args := m.Called(ctx, userProductID, config)
 	return args.Error(0)
 }
 
-func (m *mockProductRepo) SetPartialConfig(userProductID int,
-	config map[string]interface{}) error {
-	args := m.Called(userProductID, config)
+func (m *mockProductRepo) SetPartialConfig(ctx context.Context, userProductID int,
+	patch ProductPatch) error {
+	args := m.Called(ctx, userProductID, patch)
 	return args.Error(0)
 }
 
-func (m *mockProductRepo) SetExpiration(userProductID int,
+func (m *mockProductRepo) SetExpiration(ctx context.Context, userProductID int,
 	expiredAt time.Time) error {
-	args := m.Called(userProductID, expiredAt)
+	args := m.Called(ctx, userProductID, expiredAt)
 	return args.Error(0)
 }
 
-func (m *mockProductRepo) SetStatus(userProductID int,
+func (m *mockProductRepo) SetStatus(ctx context.Context, userProductID int,
 	status domain.ProductStatus) error {
-	args := m.Called(userProductID, status)
+	args := m.Called(ctx, userProductID, status)
 	return args.Error(0)
 }
 
-func (m *mockProductRepo) ExpireProducts() error {
-	args := m.Called()
-	return args.Error(0)
+func (m *mockProductRepo) GetActiveProducts(ctx context.Context) ([]domain.Product, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]domain.Product), args.Error(1)
+}
+
+func (m *mockProductRepo) CountActiveProducts(ctx context.Context) (map[domain.ProductType]int, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(map[domain.ProductType]int), args.Error(1)
+}
+
+func (m *mockProductRepo) ExpireProducts(ctx context.Context) ([]domain.Product, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]domain.Product), args.Error(1)
+}
+
+func (m *mockProductRepo) ActivateProducts(ctx context.Context) ([]domain.Product, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]domain.Product), args.Error(1)
+}
+
+func (m *mockProductRepo) GetProductsExpiringBefore(ctx context.Context, date time.Time) ([]domain.Product, error) {
+	args := m.Called(ctx, date)
+	return args.Get(0).([]domain.Product), args.Error(1)
 }
 
 type mockAdRepo struct {
 	mock.Mock
 }
 
-func (m *mockAdRepo) GetUserAds(userID int,
-	productParams domain.ProductParams) (domain.Ads, error) {
-	args := m.Called(userID, productParams)
+func (m *mockAdRepo) GetUserAds(ctx context.Context, userID int,
+	productParams domain.ProductParams, maxAds int) (domain.Ads, error) {
+	args := m.Called(ctx, userID, productParams, maxAds)
 	return args.Get(0).(domain.Ads), args.Error(1)
 }
 
-func (m *mockAdRepo) GetAd(listID string) (domain.Ad, error) {
-	args := m.Called(listID)
+func (m *mockAdRepo) GetAd(ctx context.Context, listID string) (domain.Ad, error) {
+	args := m.Called(ctx, listID)
 	return args.Get(0).(domain.Ad), args.Error(1)
 }
 
@@ -106,52 +133,62 @@ type mockCacheRepo struct {
 	mock.Mock
 }
 
-func (m *mockCacheRepo) SetCache(key string, typ CacheType, data interface{},
+func (m *mockCacheRepo) SetCache(ctx context.Context, key string, typ CacheType, data interface{},
 	expiration time.Duration) error {
-	args := m.Called(key, typ, data, expiration)
+	args := m.Called(ctx, key, typ, data, expiration)
 	return args.Error(0)
 }
 
-func (m *mockCacheRepo) GetCache(key string, typ CacheType) ([]byte, error) {
-	args := m.Called(key, typ)
+func (m *mockCacheRepo) GetCache(ctx context.Context, key string, typ CacheType) ([]byte, error) {
+	args := m.Called(ctx, key, typ)
 	return args.Get(0).([]byte), args.Error(1)
 }
 
+func (m *mockCacheRepo) DelCache(ctx context.Context, key string, typ CacheType) error {
+	args := m.Called(ctx, key, typ)
+	return args.Error(0)
+}
+
 type mockgetUserAdsLogger struct {
 	mock.Mock
 }
 
-func (m *mockgetUserAdsLogger) LogWarnGettingCache(userID int, err error) {
-	m.Called(userID, err)
+func (m *mockgetUserAdsLogger) LogWarnGettingCache(ctx context.Context, userID int, err error) {
+	m.Called(ctx, userID, err)
 }
 
-func (m *mockgetUserAdsLogger) LogWarnSettingCache(userID int, err error) {
-	m.Called(userID, err)
+func (m *mockgetUserAdsLogger) LogWarnSettingCache(ctx context.Context, userID int, err error) {
+	m.Called(ctx, userID, err)
 }
 
-func (m *mockgetUserAdsLogger) LogInfoActiveProductNotFound(userID int, product domain.Product) {
-	m.Called(userID, product)
+func (m *mockgetUserAdsLogger) LogInfoActiveProductNotFound(ctx context.Context, userID int, product domain.Product) {
+	m.Called(ctx, userID, product)
 }
 
-func (m *mockgetUserAdsLogger) LogInfoProductExpired(userID int, product domain.Product) {
-	m.Called(userID, product)
+func (m *mockgetUserAdsLogger) LogInfoProductExpired(ctx context.Context, userID int, product domain.Product) {
+	m.Called(ctx, userID, product)
 }
 
-func (m *mockgetUserAdsLogger) LogErrorGettingUserAdsData(userID int, err error) {
-	m.Called(userID, err)
+func (m *mockgetUserAdsLogger) LogErrorGettingUserAdsData(ctx context.Context, userID int, err error) {
+	m.Called(ctx, userID, err)
 }
 
-func (m *mockgetUserAdsLogger) LogNotEnoughAds(userID int) {
-	m.Called(userID)
+func (m *mockgetUserAdsLogger) LogNotEnoughAds(ctx context.Context, userID int) {
+	m.Called(ctx, userID)
 }
 
+// tSettings is the settings snapshot of the getUserAds requests
+var tSettings = Settings{CacheTTL: time.Hour, MinAdsToDisplay: 2, MaxAdsToDisplay: 15}
+
 func TestGetUserAdsOkWithoutCache(t *testing.T) {
 	mProductRepo := &mockProductRepo{}
 	mAdRepo := &mockAdRepo{}
 	mCacheRepo := &mockCacheRepo{}
 	mLogger := &mockgetUserAdsLogger{}
+	mMetrics := &mockMetrics{}
 	interactor := MakeGetUserAdsInteractor(mAdRepo, mProductRepo,
-		mCacheRepo, mLogger, time.Hour, 2)
+		mCacheRepo, mLogger,
+		makeMockTransactionRunner(mProductRepo, nil, nil), false, &mockTracer{}, mMetrics)
 	productParams := domain.ProductParams{Categories: []int{2020}, Limit: 2, PriceRange: 200}
 	tAds := domain.Ads{
 		{ID: "1", Subject: "Mi auto", UserID: 123},
@@ -160,20 +197,20 @@ func TestGetUserAdsOkWithoutCache(t *testing.T) {
 	testTime := time.Now().Add(time.Hour * 24)
 	product := domain.Product{Config: productParams, UserID: 123,
 		ExpiredAt: testTime, Status: domain.ActiveProduct}
-	mCacheRepo.On("GetCache", mock.AnythingOfType("string"), ProductCacheType).
+	mCacheRepo.On("GetCache", mock.Anything, mock.AnythingOfType("string"), ProductCacheType).
 		Return([]byte{}, fmt.Errorf("cache not found"))
-	mLogger.On("LogWarnGettingCache", mock.Anything, mock.Anything)
-	mLogger.On("LogWarnSettingCache", mock.Anything, mock.Anything)
-	mProductRepo.On("GetUserActiveProduct", mock.AnythingOfType("int"),
+	mLogger.On("LogWarnGettingCache", mock.Anything, mock.Anything, mock.Anything)
+	mLogger.On("LogWarnSettingCache", mock.Anything, mock.Anything, mock.Anything)
+	mProductRepo.On("GetUserActiveProduct", mock.Anything, mock.AnythingOfType("int"),
 		domain.PremiumCarousel).Return(product, nil)
-	mAdRepo.On("GetUserAds", mock.AnythingOfType("int"),
-		mock.AnythingOfType("ProductParams")).Return(tAds, nil)
-	mCacheRepo.On("SetCache", "user:123:PREMIUM_CAROUSEL",
+	mAdRepo.On("GetUserAds", mock.Anything, mock.AnythingOfType("int"),
+		mock.AnythingOfType("ProductParams"), 15).Return(tAds, nil)
+	mCacheRepo.On("SetCache", mock.Anything, "user:123:PREMIUM_CAROUSEL",
 		ProductCacheType,
 		product,
 		time.Hour).
 		Return(fmt.Errorf("error setting cache"))
-	ads, err := interactor.GetUserAds(domain.Ad{UserID: 123})
+	ads, err := interactor.GetUserAds(context.Background(), domain.Ad{UserID: 123}, tSettings)
 	expected := tAds
 	assert.NoError(t, err)
 	assert.Equal(t, expected, ads)
@@ -181,6 +218,8 @@ func TestGetUserAdsOkWithoutCache(t *testing.T) {
 	mAdRepo.AssertExpectations(t)
 	mCacheRepo.AssertExpectations(t)
 	mLogger.AssertExpectations(t)
+	assert.Equal(t, []int{2}, mMetrics.served)
+	assert.Equal(t, 1, mMetrics.misses[ProductCacheType])
 }
 
 func TestGetUserAdsOkWithoutCacheAndInactiveProduct(t *testing.T) {
@@ -188,31 +227,34 @@ func TestGetUserAdsOkWithoutCacheAndInactiveProduct(t *testing.T) {
 	mAdRepo := &mockAdRepo{}
 	mCacheRepo := &mockCacheRepo{}
 	mLogger := &mockgetUserAdsLogger{}
+	mMetrics := &mockMetrics{}
 	interactor := MakeGetUserAdsInteractor(mAdRepo, mProductRepo,
-		mCacheRepo, mLogger, time.Hour, 2)
+		mCacheRepo, mLogger,
+		makeMockTransactionRunner(mProductRepo, nil, nil), false, &mockTracer{}, mMetrics)
 
-	mCacheRepo.On("GetCache", mock.AnythingOfType("string"), ProductCacheType).
+	mCacheRepo.On("GetCache", mock.Anything, mock.AnythingOfType("string"), ProductCacheType).
 		Return([]byte{}, fmt.Errorf("cache not found"))
-	mLogger.On("LogWarnGettingCache", mock.Anything, mock.Anything)
-	mLogger.On("LogInfoActiveProductNotFound", mock.Anything, mock.Anything)
-	mLogger.On("LogWarnSettingCache", mock.Anything, mock.Anything)
-	mProductRepo.On("GetUserActiveProduct", mock.AnythingOfType("int"),
-		domain.PremiumCarousel).Return(domain.Product{}, fmt.Errorf("err"))
+	mLogger.On("LogWarnGettingCache", mock.Anything, mock.Anything, mock.Anything)
+	mLogger.On("LogInfoActiveProductNotFound", mock.Anything, mock.Anything, mock.Anything)
+	mLogger.On("LogWarnSettingCache", mock.Anything, mock.Anything, mock.Anything)
+	mProductRepo.On("GetUserActiveProduct", mock.Anything, mock.AnythingOfType("int"),
+		domain.PremiumCarousel).Return(domain.Product{}, ErrProductNotFound)
 
 	product := domain.Product{UserID: 123, Status: domain.InactiveProduct}
 
-	mCacheRepo.On("SetCache", "user:123:PREMIUM_CAROUSEL",
+	mCacheRepo.On("SetCache", mock.Anything, "user:123:PREMIUM_CAROUSEL",
 		ProductCacheType,
 		product,
 		time.Hour).
 		Return(fmt.Errorf("error setting cache"))
-	_, err := interactor.GetUserAds(domain.Ad{UserID: 123})
+	_, err := interactor.GetUserAds(context.Background(), domain.Ad{UserID: 123}, tSettings)
 
-	assert.Error(t, err)
+	assert.True(t, errors.Is(err, ErrProductNotActive))
 	mProductRepo.AssertExpectations(t)
 	mAdRepo.AssertExpectations(t)
 	mCacheRepo.AssertExpectations(t)
 	mLogger.AssertExpectations(t)
+	assert.Equal(t, []EmptyCarouselReason{NoProductReason}, mMetrics.empty)
 }
 
 func TestGetUserAdsOkWithCache(t *testing.T) {
@@ -220,8 +262,10 @@ func TestGetUserAdsOkWithCache(t *testing.T) {
 	mAdRepo := &mockAdRepo{}
 	mCacheRepo := &mockCacheRepo{}
 	mLogger := &mockgetUserAdsLogger{}
+	mMetrics := &mockMetrics{}
 	interactor := MakeGetUserAdsInteractor(mAdRepo, mProductRepo,
-		mCacheRepo, mLogger, time.Hour, 2)
+		mCacheRepo, mLogger,
+		makeMockTransactionRunner(mProductRepo, nil, nil), false, &mockTracer{}, mMetrics)
 	productParams := domain.ProductParams{Categories: []int{2020}, Limit: 2}
 	tAds := domain.Ads{
 		{ID: "1", Subject: "Mi auto", UserID: 123},
@@ -231,11 +275,11 @@ func TestGetUserAdsOkWithCache(t *testing.T) {
 	product := domain.Product{Config: productParams,
 		ExpiredAt: testTime, Status: domain.ActiveProduct}
 	productBytes, _ := json.Marshal(product)
-	mCacheRepo.On("GetCache", mock.AnythingOfType("string"), ProductCacheType).
+	mCacheRepo.On("GetCache", mock.Anything, mock.AnythingOfType("string"), ProductCacheType).
 		Return(productBytes, nil)
-	mAdRepo.On("GetUserAds", mock.AnythingOfType("int"),
-		mock.AnythingOfType("ProductParams")).Return(tAds, nil)
-	ads, err := interactor.GetUserAds(domain.Ad{UserID: 123})
+	mAdRepo.On("GetUserAds", mock.Anything, mock.AnythingOfType("int"),
+		mock.AnythingOfType("ProductParams"), 15).Return(tAds, nil)
+	ads, err := interactor.GetUserAds(context.Background(), domain.Ad{UserID: 123}, tSettings)
 	expected := tAds
 	assert.NoError(t, err)
 	assert.Equal(t, expected, ads)
@@ -243,6 +287,7 @@ func TestGetUserAdsOkWithCache(t *testing.T) {
 	mAdRepo.AssertExpectations(t)
 	mCacheRepo.AssertExpectations(t)
 	mLogger.AssertExpectations(t)
+	assert.Equal(t, 1, mMetrics.hits[ProductCacheType])
 }
 
 func TestGetUserAdsErrorProductInactive(t *testing.T) {
@@ -251,17 +296,19 @@ func TestGetUserAdsErrorProductInactive(t *testing.T) {
 	mCacheRepo := &mockCacheRepo{}
 	mLogger := &mockgetUserAdsLogger{}
 	interactor := MakeGetUserAdsInteractor(mAdRepo, mProductRepo,
-		mCacheRepo, mLogger, time.Hour, 2)
+		mCacheRepo, mLogger,
+		makeMockTransactionRunner(mProductRepo, nil, nil), false, &mockTracer{}, &mockMetrics{})
 	productParams := domain.ProductParams{Categories: []int{2020}, Limit: 2}
 	testTime := time.Now().Add(time.Hour * 24)
 	product := domain.Product{Config: productParams,
 		ExpiredAt: testTime, Status: domain.InactiveProduct}
 	productBytes, _ := json.Marshal(product)
-	mCacheRepo.On("GetCache", mock.AnythingOfType("string"), ProductCacheType).
+	mCacheRepo.On("GetCache", mock.Anything, mock.AnythingOfType("string"), ProductCacheType).
 		Return(productBytes, nil)
-	mLogger.On("LogInfoActiveProductNotFound", mock.Anything, mock.Anything)
+	mLogger.On("LogInfoActiveProductNotFound", mock.Anything, mock.Anything, mock.Anything)
 
-	interactor.GetUserAds(domain.Ad{UserID: 123})
+	_, err := interactor.GetUserAds(context.Background(), domain.Ad{UserID: 123}, tSettings)
+	assert.True(t, errors.Is(err, ErrProductNotActive))
 	mProductRepo.AssertExpectations(t)
 	mAdRepo.AssertExpectations(t)
 	mCacheRepo.AssertExpectations(t)
@@ -273,33 +320,93 @@ func TestGetUserAdsErrorProductExpired(t *testing.T) {
 	mAdRepo := &mockAdRepo{}
 	mCacheRepo := &mockCacheRepo{}
 	mLogger := &mockgetUserAdsLogger{}
+	mMetrics := &mockMetrics{}
 	interactor := MakeGetUserAdsInteractor(mAdRepo, mProductRepo,
-		mCacheRepo, mLogger, time.Hour, 2)
+		mCacheRepo, mLogger,
+		makeMockTransactionRunner(mProductRepo, nil, nil), false, &mockTracer{}, mMetrics)
 	productParams := domain.ProductParams{Categories: []int{2020}, Limit: 2}
 	testTime := time.Now().Add(time.Hour * -24)
 	product := domain.Product{Config: productParams,
 		ExpiredAt: testTime, UserID: 123, Status: domain.ActiveProduct}
 	productBytes, _ := json.Marshal(product)
-	mCacheRepo.On("GetCache", mock.AnythingOfType("string"), ProductCacheType).
+	mCacheRepo.On("GetCache", mock.Anything, mock.AnythingOfType("string"), ProductCacheType).
 		Return(productBytes, nil)
-	mLogger.On("LogInfoProductExpired", mock.Anything, mock.Anything)
-	mLogger.On("LogWarnSettingCache", mock.Anything, mock.Anything)
+	mLogger.On("LogInfoProductExpired", mock.Anything, mock.Anything, mock.Anything)
+	mLogger.On("LogWarnSettingCache", mock.Anything, mock.Anything, mock.Anything)
 
 	product.Status = domain.ExpiredProduct
-	mCacheRepo.On("SetCache", "user:123:PREMIUM_CAROUSEL",
+	mCacheRepo.On("SetCache", mock.Anything, "user:123:PREMIUM_CAROUSEL",
 		ProductCacheType,
 		mock.AnythingOfType("Product"),
 		time.Hour).
 		Return(fmt.Errorf("error setting cache"))
-	mProductRepo.On("SetStatus", mock.AnythingOfType("int"),
+	mProductRepo.On("IncrementVersion", mock.Anything, 0, 0).Return(1, nil)
+	mProductRepo.On("SetStatus", mock.Anything, mock.AnythingOfType("int"),
 		domain.ExpiredProduct).Return(nil)
-	_, err := interactor.GetUserAds(domain.Ad{UserID: 123})
+	_, err := interactor.GetUserAds(context.Background(), domain.Ad{UserID: 123}, tSettings)
 
-	assert.NoError(t, err)
+	assert.True(t, errors.Is(err, ErrProductExpired))
 	mProductRepo.AssertExpectations(t)
 	mAdRepo.AssertExpectations(t)
 	mCacheRepo.AssertExpectations(t)
 	mLogger.AssertExpectations(t)
+	assert.Equal(t, []EmptyCarouselReason{ProductExpiredReason}, mMetrics.empty)
+}
+
+func TestGetUserAdsProductExpiredStoresEvent(t *testing.T) {
+	mProductRepo := &mockProductRepo{}
+	mAdRepo := &mockAdRepo{}
+	mCacheRepo := &mockCacheRepo{}
+	mLogger := &mockgetUserAdsLogger{}
+	mBackendEventRepo := &mockBackendEventRepo{}
+	interactor := MakeGetUserAdsInteractor(mAdRepo, mProductRepo,
+		mCacheRepo, mLogger,
+		makeMockTransactionRunner(mProductRepo, nil, mBackendEventRepo), true, &mockTracer{}, &mockMetrics{})
+	product := domain.Product{ID: 1, UserID: 123, Status: domain.ActiveProduct,
+		ExpiredAt: time.Now().Add(time.Hour * -24)}
+	productBytes, _ := json.Marshal(product)
+	mCacheRepo.On("GetCache", mock.Anything, mock.AnythingOfType("string"), ProductCacheType).
+		Return(productBytes, nil)
+	mLogger.On("LogInfoProductExpired", mock.Anything, 123, mock.Anything)
+	mCacheRepo.On("SetCache", mock.Anything, "user:123:PREMIUM_CAROUSEL", ProductCacheType,
+		mock.AnythingOfType("Product"), time.Hour).Return(nil)
+	mProductRepo.On("IncrementVersion", mock.Anything, 1, 0).Return(1, nil)
+	mProductRepo.On("SetStatus", mock.Anything, 1, domain.ExpiredProduct).Return(nil)
+	mBackendEventRepo.On("PushExpiration", mock.Anything, mock.MatchedBy(func(p domain.Product) bool {
+		return p.ID == 1 && p.Status == domain.ExpiredProduct
+	})).Return(nil)
+	_, err := interactor.GetUserAds(context.Background(), domain.Ad{UserID: 123}, tSettings)
+	assert.True(t, errors.Is(err, ErrProductExpired))
+	mProductRepo.AssertExpectations(t)
+	mCacheRepo.AssertExpectations(t)
+	mLogger.AssertExpectations(t)
+	mBackendEventRepo.AssertExpectations(t)
+}
+
+func TestGetUserAdsProductExpiredChangedMeanwhile(t *testing.T) {
+	mProductRepo := &mockProductRepo{}
+	mAdRepo := &mockAdRepo{}
+	mCacheRepo := &mockCacheRepo{}
+	mLogger := &mockgetUserAdsLogger{}
+	mBackendEventRepo := &mockBackendEventRepo{}
+	interactor := MakeGetUserAdsInteractor(mAdRepo, mProductRepo,
+		mCacheRepo, mLogger,
+		makeMockTransactionRunner(mProductRepo, nil, mBackendEventRepo), true, &mockTracer{}, &mockMetrics{})
+	product := domain.Product{ID: 1, UserID: 123, Status: domain.ActiveProduct,
+		ExpiredAt: time.Now().Add(time.Hour * -24), Version: 2}
+	productBytes, _ := json.Marshal(product)
+	mCacheRepo.On("GetCache", mock.Anything, mock.AnythingOfType("string"), ProductCacheType).
+		Return(productBytes, nil)
+	mLogger.On("LogInfoProductExpired", mock.Anything, 123, mock.Anything)
+	mCacheRepo.On("SetCache", mock.Anything, "user:123:PREMIUM_CAROUSEL", ProductCacheType,
+		mock.AnythingOfType("Product"), time.Hour).Return(nil)
+	mProductRepo.On("IncrementVersion", mock.Anything, 1, 2).Return(0, ErrVersionMismatch)
+	_, err := interactor.GetUserAds(context.Background(), domain.Ad{UserID: 123}, tSettings)
+	assert.True(t, errors.Is(err, ErrProductExpired))
+	mProductRepo.AssertExpectations(t)
+	mCacheRepo.AssertExpectations(t)
+	mLogger.AssertExpectations(t)
+	mBackendEventRepo.AssertExpectations(t)
 }
 
 func TestGetUserAdsErrorGetAds(t *testing.T) {
@@ -307,27 +414,30 @@ func TestGetUserAdsErrorGetAds(t *testing.T) {
 	mAdRepo := &mockAdRepo{}
 	mCacheRepo := &mockCacheRepo{}
 	mLogger := &mockgetUserAdsLogger{}
+	mMetrics := &mockMetrics{}
 	interactor := MakeGetUserAdsInteractor(mAdRepo, mProductRepo,
-		mCacheRepo, mLogger, time.Hour, 2)
+		mCacheRepo, mLogger,
+		makeMockTransactionRunner(mProductRepo, nil, nil), false, &mockTracer{}, mMetrics)
 	productParams := domain.ProductParams{Categories: []int{2020}, Limit: 2}
 
 	testTime := time.Now().Add(time.Hour * 24)
 	product := domain.Product{Config: productParams,
 		ExpiredAt: testTime, Status: domain.ActiveProduct}
 	productBytes, _ := json.Marshal(product)
-	mCacheRepo.On("GetCache", mock.AnythingOfType("string"), ProductCacheType).
+	mCacheRepo.On("GetCache", mock.Anything, mock.AnythingOfType("string"), ProductCacheType).
 		Return(productBytes, nil)
-	mLogger.On("LogErrorGettingUserAdsData", mock.Anything, mock.Anything)
+	mLogger.On("LogErrorGettingUserAdsData", mock.Anything, mock.Anything, mock.Anything)
 
-	mAdRepo.On("GetUserAds", mock.AnythingOfType("int"),
-		mock.AnythingOfType("ProductParams")).Return(domain.Ads{}, fmt.Errorf("err"))
-	_, err := interactor.GetUserAds(domain.Ad{UserID: 123})
+	mAdRepo.On("GetUserAds", mock.Anything, mock.AnythingOfType("int"),
+		mock.AnythingOfType("ProductParams"), 15).Return(domain.Ads{}, fmt.Errorf("err"))
+	_, err := interactor.GetUserAds(context.Background(), domain.Ad{UserID: 123}, tSettings)
 
-	assert.Error(t, err)
+	assert.True(t, errors.Is(err, &DomainError{Code: SearchUnavailableCode}))
 	mProductRepo.AssertExpectations(t)
 	mAdRepo.AssertExpectations(t)
 	mCacheRepo.AssertExpectations(t)
 	mLogger.AssertExpectations(t)
+	assert.Equal(t, []EmptyCarouselReason{SearchErrorReason}, mMetrics.empty)
 }
 
 func TestGetUserAdsNotEnoughAds(t *testing.T) {
@@ -335,23 +445,107 @@ func TestGetUserAdsNotEnoughAds(t *testing.T) {
 	mAdRepo := &mockAdRepo{}
 	mCacheRepo := &mockCacheRepo{}
 	mLogger := &mockgetUserAdsLogger{}
+	mMetrics := &mockMetrics{}
 	interactor := MakeGetUserAdsInteractor(mAdRepo, mProductRepo,
-		mCacheRepo, mLogger, time.Hour, 2)
+		mCacheRepo, mLogger,
+		makeMockTransactionRunner(mProductRepo, nil, nil), false, &mockTracer{}, mMetrics)
 	productParams := domain.ProductParams{Categories: []int{2020}, Limit: 2}
 
 	testTime := time.Now().Add(time.Hour * 24)
 	product := domain.Product{Config: productParams,
 		ExpiredAt: testTime, Status: domain.ActiveProduct}
 	productBytes, _ := json.Marshal(product)
-	mCacheRepo.On("GetCache", mock.AnythingOfType("string"), ProductCacheType).
+	mCacheRepo.On("GetCache", mock.Anything, mock.AnythingOfType("string"), ProductCacheType).
 		Return(productBytes, nil)
-	mLogger.On("LogNotEnoughAds", mock.Anything)
+	mLogger.On("LogNotEnoughAds", mock.Anything, mock.Anything)
+
+	mAdRepo.On("GetUserAds", mock.Anything, mock.AnythingOfType("int"),
+		mock.AnythingOfType("ProductParams"), 15).Return(domain.Ads{domain.Ad{}}, nil)
+	_, err := interactor.GetUserAds(context.Background(), domain.Ad{UserID: 123}, tSettings)
 
-	mAdRepo.On("GetUserAds", mock.AnythingOfType("int"),
-		mock.AnythingOfType("ProductParams")).Return(domain.Ads{domain.Ad{}}, nil)
-	_, err := interactor.GetUserAds(domain.Ad{UserID: 123})
+	assert.True(t, errors.Is(err, ErrNotEnoughAds))
+	mProductRepo.AssertExpectations(t)
+	mAdRepo.AssertExpectations(t)
+	mCacheRepo.AssertExpectations(t)
+	mLogger.AssertExpectations(t)
+	assert.Equal(t, []EmptyCarouselReason{NotEnoughAdsReason}, mMetrics.empty)
+}
+
+func TestGetUserAdsErrorGettingActiveProduct(t *testing.T) {
+	mProductRepo := &mockProductRepo{}
+	mAdRepo := &mockAdRepo{}
+	mCacheRepo := &mockCacheRepo{}
+	mLogger := &mockgetUserAdsLogger{}
+	mMetrics := &mockMetrics{}
+	interactor := MakeGetUserAdsInteractor(mAdRepo, mProductRepo,
+		mCacheRepo, mLogger,
+		makeMockTransactionRunner(mProductRepo, nil, nil), false, &mockTracer{}, mMetrics)
+
+	mCacheRepo.On("GetCache", mock.Anything, mock.AnythingOfType("string"), ProductCacheType).
+		Return([]byte{}, fmt.Errorf("cache not found"))
+	mLogger.On("LogWarnGettingCache", mock.Anything, mock.Anything, mock.Anything)
+	mLogger.On("LogErrorGettingUserAdsData", mock.Anything, 123, mock.Anything)
+	mProductRepo.On("GetUserActiveProduct", mock.Anything, 123,
+		domain.PremiumCarousel).Return(domain.Product{}, fmt.Errorf("err"))
+	_, err := interactor.GetUserAds(context.Background(), domain.Ad{UserID: 123}, tSettings)
+
+	assert.True(t, errors.Is(err, &DomainError{Code: DatabaseUnavailableCode}))
+	mProductRepo.AssertExpectations(t)
+	mAdRepo.AssertExpectations(t)
+	mCacheRepo.AssertExpectations(t)
+	mLogger.AssertExpectations(t)
+	assert.Equal(t, []EmptyCarouselReason{DatabaseErrorReason}, mMetrics.empty)
+}
+
+func TestGetUserAdsErrorExpiringProduct(t *testing.T) {
+	mProductRepo := &mockProductRepo{}
+	mAdRepo := &mockAdRepo{}
+	mCacheRepo := &mockCacheRepo{}
+	mLogger := &mockgetUserAdsLogger{}
+	interactor := MakeGetUserAdsInteractor(mAdRepo, mProductRepo,
+		mCacheRepo, mLogger,
+		makeMockTransactionRunner(mProductRepo, nil, nil), false, &mockTracer{}, &mockMetrics{})
+	product := domain.Product{ID: 1, ExpiredAt: time.Now().Add(-time.Hour),
+		UserID: 123, Status: domain.ActiveProduct}
+	productBytes, _ := json.Marshal(product)
+	mCacheRepo.On("GetCache", mock.Anything, mock.AnythingOfType("string"), ProductCacheType).
+		Return(productBytes, nil)
+	mLogger.On("LogInfoProductExpired", mock.Anything, mock.Anything, mock.Anything)
+	mCacheRepo.On("SetCache", mock.Anything, "user:123:PREMIUM_CAROUSEL",
+		ProductCacheType,
+		mock.AnythingOfType("Product"),
+		time.Hour).
+		Return(nil)
+	mProductRepo.On("IncrementVersion", mock.Anything, 1, 0).Return(1, nil)
+	mProductRepo.On("SetStatus", mock.Anything, 1, domain.ExpiredProduct).Return(fmt.Errorf("err"))
+	_, err := interactor.GetUserAds(context.Background(), domain.Ad{UserID: 123}, tSettings)
+
+	assert.True(t, errors.Is(err, &DomainError{Code: DatabaseUnavailableCode}))
+	mProductRepo.AssertExpectations(t)
+	mAdRepo.AssertExpectations(t)
+	mCacheRepo.AssertExpectations(t)
+	mLogger.AssertExpectations(t)
+}
+
+func TestGetUserAdsNoAdsFound(t *testing.T) {
+	mProductRepo := &mockProductRepo{}
+	mAdRepo := &mockAdRepo{}
+	mCacheRepo := &mockCacheRepo{}
+	mLogger := &mockgetUserAdsLogger{}
+	interactor := MakeGetUserAdsInteractor(mAdRepo, mProductRepo,
+		mCacheRepo, mLogger,
+		makeMockTransactionRunner(mProductRepo, nil, nil), false, &mockTracer{}, &mockMetrics{})
+	product := domain.Product{ExpiredAt: time.Now().Add(time.Hour),
+		Status: domain.ActiveProduct}
+	productBytes, _ := json.Marshal(product)
+	mCacheRepo.On("GetCache", mock.Anything, mock.AnythingOfType("string"), ProductCacheType).
+		Return(productBytes, nil)
+	mLogger.On("LogNotEnoughAds", mock.Anything, 123)
+	mAdRepo.On("GetUserAds", mock.Anything, 123, mock.AnythingOfType("ProductParams"), 15).
+		Return(domain.Ads{}, fmt.Errorf("no results: %w", ErrNotEnoughAds))
+	_, err := interactor.GetUserAds(context.Background(), domain.Ad{UserID: 123}, tSettings)
 
-	assert.Error(t, err)
+	assert.True(t, errors.Is(err, ErrNotEnoughAds))
 	mProductRepo.AssertExpectations(t)
 	mAdRepo.AssertExpectations(t)
 	mCacheRepo.AssertExpectations(t)
@@ -0,0 +1,48 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/domain"
+)
+
+// GetUserProductInteractor wraps GetUserProduct operations
+type GetUserProductInteractor interface {
+	GetUserProduct(ctx context.Context, userProductID int) (domain.Product, error)
+}
+
+// getUserProductInteractor defines the interactor for GetUserProduct usecase
+type getUserProductInteractor struct {
+	productRepo ProductRepository
+	logger      GetUserProductLogger
+	tracer      Tracer
+}
+
+// GetUserProductLogger logs GetUserProduct events
+type GetUserProductLogger interface {
+	LogErrorGettingUserProduct(ctx context.Context, userProductID int, err error)
+}
+
+// MakeGetUserProductInteractor creates a new instance of GetUserProductInteractor
+func MakeGetUserProductInteractor(productRepo ProductRepository,
+	logger GetUserProductLogger, tracer Tracer) GetUserProductInteractor {
+	return &getUserProductInteractor{productRepo: productRepo, logger: logger,
+		tracer: tracer}
+}
+
+// GetUserProduct gets a single user product by its ID
+func (interactor *getUserProductInteractor) GetUserProduct(
+	ctx context.Context, userProductID int) (product domain.Product, err error) {
+	ctx, span := interactor.tracer.Start(ctx, "GetUserProduct")
+	defer func() { span.End(err) }()
+	product, err = interactor.productRepo.GetUserProductByID(ctx, userProductID)
+	if errors.Is(err, ErrProductNotFound) {
+		return domain.Product{}, err
+	}
+	if err != nil {
+		interactor.logger.LogErrorGettingUserProduct(ctx, userProductID, err)
+		return domain.Product{}, newDatabaseError("error loading product", err)
+	}
+	return product, nil
+}
@@ -0,0 +1,57 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/domain"
+)
+
+type mockGetUserProductLogger struct {
+	mock.Mock
+}
+
+func (m *mockGetUserProductLogger) LogErrorGettingUserProduct(ctx context.Context, userProductID int, err error) {
+	m.Called(ctx, userProductID, err)
+}
+
+func TestGetUserProductOk(t *testing.T) {
+	mProductRepo := &mockProductRepo{}
+	mLogger := &mockGetUserProductLogger{}
+	interactor := MakeGetUserProductInteractor(mProductRepo, mLogger, &mockTracer{})
+	product := domain.Product{ID: 1, Version: 2}
+	mProductRepo.On("GetUserProductByID", mock.Anything, 1).Return(product, nil)
+	res, err := interactor.GetUserProduct(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, product, res)
+	mProductRepo.AssertExpectations(t)
+	mLogger.AssertExpectations(t)
+}
+
+func TestGetUserProductNotFound(t *testing.T) {
+	mProductRepo := &mockProductRepo{}
+	mLogger := &mockGetUserProductLogger{}
+	interactor := MakeGetUserProductInteractor(mProductRepo, mLogger, &mockTracer{})
+	mProductRepo.On("GetUserProductByID", mock.Anything, 1).
+		Return(domain.Product{}, ErrProductNotFound)
+	_, err := interactor.GetUserProduct(context.Background(), 1)
+	assert.Equal(t, ErrProductNotFound, err)
+	mProductRepo.AssertExpectations(t)
+	mLogger.AssertExpectations(t)
+}
+
+func TestGetUserProductError(t *testing.T) {
+	mProductRepo := &mockProductRepo{}
+	mLogger := &mockGetUserProductLogger{}
+	interactor := MakeGetUserProductInteractor(mProductRepo, mLogger, &mockTracer{})
+	mProductRepo.On("GetUserProductByID", mock.Anything, 1).
+		Return(domain.Product{}, fmt.Errorf("err"))
+	mLogger.On("LogErrorGettingUserProduct", mock.Anything, 1, mock.Anything)
+	_, err := interactor.GetUserProduct(context.Background(), 1)
+	assert.Error(t, err)
+	mProductRepo.AssertExpectations(t)
+	mLogger.AssertExpectations(t)
+}
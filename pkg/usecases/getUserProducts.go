@@ -1,50 +1,55 @@
 package usecases
 
 import (
-	"fmt"
+	"context"
 
 	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/domain"
 )
 
 // GetUserProductsInteractor wraps GetUserProducts operations
 type GetUserProductsInteractor interface {
-	GetUserProducts(email string, page int) ([]domain.Product, int, int, error)
+	GetUserProducts(ctx context.Context, email string,
+		page int) ([]domain.Product, int, int, error)
 }
 
 // getUserProductsInteractor defines the interactor for GetUserProducts usecase
 type getUserProductsInteractor struct {
 	productRepo ProductRepository
 	logger      GetUserProductsLogger
+	tracer      Tracer
 }
 
 // GetUserProductsLogger logs GetUserProducts events
 type GetUserProductsLogger interface {
-	LogErrorGettingUserProducts(err error)
-	LogErrorGettingUserProductsByEmail(email string, err error)
+	LogErrorGettingUserProducts(ctx context.Context, err error)
+	LogErrorGettingUserProductsByEmail(ctx context.Context, email string, err error)
 }
 
 // MakeGetUserProductsInteractor creates a new instance of GetUserProductsInteractor
 func MakeGetUserProductsInteractor(productRepo ProductRepository,
-	logger GetUserProductsLogger) GetUserProductsInteractor {
-	return &getUserProductsInteractor{productRepo: productRepo, logger: logger}
+	logger GetUserProductsLogger, tracer Tracer) GetUserProductsInteractor {
+	return &getUserProductsInteractor{productRepo: productRepo, logger: logger,
+		tracer: tracer}
 }
 
 // GetUserProducts gets all user products using pagination
-func (interactor *getUserProductsInteractor) GetUserProducts(email string,
-	page int) (products []domain.Product, currentPage int, totalPages int, err error) {
+func (interactor *getUserProductsInteractor) GetUserProducts(ctx context.Context,
+	email string, page int) (products []domain.Product, currentPage int, totalPages int, err error) {
+	ctx, span := interactor.tracer.Start(ctx, "GetUserProducts")
+	defer func() { span.End(err) }()
 	if email == "" {
 		products, currentPage, totalPages, err = interactor.productRepo.
-			GetUserProducts(page)
+			GetUserProducts(ctx, page)
 		if err != nil {
-			interactor.logger.LogErrorGettingUserProducts(err)
-			return []domain.Product{}, 0, 0, fmt.Errorf("error loading products: %+v", err)
+			interactor.logger.LogErrorGettingUserProducts(ctx, err)
+			return []domain.Product{}, 0, 0, newDatabaseError("error loading products", err)
 		}
 	} else {
 		products, currentPage, totalPages, err = interactor.productRepo.
-			GetUserProductsByEmail(email, page)
+			GetUserProductsByEmail(ctx, email, page)
 		if err != nil {
-			interactor.logger.LogErrorGettingUserProductsByEmail(email, err)
-			return []domain.Product{}, 0, 0, fmt.Errorf("error loading products: %+v", err)
+			interactor.logger.LogErrorGettingUserProductsByEmail(ctx, email, err)
+			return []domain.Product{}, 0, 0, newDatabaseError("error loading products", err)
 		}
 	}
 	return
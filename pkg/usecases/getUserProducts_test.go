@@ -1,6 +1,7 @@
 package usecases
 
 import (
+	"context"
 	"fmt"
 	"testing"
 
@@ -13,25 +14,25 @@ type mockGetUserProductsLogger struct {
 	mock.Mock
 }
 
-func (m *mockGetUserProductsLogger) LogErrorGettingUserProducts(err error) {
-	m.Called(err)
+func (m *mockGetUserProductsLogger) LogErrorGettingUserProducts(ctx context.Context, err error) {
+	m.Called(ctx, err)
 }
 
-func (m *mockGetUserProductsLogger) LogErrorGettingUserProductsByEmail(email string, err error) {
-	m.Called(email, err)
+func (m *mockGetUserProductsLogger) LogErrorGettingUserProductsByEmail(ctx context.Context, email string, err error) {
+	m.Called(ctx, email, err)
 }
 
 func TestGetUserProductsByEmailOk(t *testing.T) {
 	mProductRepo := &mockProductRepo{}
 	mLogger := &mockGetUserProductsLogger{}
-	interactor := MakeGetUserProductsInteractor(mProductRepo, mLogger)
+	interactor := MakeGetUserProductsInteractor(mProductRepo, mLogger, &mockTracer{})
 	products := []domain.Product{}
-	mProductRepo.On("GetUserProductsByEmail",
+	mProductRepo.On("GetUserProductsByEmail", mock.Anything,
 		mock.AnythingOfType("string"),
 		mock.AnythingOfType("int"),
 	).Return(products, 1, 1, nil)
 	res, currentPage,
-		totalPages, err := interactor.GetUserProducts("test@test.cl", 1)
+		totalPages, err := interactor.GetUserProducts(context.Background(), "test@test.cl", 1)
 	assert.NoError(t, err)
 	assert.Equal(t, products, res)
 	assert.Equal(t, 1, currentPage)
@@ -43,14 +44,14 @@ func TestGetUserProductsByEmailOk(t *testing.T) {
 func TestGetUserProductsByEmailError(t *testing.T) {
 	mProductRepo := &mockProductRepo{}
 	mLogger := &mockGetUserProductsLogger{}
-	interactor := MakeGetUserProductsInteractor(mProductRepo, mLogger)
-	mLogger.On("LogErrorGettingUserProductsByEmail",
+	interactor := MakeGetUserProductsInteractor(mProductRepo, mLogger, &mockTracer{})
+	mLogger.On("LogErrorGettingUserProductsByEmail", mock.Anything,
 		mock.Anything, mock.Anything)
-	mProductRepo.On("GetUserProductsByEmail",
+	mProductRepo.On("GetUserProductsByEmail", mock.Anything,
 		mock.AnythingOfType("string"),
 		mock.AnythingOfType("int"),
 	).Return([]domain.Product{}, 0, 0, fmt.Errorf("err"))
-	_, _, _, err := interactor.GetUserProducts("test@test.cl", 1)
+	_, _, _, err := interactor.GetUserProducts(context.Background(), "test@test.cl", 1)
 	assert.Error(t, err)
 	mProductRepo.AssertExpectations(t)
 	mLogger.AssertExpectations(t)
@@ -59,13 +60,13 @@ func TestGetUserProductsByEmailError(t *testing.T) {
 func TestGetUserProductsOk(t *testing.T) {
 	mProductRepo := &mockProductRepo{}
 	mLogger := &mockGetUserProductsLogger{}
-	interactor := MakeGetUserProductsInteractor(mProductRepo, mLogger)
+	interactor := MakeGetUserProductsInteractor(mProductRepo, mLogger, &mockTracer{})
 	products := []domain.Product{}
-	mProductRepo.On("GetUserProducts",
+	mProductRepo.On("GetUserProducts", mock.Anything,
 		mock.AnythingOfType("int"),
 	).Return(products, 1, 1, nil)
 	res, currentPage,
-		totalPages, err := interactor.GetUserProducts("", 1)
+		totalPages, err := interactor.GetUserProducts(context.Background(), "", 1)
 	assert.NoError(t, err)
 	assert.Equal(t, products, res)
 	assert.Equal(t, 1, currentPage)
@@ -77,13 +78,13 @@ func TestGetUserProductsOk(t *testing.T) {
 func TestGetUserProductsError(t *testing.T) {
 	mProductRepo := &mockProductRepo{}
 	mLogger := &mockGetUserProductsLogger{}
-	interactor := MakeGetUserProductsInteractor(mProductRepo, mLogger)
-	mLogger.On("LogErrorGettingUserProducts",
+	interactor := MakeGetUserProductsInteractor(mProductRepo, mLogger, &mockTracer{})
+	mLogger.On("LogErrorGettingUserProducts", mock.Anything,
 		mock.Anything, mock.Anything)
-	mProductRepo.On("GetUserProducts",
+	mProductRepo.On("GetUserProducts", mock.Anything,
 		mock.AnythingOfType("int"),
 	).Return([]domain.Product{}, 0, 0, fmt.Errorf("err"))
-	_, _, _, err := interactor.GetUserProducts("", 1)
+	_, _, _, err := interactor.GetUserProducts(context.Background(), "", 1)
 	assert.Error(t, err)
 	mProductRepo.AssertExpectations(t)
 	mLogger.AssertExpectations(t)
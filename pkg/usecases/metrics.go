@@ -0,0 +1,28 @@
+package usecases
+
+// EmptyCarouselReason tells why a carousel was served without ads
+type EmptyCarouselReason string
+
+const (
+	// NoProductReason is given when the user has no active product
+	NoProductReason EmptyCarouselReason = "no_product"
+	// ProductExpiredReason is given when the product of the user expired
+	ProductExpiredReason EmptyCarouselReason = "expired"
+	// NotEnoughAdsReason is given when the user has too few ads to display
+	NotEnoughAdsReason EmptyCarouselReason = "not_enough_ads"
+	// SearchErrorReason is given when the ads could not be searched
+	SearchErrorReason EmptyCarouselReason = "search_error"
+	// DatabaseErrorReason is given when the product could not be read or stored
+	DatabaseErrorReason EmptyCarouselReason = "database_error"
+)
+
+// Metrics collects the business metrics of the usecases
+type Metrics interface {
+	// CollectCarouselServed counts a carousel served with the given ads
+	CollectCarouselServed(ads int)
+	// CollectEmptyCarousel counts a carousel served without ads
+	CollectEmptyCarousel(reason EmptyCarouselReason)
+	// CollectCacheLookup counts a read of the cache, hit tells whether the
+	// value was found
+	CollectCacheLookup(typ CacheType, hit bool)
+}
@@ -0,0 +1,28 @@
+package usecases
+
+// mockMetrics records the metrics collected through it
+type mockMetrics struct {
+	served []int
+	empty  []EmptyCarouselReason
+	hits   map[CacheType]int
+	misses map[CacheType]int
+}
+
+func (m *mockMetrics) CollectCarouselServed(ads int) {
+	m.served = append(m.served, ads)
+}
+
+func (m *mockMetrics) CollectEmptyCarousel(reason EmptyCarouselReason) {
+	m.empty = append(m.empty, reason)
+}
+
+func (m *mockMetrics) CollectCacheLookup(typ CacheType, hit bool) {
+	if m.hits == nil {
+		m.hits, m.misses = map[CacheType]int{}, map[CacheType]int{}
+	}
+	if hit {
+		m.hits[typ]++
+	} else {
+		m.misses[typ]++
+	}
+}
@@ -0,0 +1,55 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"reflect"
+
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/domain"
+)
+
+// getProductBeforeUpdate gets the product to be updated so the changes made
+// can be told. The version is checked so the product is known to be
+// unchanged until the update takes place
+func getProductBeforeUpdate(ctx context.Context, productRepo ProductRepository,
+	userProductID int, version int) (domain.Product, error) {
+	product, err := productRepo.GetUserProductByID(ctx, userProductID)
+	if errors.Is(err, ErrProductNotFound) {
+		return domain.Product{}, err
+	}
+	if err != nil {
+		return domain.Product{}, newDatabaseError("cannot get control-panel configuration", err)
+	}
+	if product.Version != version {
+		return domain.Product{}, ErrVersionMismatch
+	}
+	return product, nil
+}
+
+// pushProductChanges pushes the lifecycle events telling the changes made
+// to a product by an update. An active product leaving that status is
+// cancelled, while any other change of status is pushed as such. Postponed
+// expirations are extensions, any other change on the expiration or on the
+// configuration is pushed as a config change
+func pushProductChanges(ctx context.Context, repo BackendEventsRepository,
+	before, after domain.Product) error {
+	var err error
+	switch {
+	case before.Status == after.Status:
+	case before.Status == domain.ActiveProduct:
+		err = repo.PushCancellation(ctx, after, before.Status)
+	default:
+		err = repo.PushStatusChange(ctx, after, before.Status)
+	}
+	if err == nil && after.ExpiredAt.After(before.ExpiredAt) {
+		err = repo.PushExtension(ctx, after, before.ExpiredAt)
+	}
+	if err == nil && (!reflect.DeepEqual(before.Config, after.Config) ||
+		after.ExpiredAt.Before(before.ExpiredAt)) {
+		err = repo.PushConfigChange(ctx, after, before.Config)
+	}
+	if err != nil {
+		return newDatabaseError("cannot store the product events", err)
+	}
+	return nil
+}
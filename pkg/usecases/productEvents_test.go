@@ -0,0 +1,54 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/domain"
+)
+
+func TestPushProductChangesStatusChange(t *testing.T) {
+	mRepo := &mockBackendEventRepo{}
+	before := domain.Product{ID: 1, Status: domain.InactiveProduct}
+	after := domain.Product{ID: 1, Status: domain.ActiveProduct}
+	mRepo.On("PushStatusChange", mock.Anything, after, domain.InactiveProduct).Return(nil)
+	err := pushProductChanges(context.Background(), mRepo, before, after)
+	assert.NoError(t, err)
+	mRepo.AssertExpectations(t)
+}
+
+func TestPushProductChangesShortenedExpiration(t *testing.T) {
+	mRepo := &mockBackendEventRepo{}
+	now := time.Now()
+	before := domain.Product{ID: 1, ExpiredAt: now}
+	after := domain.Product{ID: 1, ExpiredAt: now.Add(-time.Hour)}
+	mRepo.On("PushConfigChange", mock.Anything, after, before.Config).Return(nil)
+	err := pushProductChanges(context.Background(), mRepo, before, after)
+	assert.NoError(t, err)
+	mRepo.AssertExpectations(t)
+}
+
+func TestPushProductChangesNoChanges(t *testing.T) {
+	mRepo := &mockBackendEventRepo{}
+	product := domain.Product{ID: 1, Status: domain.ActiveProduct,
+		Config: domain.ProductParams{Limit: 10}}
+	err := pushProductChanges(context.Background(), mRepo, product, product)
+	assert.NoError(t, err)
+	mRepo.AssertExpectations(t)
+}
+
+func TestPushProductChangesError(t *testing.T) {
+	mRepo := &mockBackendEventRepo{}
+	before := domain.Product{ID: 1, Status: domain.ActiveProduct}
+	after := domain.Product{ID: 1, Status: domain.InactiveProduct,
+		Config: domain.ProductParams{Limit: 10}}
+	mRepo.On("PushCancellation", mock.Anything, after, domain.ActiveProduct).Return(fmt.Errorf("err"))
+	err := pushProductChanges(context.Background(), mRepo, before, after)
+	assert.Error(t, err)
+	mRepo.AssertExpectations(t)
+}
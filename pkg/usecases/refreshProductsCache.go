@@ -0,0 +1,88 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/domain"
+)
+
+// RefreshProductsCacheInteractor wraps RefreshProductsCache operations
+type RefreshProductsCacheInteractor interface {
+	RefreshProductsCache(ctx context.Context) error
+}
+
+// refreshProductsCacheInteractor defines the interactor for
+// RefreshProductsCache usecase
+type refreshProductsCacheInteractor struct {
+	productRepo ProductRepository
+	cacheRepo   CacheRepository
+	logger      RefreshProductsCacheLogger
+	settings    SettingsProvider
+	tracer      Tracer
+}
+
+// RefreshProductsCacheLogger logs RefreshProductsCache events
+type RefreshProductsCacheLogger interface {
+	LogErrorRefreshingCache(err error)
+	LogWarnSettingCache(userID int, err error)
+}
+
+// MakeRefreshProductsCacheInteractor creates a new instance of
+// RefreshProductsCacheInteractor
+func MakeRefreshProductsCacheInteractor(productRepo ProductRepository,
+	cacheRepo CacheRepository, logger RefreshProductsCacheLogger,
+	settings SettingsProvider, tracer Tracer) RefreshProductsCacheInteractor {
+	return &refreshProductsCacheInteractor{productRepo: productRepo,
+		cacheRepo: cacheRepo, logger: logger, settings: settings, tracer: tracer}
+}
+
+// RefreshProductsCache sets the product cache of every user holding an
+// active product, so served carousels follow the database state
+func (interactor *refreshProductsCacheInteractor) RefreshProductsCache(
+	ctx context.Context) (err error) {
+	ctx, span := interactor.tracer.Start(ctx, "RefreshProductsCache")
+	defer func() { span.End(err) }()
+	products, err := interactor.productRepo.GetActiveProducts(ctx)
+	if err != nil {
+		interactor.logger.LogErrorRefreshingCache(err)
+		return newDatabaseError("cannot get active products", err)
+	}
+	refreshed := make(map[int]bool)
+	for _, product := range products {
+		// products are sorted so the first one of each user is the one served
+		if refreshed[product.UserID] {
+			continue
+		}
+		refreshed[product.UserID] = true
+		err := interactor.cacheRepo.SetCache(ctx, makeProductCacheKey(product.UserID),
+			ProductCacheType, product, interactor.settings.Settings().CacheTTL)
+		if err != nil {
+			interactor.logger.LogWarnSettingCache(product.UserID, err)
+		}
+	}
+	return nil
+}
+
+// makeProductCacheKey returns the cache key of the user premium carousel
+func makeProductCacheKey(userID int) string {
+	return strings.Join([]string{"user", strconv.Itoa(userID),
+		string(domain.PremiumCarousel)}, ":")
+}
+
+// refreshUserProductCache sets the cache of the user with the product served
+// to them, an inactive placeholder is cached when there is none
+func refreshUserProductCache(ctx context.Context, productRepo ProductRepository,
+	cacheRepo CacheRepository, userID int, cacheTTL time.Duration) error {
+	product, err := productRepo.GetUserActiveProduct(ctx, userID, domain.PremiumCarousel)
+	if errors.Is(err, ErrProductNotFound) {
+		product = domain.Product{UserID: userID, Status: domain.InactiveProduct}
+	} else if err != nil {
+		return err
+	}
+	return cacheRepo.SetCache(ctx, makeProductCacheKey(userID), ProductCacheType,
+		product, cacheTTL)
+}
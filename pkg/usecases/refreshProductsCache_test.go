@@ -0,0 +1,65 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/domain"
+)
+
+type mockRefreshProductsCacheLogger struct {
+	mock.Mock
+}
+
+func (m *mockRefreshProductsCacheLogger) LogErrorRefreshingCache(err error) {
+	m.Called(err)
+}
+
+func (m *mockRefreshProductsCacheLogger) LogWarnSettingCache(userID int, err error) {
+	m.Called(userID, err)
+}
+
+func TestRefreshProductsCacheOk(t *testing.T) {
+	mProductRepo := &mockProductRepo{}
+	mCacheRepo := &mockCacheRepo{}
+	mLogger := &mockRefreshProductsCacheLogger{}
+	interactor := MakeRefreshProductsCacheInteractor(mProductRepo, mCacheRepo,
+		mLogger, StaticSettings{CacheTTL: time.Hour}, &mockTracer{})
+	first := domain.Product{ID: 1, UserID: 123, Type: domain.PremiumCarousel,
+		Status: domain.ActiveProduct}
+	second := domain.Product{ID: 2, UserID: 123, Type: domain.PremiumCarousel,
+		Status: domain.ActiveProduct}
+	other := domain.Product{ID: 3, UserID: 456, Type: domain.PremiumCarousel,
+		Status: domain.ActiveProduct}
+	mProductRepo.On("GetActiveProducts", mock.Anything, mock.Anything).Return([]domain.Product{first, second, other}, nil)
+	mCacheRepo.On("SetCache", mock.Anything, "user:123:PREMIUM_CAROUSEL", ProductCacheType,
+		first, time.Hour).Return(nil).Once()
+	mCacheRepo.On("SetCache", mock.Anything, "user:456:PREMIUM_CAROUSEL", ProductCacheType,
+		other, time.Hour).Return(fmt.Errorf("err")).Once()
+	mLogger.On("LogWarnSettingCache", 456, mock.Anything)
+	err := interactor.RefreshProductsCache(context.Background())
+	assert.NoError(t, err)
+	mProductRepo.AssertExpectations(t)
+	mCacheRepo.AssertExpectations(t)
+	mLogger.AssertExpectations(t)
+}
+
+func TestRefreshProductsCacheRepoError(t *testing.T) {
+	mProductRepo := &mockProductRepo{}
+	mCacheRepo := &mockCacheRepo{}
+	mLogger := &mockRefreshProductsCacheLogger{}
+	interactor := MakeRefreshProductsCacheInteractor(mProductRepo, mCacheRepo,
+		mLogger, StaticSettings{CacheTTL: time.Hour}, &mockTracer{})
+	mProductRepo.On("GetActiveProducts", mock.Anything, mock.Anything).Return([]domain.Product{}, fmt.Errorf("err"))
+	mLogger.On("LogErrorRefreshingCache", mock.Anything)
+	err := interactor.RefreshProductsCache(context.Background())
+	assert.Error(t, err)
+	mProductRepo.AssertExpectations(t)
+	mCacheRepo.AssertExpectations(t)
+	mLogger.AssertExpectations(t)
+}
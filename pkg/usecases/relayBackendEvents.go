@@ -0,0 +1,134 @@
+package usecases
+
+import (
+	"context"
+	"time"
+)
+
+// RelayBackendEventsInteractor wraps RelayBackendEvents operations
+type RelayBackendEventsInteractor interface {
+	RelayBackendEvents(ctx context.Context) error
+	PurgeBackendEvents(ctx context.Context) error
+}
+
+// relayBackendEventsInteractor defines the interactor for RelayBackendEvents
+// usecase
+type relayBackendEventsInteractor struct {
+	outboxRepo OutboxRepository
+	publisher  EventsPublisher
+	logger     RelayBackendEventsLogger
+	batchSize  int
+	maxBackoff time.Duration
+	retention  time.Duration
+	now        func() time.Time
+	tracer     Tracer
+}
+
+// RelayBackendEventsLogger logs RelayBackendEvents events
+type RelayBackendEventsLogger interface {
+	LogErrorGettingPendingEvents(err error)
+	LogWarnPublishingEvent(eventID int, attempts int, err error)
+	LogErrorMarkingEvent(eventID int, err error)
+	LogErrorPurgingEvents(err error)
+}
+
+// MakeRelayBackendEventsInteractor creates a new instance of
+// RelayBackendEventsInteractor. Failed events are retried with an exponential
+// backoff up to maxBackoff, sent events are kept for retention
+func MakeRelayBackendEventsInteractor(
+	outboxRepo OutboxRepository,
+	publisher EventsPublisher,
+	logger RelayBackendEventsLogger,
+	batchSize int,
+	maxBackoff time.Duration,
+	retention time.Duration,
+	tracer Tracer,
+) RelayBackendEventsInteractor {
+	return &relayBackendEventsInteractor{
+		outboxRepo: outboxRepo,
+		publisher:  publisher,
+		logger:     logger,
+		batchSize:  batchSize,
+		maxBackoff: maxBackoff,
+		retention:  retention,
+		now:        time.Now,
+		tracer:     tracer,
+	}
+}
+
+// RelayBackendEvents publishes the pending outbox events in order, marking
+// each one sent. An event failing to be published holds back the following
+// events of its key until it is retried, so each key keeps its order. Events
+// published but not marked sent are published again, so delivery is at
+// least once
+func (interactor *relayBackendEventsInteractor) RelayBackendEvents(
+	ctx context.Context) (err error) {
+	ctx, span := interactor.tracer.Start(ctx, "RelayBackendEvents")
+	defer func() { span.End(err) }()
+	for {
+		events, err := interactor.outboxRepo.GetPendingEvents(ctx, interactor.batchSize)
+		if err != nil {
+			interactor.logger.LogErrorGettingPendingEvents(err)
+			return newDatabaseError("error getting pending events", err)
+		}
+		if !interactor.relay(ctx, events) || len(events) < interactor.batchSize {
+			return nil
+		}
+	}
+}
+
+// PurgeBackendEvents deletes the outbox events sent longer than the retention
+// ago, the pending ones are kept however old they are
+func (interactor *relayBackendEventsInteractor) PurgeBackendEvents(
+	ctx context.Context) (err error) {
+	ctx, span := interactor.tracer.Start(ctx, "PurgeBackendEvents")
+	defer func() { span.End(err) }()
+	err = interactor.outboxRepo.PurgeSent(ctx, interactor.now().Add(-interactor.retention))
+	if err != nil {
+		interactor.logger.LogErrorPurgingEvents(err)
+		return newDatabaseError("error purging sent events", err)
+	}
+	return nil
+}
+
+// relay publishes a batch of events, returns whether all of them were
+// published and marked sent. The events after a failed one of the same key
+// are left pending even if they were published, so the last copy of each key
+// reaching kafka keeps the order
+func (interactor *relayBackendEventsInteractor) relay(ctx context.Context,
+	events []OutboxEvent) bool {
+	errs := interactor.publisher.Publish(ctx, events)
+	held := map[string]bool{}
+	for i, event := range events {
+		if held[event.Key] {
+			continue
+		}
+		if err := errs[i]; err != nil {
+			held[event.Key] = true
+			interactor.logger.LogWarnPublishingEvent(event.ID, event.Attempts+1, err)
+			next := interactor.now().Add(interactor.backoff(event.Attempts + 1))
+			if err := interactor.outboxRepo.MarkFailed(ctx, event.ID, err, next); err != nil {
+				interactor.logger.LogErrorMarkingEvent(event.ID, err)
+			}
+			continue
+		}
+		if err := interactor.outboxRepo.MarkSent(ctx, event.ID); err != nil {
+			held[event.Key] = true
+			interactor.logger.LogErrorMarkingEvent(event.ID, err)
+		}
+	}
+	return len(held) == 0
+}
+
+// backoff returns the time to wait before the next attempt, doubling from
+// one second on each failed attempt
+func (interactor *relayBackendEventsInteractor) backoff(attempts int) time.Duration {
+	backoff := time.Second
+	for i := 1; i < attempts && backoff < interactor.maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > interactor.maxBackoff {
+		return interactor.maxBackoff
+	}
+	return backoff
+}
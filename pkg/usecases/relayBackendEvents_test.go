@@ -0,0 +1,175 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockOutboxRepo struct {
+	mock.Mock
+}
+
+func (m *mockOutboxRepo) GetPendingEvents(ctx context.Context, limit int) ([]OutboxEvent, error) {
+	args := m.Called(ctx, limit)
+	return args.Get(0).([]OutboxEvent), args.Error(1)
+}
+
+func (m *mockOutboxRepo) MarkSent(ctx context.Context, eventID int) error {
+	args := m.Called(ctx, eventID)
+	return args.Error(0)
+}
+
+func (m *mockOutboxRepo) MarkFailed(ctx context.Context, eventID int, cause error, next time.Time) error {
+	args := m.Called(ctx, eventID, cause, next)
+	return args.Error(0)
+}
+
+func (m *mockOutboxRepo) PurgeSent(ctx context.Context, before time.Time) error {
+	args := m.Called(ctx, before)
+	return args.Error(0)
+}
+
+type mockEventsPublisher struct {
+	mock.Mock
+}
+
+func (m *mockEventsPublisher) Publish(ctx context.Context, events []OutboxEvent) []error {
+	args := m.Called(ctx, events)
+	return args.Get(0).([]error)
+}
+
+type mockRelayBackendEventsLogger struct {
+	mock.Mock
+}
+
+func (m *mockRelayBackendEventsLogger) LogErrorGettingPendingEvents(err error) {
+	m.Called(err)
+}
+
+func (m *mockRelayBackendEventsLogger) LogWarnPublishingEvent(eventID int, attempts int, err error) {
+	m.Called(eventID, attempts, err)
+}
+
+func (m *mockRelayBackendEventsLogger) LogErrorMarkingEvent(eventID int, err error) {
+	m.Called(eventID, err)
+}
+
+func (m *mockRelayBackendEventsLogger) LogErrorPurgingEvents(err error) {
+	m.Called(err)
+}
+
+func makeTestRelayBackendEvents(now time.Time, outboxRepo OutboxRepository,
+	publisher EventsPublisher, logger RelayBackendEventsLogger) RelayBackendEventsInteractor {
+	interactor := MakeRelayBackendEventsInteractor(outboxRepo, publisher, logger,
+		3, time.Minute, time.Hour, &mockTracer{})
+	interactor.(*relayBackendEventsInteractor).now = func() time.Time { return now }
+	return interactor
+}
+
+func TestRelayBackendEventsOK(t *testing.T) {
+	mOutboxRepo := &mockOutboxRepo{}
+	mPublisher := &mockEventsPublisher{}
+	mLogger := &mockRelayBackendEventsLogger{}
+	interactor := makeTestRelayBackendEvents(time.Now(), mOutboxRepo, mPublisher, mLogger)
+	first := []OutboxEvent{{ID: 1, Key: "1"}, {ID: 2, Key: "2"}, {ID: 3, Key: "1"}}
+	second := []OutboxEvent{{ID: 4, Key: "3"}}
+	mOutboxRepo.On("GetPendingEvents", mock.Anything, 3).Return(first, nil).Once()
+	mOutboxRepo.On("GetPendingEvents", mock.Anything, 3).Return(second, nil).Once()
+	mPublisher.On("Publish", mock.Anything, first).Return([]error{nil, nil, nil}).Once()
+	mPublisher.On("Publish", mock.Anything, second).Return([]error{nil}).Once()
+	for _, event := range append(first, second...) {
+		mOutboxRepo.On("MarkSent", mock.Anything, event.ID).Return(nil).Once()
+	}
+	err := interactor.RelayBackendEvents(context.Background())
+	assert.NoError(t, err)
+	mOutboxRepo.AssertExpectations(t)
+	mPublisher.AssertExpectations(t)
+	mLogger.AssertExpectations(t)
+}
+
+func TestRelayBackendEventsHoldsKeyOnFailure(t *testing.T) {
+	mOutboxRepo := &mockOutboxRepo{}
+	mPublisher := &mockEventsPublisher{}
+	mLogger := &mockRelayBackendEventsLogger{}
+	now := time.Now()
+	interactor := makeTestRelayBackendEvents(now, mOutboxRepo, mPublisher, mLogger)
+	events := []OutboxEvent{{ID: 1, Key: "1", Attempts: 2}, {ID: 2, Key: "2"},
+		{ID: 3, Key: "1"}}
+	mOutboxRepo.On("GetPendingEvents", mock.Anything, 3).Return(events, nil).Once()
+	// The third event was published, it's sent again after the first one
+	mPublisher.On("Publish", mock.Anything, events).Return([]error{fmt.Errorf("err"), nil, nil})
+	mLogger.On("LogWarnPublishingEvent", 1, 3, mock.Anything)
+	mOutboxRepo.On("MarkFailed", mock.Anything, 1, mock.Anything, now.Add(4*time.Second)).Return(nil)
+	mOutboxRepo.On("MarkSent", mock.Anything, 2).Return(nil)
+	err := interactor.RelayBackendEvents(context.Background())
+	assert.NoError(t, err)
+	mOutboxRepo.AssertExpectations(t)
+	mPublisher.AssertExpectations(t)
+	mLogger.AssertExpectations(t)
+}
+
+func TestRelayBackendEventsMarkError(t *testing.T) {
+	mOutboxRepo := &mockOutboxRepo{}
+	mPublisher := &mockEventsPublisher{}
+	mLogger := &mockRelayBackendEventsLogger{}
+	interactor := makeTestRelayBackendEvents(time.Now(), mOutboxRepo, mPublisher, mLogger)
+	events := []OutboxEvent{{ID: 1, Key: "1"}, {ID: 2, Key: "1"}}
+	mOutboxRepo.On("GetPendingEvents", mock.Anything, 3).Return(events, nil).Once()
+	mPublisher.On("Publish", mock.Anything, events).Return([]error{nil, nil})
+	mOutboxRepo.On("MarkSent", mock.Anything, 1).Return(fmt.Errorf("err"))
+	mLogger.On("LogErrorMarkingEvent", 1, mock.Anything)
+	err := interactor.RelayBackendEvents(context.Background())
+	assert.NoError(t, err)
+	mOutboxRepo.AssertExpectations(t)
+	mPublisher.AssertExpectations(t)
+	mLogger.AssertExpectations(t)
+}
+
+func TestRelayBackendEventsRepoError(t *testing.T) {
+	mOutboxRepo := &mockOutboxRepo{}
+	mPublisher := &mockEventsPublisher{}
+	mLogger := &mockRelayBackendEventsLogger{}
+	interactor := makeTestRelayBackendEvents(time.Now(), mOutboxRepo, mPublisher, mLogger)
+	mOutboxRepo.On("GetPendingEvents", mock.Anything, 3).Return([]OutboxEvent{}, fmt.Errorf("err"))
+	mLogger.On("LogErrorGettingPendingEvents", mock.Anything)
+	err := interactor.RelayBackendEvents(context.Background())
+	assert.Error(t, err)
+	mOutboxRepo.AssertExpectations(t)
+	mLogger.AssertExpectations(t)
+}
+
+func TestRelayBackendEventsBackoff(t *testing.T) {
+	interactor := MakeRelayBackendEventsInteractor(nil, nil, nil, 1, time.Minute, time.Hour, &mockTracer{}).(*relayBackendEventsInteractor)
+	assert.Equal(t, time.Second, interactor.backoff(1))
+	assert.Equal(t, 8*time.Second, interactor.backoff(4))
+	assert.Equal(t, time.Minute, interactor.backoff(100))
+}
+
+func TestPurgeBackendEventsOK(t *testing.T) {
+	mOutboxRepo := &mockOutboxRepo{}
+	mLogger := &mockRelayBackendEventsLogger{}
+	now := time.Now()
+	interactor := makeTestRelayBackendEvents(now, mOutboxRepo, &mockEventsPublisher{}, mLogger)
+	mOutboxRepo.On("PurgeSent", mock.Anything, now.Add(-time.Hour)).Return(nil)
+	err := interactor.PurgeBackendEvents(context.Background())
+	assert.NoError(t, err)
+	mOutboxRepo.AssertExpectations(t)
+	mLogger.AssertExpectations(t)
+}
+
+func TestPurgeBackendEventsError(t *testing.T) {
+	mOutboxRepo := &mockOutboxRepo{}
+	mLogger := &mockRelayBackendEventsLogger{}
+	interactor := makeTestRelayBackendEvents(time.Now(), mOutboxRepo, &mockEventsPublisher{}, mLogger)
+	mOutboxRepo.On("PurgeSent", mock.Anything, mock.Anything).Return(fmt.Errorf("err"))
+	mLogger.On("LogErrorPurgingEvents", mock.Anything)
+	err := interactor.PurgeBackendEvents(context.Background())
+	assert.Error(t, err)
+	mOutboxRepo.AssertExpectations(t)
+	mLogger.AssertExpectations(t)
+}
@@ -0,0 +1,83 @@
+package usecases
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// ReplaySoldProductsInteractor wraps ReplaySoldProducts operations
+type ReplaySoldProductsInteractor interface {
+	ReplaySoldProducts(ctx context.Context, from, to time.Time,
+		dryRun bool) (int, error)
+}
+
+// replaySoldProductsInteractor defines the interactor for
+// replaySoldProducts usecase
+type replaySoldProductsInteractor struct {
+	productRepo       ProductRepository
+	backendEventsRepo BackendEventsRepository
+	logger            ReplaySoldProductsLogger
+	// interval is the minimum time between two events
+	interval time.Duration
+	sleep    func(time.Duration)
+	tracer   Tracer
+}
+
+// ReplaySoldProductsLogger logs ReplaySoldProducts events
+type ReplaySoldProductsLogger interface {
+	LogErrorGettingReport(ctx context.Context, err error)
+	LogReplayingSoldProduct(ctx context.Context, productID int, dryRun bool)
+	LogErrorReplayingSoldProduct(ctx context.Context, productID int, createdAt time.Time, err error)
+}
+
+// MakeReplaySoldProductsInteractor creates a new instance of
+// ReplaySoldProductsInteractor that sends at most rate events per second,
+// a rate of zero doesn't limit them
+func MakeReplaySoldProductsInteractor(productRepo ProductRepository,
+	backendEventsRepo BackendEventsRepository,
+	logger ReplaySoldProductsLogger, rate int,
+	tracer Tracer) ReplaySoldProductsInteractor {
+	interactor := &replaySoldProductsInteractor{productRepo: productRepo,
+		backendEventsRepo: backendEventsRepo, logger: logger, sleep: time.Sleep,
+		tracer: tracer}
+	if rate > 0 {
+		interactor.interval = time.Second / time.Duration(rate)
+	}
+	return interactor
+}
+
+// ReplaySoldProducts sends again the sold event of the products of the
+// report between the given dates, from the oldest to the newest, and returns
+// how many were sent. A dry run only logs the products. It stops on the first
+// failure, so the replay can be resumed from the date of the failed product
+func (interactor *replaySoldProductsInteractor) ReplaySoldProducts(
+	ctx context.Context, from, to time.Time, dryRun bool) (sent int, err error) {
+	ctx, span := interactor.tracer.Start(ctx, "ReplaySoldProducts")
+	defer func() { span.End(err) }()
+	products, err := interactor.productRepo.GetReport(ctx, from, to)
+	if err != nil {
+		interactor.logger.LogErrorGettingReport(ctx, err)
+		return 0, newDatabaseError("error loading report", err)
+	}
+	sort.SliceStable(products, func(i, j int) bool {
+		if products[i].CreatedAt.Equal(products[j].CreatedAt) {
+			return products[i].ID < products[j].ID
+		}
+		return products[i].CreatedAt.Before(products[j].CreatedAt)
+	})
+	for i, product := range products {
+		interactor.logger.LogReplayingSoldProduct(ctx, product.ID, dryRun)
+		if dryRun {
+			continue
+		}
+		if i > 0 && interactor.interval > 0 {
+			interactor.sleep(interactor.interval)
+		}
+		if err := interactor.backendEventsRepo.PushSoldProduct(ctx, product); err != nil {
+			interactor.logger.LogErrorReplayingSoldProduct(ctx, product.ID, product.CreatedAt, err)
+			return i, newEventsError("cannot replay the sold product event", err)
+		}
+	}
+	return len(products), nil
+}
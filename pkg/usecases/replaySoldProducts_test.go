@@ -0,0 +1,110 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/domain"
+)
+
+type mockReplaySoldProductsLogger struct {
+	mock.Mock
+}
+
+func (m *mockReplaySoldProductsLogger) LogErrorGettingReport(ctx context.Context, err error) {
+	m.Called(ctx, err)
+}
+
+func (m *mockReplaySoldProductsLogger) LogReplayingSoldProduct(ctx context.Context, productID int, dryRun bool) {
+	m.Called(ctx, productID, dryRun)
+}
+
+func (m *mockReplaySoldProductsLogger) LogErrorReplayingSoldProduct(ctx context.Context, productID int,
+	createdAt time.Time, err error) {
+	m.Called(ctx, productID, createdAt, err)
+}
+
+func TestReplaySoldProductsOK(t *testing.T) {
+	mProductRepo := &mockProductRepo{}
+	mBackendEventRepo := &mockBackendEventRepo{}
+	mLogger := &mockReplaySoldProductsLogger{}
+	interactor := MakeReplaySoldProductsInteractor(mProductRepo,
+		mBackendEventRepo, mLogger, 4, &mockTracer{}).(*replaySoldProductsInteractor)
+	sleeps := []time.Duration{}
+	interactor.sleep = func(d time.Duration) { sleeps = append(sleeps, d) }
+	products := []domain.Product{{ID: 1}, {ID: 2}}
+	from, to := time.Now().Add(-time.Hour), time.Now()
+	mProductRepo.On("GetReport", mock.Anything, from, to).Return(products, nil)
+	mLogger.On("LogReplayingSoldProduct", mock.Anything, mock.Anything, false)
+	mBackendEventRepo.On("PushSoldProduct", mock.Anything, products[0]).Return(nil)
+	mBackendEventRepo.On("PushSoldProduct", mock.Anything, products[1]).Return(nil)
+	replayed, err := interactor.ReplaySoldProducts(context.Background(), from, to, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, replayed)
+	assert.Equal(t, []time.Duration{250 * time.Millisecond}, sleeps)
+	mProductRepo.AssertExpectations(t)
+	mBackendEventRepo.AssertExpectations(t)
+	mLogger.AssertExpectations(t)
+}
+
+func TestReplaySoldProductsDryRun(t *testing.T) {
+	mProductRepo := &mockProductRepo{}
+	mBackendEventRepo := &mockBackendEventRepo{}
+	mLogger := &mockReplaySoldProductsLogger{}
+	interactor := MakeReplaySoldProductsInteractor(mProductRepo,
+		mBackendEventRepo, mLogger, 0, &mockTracer{})
+	mProductRepo.On("GetReport", mock.Anything, mock.Anything, mock.Anything).
+		Return([]domain.Product{{ID: 1}, {ID: 2}}, nil)
+	mLogger.On("LogReplayingSoldProduct", mock.Anything, mock.Anything, true).Twice()
+	replayed, err := interactor.ReplaySoldProducts(context.Background(), time.Now(), time.Now(), true)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, replayed)
+	mProductRepo.AssertExpectations(t)
+	mBackendEventRepo.AssertExpectations(t)
+	mLogger.AssertExpectations(t)
+}
+
+func TestReplaySoldProductsReportError(t *testing.T) {
+	mProductRepo := &mockProductRepo{}
+	mLogger := &mockReplaySoldProductsLogger{}
+	interactor := MakeReplaySoldProductsInteractor(mProductRepo, nil, mLogger, 0, &mockTracer{})
+	mProductRepo.On("GetReport", mock.Anything, mock.Anything, mock.Anything).
+		Return([]domain.Product{}, fmt.Errorf("err"))
+	mLogger.On("LogErrorGettingReport", mock.Anything, mock.Anything)
+	_, err := interactor.ReplaySoldProducts(context.Background(), time.Now(), time.Now(), false)
+	var domainError *DomainError
+	assert.True(t, errors.As(err, &domainError))
+	assert.Equal(t, DatabaseUnavailableCode, domainError.Code)
+	mProductRepo.AssertExpectations(t)
+	mLogger.AssertExpectations(t)
+}
+
+func TestReplaySoldProductsPushError(t *testing.T) {
+	mProductRepo := &mockProductRepo{}
+	mBackendEventRepo := &mockBackendEventRepo{}
+	mLogger := &mockReplaySoldProductsLogger{}
+	interactor := MakeReplaySoldProductsInteractor(mProductRepo,
+		mBackendEventRepo, mLogger, 0, &mockTracer{})
+	// The report comes newest first, the oldest product is replayed first
+	oldest := domain.Product{ID: 1, CreatedAt: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	newest := domain.Product{ID: 2, CreatedAt: oldest.CreatedAt.Add(time.Hour)}
+	mProductRepo.On("GetReport", mock.Anything, mock.Anything, mock.Anything).
+		Return([]domain.Product{newest, oldest}, nil)
+	mLogger.On("LogReplayingSoldProduct", mock.Anything, 1, false)
+	mLogger.On("LogErrorReplayingSoldProduct", mock.Anything, 1, oldest.CreatedAt, mock.Anything)
+	mBackendEventRepo.On("PushSoldProduct", mock.Anything, oldest).Return(fmt.Errorf("err"))
+	replayed, err := interactor.ReplaySoldProducts(context.Background(), time.Now(), time.Now(), false)
+	var domainError *DomainError
+	assert.True(t, errors.As(err, &domainError))
+	assert.Equal(t, EventsUnavailableCode, domainError.Code)
+	assert.Equal(t, 0, replayed)
+	mProductRepo.AssertExpectations(t)
+	mBackendEventRepo.AssertExpectations(t)
+	mLogger.AssertExpectations(t)
+}
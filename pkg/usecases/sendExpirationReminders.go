@@ -0,0 +1,110 @@
+package usecases
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/domain"
+)
+
+// SendExpirationRemindersInteractor wraps SendExpirationReminders operations
+type SendExpirationRemindersInteractor interface {
+	SendExpirationReminders(ctx context.Context) error
+}
+
+// sendExpirationRemindersInteractor defines the interactor for
+// SendExpirationReminders usecase
+type sendExpirationRemindersInteractor struct {
+	productRepo  ProductRepository
+	transactions TransactionRunner
+	logger       SendExpirationRemindersLogger
+	windows      []time.Duration
+	now          func() time.Time
+	tracer       Tracer
+}
+
+// SendExpirationRemindersLogger logs SendExpirationReminders events
+type SendExpirationRemindersLogger interface {
+	LogErrorGettingExpiringProducts(ctx context.Context, err error)
+	LogWarnSendingReminder(ctx context.Context, productID int, err error)
+}
+
+// MakeSendExpirationRemindersInteractor creates a new instance of
+// SendExpirationRemindersInteractor. Windows are how long before the
+// expiration of a product its reminders are sent
+func MakeSendExpirationRemindersInteractor(
+	productRepo ProductRepository,
+	transactions TransactionRunner,
+	logger SendExpirationRemindersLogger,
+	windows []time.Duration,
+	tracer Tracer,
+) SendExpirationRemindersInteractor {
+	sorted := make([]time.Duration, len(windows))
+	copy(sorted, windows)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return &sendExpirationRemindersInteractor{
+		productRepo:  productRepo,
+		transactions: transactions,
+		logger:       logger,
+		windows:      sorted,
+		now:          time.Now,
+		tracer:       tracer,
+	}
+}
+
+// SendExpirationReminders pushes a reminder event for every active product
+// expiring within a window. Only the narrowest window a product falls in is
+// reminded, so a product found late does not get every wider reminder at once.
+// Reminders are claimed before being sent, so each one is sent only once per
+// expiration date even when many instances run the job
+func (interactor *sendExpirationRemindersInteractor) SendExpirationReminders(
+	ctx context.Context) (err error) {
+	ctx, span := interactor.tracer.Start(ctx, "SendExpirationReminders")
+	defer func() { span.End(err) }()
+	if len(interactor.windows) == 0 {
+		return nil
+	}
+	now := interactor.now()
+	widest := interactor.windows[len(interactor.windows)-1]
+	products, err := interactor.productRepo.GetProductsExpiringBefore(ctx, now.Add(widest))
+	if err != nil {
+		interactor.logger.LogErrorGettingExpiringProducts(ctx, err)
+		return newDatabaseError("error getting expiring products", err)
+	}
+	for _, product := range products {
+		window, ok := interactor.windowOf(product.ExpiredAt.Sub(now))
+		if !ok {
+			continue
+		}
+		if err := interactor.remind(ctx, product, window); err != nil {
+			interactor.logger.LogWarnSendingReminder(ctx, product.ID, err)
+		}
+	}
+	return nil
+}
+
+// windowOf returns the narrowest window that contains the given time left
+func (interactor *sendExpirationRemindersInteractor) windowOf(
+	left time.Duration) (time.Duration, bool) {
+	for _, window := range interactor.windows {
+		if left <= window {
+			return window, true
+		}
+	}
+	return 0, false
+}
+
+// remind claims the reminder and stores its event in the same transaction,
+// so the claim is rolled back when storing fails and retried on the next run
+func (interactor *sendExpirationRemindersInteractor) remind(ctx context.Context,
+	product domain.Product, window time.Duration) error {
+	return runInTransaction(ctx, interactor.transactions, "cannot send the expiration reminder",
+		func(repos TransactionalRepositories) error {
+			claimed, err := repos.Reminders.Claim(ctx, product, window)
+			if err != nil || !claimed {
+				return err
+			}
+			return repos.BackendEvents.PushExpirationReminder(ctx, product, window)
+		})
+}
@@ -0,0 +1,132 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"gitlab.com/yapo_team/legacy/mobile-apps/premium-carousel-api/pkg/domain"
+)
+
+type mockReminderRepo struct {
+	mock.Mock
+}
+
+func (m *mockReminderRepo) Claim(ctx context.Context, product domain.Product, window time.Duration) (bool, error) {
+	args := m.Called(ctx, product, window)
+	return args.Bool(0), args.Error(1)
+}
+
+type mockSendExpirationRemindersLogger struct {
+	mock.Mock
+}
+
+func (m *mockSendExpirationRemindersLogger) LogErrorGettingExpiringProducts(ctx context.Context, err error) {
+	m.Called(ctx, err)
+}
+
+func (m *mockSendExpirationRemindersLogger) LogWarnSendingReminder(ctx context.Context, productID int, err error) {
+	m.Called(ctx, productID, err)
+}
+
+func makeTestSendExpirationReminders(now time.Time, productRepo ProductRepository,
+	reminderRepo ExpirationReminderRepository, eventsRepo BackendEventsRepository,
+	logger SendExpirationRemindersLogger) SendExpirationRemindersInteractor {
+	transactions := makeMockTransactionRunner(nil, nil, eventsRepo)
+	transactions.repos.Reminders = reminderRepo
+	interactor := MakeSendExpirationRemindersInteractor(productRepo, transactions,
+		logger, []time.Duration{24 * time.Hour, time.Hour, 168 * time.Hour}, &mockTracer{})
+	interactor.(*sendExpirationRemindersInteractor).now = func() time.Time { return now }
+	return interactor
+}
+
+func TestSendExpirationRemindersOK(t *testing.T) {
+	mProductRepo := &mockProductRepo{}
+	mReminderRepo := &mockReminderRepo{}
+	mEventsRepo := &mockBackendEventRepo{}
+	mLogger := &mockSendExpirationRemindersLogger{}
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	interactor := makeTestSendExpirationReminders(now, mProductRepo,
+		mReminderRepo, mEventsRepo, mLogger)
+	soon := domain.Product{ID: 1, ExpiredAt: now.Add(30 * time.Minute)}
+	tomorrow := domain.Product{ID: 2, ExpiredAt: now.Add(20 * time.Hour)}
+	nextWeek := domain.Product{ID: 3, ExpiredAt: now.Add(100 * time.Hour)}
+	mProductRepo.On("GetProductsExpiringBefore", mock.Anything, now.Add(168*time.Hour)).
+		Return([]domain.Product{soon, tomorrow, nextWeek}, nil)
+	mReminderRepo.On("Claim", mock.Anything, soon, time.Hour).Return(true, nil)
+	mReminderRepo.On("Claim", mock.Anything, tomorrow, 24*time.Hour).Return(false, nil)
+	mReminderRepo.On("Claim", mock.Anything, nextWeek, 168*time.Hour).Return(true, nil)
+	mEventsRepo.On("PushExpirationReminder", mock.Anything, soon, time.Hour).Return(nil)
+	mEventsRepo.On("PushExpirationReminder", mock.Anything, nextWeek, 168*time.Hour).Return(nil)
+	err := interactor.SendExpirationReminders(context.Background())
+	assert.NoError(t, err)
+	mProductRepo.AssertExpectations(t)
+	mReminderRepo.AssertExpectations(t)
+	mEventsRepo.AssertExpectations(t)
+	mLogger.AssertExpectations(t)
+}
+
+func TestSendExpirationRemindersPushError(t *testing.T) {
+	mProductRepo := &mockProductRepo{}
+	mReminderRepo := &mockReminderRepo{}
+	mEventsRepo := &mockBackendEventRepo{}
+	mLogger := &mockSendExpirationRemindersLogger{}
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	interactor := makeTestSendExpirationReminders(now, mProductRepo,
+		mReminderRepo, mEventsRepo, mLogger)
+	product := domain.Product{ID: 1, ExpiredAt: now.Add(30 * time.Minute)}
+	mProductRepo.On("GetProductsExpiringBefore", mock.Anything, now.Add(168*time.Hour)).
+		Return([]domain.Product{product}, nil)
+	mReminderRepo.On("Claim", mock.Anything, product, time.Hour).Return(true, nil)
+	mEventsRepo.On("PushExpirationReminder", mock.Anything, product, time.Hour).
+		Return(fmt.Errorf("err"))
+	mLogger.On("LogWarnSendingReminder", mock.Anything, 1, mock.Anything)
+	err := interactor.SendExpirationReminders(context.Background())
+	assert.NoError(t, err)
+	mProductRepo.AssertExpectations(t)
+	mReminderRepo.AssertExpectations(t)
+	mEventsRepo.AssertExpectations(t)
+	mLogger.AssertExpectations(t)
+}
+
+func TestSendExpirationRemindersClaimError(t *testing.T) {
+	mProductRepo := &mockProductRepo{}
+	mReminderRepo := &mockReminderRepo{}
+	mEventsRepo := &mockBackendEventRepo{}
+	mLogger := &mockSendExpirationRemindersLogger{}
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	interactor := makeTestSendExpirationReminders(now, mProductRepo,
+		mReminderRepo, mEventsRepo, mLogger)
+	product := domain.Product{ID: 1, ExpiredAt: now.Add(30 * time.Minute)}
+	mProductRepo.On("GetProductsExpiringBefore", mock.Anything, now.Add(168*time.Hour)).
+		Return([]domain.Product{product}, nil)
+	mReminderRepo.On("Claim", mock.Anything, product, time.Hour).Return(false, fmt.Errorf("err"))
+	mLogger.On("LogWarnSendingReminder", mock.Anything, 1, mock.Anything)
+	err := interactor.SendExpirationReminders(context.Background())
+	assert.NoError(t, err)
+	mProductRepo.AssertExpectations(t)
+	mReminderRepo.AssertExpectations(t)
+	mEventsRepo.AssertExpectations(t)
+	mLogger.AssertExpectations(t)
+}
+
+func TestSendExpirationRemindersRepoError(t *testing.T) {
+	mProductRepo := &mockProductRepo{}
+	mReminderRepo := &mockReminderRepo{}
+	mEventsRepo := &mockBackendEventRepo{}
+	mLogger := &mockSendExpirationRemindersLogger{}
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	interactor := makeTestSendExpirationReminders(now, mProductRepo,
+		mReminderRepo, mEventsRepo, mLogger)
+	mProductRepo.On("GetProductsExpiringBefore", mock.Anything, now.Add(168*time.Hour)).
+		Return([]domain.Product{}, fmt.Errorf("err"))
+	mLogger.On("LogErrorGettingExpiringProducts", mock.Anything, mock.Anything)
+	err := interactor.SendExpirationReminders(context.Background())
+	assert.Error(t, err)
+	mProductRepo.AssertExpectations(t)
+	mLogger.AssertExpectations(t)
+}
@@ -1,7 +1,8 @@
 package usecases
 
 import (
-	"fmt"
+	"context"
+	"errors"
 	"strconv"
 	"strings"
 	"time"
@@ -11,58 +12,106 @@ import (
 
 // SetConfigInteractor wraps SetConfig operations
 type SetConfigInteractor interface {
-	SetConfig(userProductID int,
-		config domain.ProductParams, expiredAt time.Time) error
+	SetConfig(ctx context.Context, userProductID int, version int,
+		config domain.ProductParams, expiredAt time.Time) (domain.Product, error)
 }
 
 // setConfigInteractor defines the interactor for setConfig usecase
 type setConfigInteractor struct {
-	productRepo ProductRepository
-	cacheRepo   CacheRepository
-	logger      SetConfigLogger
-	cacheTTL    time.Duration
+	transactions         TransactionRunner
+	cacheRepo            CacheRepository
+	logger               SetConfigLogger
+	settings             SettingsProvider
+	backendEventsEnabled bool
+	tracer               Tracer
 }
 
 // SetConfigLogger logs SetConfig events
 type SetConfigLogger interface {
-	LogErrorSettingConfig(userProductID int, err error)
-	LogWarnSettingCache(userID int, err error)
+	LogErrorSettingConfig(ctx context.Context, userProductID int, err error)
+	LogWarnSettingCache(ctx context.Context, userID int, err error)
 }
 
 // MakeSetConfigInteractor creates a new instance of SetConfigInteractor
-func MakeSetConfigInteractor(productRepo ProductRepository,
+func MakeSetConfigInteractor(transactions TransactionRunner,
 	cacheRepo CacheRepository, logger SetConfigLogger,
-	cacheTTL time.Duration) SetConfigInteractor {
-	return &setConfigInteractor{productRepo: productRepo, cacheRepo: cacheRepo,
-		logger: logger, cacheTTL: cacheTTL}
+	settings SettingsProvider, backendEventsEnabled bool,
+	tracer Tracer) SetConfigInteractor {
+	return &setConfigInteractor{transactions: transactions, cacheRepo: cacheRepo,
+		logger: logger, settings: settings, backendEventsEnabled: backendEventsEnabled,
+		tracer: tracer}
 }
 
-// SetConfig adds user product to repository, also sets cache
-func (interactor *setConfigInteractor) SetConfig(userProductID int,
-	config domain.ProductParams, expiredAt time.Time) error {
-	err := interactor.productRepo.SetExpiration(userProductID, expiredAt)
+// SetConfig adds user product to repository, also sets cache. Returns the
+// updated product. The update is rejected with ErrVersionMismatch when the given version is
+// not the current product version. The changes made are stored as lifecycle
+// events in the same transaction
+func (interactor *setConfigInteractor) SetConfig(ctx context.Context,
+	userProductID int, version int, config domain.ProductParams,
+	expiredAt time.Time) (product domain.Product, err error) {
+	ctx, span := interactor.tracer.Start(ctx, "SetConfig")
+	defer func() { span.End(err) }()
+	err = runInTransaction(ctx, interactor.transactions, "cannot set control-panel configuration",
+		func(repos TransactionalRepositories) error {
+			var before domain.Product
+			var err error
+			if interactor.backendEventsEnabled {
+				if before, err = getProductBeforeUpdate(ctx, repos.Products,
+					userProductID, version); err != nil {
+					return err
+				}
+			}
+			if product, err = interactor.update(ctx, repos.Products, userProductID,
+				version, config, expiredAt); err != nil {
+				return err
+			}
+			if interactor.backendEventsEnabled {
+				return pushProductChanges(ctx, repos.BackendEvents, before, product)
+			}
+			return nil
+		})
+	if errors.Is(err, ErrVersionMismatch) || errors.Is(err, ErrProductNotFound) {
+		return domain.Product{}, err
+	}
+	if err != nil {
+		interactor.logger.LogErrorSettingConfig(ctx, userProductID, err)
+		return domain.Product{}, err
+	}
+	interactor.refreshCache(ctx, product)
+	return product, nil
+}
+
+// update sets the product config and expiration, returning the product updated
+func (interactor *setConfigInteractor) update(ctx context.Context,
+	productRepo ProductRepository, userProductID int, version int,
+	config domain.ProductParams, expiredAt time.Time) (domain.Product, error) {
+	_, err := productRepo.IncrementVersion(ctx, userProductID, version)
+	if errors.Is(err, ErrVersionMismatch) {
+		return domain.Product{}, err
+	}
+	if err != nil {
+		return domain.Product{}, newDatabaseError("cannot set control-panel configuration", err)
+	}
+	err = productRepo.SetExpiration(ctx, userProductID, expiredAt)
 	if err != nil {
-		interactor.logger.LogErrorSettingConfig(userProductID, err)
-		return fmt.Errorf("cannot set control-panel partial configuration: %+v", err)
+		return domain.Product{}, newDatabaseError("cannot set control-panel configuration", err)
 	}
-	err = interactor.productRepo.SetConfig(userProductID, config)
+	err = productRepo.SetConfig(ctx, userProductID, config)
 	if err != nil {
-		interactor.logger.LogErrorSettingConfig(userProductID, err)
-		return fmt.Errorf("cannot set control-panel partial configuration: %+v", err)
+		return domain.Product{}, newDatabaseError("cannot set control-panel configuration", err)
 	}
-	product, err := interactor.productRepo.GetUserProductByID(userProductID)
+	product, err := productRepo.GetUserProductByID(ctx, userProductID)
 	if err != nil {
-		interactor.logger.LogWarnSettingCache(product.UserID, err)
+		return domain.Product{}, newDatabaseError("cannot get control-panel configuration", err)
 	}
-	interactor.refreshCache(product)
-	return nil
+	return product, nil
 }
 
-func (interactor *setConfigInteractor) refreshCache(product domain.Product) {
+func (interactor *setConfigInteractor) refreshCache(ctx context.Context, product domain.Product) {
 	cacheError := interactor.cacheRepo.
-		SetCache(strings.Join([]string{"user", strconv.Itoa(product.UserID), string(product.Type)}, ":"),
-			ProductCacheType, product, interactor.cacheTTL)
+		SetCache(ctx, strings.Join([]string{"user", strconv.Itoa(product.UserID), string(product.Type)}, ":"),
+			ProductCacheType, product, interactor.settings.Settings().CacheTTL)
 	if cacheError != nil {
-		interactor.logger.LogWarnSettingCache(product.UserID, cacheError)
+		interactor.logger.LogWarnSettingCache(ctx, product.UserID, cacheError)
 	}
 }
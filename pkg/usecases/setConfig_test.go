@@ -1,6 +1,8 @@
 package usecases
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"testing"
 	"time"
@@ -15,34 +17,36 @@ type mockSetConfigLogger struct {
 	mock.Mock
 }
 
-func (m *mockSetConfigLogger) LogErrorSettingConfig(userProductID int, err error) {
-	m.Called(userProductID, err)
+func (m *mockSetConfigLogger) LogErrorSettingConfig(ctx context.Context, userProductID int, err error) {
+	m.Called(ctx, userProductID, err)
 }
 
-func (m *mockSetConfigLogger) LogWarnSettingCache(UserID int, err error) {
-	m.Called(UserID, err)
+func (m *mockSetConfigLogger) LogWarnSettingCache(ctx context.Context, UserID int, err error) {
+	m.Called(ctx, UserID, err)
 }
 
 func TestSetConfigOK(t *testing.T) {
+	product := domain.Product{ID: 1, UserID: 123, Type: domain.PremiumCarousel, Version: 3}
 	mProductRepo := &mockProductRepo{}
 	mCacheRepo := &mockCacheRepo{}
 	mLogger := &mockSetConfigLogger{}
-	interactor := MakeSetConfigInteractor(mProductRepo,
-		mCacheRepo, mLogger, time.Hour)
-	mProductRepo.On("SetExpiration", mock.AnythingOfType("int"),
+	interactor := MakeSetConfigInteractor(
+		makeMockTransactionRunner(mProductRepo, nil, nil),
+		mCacheRepo, mLogger, StaticSettings{CacheTTL: time.Hour}, false, &mockTracer{})
+	mProductRepo.On("IncrementVersion", mock.Anything, 1, 2).Return(3, nil)
+	mProductRepo.On("SetExpiration", mock.Anything, mock.AnythingOfType("int"),
 		mock.Anything).Return(nil)
-	mProductRepo.On("SetConfig", mock.AnythingOfType("int"),
+	mProductRepo.On("SetConfig", mock.Anything, mock.AnythingOfType("int"),
 		mock.AnythingOfType("ProductParams")).
 		Return(nil)
-	mProductRepo.On("GetUserProductByID", mock.AnythingOfType("int")).
-		Return(domain.Product{}, nil)
-	mCacheRepo.On("SetCache", mock.AnythingOfType("string"),
-		ProductCacheType,
-		mock.AnythingOfType("Product"),
-		mock.Anything).
+	mProductRepo.On("GetUserProductByID", mock.Anything, mock.AnythingOfType("int")).
+		Return(product, nil)
+	mCacheRepo.On("SetCache", mock.Anything, "user:123:PREMIUM_CAROUSEL",
+		ProductCacheType, product, mock.Anything).
 		Return(nil)
-	err := interactor.SetConfig(1, domain.ProductParams{}, time.Now().Add(time.Hour))
+	updated, err := interactor.SetConfig(context.Background(), 1, 2, domain.ProductParams{}, time.Now().Add(time.Hour))
 	assert.NoError(t, err)
+	assert.Equal(t, product, updated)
 	mCacheRepo.AssertExpectations(t)
 	mLogger.AssertExpectations(t)
 	mProductRepo.AssertExpectations(t)
@@ -52,13 +56,15 @@ func TestSetConfigErrorOnSetExpiration(t *testing.T) {
 	mProductRepo := &mockProductRepo{}
 	mCacheRepo := &mockCacheRepo{}
 	mLogger := &mockSetConfigLogger{}
-	interactor := MakeSetConfigInteractor(mProductRepo,
-		mCacheRepo, mLogger, time.Hour)
-	mProductRepo.On("SetExpiration", mock.AnythingOfType("int"),
+	interactor := MakeSetConfigInteractor(
+		makeMockTransactionRunner(mProductRepo, nil, nil),
+		mCacheRepo, mLogger, StaticSettings{CacheTTL: time.Hour}, false, &mockTracer{})
+	mProductRepo.On("IncrementVersion", mock.Anything, 1, 2).Return(3, nil)
+	mProductRepo.On("SetExpiration", mock.Anything, mock.AnythingOfType("int"),
 		mock.Anything).Return(fmt.Errorf("err"))
-	mLogger.On("LogErrorSettingConfig",
+	mLogger.On("LogErrorSettingConfig", mock.Anything,
 		mock.AnythingOfType("int"), mock.Anything)
-	err := interactor.SetConfig(1, domain.ProductParams{}, time.Now().Add(time.Hour))
+	_, err := interactor.SetConfig(context.Background(), 1, 2, domain.ProductParams{}, time.Now().Add(time.Hour))
 	assert.Error(t, err)
 	mCacheRepo.AssertExpectations(t)
 	mLogger.AssertExpectations(t)
@@ -69,45 +75,124 @@ func TestSetConfigOKErrorOnSetConfig(t *testing.T) {
 	mProductRepo := &mockProductRepo{}
 	mCacheRepo := &mockCacheRepo{}
 	mLogger := &mockSetConfigLogger{}
-	interactor := MakeSetConfigInteractor(mProductRepo,
-		mCacheRepo, mLogger, time.Hour)
-	mProductRepo.On("SetExpiration", mock.AnythingOfType("int"),
+	interactor := MakeSetConfigInteractor(
+		makeMockTransactionRunner(mProductRepo, nil, nil),
+		mCacheRepo, mLogger, StaticSettings{CacheTTL: time.Hour}, false, &mockTracer{})
+	mProductRepo.On("IncrementVersion", mock.Anything, 1, 2).Return(3, nil)
+	mProductRepo.On("SetExpiration", mock.Anything, mock.AnythingOfType("int"),
 		mock.Anything).Return(nil)
-	mProductRepo.On("SetConfig", mock.AnythingOfType("int"),
+	mProductRepo.On("SetConfig", mock.Anything, mock.AnythingOfType("int"),
 		mock.AnythingOfType("ProductParams")).
 		Return(fmt.Errorf("err"))
-	mLogger.On("LogErrorSettingConfig",
+	mLogger.On("LogErrorSettingConfig", mock.Anything,
 		mock.AnythingOfType("int"), mock.Anything)
-	err := interactor.SetConfig(1, domain.ProductParams{}, time.Now().Add(time.Hour))
+	_, err := interactor.SetConfig(context.Background(), 1, 2, domain.ProductParams{}, time.Now().Add(time.Hour))
 	assert.Error(t, err)
 	mCacheRepo.AssertExpectations(t)
 	mLogger.AssertExpectations(t)
 	mProductRepo.AssertExpectations(t)
 }
 
-func TestSetConfigOKErrorOnGetUserProductByID(t *testing.T) {
+func TestSetConfigErrorOnGetUserProductByID(t *testing.T) {
 	mProductRepo := &mockProductRepo{}
 	mCacheRepo := &mockCacheRepo{}
 	mLogger := &mockSetConfigLogger{}
-	interactor := MakeSetConfigInteractor(mProductRepo,
-		mCacheRepo, mLogger, time.Hour)
-	mProductRepo.On("SetExpiration", mock.AnythingOfType("int"),
+	interactor := MakeSetConfigInteractor(
+		makeMockTransactionRunner(mProductRepo, nil, nil),
+		mCacheRepo, mLogger, StaticSettings{CacheTTL: time.Hour}, false, &mockTracer{})
+	mProductRepo.On("IncrementVersion", mock.Anything, 1, 2).Return(3, nil)
+	mProductRepo.On("SetExpiration", mock.Anything, mock.AnythingOfType("int"),
 		mock.Anything).Return(nil)
-	mProductRepo.On("SetConfig", mock.AnythingOfType("int"),
+	mProductRepo.On("SetConfig", mock.Anything, mock.AnythingOfType("int"),
 		mock.AnythingOfType("ProductParams")).
 		Return(nil)
-	mProductRepo.On("GetUserProductByID", mock.AnythingOfType("int")).
+	mProductRepo.On("GetUserProductByID", mock.Anything, mock.AnythingOfType("int")).
 		Return(domain.Product{}, fmt.Errorf("err"))
-	mCacheRepo.On("SetCache", mock.AnythingOfType("string"),
-		ProductCacheType,
-		mock.AnythingOfType("Product"),
-		mock.Anything).
-		Return(fmt.Errorf("err"))
-	mLogger.On("LogWarnSettingCache",
-		mock.Anything, mock.Anything)
-	err := interactor.SetConfig(1, domain.ProductParams{}, time.Now().Add(time.Hour))
+	mLogger.On("LogErrorSettingConfig", mock.Anything, 1, mock.Anything)
+	_, err := interactor.SetConfig(context.Background(), 1, 2, domain.ProductParams{}, time.Now().Add(time.Hour))
+	assert.Error(t, err)
+	mCacheRepo.AssertExpectations(t)
+	mLogger.AssertExpectations(t)
+	mProductRepo.AssertExpectations(t)
+}
+
+func TestSetConfigVersionMismatch(t *testing.T) {
+	mProductRepo := &mockProductRepo{}
+	mCacheRepo := &mockCacheRepo{}
+	mLogger := &mockSetConfigLogger{}
+	interactor := MakeSetConfigInteractor(
+		makeMockTransactionRunner(mProductRepo, nil, nil),
+		mCacheRepo, mLogger, StaticSettings{CacheTTL: time.Hour}, false, &mockTracer{})
+	mProductRepo.On("IncrementVersion", mock.Anything, 1, 2).Return(0, ErrVersionMismatch)
+	_, err := interactor.SetConfig(context.Background(), 1, 2, domain.ProductParams{}, time.Now().Add(time.Hour))
+	assert.Equal(t, ErrVersionMismatch, err)
+	mCacheRepo.AssertExpectations(t)
+	mLogger.AssertExpectations(t)
+	mProductRepo.AssertExpectations(t)
+}
+
+func TestSetConfigErrorIncrementingVersion(t *testing.T) {
+	mProductRepo := &mockProductRepo{}
+	mCacheRepo := &mockCacheRepo{}
+	mLogger := &mockSetConfigLogger{}
+	interactor := MakeSetConfigInteractor(
+		makeMockTransactionRunner(mProductRepo, nil, nil),
+		mCacheRepo, mLogger, StaticSettings{CacheTTL: time.Hour}, false, &mockTracer{})
+	mProductRepo.On("IncrementVersion", mock.Anything, 1, 2).Return(0, fmt.Errorf("err"))
+	mLogger.On("LogErrorSettingConfig", mock.Anything, 1, mock.Anything)
+	_, err := interactor.SetConfig(context.Background(), 1, 2, domain.ProductParams{}, time.Now().Add(time.Hour))
+	assert.Error(t, err)
+	mCacheRepo.AssertExpectations(t)
+	mLogger.AssertExpectations(t)
+	mProductRepo.AssertExpectations(t)
+}
+
+func TestSetConfigPushesEvents(t *testing.T) {
+	now := time.Now()
+	before := domain.Product{ID: 1, UserID: 123, Type: domain.PremiumCarousel,
+		Status: domain.ActiveProduct, ExpiredAt: now, Version: 2,
+		Config: domain.ProductParams{Limit: 10}}
+	after := before
+	after.Version = 3
+	after.ExpiredAt = now.Add(time.Hour)
+	after.Config = domain.ProductParams{Limit: 20}
+	mProductRepo := &mockProductRepo{}
+	mCacheRepo := &mockCacheRepo{}
+	mLogger := &mockSetConfigLogger{}
+	mBackendEventRepo := &mockBackendEventRepo{}
+	interactor := MakeSetConfigInteractor(
+		makeMockTransactionRunner(mProductRepo, nil, mBackendEventRepo),
+		mCacheRepo, mLogger, StaticSettings{CacheTTL: time.Hour}, true, &mockTracer{})
+	mProductRepo.On("GetUserProductByID", mock.Anything, 1).Return(before, nil).Once()
+	mProductRepo.On("IncrementVersion", mock.Anything, 1, 2).Return(3, nil)
+	mProductRepo.On("SetExpiration", mock.Anything, 1, after.ExpiredAt).Return(nil)
+	mProductRepo.On("SetConfig", mock.Anything, 1, after.Config).Return(nil)
+	mProductRepo.On("GetUserProductByID", mock.Anything, 1).Return(after, nil).Once()
+	mCacheRepo.On("SetCache", mock.Anything, "user:123:PREMIUM_CAROUSEL",
+		ProductCacheType, after, time.Hour).Return(nil)
+	mBackendEventRepo.On("PushExtension", mock.Anything, after, now).Return(nil)
+	mBackendEventRepo.On("PushConfigChange", mock.Anything, after, before.Config).Return(nil)
+	updated, err := interactor.SetConfig(context.Background(), 1, 2, after.Config, after.ExpiredAt)
 	assert.NoError(t, err)
+	assert.Equal(t, after, updated)
+	mProductRepo.AssertExpectations(t)
 	mCacheRepo.AssertExpectations(t)
 	mLogger.AssertExpectations(t)
+	mBackendEventRepo.AssertExpectations(t)
+}
+
+func TestSetConfigPushingEventsVersionMismatch(t *testing.T) {
+	mProductRepo := &mockProductRepo{}
+	mCacheRepo := &mockCacheRepo{}
+	mLogger := &mockSetConfigLogger{}
+	mBackendEventRepo := &mockBackendEventRepo{}
+	interactor := MakeSetConfigInteractor(
+		makeMockTransactionRunner(mProductRepo, nil, mBackendEventRepo),
+		mCacheRepo, mLogger, StaticSettings{CacheTTL: time.Hour}, true, &mockTracer{})
+	mProductRepo.On("GetUserProductByID", mock.Anything, 1).
+		Return(domain.Product{ID: 1, Version: 5}, nil)
+	_, err := interactor.SetConfig(context.Background(), 1, 2, domain.ProductParams{}, time.Now())
+	assert.True(t, errors.Is(err, ErrVersionMismatch))
 	mProductRepo.AssertExpectations(t)
+	mBackendEventRepo.AssertExpectations(t)
 }
@@ -1,7 +1,8 @@
 package usecases
 
 import (
-	"fmt"
+	"context"
+	"errors"
 	"strconv"
 	"strings"
 	"time"
@@ -11,54 +12,117 @@ import (
 
 // SetPartialConfigInteractor wraps SetPartialConfig operations
 type SetPartialConfigInteractor interface {
-	SetPartialConfig(userProductID int,
-		configMap map[string]interface{}) error
+	SetPartialConfig(ctx context.Context, userProductID int, version int,
+		patch ProductPatch) (domain.Product, error)
+}
+
+// ProductPatch holds a partial update over a product following JSON merge
+// patch semantics (RFC 7396). Nil members are left untouched while non nil
+// members replace the current value
+type ProductPatch struct {
+	Status             *domain.ProductStatus
+	ExpiredAt          *time.Time
+	Categories         *[]int
+	Exclude            *[]string
+	Keywords           *[]string
+	Limit              *int
+	PriceRange         *int
+	FillGapsWithRandom *bool
+	Comment            *string
 }
 
 // setPartialConfigInteractor defines the interactor for setPartialConfig usecase
 type setPartialConfigInteractor struct {
-	productRepo ProductRepository
-	cacheRepo   CacheRepository
-	logger      SetPartialConfigLogger
-	cacheTTL    time.Duration
+	transactions         TransactionRunner
+	cacheRepo            CacheRepository
+	logger               SetPartialConfigLogger
+	settings             SettingsProvider
+	backendEventsEnabled bool
+	tracer               Tracer
 }
 
 // SetPartialConfigLogger logs SetPartialConfig events
 type SetPartialConfigLogger interface {
-	LogErrorSettingPartialConfig(userProductID int, err error)
-	LogWarnSettingCache(userID int, err error)
+	LogErrorSettingPartialConfig(ctx context.Context, userProductID int, err error)
+	LogWarnSettingCache(ctx context.Context, userID int, err error)
 }
 
 // MakeSetPartialConfigInteractor creates a new instance of SetPartialConfigInteractor
-func MakeSetPartialConfigInteractor(productRepo ProductRepository,
+func MakeSetPartialConfigInteractor(transactions TransactionRunner,
 	cacheRepo CacheRepository, logger SetPartialConfigLogger,
-	cacheTTL time.Duration) SetPartialConfigInteractor {
-	return &setPartialConfigInteractor{productRepo: productRepo, cacheRepo: cacheRepo,
-		logger: logger, cacheTTL: cacheTTL}
+	settings SettingsProvider, backendEventsEnabled bool,
+	tracer Tracer) SetPartialConfigInteractor {
+	return &setPartialConfigInteractor{transactions: transactions, cacheRepo: cacheRepo,
+		logger: logger, settings: settings, backendEventsEnabled: backendEventsEnabled,
+		tracer: tracer}
+}
+
+// SetPartialConfig sets partial configuration to userProduct also sets cache.
+// Returns the updated product. The update is rejected with ErrVersionMismatch when the given version is
+// not the current product version. The changes made are stored as lifecycle
+// events in the same transaction
+func (interactor *setPartialConfigInteractor) SetPartialConfig(ctx context.Context,
+	userProductID int, version int, patch ProductPatch) (product domain.Product, err error) {
+	ctx, span := interactor.tracer.Start(ctx, "SetPartialConfig")
+	defer func() { span.End(err) }()
+	err = runInTransaction(ctx, interactor.transactions, "cannot set control-panel partial configuration",
+		func(repos TransactionalRepositories) error {
+			var before domain.Product
+			var err error
+			if interactor.backendEventsEnabled {
+				if before, err = getProductBeforeUpdate(ctx, repos.Products,
+					userProductID, version); err != nil {
+					return err
+				}
+			}
+			if product, err = interactor.update(ctx, repos.Products, userProductID,
+				version, patch); err != nil {
+				return err
+			}
+			if interactor.backendEventsEnabled {
+				return pushProductChanges(ctx, repos.BackendEvents, before, product)
+			}
+			return nil
+		})
+	if errors.Is(err, ErrVersionMismatch) || errors.Is(err, ErrProductNotFound) {
+		return domain.Product{}, err
+	}
+	if err != nil {
+		interactor.logger.LogErrorSettingPartialConfig(ctx, userProductID, err)
+		return domain.Product{}, err
+	}
+	interactor.refreshCache(ctx, product)
+	return product, nil
 }
 
-// SetPartialConfig sets partial configuration to userProduct also sets cache
-func (interactor *setPartialConfigInteractor) SetPartialConfig(userProductID int,
-	configMap map[string]interface{}) error {
-	err := interactor.productRepo.SetPartialConfig(userProductID, configMap)
+// update applies the patch to the product, returning the product updated
+func (interactor *setPartialConfigInteractor) update(ctx context.Context,
+	productRepo ProductRepository, userProductID int, version int,
+	patch ProductPatch) (domain.Product, error) {
+	_, err := productRepo.IncrementVersion(ctx, userProductID, version)
+	if errors.Is(err, ErrVersionMismatch) {
+		return domain.Product{}, err
+	}
+	if err != nil {
+		return domain.Product{}, newDatabaseError("cannot set control-panel partial configuration", err)
+	}
+	err = productRepo.SetPartialConfig(ctx, userProductID, patch)
 	if err != nil {
-		interactor.logger.LogErrorSettingPartialConfig(userProductID, err)
-		return fmt.Errorf("cannot set control-panel partial configuration: %+v", err)
+		return domain.Product{}, newDatabaseError("cannot set control-panel partial configuration", err)
 	}
-	product, err := interactor.productRepo.GetUserProductByID(userProductID)
+	product, err := productRepo.GetUserProductByID(ctx, userProductID)
 	if err != nil {
-		interactor.logger.LogWarnSettingCache(product.UserID, err)
-		return err
+		return domain.Product{}, newDatabaseError("cannot get control-panel configuration", err)
 	}
-	interactor.refreshCache(product)
-	return nil
+	return product, nil
 }
 
-func (interactor *setPartialConfigInteractor) refreshCache(product domain.Product) {
+func (interactor *setPartialConfigInteractor) refreshCache(ctx context.Context, product domain.Product) {
 	cacheError := interactor.cacheRepo.
-		SetCache(strings.Join([]string{"user", strconv.Itoa(product.UserID),
-			string(product.Type)}, ":"), ProductCacheType, product, interactor.cacheTTL)
+		SetCache(ctx, strings.Join([]string{"user", strconv.Itoa(product.UserID),
+			string(product.Type)}, ":"), ProductCacheType, product,
+			interactor.settings.Settings().CacheTTL)
 	if cacheError != nil {
-		interactor.logger.LogWarnSettingCache(product.UserID, cacheError)
+		interactor.logger.LogWarnSettingCache(ctx, product.UserID, cacheError)
 	}
 }
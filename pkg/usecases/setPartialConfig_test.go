@@ -1,6 +1,7 @@
 package usecases
 
 import (
+	"context"
 	"fmt"
 	"testing"
 	"time"
@@ -15,31 +16,33 @@ type mockSetPartialConfigLogger struct {
 	mock.Mock
 }
 
-func (m *mockSetPartialConfigLogger) LogErrorSettingPartialConfig(userProductID int, err error) {
-	m.Called(userProductID, err)
+func (m *mockSetPartialConfigLogger) LogErrorSettingPartialConfig(ctx context.Context, userProductID int, err error) {
+	m.Called(ctx, userProductID, err)
 }
 
-func (m *mockSetPartialConfigLogger) LogWarnSettingCache(UserID int, err error) {
-	m.Called(UserID, err)
+func (m *mockSetPartialConfigLogger) LogWarnSettingCache(ctx context.Context, UserID int, err error) {
+	m.Called(ctx, UserID, err)
 }
 
 func TestSetPartialConfigOK(t *testing.T) {
+	product := domain.Product{ID: 1, UserID: 123, Type: domain.PremiumCarousel, Version: 3}
 	mProductRepo := &mockProductRepo{}
 	mCacheRepo := &mockCacheRepo{}
 	mLogger := &mockSetPartialConfigLogger{}
-	interactor := MakeSetPartialConfigInteractor(mProductRepo,
-		mCacheRepo, mLogger, time.Hour)
-	mProductRepo.On("SetPartialConfig", mock.AnythingOfType("int"),
+	interactor := MakeSetPartialConfigInteractor(
+		makeMockTransactionRunner(mProductRepo, nil, nil),
+		mCacheRepo, mLogger, StaticSettings{CacheTTL: time.Hour}, false, &mockTracer{})
+	mProductRepo.On("IncrementVersion", mock.Anything, 1, 2).Return(3, nil)
+	mProductRepo.On("SetPartialConfig", mock.Anything, mock.AnythingOfType("int"),
 		mock.Anything).Return(nil)
-	mProductRepo.On("GetUserProductByID", mock.AnythingOfType("int")).
-		Return(domain.Product{}, nil)
-	mCacheRepo.On("SetCache", mock.AnythingOfType("string"),
-		ProductCacheType,
-		mock.AnythingOfType("Product"),
-		mock.Anything).
+	mProductRepo.On("GetUserProductByID", mock.Anything, mock.AnythingOfType("int")).
+		Return(product, nil)
+	mCacheRepo.On("SetCache", mock.Anything, "user:123:PREMIUM_CAROUSEL",
+		ProductCacheType, product, mock.Anything).
 		Return(nil)
-	err := interactor.SetPartialConfig(1, map[string]interface{}{})
+	updated, err := interactor.SetPartialConfig(context.Background(), 1, 2, ProductPatch{})
 	assert.NoError(t, err)
+	assert.Equal(t, product, updated)
 	mCacheRepo.AssertExpectations(t)
 	mLogger.AssertExpectations(t)
 	mProductRepo.AssertExpectations(t)
@@ -49,13 +52,15 @@ func TestSetPartialConfigErrorSettingPartialConfig(t *testing.T) {
 	mProductRepo := &mockProductRepo{}
 	mCacheRepo := &mockCacheRepo{}
 	mLogger := &mockSetPartialConfigLogger{}
-	interactor := MakeSetPartialConfigInteractor(mProductRepo,
-		mCacheRepo, mLogger, time.Hour)
-	mProductRepo.On("SetPartialConfig", mock.AnythingOfType("int"),
+	interactor := MakeSetPartialConfigInteractor(
+		makeMockTransactionRunner(mProductRepo, nil, nil),
+		mCacheRepo, mLogger, StaticSettings{CacheTTL: time.Hour}, false, &mockTracer{})
+	mProductRepo.On("IncrementVersion", mock.Anything, 1, 2).Return(3, nil)
+	mProductRepo.On("SetPartialConfig", mock.Anything, mock.AnythingOfType("int"),
 		mock.Anything).Return(fmt.Errorf("err"))
-	mLogger.On("LogErrorSettingPartialConfig", mock.AnythingOfType("int"),
+	mLogger.On("LogErrorSettingPartialConfig", mock.Anything, mock.AnythingOfType("int"),
 		mock.Anything)
-	err := interactor.SetPartialConfig(1, map[string]interface{}{})
+	_, err := interactor.SetPartialConfig(context.Background(), 1, 2, ProductPatch{})
 	assert.Error(t, err)
 	mCacheRepo.AssertExpectations(t)
 	mLogger.AssertExpectations(t)
@@ -66,15 +71,16 @@ func TestSetPartialConfigErrorGettingProduct(t *testing.T) {
 	mProductRepo := &mockProductRepo{}
 	mCacheRepo := &mockCacheRepo{}
 	mLogger := &mockSetPartialConfigLogger{}
-	interactor := MakeSetPartialConfigInteractor(mProductRepo,
-		mCacheRepo, mLogger, time.Hour)
-	mLogger.On("LogWarnSettingCache", mock.Anything,
-		mock.Anything)
-	mProductRepo.On("SetPartialConfig", mock.AnythingOfType("int"),
+	interactor := MakeSetPartialConfigInteractor(
+		makeMockTransactionRunner(mProductRepo, nil, nil),
+		mCacheRepo, mLogger, StaticSettings{CacheTTL: time.Hour}, false, &mockTracer{})
+	mLogger.On("LogErrorSettingPartialConfig", mock.Anything, 1, mock.Anything)
+	mProductRepo.On("IncrementVersion", mock.Anything, 1, 2).Return(3, nil)
+	mProductRepo.On("SetPartialConfig", mock.Anything, mock.AnythingOfType("int"),
 		mock.Anything).Return(nil)
-	mProductRepo.On("GetUserProductByID", mock.AnythingOfType("int")).
+	mProductRepo.On("GetUserProductByID", mock.Anything, mock.AnythingOfType("int")).
 		Return(domain.Product{}, fmt.Errorf("err"))
-	err := interactor.SetPartialConfig(1, map[string]interface{}{})
+	_, err := interactor.SetPartialConfig(context.Background(), 1, 2, ProductPatch{})
 	assert.Error(t, err)
 	mCacheRepo.AssertExpectations(t)
 	mLogger.AssertExpectations(t)
@@ -85,22 +91,87 @@ func TestSetPartialConfigErrorSettingCache(t *testing.T) {
 	mProductRepo := &mockProductRepo{}
 	mCacheRepo := &mockCacheRepo{}
 	mLogger := &mockSetPartialConfigLogger{}
-	interactor := MakeSetPartialConfigInteractor(mProductRepo,
-		mCacheRepo, mLogger, time.Hour)
-	mProductRepo.On("SetPartialConfig", mock.AnythingOfType("int"),
+	interactor := MakeSetPartialConfigInteractor(
+		makeMockTransactionRunner(mProductRepo, nil, nil),
+		mCacheRepo, mLogger, StaticSettings{CacheTTL: time.Hour}, false, &mockTracer{})
+	mProductRepo.On("IncrementVersion", mock.Anything, 1, 2).Return(3, nil)
+	mProductRepo.On("SetPartialConfig", mock.Anything, mock.AnythingOfType("int"),
 		mock.Anything).Return(nil)
-	mProductRepo.On("GetUserProductByID", mock.AnythingOfType("int")).
+	mProductRepo.On("GetUserProductByID", mock.Anything, mock.AnythingOfType("int")).
 		Return(domain.Product{}, nil)
-	mCacheRepo.On("SetCache", mock.AnythingOfType("string"),
+	mCacheRepo.On("SetCache", mock.Anything, mock.AnythingOfType("string"),
 		ProductCacheType,
 		mock.AnythingOfType("Product"),
 		mock.Anything).
 		Return(fmt.Errorf("err"))
-	mLogger.On("LogWarnSettingCache", mock.Anything,
+	mLogger.On("LogWarnSettingCache", mock.Anything, mock.Anything,
 		mock.Anything)
-	err := interactor.SetPartialConfig(1, map[string]interface{}{})
+	_, err := interactor.SetPartialConfig(context.Background(), 1, 2, ProductPatch{})
 	assert.NoError(t, err)
 	mCacheRepo.AssertExpectations(t)
 	mLogger.AssertExpectations(t)
 	mProductRepo.AssertExpectations(t)
 }
+
+func TestSetPartialConfigVersionMismatch(t *testing.T) {
+	mProductRepo := &mockProductRepo{}
+	mCacheRepo := &mockCacheRepo{}
+	mLogger := &mockSetPartialConfigLogger{}
+	interactor := MakeSetPartialConfigInteractor(
+		makeMockTransactionRunner(mProductRepo, nil, nil),
+		mCacheRepo, mLogger, StaticSettings{CacheTTL: time.Hour}, false, &mockTracer{})
+	mProductRepo.On("IncrementVersion", mock.Anything, 1, 2).Return(0, ErrVersionMismatch)
+	_, err := interactor.SetPartialConfig(context.Background(), 1, 2, ProductPatch{})
+	assert.Equal(t, ErrVersionMismatch, err)
+	mCacheRepo.AssertExpectations(t)
+	mLogger.AssertExpectations(t)
+	mProductRepo.AssertExpectations(t)
+}
+
+func TestSetPartialConfigErrorIncrementingVersion(t *testing.T) {
+	mProductRepo := &mockProductRepo{}
+	mCacheRepo := &mockCacheRepo{}
+	mLogger := &mockSetPartialConfigLogger{}
+	interactor := MakeSetPartialConfigInteractor(
+		makeMockTransactionRunner(mProductRepo, nil, nil),
+		mCacheRepo, mLogger, StaticSettings{CacheTTL: time.Hour}, false, &mockTracer{})
+	mProductRepo.On("IncrementVersion", mock.Anything, 1, 2).Return(0, fmt.Errorf("err"))
+	mLogger.On("LogErrorSettingPartialConfig", mock.Anything, 1, mock.Anything)
+	_, err := interactor.SetPartialConfig(context.Background(), 1, 2, ProductPatch{})
+	assert.Error(t, err)
+	mCacheRepo.AssertExpectations(t)
+	mLogger.AssertExpectations(t)
+	mProductRepo.AssertExpectations(t)
+}
+
+func TestSetPartialConfigPushesCancellation(t *testing.T) {
+	before := domain.Product{ID: 1, UserID: 123, Type: domain.PremiumCarousel,
+		Status: domain.ActiveProduct, Version: 2}
+	after := before
+	after.Status = domain.InactiveProduct
+	after.Version = 3
+	status := domain.InactiveProduct
+	patch := ProductPatch{Status: &status}
+	mProductRepo := &mockProductRepo{}
+	mCacheRepo := &mockCacheRepo{}
+	mLogger := &mockSetPartialConfigLogger{}
+	mBackendEventRepo := &mockBackendEventRepo{}
+	interactor := MakeSetPartialConfigInteractor(
+		makeMockTransactionRunner(mProductRepo, nil, mBackendEventRepo),
+		mCacheRepo, mLogger, StaticSettings{CacheTTL: time.Hour}, true, &mockTracer{})
+	mProductRepo.On("GetUserProductByID", mock.Anything, 1).Return(before, nil).Once()
+	mProductRepo.On("IncrementVersion", mock.Anything, 1, 2).Return(3, nil)
+	mProductRepo.On("SetPartialConfig", mock.Anything, 1, patch).Return(nil)
+	mProductRepo.On("GetUserProductByID", mock.Anything, 1).Return(after, nil).Once()
+	mCacheRepo.On("SetCache", mock.Anything, "user:123:PREMIUM_CAROUSEL",
+		ProductCacheType, after, time.Hour).Return(nil)
+	mBackendEventRepo.On("PushCancellation", mock.Anything, after, domain.ActiveProduct).
+		Return(nil)
+	updated, err := interactor.SetPartialConfig(context.Background(), 1, 2, patch)
+	assert.NoError(t, err)
+	assert.Equal(t, after, updated)
+	mProductRepo.AssertExpectations(t)
+	mCacheRepo.AssertExpectations(t)
+	mLogger.AssertExpectations(t)
+	mBackendEventRepo.AssertExpectations(t)
+}
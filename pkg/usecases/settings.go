@@ -0,0 +1,32 @@
+package usecases
+
+import (
+	"time"
+)
+
+// Settings holds the settings that may be reloaded while the service runs
+type Settings struct {
+	// CacheTTL is how long the products and ads are kept in the cache
+	CacheTTL time.Duration
+	// MinAdsToDisplay is the least ads a carousel needs to be served
+	MinAdsToDisplay int
+	// MaxAdsToDisplay caps the ads of a carousel
+	MaxAdsToDisplay int
+	// CurrencySymbol and UnitOfAccountSymbol are shown next to the prices
+	CurrencySymbol      string
+	UnitOfAccountSymbol string
+}
+
+// SettingsProvider gives the settings in force. Each call returns a
+// consistent snapshot, the settings may change between calls
+type SettingsProvider interface {
+	Settings() Settings
+}
+
+// StaticSettings provides settings that never change
+type StaticSettings Settings
+
+// Settings returns the settings
+func (s StaticSettings) Settings() Settings {
+	return Settings(s)
+}
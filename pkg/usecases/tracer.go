@@ -0,0 +1,19 @@
+package usecases
+
+import (
+	"context"
+)
+
+// Tracer starts the spans timing the usecases, so the time of a request can
+// be followed through every usecase and repository it calls
+type Tracer interface {
+	// Start starts a span for the named operation as a child of the span
+	// held by ctx, if any, and returns a copy of ctx holding the new span
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is an operation being timed by a Tracer
+type Span interface {
+	// End ends the span, marking it as failed when err is not nil
+	End(err error)
+}
@@ -0,0 +1,28 @@
+package usecases
+
+import (
+	"context"
+)
+
+// mockTracer records the spans started through it
+type mockTracer struct {
+	spans []*mockSpan
+}
+
+func (m *mockTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	span := &mockSpan{name: name}
+	m.spans = append(m.spans, span)
+	return ctx, span
+}
+
+// mockSpan records how a span ended
+type mockSpan struct {
+	name  string
+	ended bool
+	err   error
+}
+
+func (m *mockSpan) End(err error) {
+	m.ended = true
+	m.err = err
+}
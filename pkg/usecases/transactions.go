@@ -0,0 +1,19 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+)
+
+// runInTransaction runs the operation through the runner. Failures that do
+// not tell their kind already, such as those starting or committing the
+// transaction, are reported as database failures
+func runInTransaction(ctx context.Context, runner TransactionRunner, message string,
+	operation func(repos TransactionalRepositories) error) error {
+	err := runner.RunInTransaction(ctx, operation)
+	var domainErr *DomainError
+	if err != nil && !errors.As(err, &domainErr) {
+		return newDatabaseError(message, err)
+	}
+	return err
+}